@@ -19,6 +19,25 @@ var (
 	Redis                string
 	RedisKeyPrefix       string
 	ConfigFilePath       string
+	BatchConcurrency     int64
+	BatchMaxRunning      int64
+	// DefaultCurrency is the currency of the prices and the groups without
+	// a currency
+	DefaultCurrency string
+	// MCPStdioEnabled allows the gateway to run the commands of the stdio
+	// mcps
+	MCPStdioEnabled bool
+	// MCPStdioDir is the directory of the files of the stdio mcps, each
+	// group has its own directory in it
+	MCPStdioDir string
+	// MCPSamplingModel is the model of the sampling requests of the mcps
+	// without a model hint the group can use
+	MCPSamplingModel string
+	// MCPSamplingMaxTokens caps the max tokens of a sampling request
+	MCPSamplingMaxTokens int64
+	// MCPSamplingMaxRequests caps the sampling requests of a connection to
+	// an mcp
+	MCPSamplingMaxRequests int64
 )
 
 func ReloadEnv() {
@@ -34,6 +53,14 @@ func ReloadEnv() {
 	Redis = env.String("REDIS", os.Getenv("REDIS_CONN_STRING"))
 	RedisKeyPrefix = os.Getenv("REDIS_KEY_PREFIX")
 	ConfigFilePath = env.String("CONFIG_FILE_PATH", "./config.yaml")
+	BatchConcurrency = env.Int64("BATCH_CONCURRENCY", 8)
+	BatchMaxRunning = env.Int64("BATCH_MAX_RUNNING", 4)
+	DefaultCurrency = env.String("DEFAULT_CURRENCY", "USD")
+	MCPStdioEnabled = env.Bool("MCP_STDIO_ENABLED", false)
+	MCPStdioDir = env.String("MCP_STDIO_DIR", "./mcp-stdio")
+	MCPSamplingModel = os.Getenv("MCP_SAMPLING_MODEL")
+	MCPSamplingMaxTokens = env.Int64("MCP_SAMPLING_MAX_TOKENS", 4096)
+	MCPSamplingMaxRequests = env.Int64("MCP_SAMPLING_MAX_REQUESTS", 10)
 }
 
 func init() {
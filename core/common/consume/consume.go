@@ -4,9 +4,11 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/wavespeed/llm-server/core/common/balance"
+	"github.com/wavespeed/llm-server/core/common/hold"
 	"github.com/wavespeed/llm-server/core/common/notify"
 	"github.com/wavespeed/llm-server/core/model"
 	"github.com/wavespeed/llm-server/core/relay/meta"
@@ -15,12 +17,35 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var consumeWaitGroup sync.WaitGroup
+var (
+	consumeWaitGroup sync.WaitGroup
+	pendingConsumes  atomic.Int64
+)
 
 func Wait() {
 	consumeWaitGroup.Wait()
 }
 
+// Pending returns the number of the consumes which are not recorded yet
+func Pending() int64 {
+	return pendingConsumes.Load()
+}
+
+// MetaHold is the *hold.Hold of the request, a reference of it is released
+// after the consume is recorded
+const MetaHold = "consume_hold"
+
+func getHold(meta *meta.Meta) *hold.Hold {
+	v, ok := meta.Get(MetaHold)
+	if !ok {
+		return nil
+	}
+
+	h, _ := v.(*hold.Hold)
+
+	return h
+}
+
 func AsyncConsume(
 	postGroupConsumer balance.PostGroupConsumer,
 	code int,
@@ -38,38 +63,50 @@ func AsyncConsume(
 	user string,
 	metadata map[string]string,
 ) {
-	if !checkNeedRecordConsume(code, meta) {
+	h := getHold(meta)
+
+	if !checkNeedRecordConsume(code, meta, usage) {
+		h.Release()
 		return
 	}
 
 	consumeWaitGroup.Add(1)
-	defer func() {
-		consumeWaitGroup.Done()
-
-		if r := recover(); r != nil {
-			log.Errorf("panic in consume: %v", r)
-		}
+	pendingConsumes.Add(1)
+
+	now := time.Now()
+
+	go func() {
+		defer func() {
+			// released after the amount is posted to the balance
+			h.Release()
+			pendingConsumes.Add(-1)
+			consumeWaitGroup.Done()
+
+			if r := recover(); r != nil {
+				log.Errorf("panic in consume: %v", r)
+			}
+		}()
+
+		Consume(
+			context.Background(),
+			now,
+			postGroupConsumer,
+			firstByteAt,
+			upstreamRequestAt,
+			upstreamResponseAt,
+			code,
+			meta,
+			usage,
+			modelPrice,
+			content,
+			ip,
+			retryTimes,
+			requestDetail,
+			downstreamResult,
+			user,
+			metadata,
+		)
 	}()
-
-	go Consume(
-		context.Background(),
-		time.Now(),
-		postGroupConsumer,
-		firstByteAt,
-		upstreamRequestAt,
-		upstreamResponseAt,
-		code,
-		meta,
-		usage,
-		modelPrice,
-		content,
-		ip,
-		retryTimes,
-		requestDetail,
-		downstreamResult,
-		user,
-		metadata,
-	)
 }
 
 func Consume(
@@ -91,13 +128,31 @@ func Consume(
 	user string,
 	metadata map[string]string,
 ) {
-	if !checkNeedRecordConsume(code, meta) {
+	if !checkNeedRecordConsume(code, meta, usage) {
 		return
 	}
 
-	amount := CalculateAmount(code, usage, modelPrice)
+	originalAmount := CalculateAmount(code, usage, modelPrice)
+	amount := ConvertAmount(originalAmount, modelPrice, meta)
+	costAmount := CalculateCost(code, usage, meta)
 	amount = consumeAmount(ctx, amount, postGroupConsumer, meta)
 
+	if err := model.ChargeBudgets(meta.Group.ID, meta.Token.Name, user, amount); err != nil {
+		log.Error("error charge budgets: " + err.Error())
+		notify.ErrorThrottle("chargeBudgets", time.Minute*5, "charge budgets failed", err.Error())
+	}
+
+	observeMetrics(
+		meta,
+		code,
+		firstByteAt,
+		upstreamRequestAt,
+		upstreamResponseAt,
+		usage,
+		amount,
+		downstreamResult,
+	)
+
 	selectedModelPrice := modelPrice.SelectConditionalPrice(usage)
 	selectedModelPrice.ConditionalPrices = nil
 
@@ -114,6 +169,8 @@ func Consume(
 		ip,
 		requestDetail,
 		amount,
+		originalAmount,
+		costAmount,
 		retryTimes,
 		downstreamResult,
 		user,
@@ -133,7 +190,20 @@ func Summary(
 	modelPrice model.Price,
 	downstreamResult bool,
 ) {
-	amount := CalculateAmount(code, usage, modelPrice)
+	originalAmount := CalculateAmount(code, usage, modelPrice)
+	amount := ConvertAmount(originalAmount, modelPrice, meta)
+	costAmount := CalculateCost(code, usage, meta)
+
+	observeMetrics(
+		meta,
+		code,
+		firstByteAt,
+		time.Time{},
+		time.Time{},
+		usage,
+		amount,
+		downstreamResult,
+	)
 
 	recordSummary(
 		time.Now(),
@@ -142,18 +212,105 @@ func Summary(
 		firstByteAt,
 		usage,
 		amount,
+		originalAmount,
+		costAmount,
 		downstreamResult,
 	)
 }
 
-func checkNeedRecordConsume(code int, meta *meta.Meta) bool {
+// ConvertAmount converts the amount from the currency of the price into the
+// billing currency of the group at the request time, the distributor rejects
+// the requests without an exchange rate so the amount is zero only when the
+// rate was removed during the request, an unconverted amount is never
+// charged
+func ConvertAmount(amount float64, modelPrice model.Price, meta *meta.Meta) float64 {
+	return convertAmount(amount, modelPrice.Currency, meta)
+}
+
+func convertAmount(amount float64, currency string, meta *meta.Meta) float64 {
+	converted, err := model.ConvertCurrency(
+		amount,
+		currency,
+		meta.Group.Currency,
+		meta.RequestAt,
+	)
+	if err != nil {
+		log.Error("error convert amount: " + err.Error())
+		notify.ErrorThrottle(
+			"convertAmount",
+			time.Minute*5,
+			"convert amount failed",
+			err.Error(),
+		)
+	}
+
+	return converted
+}
+
+// CalculateChannelCost returns what the channel charges for the usage of the
+// model and the currency of the cost, the models without a cost price of the
+// channel cost the model price times the cost ratio, zero is an unknown cost
+func CalculateChannelCost(
+	code int,
+	usage model.Usage,
+	modelName string,
+	modelPrice model.Price,
+	channel meta.ChannelMeta,
+) (float64, string) {
+	costPrice, ok := channel.CostPrices[modelName]
+	if ok {
+		if costPrice.Currency == "" {
+			costPrice.Currency = channel.Currency
+		}
+
+		return CalculateAmount(code, usage, costPrice), costPrice.Currency
+	}
+
+	if channel.CostRatio <= 0 {
+		return 0, modelPrice.Currency
+	}
+
+	return decimal.NewFromFloat(CalculateAmount(code, usage, modelPrice)).
+		Mul(decimal.NewFromFloat(channel.CostRatio)).
+		InexactFloat64(), modelPrice.Currency
+}
+
+// CalculateCost returns what the upstream of the channel charges for the
+// usage in the billing currency of the group
+func CalculateCost(code int, usage model.Usage, meta *meta.Meta) float64 {
+	cost, currency := CalculateChannelCost(
+		code,
+		usage,
+		meta.OriginModel,
+		meta.ModelConfig.Price,
+		meta.Channel,
+	)
+
+	return convertAmount(cost, currency, meta)
+}
+
+func checkNeedRecordConsume(code int, meta *meta.Meta, usage model.Usage) bool {
 	switch meta.Mode {
+	case mode.FineTuningJobsGet, mode.FineTuningJobsList:
+		// a succeeded job is billed once by its trained tokens
+		return code != http.StatusOK || usage.TrainedTokens > 0
+	case mode.Realtime:
+		// the responses of a session are billed as they are done
+		return code != http.StatusOK || usage.InputTokens+usage.OutputTokens > 0
 	case mode.VideoGenerationsGetJobs,
 		mode.VideoGenerationsContent,
 		mode.ResponsesGet,
 		mode.ResponsesDelete,
 		mode.ResponsesCancel,
-		mode.ResponsesInputItems:
+		mode.ResponsesInputItems,
+		mode.FilesGet,
+		mode.FilesDelete,
+		mode.FilesContent,
+		mode.FineTuningJobsCancel,
+		mode.FineTuningJobsEvents,
+		mode.FineTuningJobsCheckpoints,
+		mode.AnthropicCountTokens,
+		mode.GeminiCountTokens:
 		return code != http.StatusOK
 	default:
 		return true
@@ -208,6 +365,10 @@ func CalculateAmount(
 		outputTokens -= usage.ImageOutputTokens
 	}
 
+	if modelPrice.AudioOutputPrice > 0 {
+		outputTokens -= usage.AudioOutputTokens
+	}
+
 	outputPrice := float64(modelPrice.OutputPrice)
 
 	outputPriceUnit := modelPrice.GetOutputPriceUnit()
@@ -242,6 +403,10 @@ func CalculateAmount(
 		Mul(decimal.NewFromFloat(float64(modelPrice.WebSearchPrice))).
 		Div(decimal.NewFromInt(modelPrice.GetWebSearchPriceUnit()))
 
+	trainingAmount := decimal.NewFromInt(int64(usage.TrainedTokens)).
+		Mul(decimal.NewFromFloat(float64(modelPrice.TrainingPrice))).
+		Div(decimal.NewFromInt(modelPrice.GetTrainingPriceUnit()))
+
 	outputAmount := decimal.NewFromInt(int64(outputTokens)).
 		Mul(decimal.NewFromFloat(outputPrice)).
 		Div(decimal.NewFromInt(outputPriceUnit))
@@ -250,14 +415,20 @@ func CalculateAmount(
 		Mul(decimal.NewFromFloat(float64(modelPrice.ImageOutputPrice))).
 		Div(decimal.NewFromInt(modelPrice.GetImageOutputPriceUnit()))
 
+	audioOutputAmount := decimal.NewFromInt(int64(usage.AudioOutputTokens)).
+		Mul(decimal.NewFromFloat(float64(modelPrice.AudioOutputPrice))).
+		Div(decimal.NewFromInt(modelPrice.GetAudioOutputPriceUnit()))
+
 	return inputAmount.
 		Add(imageInputAmount).
 		Add(audioInputAmount).
 		Add(cachedAmount).
 		Add(cacheCreationAmount).
 		Add(webSearchAmount).
+		Add(trainingAmount).
 		Add(outputAmount).
 		Add(imageOutputAmount).
+		Add(audioOutputAmount).
 		InexactFloat64()
 }
 
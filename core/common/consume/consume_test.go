@@ -1,11 +1,14 @@
 package consume_test
 
 import (
+	"math"
 	"net/http"
 	"testing"
 
 	"github.com/wavespeed/llm-server/core/common/consume"
 	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	"github.com/wavespeed/llm-server/core/relay/mode"
 )
 
 func TestCalculateAmount(t *testing.T) {
@@ -238,6 +241,19 @@ func TestCalculateAmount(t *testing.T) {
 			// Total: 0.0025 + 0 + 0 + 0.032 = 0.0345
 			want: 0.0345,
 		},
+		{
+			name: "Fine-Tuning Trained Tokens",
+			code: http.StatusOK,
+			usage: model.Usage{
+				TrainedTokens: 2000000,
+			},
+			price: model.Price{
+				TrainingPrice:     3,
+				TrainingPriceUnit: 1000000,
+			},
+			// Training: 2000000 / 1000000 * 3 = 6
+			want: 6,
+		},
 	}
 
 	for _, tt := range tests {
@@ -461,3 +477,130 @@ func TestCalculateAmountWithConditionalPricing(t *testing.T) {
 		}
 	}
 }
+
+func TestCalculateCost(t *testing.T) {
+	model.SetExchangeRates([]*model.ExchangeRate{
+		{From: "USD", To: "CNY", Rate: 7},
+	})
+	defer model.SetExchangeRates(nil)
+
+	usage := model.Usage{
+		InputTokens:  1000,
+		OutputTokens: 500,
+	}
+
+	newMeta := func(channel *model.Channel) *meta.Meta {
+		return meta.NewMeta(
+			channel,
+			mode.ChatCompletions,
+			"gpt-4o",
+			model.ModelConfig{
+				Price: model.Price{
+					InputPrice:  0.002,
+					OutputPrice: 0.004,
+				},
+			},
+			meta.WithGroup(model.GroupCache{Currency: "CNY"}),
+		)
+	}
+
+	tests := []struct {
+		name    string
+		channel *model.Channel
+		want    float64
+	}{
+		{
+			name:    "Unknown Cost",
+			channel: &model.Channel{},
+			want:    0,
+		},
+		{
+			name:    "Cost Ratio",
+			channel: &model.Channel{CostRatio: 0.5},
+			want:    0.014, // (0.002 + 0.004 * 500/1000) * 7 * 0.5
+		},
+		{
+			name: "Cost Price In Channel Currency",
+			channel: &model.Channel{
+				Currency:  "CNY",
+				CostRatio: 0.5,
+				CostPrices: map[string]model.Price{
+					"gpt-4o": {InputPrice: 0.01, OutputPrice: 0.02},
+				},
+			},
+			want: 0.02, // 0.01 * 1000/1000 + 0.02 * 500/1000
+		},
+	}
+
+	for _, tt := range tests {
+		got := consume.CalculateCost(http.StatusOK, usage, newMeta(tt.channel))
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("CalculateCost()\n%s\n\tgot: %v\n\twant: %v\n\t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateMCPAmount(t *testing.T) {
+	price := model.MCPPrice{
+		DefaultToolsCallPrice: 0.01,
+		ToolsCallPrices: map[string]float64{
+			"crawl": 0.05,
+		},
+	}
+
+	tests := []struct {
+		name string
+		call consume.MCPToolsCall
+		want float64
+	}{
+		{
+			name: "tool price",
+			call: consume.MCPToolsCall{Price: price.ToolsCallPrice("crawl")},
+			want: 0.05,
+		},
+		{
+			name: "default price",
+			call: consume.MCPToolsCall{Price: price.ToolsCallPrice("search")},
+			want: 0.01,
+		},
+		{
+			name: "failed call",
+			call: consume.MCPToolsCall{Price: 0.05, Error: "timeout"},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := consume.CalculateMCPAmount(tt.call)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CalculateMCPAmount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckNeedRecordConsumeFineTuningJobs(t *testing.T) {
+	tests := []struct {
+		mode  mode.Mode
+		code  int
+		usage model.Usage
+		want  bool
+	}{
+		{mode.FineTuningJobsGet, http.StatusOK, model.Usage{}, false},
+		{mode.FineTuningJobsGet, http.StatusOK, model.Usage{TrainedTokens: 100}, true},
+		{mode.FineTuningJobsGet, http.StatusNotFound, model.Usage{}, true},
+		{mode.FineTuningJobsList, http.StatusOK, model.Usage{}, false},
+		{mode.FineTuningJobsList, http.StatusOK, model.Usage{TrainedTokens: 100}, true},
+		{mode.FineTuningJobsList, http.StatusBadRequest, model.Usage{}, true},
+		{mode.FineTuningJobsCancel, http.StatusOK, model.Usage{TrainedTokens: 100}, false},
+	}
+
+	for _, tt := range tests {
+		got := consume.CheckNeedRecordConsume(tt.code, &meta.Meta{Mode: tt.mode}, tt.usage)
+		if got != tt.want {
+			t.Errorf("CheckNeedRecordConsume(%s, %d, %d) = %v, want %v",
+				tt.mode, tt.code, tt.usage.TrainedTokens, got, tt.want)
+		}
+	}
+}
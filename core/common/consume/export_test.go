@@ -0,0 +1,4 @@
+package consume
+
+// Export for testing
+var CheckNeedRecordConsume = checkNeedRecordConsume
@@ -0,0 +1,132 @@
+package consume
+
+import (
+	"context"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/common/balance"
+	"github.com/wavespeed/llm-server/core/common/notify"
+	"github.com/wavespeed/llm-server/core/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// MCPToolsCall is a tools/call passing through an mcp server of the gateway
+type MCPToolsCall struct {
+	RequestAt     time.Time
+	Latency       time.Duration
+	Group         model.GroupCache
+	Token         model.TokenCache
+	MCPID         string
+	Scope         model.MCPScope
+	Tool          string
+	ArgumentsSize int
+	// Error is the error of the call, the failed calls are not charged
+	Error string
+	// Price is the price of the call in the default currency
+	Price float64
+	IP    string
+}
+
+func AsyncConsumeMCP(postGroupConsumer balance.PostGroupConsumer, call MCPToolsCall) {
+	consumeWaitGroup.Add(1)
+	pendingConsumes.Add(1)
+
+	go func() {
+		defer func() {
+			pendingConsumes.Add(-1)
+			consumeWaitGroup.Done()
+
+			if r := recover(); r != nil {
+				log.Errorf("panic in consume mcp: %v", r)
+			}
+		}()
+
+		ConsumeMCP(context.Background(), time.Now(), postGroupConsumer, call)
+	}()
+}
+
+// ConsumeMCP charges the price of the tools call to the group and records it
+// in the mcp logs, the mcp logs are the summary of the mcp usage so the model
+// summaries only hold the models
+func ConsumeMCP(
+	ctx context.Context,
+	now time.Time,
+	postGroupConsumer balance.PostGroupConsumer,
+	call MCPToolsCall,
+) {
+	amount := CalculateMCPAmount(call)
+	if amount > 0 && postGroupConsumer != nil {
+		consumed, err := postGroupConsumer.PostGroupConsume(ctx, call.Token.Name, amount)
+		if err != nil {
+			log.Error("error consuming mcp tools call: " + err.Error())
+
+			if err := model.CreateConsumeError(
+				"",
+				call.RequestAt,
+				call.Group.ID,
+				call.Token.Name,
+				model.MCPConsumeErrorModel(call.MCPID),
+				err.Error(),
+				amount,
+				call.Token.ID,
+			); err != nil {
+				log.Error("failed to create consume error: " + err.Error())
+			}
+		} else {
+			amount = consumed
+		}
+	}
+
+	if err := model.ChargeBudgets(call.Group.ID, call.Token.Name, "", amount); err != nil {
+		log.Error("error charge budgets: " + err.Error())
+		notify.ErrorThrottle("chargeBudgets", time.Minute*5, "charge budgets failed", err.Error())
+	}
+
+	err := model.RecordMCPLog(&model.MCPLog{
+		RequestAt:           call.RequestAt,
+		CreatedAt:           now,
+		GroupID:             call.Group.ID,
+		TokenID:             call.Token.ID,
+		TokenName:           model.EmptyNullString(call.Token.Name),
+		MCPID:               call.MCPID,
+		Scope:               call.Scope,
+		Tool:                call.Tool,
+		ArgumentsSize:       call.ArgumentsSize,
+		LatencyMilliseconds: call.Latency.Milliseconds(),
+		Error:               call.Error,
+		UsedAmount:          amount,
+		Currency:            model.NormalizeCurrency(call.Group.Currency),
+		IP:                  model.EmptyNullString(call.IP),
+	})
+	if err != nil {
+		log.Error("error record mcp log: " + err.Error())
+		notify.ErrorThrottle("recordMCPLog", time.Minute*5, "record mcp log failed", err.Error())
+	}
+
+}
+
+// CalculateMCPAmount returns the price of the tools call in the billing
+// currency of the group, the failed calls are free
+func CalculateMCPAmount(call MCPToolsCall) float64 {
+	if call.Error != "" || call.Price <= 0 {
+		return 0
+	}
+
+	amount, err := model.ConvertCurrency(
+		call.Price,
+		"",
+		call.Group.Currency,
+		call.RequestAt,
+	)
+	if err != nil {
+		log.Error("error convert amount: " + err.Error())
+		notify.ErrorThrottle(
+			"convertAmount",
+			time.Minute*5,
+			"convert amount failed",
+			err.Error(),
+		)
+	}
+
+	return amount
+}
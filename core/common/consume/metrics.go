@@ -0,0 +1,45 @@
+package consume
+
+import (
+	"time"
+
+	"github.com/wavespeed/llm-server/core/common/metrics"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+)
+
+func observeMetrics(
+	meta *meta.Meta,
+	code int,
+	firstByteAt time.Time,
+	upstreamRequestAt time.Time,
+	upstreamResponseAt time.Time,
+	usage model.Usage,
+	amount float64,
+	downstreamResult bool,
+) {
+	metrics.ObserveRequest(metrics.Request{
+		Mode:               meta.Mode.String(),
+		Model:              meta.OriginModel,
+		ChannelID:          meta.Channel.ID,
+		Code:               code,
+		Downstream:         downstreamResult,
+		RequestAt:          meta.RequestAt,
+		FirstByteAt:        firstByteAt,
+		UpstreamRequestAt:  upstreamRequestAt,
+		UpstreamResponseAt: upstreamResponseAt,
+		Tokens: map[string]int64{
+			"input":          int64(usage.InputTokens),
+			"image_input":    int64(usage.ImageInputTokens),
+			"audio_input":    int64(usage.AudioInputTokens),
+			"output":         int64(usage.OutputTokens),
+			"image_output":   int64(usage.ImageOutputTokens),
+			"audio_output":   int64(usage.AudioOutputTokens),
+			"cached":         int64(usage.CachedTokens),
+			"cache_creation": int64(usage.CacheCreationTokens),
+			"reasoning":      int64(usage.ReasoningTokens),
+			"trained":        int64(usage.TrainedTokens),
+		},
+		Amount: amount,
+	})
+}
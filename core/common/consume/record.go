@@ -20,6 +20,8 @@ func recordConsume(
 	ip string,
 	requestDetail *model.RequestDetail,
 	amount float64,
+	originalAmount float64,
+	costAmount float64,
 	retryTimes int,
 	downstreamResult bool,
 	user string,
@@ -49,6 +51,9 @@ func recordConsume(
 		usage,
 		modelPrice,
 		amount,
+		originalAmount,
+		costAmount,
+		model.NormalizeCurrency(meta.Group.Currency),
 		user,
 		metadata,
 	)
@@ -61,6 +66,8 @@ func recordSummary(
 	firstByteAt time.Time,
 	usage model.Usage,
 	amount float64,
+	originalAmount float64,
+	costAmount float64,
 	downstreamResult bool,
 ) {
 	model.BatchUpdateSummary(
@@ -76,5 +83,7 @@ func recordSummary(
 		downstreamResult,
 		usage,
 		amount,
+		originalAmount,
+		costAmount,
 	)
 }
@@ -0,0 +1,45 @@
+package filestorage_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/wavespeed/llm-server/core/common/filestorage"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestLocalStorage(t *testing.T) {
+	convey.Convey("LocalStorage", t, func() {
+		s := filestorage.NewLocalStorage(t.TempDir())
+		ctx := t.Context()
+
+		convey.Convey("should put, get and delete a file", func() {
+			err := s.Put(ctx, "group/1/file-a", strings.NewReader("hello"), 5)
+			convey.So(err, convey.ShouldBeNil)
+
+			r, err := s.Get(ctx, "group/1/file-a")
+			convey.So(err, convey.ShouldBeNil)
+
+			content, err := io.ReadAll(r)
+			r.Close()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(string(content), convey.ShouldEqual, "hello")
+
+			convey.So(s.Delete(ctx, "group/1/file-a"), convey.ShouldBeNil)
+
+			_, err = s.Get(ctx, "group/1/file-a")
+			convey.So(errors.Is(err, filestorage.ErrNotFound), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("should ignore deleting a missing file", func() {
+			convey.So(s.Delete(ctx, "group/1/missing"), convey.ShouldBeNil)
+		})
+
+		convey.Convey("should reject keys outside of the storage dir", func() {
+			err := s.Put(ctx, "../escape", strings.NewReader("x"), 1)
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+	})
+}
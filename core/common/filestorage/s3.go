@@ -0,0 +1,182 @@
+package filestorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+var _ Storage = (*S3Storage)(nil)
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses objects as endpoint/bucket/key, which most
+	// S3-compatible services (minio, ceph, ...) expect
+	PathStyle bool
+}
+
+// S3Storage talks to any S3-compatible object storage with plain signed
+// http requests
+type S3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	signer   *v4.Signer
+	client   *http.Client
+}
+
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, errors.New("s3 file storage requires endpoint and bucket")
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+
+	return &S3Storage{
+		config:   config,
+		endpoint: endpoint,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			o.DisableURIPathEscaping = true
+		}),
+		client: &http.Client{},
+	}, nil
+}
+
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	escapedKey := strings.Join(segments, "/")
+
+	if s.config.PathStyle {
+		u.RawPath = strings.TrimSuffix(u.Path, "/") + "/" + url.PathEscape(s.config.Bucket) + "/" + escapedKey
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.RawPath = strings.TrimSuffix(u.Path, "/") + "/" + escapedKey
+	}
+
+	u.Path, _ = url.PathUnescape(u.RawPath)
+
+	return &u
+}
+
+func (s *S3Storage) do(
+	ctx context.Context,
+	method, key string,
+	body io.Reader,
+	size int64,
+) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.ContentLength = size
+	}
+
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	err = s.signer.SignHTTP(
+		ctx,
+		aws.Credentials{
+			AccessKeyID:     s.config.AccessKey,
+			SecretAccessKey: s.config.SecretKey,
+		},
+		req,
+		unsignedPayload,
+		"s3",
+		s.config.Region,
+		time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sign s3 request failed: %w", err)
+	}
+
+	return s.client.Do(req)
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("s3 request failed: status %d: %s", resp.StatusCode, body)
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	// s3 does not accept chunked uploads without a content length
+	if size < 0 {
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		r = bytes.NewReader(buf)
+		size = int64(len(buf))
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, key, r, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, s3Error(resp)
+	}
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent &&
+		resp.StatusCode != http.StatusOK &&
+		resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+
+	return nil
+}
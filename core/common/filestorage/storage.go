@@ -0,0 +1,87 @@
+package filestorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/wavespeed/llm-server/core/common/env"
+)
+
+var ErrNotFound = errors.New("file not found in storage")
+
+// Storage is the blob backend behind the files API, keys are slash separated
+// paths owned by the caller.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+const (
+	TypeLocal = "local"
+	TypeS3    = "s3"
+
+	defaultLocalPath   = "./data/files"
+	defaultMaxFileSize = 512 * 1024 * 1024 // 512MB
+)
+
+var (
+	defaultStorage atomic.Value
+	maxFileSize    atomic.Int64
+)
+
+func init() {
+	defaultStorage.Store(storageHolder{NewLocalStorage(defaultLocalPath)})
+	maxFileSize.Store(defaultMaxFileSize)
+}
+
+type storageHolder struct {
+	Storage
+}
+
+func Default() Storage {
+	v, ok := defaultStorage.Load().(storageHolder)
+	if !ok {
+		panic(fmt.Sprintf("file storage type error: %T", v))
+	}
+
+	return v.Storage
+}
+
+func SetDefault(s Storage) {
+	defaultStorage.Store(storageHolder{s})
+}
+
+func GetMaxFileSize() int64 {
+	return maxFileSize.Load()
+}
+
+func Init() error {
+	maxFileSize.Store(env.Int64("FILE_MAX_SIZE", defaultMaxFileSize))
+
+	switch typ := env.String("FILE_STORAGE_TYPE", TypeLocal); typ {
+	case TypeLocal:
+		SetDefault(NewLocalStorage(env.String("FILE_STORAGE_PATH", defaultLocalPath)))
+	case TypeS3:
+		s, err := NewS3Storage(S3Config{
+			Endpoint:  env.String("FILE_STORAGE_S3_ENDPOINT", ""),
+			Region:    env.String("FILE_STORAGE_S3_REGION", "us-east-1"),
+			Bucket:    env.String("FILE_STORAGE_S3_BUCKET", ""),
+			AccessKey: env.String("FILE_STORAGE_S3_ACCESS_KEY", ""),
+			SecretKey: env.String("FILE_STORAGE_S3_SECRET_KEY", ""),
+			PathStyle: env.Bool("FILE_STORAGE_S3_PATH_STYLE", true),
+		})
+		if err != nil {
+			return err
+		}
+
+		SetDefault(s)
+	default:
+		return fmt.Errorf("unknown file storage type: %s", typ)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+// Package hold reserves the estimated cost of the requests against the
+// group balance and the token quota until the requests are consumed, so the
+// concurrent requests of a group can not spend more than its balance
+package hold
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavespeed/llm-server/core/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultTTL is the lifetime of a hold that is never released, it bounds the
+// reservations leaked by a crashed replica
+const DefaultTTL = time.Hour
+
+// Limit is a balance the holds of a key are placed against, the sum of the
+// holds of the key can not exceed Available
+type Limit struct {
+	Key       string
+	Available float64
+}
+
+// GroupKey is the key of the holds against the balance of a group
+func GroupKey(group string) string {
+	return "hold:{" + group + "}"
+}
+
+// TokenKey is the key of the holds against the quota of a token, it shares
+// the hash slot of the group key so both are updated by one script
+func TokenKey(group string, tokenID int) string {
+	return "hold:{" + group + "}:token:" + strconv.Itoa(tokenID)
+}
+
+// Hold is an amount reserved against the limits of a request, it is
+// released when every retained reference is released
+type Hold struct {
+	ID     string
+	Amount float64
+	keys   []string
+	refs   atomic.Int32
+	// inMemory is set when the hold is placed in the memory of the replica
+	// because redis is unavailable
+	inMemory bool
+}
+
+// Retain adds a reference to the hold, a nil hold is ignored
+func (h *Hold) Retain() {
+	if h == nil {
+		return
+	}
+
+	h.refs.Add(1)
+}
+
+// Release drops a reference to the hold, the reservation is removed with the
+// last reference
+func (h *Hold) Release() {
+	if h == nil || h.refs.Add(-1) != 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if common.RedisEnabled && !h.inMemory {
+		err := redisRelease(ctx, h.ID, h.keys)
+		if err == nil {
+			return
+		}
+
+		log.Error("redis release hold error: " + err.Error())
+	}
+
+	memHolds.release(h.ID, h.keys)
+}
+
+// Place reserves the amount against all limits, it returns false without
+// reserving anything when the amount does not fit in the available amount
+// of one of the limits minus its current holds
+func Place(ctx context.Context, amount float64, ttl time.Duration, limits ...Limit) (*Hold, bool) {
+	if amount <= 0 || len(limits) == 0 {
+		return nil, true
+	}
+
+	h := &Hold{
+		ID:     uuid.NewString(),
+		Amount: amount,
+		keys:   make([]string, len(limits)),
+	}
+	h.refs.Store(1)
+
+	for i, limit := range limits {
+		h.keys[i] = limit.Key
+	}
+
+	if common.RedisEnabled {
+		ok, err := redisPlace(ctx, h, ttl, limits)
+		if err == nil {
+			if !ok {
+				return nil, false
+			}
+
+			return h, true
+		}
+
+		log.Error("redis place hold error: " + err.Error())
+	}
+
+	if !memHolds.place(h, ttl, limits) {
+		return nil, false
+	}
+
+	h.inMemory = true
+
+	return h, true
+}
+
+// Held returns the amount held against a key
+func Held(ctx context.Context, key string) float64 {
+	if common.RedisEnabled {
+		held, err := redisHeld(ctx, key)
+		if err == nil {
+			return held
+		}
+
+		log.Error("redis get held error: " + err.Error())
+	}
+
+	return memHolds.held(key)
+}
@@ -0,0 +1,78 @@
+package hold_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/common/hold"
+)
+
+func TestPlaceAndRelease(t *testing.T) {
+	ctx := context.Background()
+	group := hold.GroupKey("TestPlaceAndRelease")
+	token := hold.TokenKey("TestPlaceAndRelease", 1)
+
+	first, ok := hold.Place(ctx, 0.06, time.Minute,
+		hold.Limit{Key: group, Available: 0.1},
+		hold.Limit{Key: token, Available: 1},
+	)
+	if !ok || first == nil {
+		t.Fatal("Expected the first hold to be placed")
+	}
+
+	if _, ok := hold.Place(ctx, 0.06, time.Minute, hold.Limit{Key: group, Available: 0.1}); ok {
+		t.Error("Expected the second hold to exceed the group balance")
+	}
+
+	if held := hold.Held(ctx, token); held != 0.06 {
+		t.Errorf("Expected 0.06 held against the token, Got %v", held)
+	}
+
+	// a consume still holds a reference
+	first.Retain()
+	first.Release()
+
+	if held := hold.Held(ctx, group); held != 0.06 {
+		t.Errorf("Expected 0.06 held until the last release, Got %v", held)
+	}
+
+	first.Release()
+
+	if held := hold.Held(ctx, group); held != 0 {
+		t.Errorf("Expected nothing held, Got %v", held)
+	}
+
+	if _, ok := hold.Place(ctx, 0.06, time.Minute, hold.Limit{Key: group, Available: 0.1}); !ok {
+		t.Error("Expected the hold to be placed after the release")
+	}
+}
+
+func TestPlaceWithoutLimits(t *testing.T) {
+	h, ok := hold.Place(context.Background(), 1, time.Minute)
+	if !ok || h != nil {
+		t.Errorf("Expected no hold without limits, Got %v, %v", h, ok)
+	}
+
+	// releasing a nil hold is a no-op
+	h.Release()
+}
+
+func TestHoldExpires(t *testing.T) {
+	ctx := context.Background()
+	group := hold.GroupKey("TestHoldExpires")
+
+	if _, ok := hold.Place(ctx, 1, 100*time.Millisecond, hold.Limit{Key: group, Available: 1}); !ok {
+		t.Fatal("Expected the hold to be placed")
+	}
+
+	if _, ok := hold.Place(ctx, 1, time.Minute, hold.Limit{Key: group, Available: 1}); ok {
+		t.Error("Expected the balance to be held")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if _, ok := hold.Place(ctx, 1, time.Minute, hold.Limit{Key: group, Available: 1}); !ok {
+		t.Error("Expected the expired hold to be dropped")
+	}
+}
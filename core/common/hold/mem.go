@@ -0,0 +1,86 @@
+package hold
+
+import (
+	"sync"
+	"time"
+)
+
+type memHold struct {
+	amount   float64
+	expireAt time.Time
+}
+
+type memStore struct {
+	mu    sync.Mutex
+	holds map[string]map[string]memHold
+}
+
+var memHolds = &memStore{
+	holds: make(map[string]map[string]memHold),
+}
+
+// heldLocked sums the live holds of a key and drops the expired ones
+func (s *memStore) heldLocked(key string, now time.Time) float64 {
+	var held float64
+
+	for id, h := range s.holds[key] {
+		if !now.Before(h.expireAt) {
+			delete(s.holds[key], id)
+			continue
+		}
+
+		held += h.amount
+	}
+
+	if len(s.holds[key]) == 0 {
+		delete(s.holds, key)
+	}
+
+	return held
+}
+
+func (s *memStore) place(h *Hold, ttl time.Duration, limits []Limit) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	for _, limit := range limits {
+		if s.heldLocked(limit.Key, now)+h.Amount > limit.Available {
+			return false
+		}
+	}
+
+	for _, limit := range limits {
+		if s.holds[limit.Key] == nil {
+			s.holds[limit.Key] = make(map[string]memHold)
+		}
+
+		s.holds[limit.Key][h.ID] = memHold{
+			amount:   h.Amount,
+			expireAt: now.Add(ttl),
+		}
+	}
+
+	return true
+}
+
+func (s *memStore) release(id string, keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.holds[key], id)
+
+		if len(s.holds[key]) == 0 {
+			delete(s.holds, key)
+		}
+	}
+}
+
+func (s *memStore) held(key string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.heldLocked(key, time.Now())
+}
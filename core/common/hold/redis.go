@@ -0,0 +1,108 @@
+package hold
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/redis/go-redis/v9"
+)
+
+// the holds of a key are the fields of a hash, the value of a field is
+// "amount:expire_at", the expired holds are dropped when the key is summed
+const heldLuaScript = `
+local function held(key, now)
+	local total = 0
+	local fields = redis.call('HGETALL', key)
+	for i = 1, #fields, 2 do
+		local amount, expire_at = fields[i+1]:match("^([^:]+):(%d+)$")
+		if not amount or tonumber(expire_at) <= now then
+			redis.call('HDEL', key, fields[i])
+		else
+			total = total + tonumber(amount)
+		end
+	end
+	return total
+end
+`
+
+var placeScript = redis.NewScript(heldLuaScript + `
+local id = ARGV[1]
+local amount = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+for i, key in ipairs(KEYS) do
+	if held(key, now) + amount > tonumber(ARGV[4 + i]) then
+		return 0
+	end
+end
+
+for _, key in ipairs(KEYS) do
+	redis.call('HSET', key, id, ARGV[2] .. ":" .. (now + ttl))
+	if redis.call('TTL', key) < ttl then
+		redis.call('EXPIRE', key, ttl)
+	end
+end
+
+return 1
+`)
+
+var heldScript = redis.NewScript(heldLuaScript + `
+return tostring(held(KEYS[1], tonumber(ARGV[1])))
+`)
+
+func redisKeys(keys []string) []string {
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = common.RedisKey(key)
+	}
+
+	return redisKeys
+}
+
+func redisPlace(ctx context.Context, h *Hold, ttl time.Duration, limits []Limit) (bool, error) {
+	args := []any{
+		h.ID,
+		strconv.FormatFloat(h.Amount, 'f', -1, 64),
+		time.Now().Unix(),
+		int64(math.Ceil(ttl.Seconds())),
+	}
+	for _, limit := range limits {
+		args = append(args, strconv.FormatFloat(limit.Available, 'f', -1, 64))
+	}
+
+	placed, err := placeScript.Run(ctx, common.RDB, redisKeys(h.keys), args...).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return placed == 1, nil
+}
+
+func redisRelease(ctx context.Context, id string, keys []string) error {
+	pipe := common.RDB.Pipeline()
+	for _, key := range redisKeys(keys) {
+		pipe.HDel(ctx, key, id)
+	}
+
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+func redisHeld(ctx context.Context, key string) (float64, error) {
+	held, err := heldScript.Run(
+		ctx,
+		common.RDB,
+		[]string{common.RedisKey(key)},
+		time.Now().Unix(),
+	).Text()
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(held, 64)
+}
@@ -0,0 +1,131 @@
+// Package metrics holds the prometheus metrics of the relay and billing
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "aiproxy"
+
+var latencyBuckets = []float64{
+	0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300,
+}
+
+var (
+	requestLabels = []string{"mode", "model", "channel"}
+
+	// Registry is the registry of the /metrics endpoint
+	Registry = prometheus.NewRegistry()
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_requests_total",
+		Help:      "Relay requests by status code, retries are counted per attempt.",
+	}, append(requestLabels, "code", "downstream"))
+
+	ttfbSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_ttfb_seconds",
+		Help:      "Time from the request to the first byte sent to the client.",
+		Buckets:   latencyBuckets,
+	}, requestLabels)
+
+	upstreamSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_upstream_response_seconds",
+		Help:      "Time from the upstream request to the upstream response.",
+		Buckets:   latencyBuckets,
+	}, requestLabels)
+
+	internalProcessSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_internal_process_seconds",
+		Help:      "Time spent in the gateway before the upstream request.",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, requestLabels)
+
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_tokens_total",
+		Help:      "Tokens used by the relay requests.",
+	}, append(requestLabels, "type"))
+
+	spendTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_spend_total",
+		Help:      "Amount billed to the groups.",
+	}, requestLabels)
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		requestsTotal,
+		ttfbSeconds,
+		upstreamSeconds,
+		internalProcessSeconds,
+		tokensTotal,
+		spendTotal,
+	)
+}
+
+// Request is a finished relay request
+type Request struct {
+	Mode               string
+	Model              string
+	ChannelID          int
+	Code               int
+	Downstream         bool
+	RequestAt          time.Time
+	FirstByteAt        time.Time
+	UpstreamRequestAt  time.Time
+	UpstreamResponseAt time.Time
+	// Tokens is the used tokens by their type
+	Tokens map[string]int64
+	Amount float64
+}
+
+// ObserveRequest records the request, the timings follow the log, a zero or
+// inconsistent time is not observed
+func ObserveRequest(r Request) {
+	channel := strconv.Itoa(r.ChannelID)
+
+	requestsTotal.WithLabelValues(
+		r.Mode,
+		r.Model,
+		channel,
+		strconv.Itoa(r.Code),
+		strconv.FormatBool(r.Downstream),
+	).Inc()
+
+	if !r.RequestAt.IsZero() && r.FirstByteAt.After(r.RequestAt) {
+		ttfbSeconds.WithLabelValues(r.Mode, r.Model, channel).
+			Observe(r.FirstByteAt.Sub(r.RequestAt).Seconds())
+	}
+
+	if !r.UpstreamRequestAt.IsZero() {
+		if !r.RequestAt.IsZero() && !r.UpstreamRequestAt.Before(r.RequestAt) {
+			internalProcessSeconds.WithLabelValues(r.Mode, r.Model, channel).
+				Observe(r.UpstreamRequestAt.Sub(r.RequestAt).Seconds())
+		}
+
+		if !r.UpstreamResponseAt.IsZero() && !r.UpstreamResponseAt.Before(r.UpstreamRequestAt) {
+			upstreamSeconds.WithLabelValues(r.Mode, r.Model, channel).
+				Observe(r.UpstreamResponseAt.Sub(r.UpstreamRequestAt).Seconds())
+		}
+	}
+
+	for tokenType, tokens := range r.Tokens {
+		if tokens > 0 {
+			tokensTotal.WithLabelValues(r.Mode, r.Model, channel, tokenType).Add(float64(tokens))
+		}
+	}
+
+	if r.Amount > 0 {
+		spendTotal.WithLabelValues(r.Mode, r.Model, channel).Add(r.Amount)
+	}
+}
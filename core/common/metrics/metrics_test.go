@@ -0,0 +1,114 @@
+package metrics_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/wavespeed/llm-server/core/common/metrics"
+)
+
+func gather(t *testing.T) map[string]*dto.MetricFamily {
+	t.Helper()
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	result := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		result[f.GetName()] = f
+	}
+
+	return result
+}
+
+func findMetric(f *dto.MetricFamily, labels map[string]string) *dto.Metric {
+	if f == nil {
+		return nil
+	}
+
+	for _, m := range f.GetMetric() {
+		matched := 0
+
+		for _, l := range m.GetLabel() {
+			if v, ok := labels[l.GetName()]; ok && v == l.GetValue() {
+				matched++
+			}
+		}
+
+		if matched == len(labels) {
+			return m
+		}
+	}
+
+	return nil
+}
+
+func TestObserveRequest(t *testing.T) {
+	requestAt := time.Now()
+	// the registry is global, keep the runs apart
+	modelName := "metrics-test-" + strconv.FormatInt(requestAt.UnixNano(), 10)
+
+	metrics.ObserveRequest(metrics.Request{
+		Mode:               "ChatCompletions",
+		Model:              modelName,
+		ChannelID:          7,
+		Code:               200,
+		Downstream:         true,
+		RequestAt:          requestAt,
+		FirstByteAt:        requestAt.Add(300 * time.Millisecond),
+		UpstreamRequestAt:  requestAt.Add(10 * time.Millisecond),
+		UpstreamResponseAt: requestAt.Add(250 * time.Millisecond),
+		Tokens:             map[string]int64{"input": 100, "output": 20, "cached": 0},
+		Amount:             0.5,
+	})
+	// a request rejected before the upstream has no timings
+	metrics.ObserveRequest(metrics.Request{
+		Mode:       "ChatCompletions",
+		Model:      modelName,
+		ChannelID:  7,
+		Code:       429,
+		Downstream: true,
+		RequestAt:  requestAt,
+	})
+
+	families := gather(t)
+	labels := map[string]string{"model": modelName, "channel": "7"}
+
+	for code, want := range map[string]float64{"200": 1, "429": 1} {
+		m := findMetric(families["aiproxy_relay_requests_total"],
+			map[string]string{"model": modelName, "code": code})
+		if m == nil || m.GetCounter().GetValue() != want {
+			t.Errorf("relay_requests_total{code=%s} = %v, want %v", code, m, want)
+		}
+	}
+
+	ttfb := findMetric(families["aiproxy_relay_ttfb_seconds"], labels)
+	if ttfb == nil || ttfb.GetHistogram().GetSampleCount() != 1 {
+		t.Errorf("relay_ttfb_seconds = %v, want one sample", ttfb)
+	}
+
+	upstream := findMetric(families["aiproxy_relay_upstream_response_seconds"], labels)
+	if upstream == nil || upstream.GetHistogram().GetSampleSum() != 0.24 {
+		t.Errorf("relay_upstream_response_seconds = %v, want 0.24s", upstream)
+	}
+
+	input := findMetric(families["aiproxy_relay_tokens_total"],
+		map[string]string{"model": modelName, "type": "input"})
+	if input == nil || input.GetCounter().GetValue() != 100 {
+		t.Errorf("relay_tokens_total{type=input} = %v, want 100", input)
+	}
+
+	if cached := findMetric(families["aiproxy_relay_tokens_total"],
+		map[string]string{"model": modelName, "type": "cached"}); cached != nil {
+		t.Errorf("relay_tokens_total{type=cached} = %v, want no series", cached)
+	}
+
+	spend := findMetric(families["aiproxy_relay_spend_total"], labels)
+	if spend == nil || spend.GetCounter().GetValue() != 0.5 {
+		t.Errorf("relay_spend_total = %v, want 0.5", spend)
+	}
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+// DingTalkSender posts the notifications to a dingtalk robot, the secret
+// is the signing secret of the robot and is optional
+type DingTalkSender struct {
+	wh     string
+	secret string
+}
+
+func NewDingTalkSender(wh, secret string) *DingTalkSender {
+	return &DingTalkSender{
+		wh:     wh,
+		secret: secret,
+	}
+}
+
+type dingTalkMarkdown struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type dingTalkMessage struct {
+	MsgType  string           `json:"msgtype"`
+	Markdown dingTalkMarkdown `json:"markdown"`
+}
+
+// signedURL appends the timestamp and the sign of the robot security settings
+func (d *DingTalkSender) signedURL(now time.Time) (string, error) {
+	if d.secret == "" {
+		return d.wh, nil
+	}
+
+	u, err := url.Parse(d.wh)
+	if err != nil {
+		return "", err
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+
+	h := hmac.New(sha256.New, []byte(d.secret))
+	h.Write([]byte(timestamp + "\n" + d.secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func (d *DingTalkSender) Send(ctx context.Context, level Level, title, message string) error {
+	if d.wh == "" {
+		return errors.New("dingtalk webhook url is empty")
+	}
+
+	wh, err := d.signedURL(time.Now())
+	if err != nil {
+		return err
+	}
+
+	body, err := sonic.Marshal(dingTalkMessage{
+		MsgType: "markdown",
+		Markdown: dingTalkMarkdown{
+			Title: title,
+			Text: fmt.Sprintf(
+				"### [%s] %s\n\n%s\n\n> %s",
+				level, title, message, notifyNote(),
+			),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	respBody, err := postJSON(ctx, wh, body, nil)
+	if err != nil {
+		return err
+	}
+
+	return checkErrcode(respBody)
+}
+
+func NewDingTalkNotify(wh, secret string) Notifier {
+	return NewMultiNotifier(Route{Name: "dingtalk", Sender: NewDingTalkSender(wh, secret)})
+}
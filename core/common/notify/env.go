@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"os"
+	"strings"
+
+	"github.com/wavespeed/llm-server/core/common/env"
+)
+
+// parseLevels parses a comma separated list of the levels, unknown levels
+// are ignored
+func parseLevels(s string) []Level {
+	var levels []Level
+
+	for level := range strings.SplitSeq(s, ",") {
+		switch l := Level(strings.ToLower(strings.TrimSpace(level))); l {
+		case LevelInfo, LevelWarn, LevelError:
+			levels = append(levels, l)
+		}
+	}
+
+	return levels
+}
+
+func splitList(s string) []string {
+	var list []string
+
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// RoutesFromEnv returns the routes of the configured notifiers, the levels
+// of a notifier are set by NOTIFY_<NAME>_LEVELS, e.g. NOTIFY_SLACK_LEVELS=warn,error
+func RoutesFromEnv() []Route {
+	var routes []Route
+
+	add := func(name string, sender Sender) {
+		routes = append(routes, Route{
+			Name:   name,
+			Sender: sender,
+			Levels: parseLevels(os.Getenv("NOTIFY_" + strings.ToUpper(name) + "_LEVELS")),
+		})
+	}
+
+	if wh := os.Getenv("NOTIFY_FEISHU_WEBHOOK"); wh != "" {
+		add("feishu", NewFeishuSender(wh))
+	}
+
+	if wh := os.Getenv("NOTIFY_SLACK_WEBHOOK"); wh != "" {
+		add("slack", NewSlackSender(wh))
+	}
+
+	if wh := os.Getenv("NOTIFY_DINGTALK_WEBHOOK"); wh != "" {
+		add("dingtalk", NewDingTalkSender(wh, os.Getenv("NOTIFY_DINGTALK_SECRET")))
+	}
+
+	if wh := os.Getenv("NOTIFY_WECOM_WEBHOOK"); wh != "" {
+		add("wecom", NewWeComSender(wh))
+	}
+
+	if wh := os.Getenv("NOTIFY_WEBHOOK_URL"); wh != "" {
+		add("webhook", NewWebhookSender(wh, os.Getenv("NOTIFY_WEBHOOK_SECRET")))
+	}
+
+	if host := os.Getenv("NOTIFY_EMAIL_SMTP_HOST"); host != "" {
+		add("email", NewEmailSender(EmailConfig{
+			Host:     host,
+			Port:     int(env.Int64("NOTIFY_EMAIL_SMTP_PORT", 587)),
+			Username: os.Getenv("NOTIFY_EMAIL_USERNAME"),
+			Password: os.Getenv("NOTIFY_EMAIL_PASSWORD"),
+			From:     os.Getenv("NOTIFY_EMAIL_FROM"),
+			To:       splitList(os.Getenv("NOTIFY_EMAIL_TO")),
+		}))
+	}
+
+	return routes
+}
@@ -5,17 +5,20 @@ import (
 	"context"
 	"errors"
 	"net/http"
-	"time"
 
 	"github.com/bytedance/sonic"
-	"github.com/wavespeed/llm-server/core/common/config"
-	"github.com/wavespeed/llm-server/core/common/trylock"
 )
 
-type FeishuNotifier struct {
+type FeishuSender struct {
 	wh string
 }
 
+func NewFeishuSender(wh string) *FeishuSender {
+	return &FeishuSender{
+		wh: wh,
+	}
+}
+
 func level2Color(level Level) string {
 	switch level {
 	case LevelInfo:
@@ -29,33 +32,12 @@ func level2Color(level Level) string {
 	}
 }
 
-func (f *FeishuNotifier) Notify(level Level, title, message string) {
-	stdNotifier.Notify(level, title, message)
-
-	go func() {
-		_ = PostToFeiShuv2(context.Background(), level2Color(level), title, message, f.wh)
-	}()
-}
-
-func (f *FeishuNotifier) NotifyThrottle(
-	level Level,
-	key string,
-	expiration time.Duration,
-	title, message string,
-) {
-	if trylock.Lock(key, expiration) {
-		stdNotifier.Notify(level, title, message)
-
-		go func() {
-			_ = PostToFeiShuv2(context.Background(), level2Color(level), title, message, f.wh)
-		}()
-	}
+func (f *FeishuSender) Send(ctx context.Context, level Level, title, message string) error {
+	return PostToFeiShuv2(ctx, level2Color(level), title, message, f.wh)
 }
 
 func NewFeishuNotify(wh string) Notifier {
-	return &FeishuNotifier{
-		wh: wh,
-	}
+	return NewMultiNotifier(Route{Name: "feishu", Sender: NewFeishuSender(wh)})
 }
 
 type FSMessagev2 struct {
@@ -127,10 +109,7 @@ func PostToFeiShuv2(ctx context.Context, color, title, text, wh string) error {
 		return errors.New("feishu webhook url is empty")
 	}
 
-	note := config.GetNotifyNote()
-	if note == "" {
-		note = "AI Proxy"
-	}
+	note := notifyNote()
 
 	u := FSMessagev2{
 		MsgType: "interactive",
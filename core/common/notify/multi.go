@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/common/config"
+	"github.com/wavespeed/llm-server/core/common/trylock"
+	log "github.com/sirupsen/logrus"
+)
+
+const sendTimeout = 10 * time.Second
+
+// Sender delivers a notification to an external service
+type Sender interface {
+	Send(ctx context.Context, level Level, title, message string) error
+}
+
+// Route sends the notifications of the levels to the sender, no levels means
+// all levels
+type Route struct {
+	Name   string
+	Sender Sender
+	Levels []Level
+}
+
+func (r *Route) match(level Level) bool {
+	return len(r.Levels) == 0 || slices.Contains(r.Levels, level)
+}
+
+// MultiNotifier logs the notifications and fans them out to the routes
+// matching their level
+type MultiNotifier struct {
+	routes []Route
+}
+
+func NewMultiNotifier(routes ...Route) *MultiNotifier {
+	return &MultiNotifier{
+		routes: routes,
+	}
+}
+
+func (m *MultiNotifier) Notify(level Level, title, message string) {
+	stdNotifier.Notify(level, title, message)
+
+	for _, route := range m.routes {
+		if !route.match(level) {
+			continue
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+
+			if err := route.Sender.Send(ctx, level, title, message); err != nil {
+				log.Errorf("send notify to %s failed: %v", route.Name, err)
+			}
+		}()
+	}
+}
+
+// NotifyThrottle locks the key in redis when enabled, so the notification
+// is sent once across the instances
+func (m *MultiNotifier) NotifyThrottle(
+	level Level,
+	key string,
+	expiration time.Duration,
+	title, message string,
+) {
+	if trylock.Lock(key, expiration) {
+		m.Notify(level, title, message)
+	}
+}
+
+func notifyNote() string {
+	note := config.GetNotifyNote()
+	if note == "" {
+		return "AI Proxy"
+	}
+
+	return note
+}
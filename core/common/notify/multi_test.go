@@ -0,0 +1,98 @@
+package notify_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/common/notify"
+)
+
+type chanSender chan notify.Level
+
+func (c chanSender) Send(_ context.Context, level notify.Level, _, _ string) error {
+	c <- level
+	return nil
+}
+
+func TestMultiNotifierRoutes(t *testing.T) {
+	oncall := make(chanSender, 3)
+	channel := make(chanSender, 3)
+
+	n := notify.NewMultiNotifier(
+		notify.Route{Name: "oncall", Sender: oncall, Levels: []notify.Level{notify.LevelError}},
+		notify.Route{Name: "channel", Sender: channel},
+	)
+
+	n.Notify(notify.LevelInfo, "info", "message")
+	n.Notify(notify.LevelError, "error", "message")
+
+	receive := func(c chanSender) []notify.Level {
+		var levels []notify.Level
+
+		timeout := time.After(time.Second)
+
+		for {
+			select {
+			case level := <-c:
+				levels = append(levels, level)
+			case <-timeout:
+				return levels
+			}
+		}
+	}
+
+	if got := receive(oncall); len(got) != 1 || got[0] != notify.LevelError {
+		t.Errorf("oncall received %v, want [error]", got)
+	}
+
+	if got := receive(channel); len(got) != 2 {
+		t.Errorf("channel received %v, want all levels", got)
+	}
+}
+
+func TestWebhookSenderSignature(t *testing.T) {
+	const secret = "secret"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(notify.WebhookTimestampHeader), 10, 64)
+		if err != nil {
+			t.Errorf("invalid timestamp header: %v", err)
+		}
+
+		want := notify.WebhookSignature(secret, timestamp, body)
+		if got := r.Header.Get(notify.WebhookSignatureHeader); got != want {
+			t.Errorf("signature = %s, want %s", got, want)
+		}
+	}))
+	defer server.Close()
+
+	err := notify.NewWebhookSender(server.URL, secret).
+		Send(t.Context(), notify.LevelWarn, "title", "message")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDingTalkSenderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sign") == "" {
+			t.Error("sign is not set")
+		}
+
+		_, _ = w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+	}))
+	defer server.Close()
+
+	err := notify.NewDingTalkSender(server.URL, "secret").
+		Send(t.Context(), notify.LevelError, "title", "message")
+	if err == nil || err.Error() != "sign not match" {
+		t.Errorf("Send() error = %v, want sign not match", err)
+	}
+}
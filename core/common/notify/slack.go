@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bytedance/sonic"
+)
+
+// SlackSender posts the notifications to a slack incoming webhook
+type SlackSender struct {
+	wh string
+}
+
+func NewSlackSender(wh string) *SlackSender {
+	return &SlackSender{
+		wh: wh,
+	}
+}
+
+func level2Emoji(level Level) string {
+	switch level {
+	case LevelError:
+		return ":red_circle:"
+	case LevelWarn:
+		return ":warning:"
+	default:
+		return ":large_green_circle:"
+	}
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+func (s *SlackSender) Send(ctx context.Context, level Level, title, message string) error {
+	if s.wh == "" {
+		return errors.New("slack webhook url is empty")
+	}
+
+	header := level2Emoji(level) + " " + title
+
+	body, err := sonic.Marshal(slackMessage{
+		// the fallback of the notifications
+		Text: header,
+		Blocks: []slackBlock{
+			{
+				Type: "header",
+				Text: &slackText{Type: "plain_text", Text: header},
+			},
+			{
+				Type: "section",
+				Text: &slackText{Type: "mrkdwn", Text: message},
+			},
+			{
+				Type:     "context",
+				Elements: []slackText{{Type: "mrkdwn", Text: notifyNote()}},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = postJSON(ctx, s.wh, body, nil)
+
+	return err
+}
+
+func NewSlackNotify(wh string) Notifier {
+	return NewMultiNotifier(Route{Name: "slack", Sender: NewSlackSender(wh)})
+}
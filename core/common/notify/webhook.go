@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+const (
+	WebhookSignatureHeader = "X-Aiproxy-Signature"
+	WebhookTimestampHeader = "X-Aiproxy-Timestamp"
+)
+
+// postJSON posts the body and returns the response body of a 2xx response
+func postJSON(
+	ctx context.Context,
+	url string,
+	body []byte,
+	header http.Header,
+) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
+
+// errcodeResp is the response of the dingtalk and wecom robots
+type errcodeResp struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func checkErrcode(respBody []byte) error {
+	var resp errcodeResp
+	if err := sonic.Unmarshal(respBody, &resp); err != nil {
+		return err
+	}
+
+	if resp.ErrCode != 0 {
+		return errors.New(resp.ErrMsg)
+	}
+
+	return nil
+}
+
+// WebhookMessage is the body of the generic webhook
+type WebhookMessage struct {
+	Level     Level  `json:"level"`
+	Title     string `json:"title"`
+	Message   string `json:"message"`
+	Note      string `json:"note"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// WebhookSender posts the notifications as json, the body is signed with
+// the secret when it is set
+type WebhookSender struct {
+	url    string
+	secret string
+}
+
+func NewWebhookSender(url, secret string) *WebhookSender {
+	return &WebhookSender{
+		url:    url,
+		secret: secret,
+	}
+}
+
+// WebhookSignature is the hex hmac-sha256 of "<timestamp>.<body>", the
+// receivers verify the X-Aiproxy-Signature header with it
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+func (w *WebhookSender) Send(ctx context.Context, level Level, title, message string) error {
+	if w.url == "" {
+		return errors.New("webhook url is empty")
+	}
+
+	timestamp := time.Now().Unix()
+
+	body, err := sonic.Marshal(WebhookMessage{
+		Level:     level,
+		Title:     title,
+		Message:   message,
+		Note:      notifyNote(),
+		Timestamp: timestamp,
+	})
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	if w.secret != "" {
+		header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+		header.Set(WebhookSignatureHeader, WebhookSignature(w.secret, timestamp, body))
+	}
+
+	_, err = postJSON(ctx, w.url, body, header)
+
+	return err
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bytedance/sonic"
+)
+
+// WeComSender posts the notifications to a wecom group robot
+type WeComSender struct {
+	wh string
+}
+
+func NewWeComSender(wh string) *WeComSender {
+	return &WeComSender{
+		wh: wh,
+	}
+}
+
+func level2WeComColor(level Level) string {
+	switch level {
+	case LevelError:
+		return "warning"
+	case LevelWarn:
+		return "comment"
+	default:
+		return "info"
+	}
+}
+
+type weComMarkdown struct {
+	Content string `json:"content"`
+}
+
+type weComMessage struct {
+	MsgType  string        `json:"msgtype"`
+	Markdown weComMarkdown `json:"markdown"`
+}
+
+func (w *WeComSender) Send(ctx context.Context, level Level, title, message string) error {
+	if w.wh == "" {
+		return errors.New("wecom webhook url is empty")
+	}
+
+	body, err := sonic.Marshal(weComMessage{
+		MsgType: "markdown",
+		Markdown: weComMarkdown{
+			Content: fmt.Sprintf(
+				"### <font color=\"%s\">[%s]</font> %s\n%s\n> %s",
+				level2WeComColor(level), level, title, message, notifyNote(),
+			),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	respBody, err := postJSON(ctx, w.wh, body, nil)
+	if err != nil {
+		return err
+	}
+
+	return checkErrcode(respBody)
+}
+
+func NewWeComNotify(wh string) Notifier {
+	return NewMultiNotifier(Route{Name: "wecom", Sender: NewWeComSender(wh)})
+}
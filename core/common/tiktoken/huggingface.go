@@ -0,0 +1,796 @@
+package tiktoken
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/bytedance/sonic"
+	"github.com/dlclark/regexp2"
+	"github.com/tiktoken-go/tokenizer"
+	"golang.org/x/text/unicode/norm"
+)
+
+// gpt2Pattern is the split pattern of the byte level pre tokenizer
+const gpt2Pattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+
+// maxBPECacheSize bounds the words cache of a tokenizer
+const maxBPECacheSize = 1 << 16
+
+type hfAddedToken struct {
+	ID      uint   `json:"id"`
+	Content string `json:"content"`
+}
+
+type hfPattern struct {
+	String *string `json:"String"`
+	Regex  *string `json:"Regex"`
+}
+
+// hfComponent is a normalizer or a pre tokenizer of the tokenizer.json
+type hfComponent struct {
+	Type             string         `json:"type"`
+	Normalizers      []*hfComponent `json:"normalizers"`
+	Pretokenizers    []*hfComponent `json:"pretokenizers"`
+	Prepend          string         `json:"prepend"`
+	Pattern          hfPattern      `json:"pattern"`
+	Content          string         `json:"content"`
+	Behavior         string         `json:"behavior"`
+	Invert           bool           `json:"invert"`
+	AddPrefixSpace   bool           `json:"add_prefix_space"`
+	UseRegex         *bool          `json:"use_regex"`
+	Replacement      string         `json:"replacement"`
+	PrependScheme    string         `json:"prepend_scheme"`
+	Split            *bool          `json:"split"`
+	IndividualDigits bool           `json:"individual_digits"`
+	StripLeft        bool           `json:"strip_left"`
+	StripRight       bool           `json:"strip_right"`
+}
+
+type hfModel struct {
+	Type                    string          `json:"type"`
+	Vocab                   map[string]uint `json:"vocab"`
+	Merges                  []any           `json:"merges"`
+	UnkToken                *string         `json:"unk_token"`
+	ByteFallback            bool            `json:"byte_fallback"`
+	IgnoreMerges            bool            `json:"ignore_merges"`
+	ContinuingSubwordPrefix *string         `json:"continuing_subword_prefix"`
+	EndOfWordSuffix         *string         `json:"end_of_word_suffix"`
+}
+
+// hfTokenizerFile is the part of the huggingface tokenizer.json the
+// tokenizer uses, the post processor is ignored as the prompts are counted
+// without the special tokens
+type hfTokenizerFile struct {
+	AddedTokens  []hfAddedToken `json:"added_tokens"`
+	Normalizer   *hfComponent   `json:"normalizer"`
+	PreTokenizer *hfComponent   `json:"pre_tokenizer"`
+	Model        hfModel        `json:"model"`
+}
+
+type bpePair struct {
+	left, right string
+}
+
+type normalizer func(string) string
+
+type preTokenizer func(pieces []string) []string
+
+var _ tokenizer.Codec = (*HFTokenizer)(nil)
+
+// HFTokenizer is a BPE tokenizer of a huggingface tokenizer.json, both the
+// byte level (gpt2, llama3, qwen, deepseek) and the sentencepiece style
+// (llama2, mistral) tokenizers are supported
+type HFTokenizer struct {
+	name string
+
+	vocab        map[string]uint
+	idToToken    map[uint]string
+	merges       map[bpePair]int
+	unkID        uint
+	hasUnk       bool
+	byteFallback bool
+	ignoreMerges bool
+
+	addedTokens  map[string]uint
+	addedPattern *regexp.Regexp
+
+	normalize    normalizer
+	preTokenize  preTokenizer
+	byteLevel    bool
+	metaspace    string
+	metaPrepends bool
+
+	cacheLock sync.RWMutex
+	cache     map[string][]uint
+}
+
+// LoadHFTokenizer loads the tokenizer.json at the path
+func LoadHFTokenizer(name, path string) (*HFTokenizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseHFTokenizer(name, data)
+}
+
+// ParseHFTokenizer parses a huggingface tokenizer.json
+func ParseHFTokenizer(name string, data []byte) (*HFTokenizer, error) {
+	var file hfTokenizerFile
+	if err := sonic.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid tokenizer.json: %w", err)
+	}
+
+	if file.Model.Type != "" && file.Model.Type != "BPE" {
+		return nil, fmt.Errorf("unsupported tokenizer model: %s", file.Model.Type)
+	}
+
+	if (file.Model.ContinuingSubwordPrefix != nil && *file.Model.ContinuingSubwordPrefix != "") ||
+		(file.Model.EndOfWordSuffix != nil && *file.Model.EndOfWordSuffix != "") {
+		return nil, errors.New("subword prefix and suffix are not supported")
+	}
+
+	if len(file.Model.Vocab) == 0 {
+		return nil, errors.New("tokenizer vocab is empty")
+	}
+
+	t := &HFTokenizer{
+		name:         name,
+		vocab:        file.Model.Vocab,
+		idToToken:    make(map[uint]string, len(file.Model.Vocab)+len(file.AddedTokens)),
+		merges:       make(map[bpePair]int, len(file.Model.Merges)),
+		byteFallback: file.Model.ByteFallback,
+		ignoreMerges: file.Model.IgnoreMerges,
+		addedTokens:  make(map[string]uint, len(file.AddedTokens)),
+		cache:        make(map[string][]uint),
+	}
+
+	for token, id := range t.vocab {
+		t.idToToken[id] = token
+	}
+
+	if file.Model.UnkToken != nil {
+		t.unkID, t.hasUnk = t.vocab[*file.Model.UnkToken]
+	}
+
+	for rank, merge := range file.Model.Merges {
+		pair, err := parseMerge(merge)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := t.merges[pair]; !ok {
+			t.merges[pair] = rank
+		}
+	}
+
+	if err := t.initAddedTokens(file.AddedTokens); err != nil {
+		return nil, err
+	}
+
+	var err error
+
+	t.normalize, err = t.buildNormalizer(file.Normalizer)
+	if err != nil {
+		return nil, err
+	}
+
+	t.preTokenize, err = t.buildPreTokenizer(file.PreTokenizer)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// parseMerge parses a merge of the "a b" or the ["a", "b"] format
+func parseMerge(merge any) (bpePair, error) {
+	switch merge := merge.(type) {
+	case string:
+		left, right, ok := strings.Cut(merge, " ")
+		if ok {
+			return bpePair{left, right}, nil
+		}
+	case []any:
+		if len(merge) == 2 {
+			left, lok := merge[0].(string)
+			right, rok := merge[1].(string)
+			if lok && rok {
+				return bpePair{left, right}, nil
+			}
+		}
+	}
+
+	return bpePair{}, fmt.Errorf("invalid merge: %v", merge)
+}
+
+func (t *HFTokenizer) initAddedTokens(addedTokens []hfAddedToken) error {
+	if len(addedTokens) == 0 {
+		return nil
+	}
+
+	contents := make([]string, 0, len(addedTokens))
+	for _, token := range addedTokens {
+		if token.Content == "" {
+			continue
+		}
+
+		t.addedTokens[token.Content] = token.ID
+		t.idToToken[token.ID] = token.Content
+		contents = append(contents, token.Content)
+	}
+
+	if len(contents) == 0 {
+		return nil
+	}
+
+	// the alternation is leftmost first, so the longer tokens go first
+	slices.SortFunc(contents, func(a, b string) int {
+		return len(b) - len(a)
+	})
+
+	for i, content := range contents {
+		contents[i] = regexp.QuoteMeta(content)
+	}
+
+	var err error
+
+	t.addedPattern, err = regexp.Compile(strings.Join(contents, "|"))
+
+	return err
+}
+
+func compilePattern(pattern hfPattern) (*regexp2.Regexp, error) {
+	switch {
+	case pattern.String != nil:
+		return regexp2.Compile(regexp2.Escape(*pattern.String), regexp2.None)
+	case pattern.Regex != nil:
+		return regexp2.Compile(*pattern.Regex, regexp2.None)
+	default:
+		return nil, errors.New("pattern is empty")
+	}
+}
+
+func (t *HFTokenizer) buildNormalizer(c *hfComponent) (normalizer, error) {
+	if c == nil {
+		return func(s string) string { return s }, nil
+	}
+
+	switch c.Type {
+	case "Sequence":
+		normalizers := make([]normalizer, 0, len(c.Normalizers))
+		for _, sub := range c.Normalizers {
+			n, err := t.buildNormalizer(sub)
+			if err != nil {
+				return nil, err
+			}
+
+			normalizers = append(normalizers, n)
+		}
+
+		return func(s string) string {
+			for _, n := range normalizers {
+				s = n(s)
+			}
+			return s
+		}, nil
+	case "NFC":
+		return norm.NFC.String, nil
+	case "NFD":
+		return norm.NFD.String, nil
+	case "NFKC":
+		return norm.NFKC.String, nil
+	case "NFKD":
+		return norm.NFKD.String, nil
+	case "Lowercase":
+		return strings.ToLower, nil
+	case "Strip":
+		return func(s string) string {
+			if c.StripLeft {
+				s = strings.TrimLeftFunc(s, unicode.IsSpace)
+			}
+			if c.StripRight {
+				s = strings.TrimRightFunc(s, unicode.IsSpace)
+			}
+			return s
+		}, nil
+	case "Prepend":
+		return func(s string) string {
+			if s == "" {
+				return s
+			}
+			return c.Prepend + s
+		}, nil
+	case "Replace":
+		if c.Pattern.String != nil {
+			old := *c.Pattern.String
+			return func(s string) string {
+				return strings.ReplaceAll(s, old, c.Content)
+			}, nil
+		}
+
+		re, err := compilePattern(c.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		content := strings.ReplaceAll(c.Content, "$", "$$")
+
+		return func(s string) string {
+			replaced, err := re.Replace(s, content, -1, -1)
+			if err != nil {
+				return s
+			}
+			return replaced
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported normalizer: %s", c.Type)
+	}
+}
+
+func (t *HFTokenizer) buildPreTokenizer(c *hfComponent) (preTokenizer, error) {
+	if c == nil {
+		return func(pieces []string) []string { return pieces }, nil
+	}
+
+	switch c.Type {
+	case "Sequence":
+		preTokenizers := make([]preTokenizer, 0, len(c.Pretokenizers))
+		for _, sub := range c.Pretokenizers {
+			p, err := t.buildPreTokenizer(sub)
+			if err != nil {
+				return nil, err
+			}
+
+			preTokenizers = append(preTokenizers, p)
+		}
+
+		return func(pieces []string) []string {
+			for _, p := range preTokenizers {
+				pieces = p(pieces)
+			}
+			return pieces
+		}, nil
+	case "Split":
+		re, err := compilePattern(c.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		return splitPreTokenizer(re, c.Behavior, c.Invert), nil
+	case "Digits":
+		pattern := `\p{Nd}+`
+		if c.IndividualDigits {
+			pattern = `\p{Nd}`
+		}
+
+		return splitPreTokenizer(regexp2.MustCompile(pattern, regexp2.None), "Isolated", false), nil
+	case "WhitespaceSplit":
+		return func(pieces []string) []string {
+			var out []string
+			for _, piece := range pieces {
+				out = append(out, strings.Fields(piece)...)
+			}
+			return out
+		}, nil
+	case "Whitespace":
+		return splitPreTokenizer(
+			regexp2.MustCompile(`\w+|[^\w\s]+`, regexp2.None),
+			"Removed",
+			true,
+		), nil
+	case "ByteLevel":
+		t.byteLevel = true
+
+		var split preTokenizer
+		if c.UseRegex == nil || *c.UseRegex {
+			split = splitPreTokenizer(regexp2.MustCompile(gpt2Pattern, regexp2.None), "Isolated", false)
+		}
+
+		return func(pieces []string) []string {
+			if c.AddPrefixSpace {
+				for i, piece := range pieces {
+					if !strings.HasPrefix(piece, " ") {
+						pieces[i] = " " + piece
+					}
+				}
+			}
+
+			if split != nil {
+				pieces = split(pieces)
+			}
+
+			for i, piece := range pieces {
+				pieces[i] = byteLevelEncode(piece)
+			}
+
+			return pieces
+		}, nil
+	case "Metaspace":
+		replacement := c.Replacement
+		if replacement == "" {
+			replacement = "▁"
+		}
+
+		scheme := c.PrependScheme
+		if scheme == "" {
+			scheme = "never"
+			if c.AddPrefixSpace {
+				scheme = "always"
+			}
+		}
+
+		t.metaspace = replacement
+		t.metaPrepends = scheme != "never"
+
+		splitPieces := c.Split == nil || *c.Split
+
+		return func(pieces []string) []string {
+			out := make([]string, 0, len(pieces))
+			for i, piece := range pieces {
+				piece = strings.ReplaceAll(piece, " ", replacement)
+				if (scheme == "always" || (scheme == "first" && i == 0)) &&
+					!strings.HasPrefix(piece, replacement) {
+					piece = replacement + piece
+				}
+
+				if !splitPieces {
+					out = append(out, piece)
+					continue
+				}
+
+				out = append(out, splitBefore(piece, replacement)...)
+			}
+			return out
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported pre tokenizer: %s", c.Type)
+	}
+}
+
+// splitBefore splits s before every sep, the sep stays with the next piece
+func splitBefore(s, sep string) []string {
+	var (
+		out   []string
+		start int
+	)
+
+	for i := 1; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], sep) {
+			out = append(out, s[start:i])
+			start = i
+		}
+	}
+
+	if start < len(s) {
+		out = append(out, s[start:])
+	}
+
+	return out
+}
+
+type splitSegment struct {
+	text  string
+	match bool
+}
+
+// splitPreTokenizer splits the pieces by the matches of re, the behavior
+// decides what happens with the matches
+func splitPreTokenizer(re *regexp2.Regexp, behavior string, invert bool) preTokenizer {
+	behavior = strings.ToLower(behavior)
+
+	return func(pieces []string) []string {
+		var out []string
+		for _, piece := range pieces {
+			out = append(out, splitByPattern(re, piece, behavior, invert)...)
+		}
+		return out
+	}
+}
+
+func splitByPattern(re *regexp2.Regexp, s, behavior string, invert bool) []string {
+	if s == "" {
+		return nil
+	}
+
+	runes := []rune(s)
+
+	var (
+		segments []splitSegment
+		last     int
+	)
+
+	m, _ := re.FindRunesMatch(runes)
+	for m != nil {
+		if m.Length == 0 {
+			m, _ = re.FindNextMatch(m)
+			continue
+		}
+
+		if m.Index > last {
+			segments = append(segments, splitSegment{text: string(runes[last:m.Index])})
+		}
+
+		segments = append(segments, splitSegment{
+			text:  string(runes[m.Index : m.Index+m.Length]),
+			match: true,
+		})
+		last = m.Index + m.Length
+
+		m, _ = re.FindNextMatch(m)
+	}
+
+	if last < len(runes) {
+		segments = append(segments, splitSegment{text: string(runes[last:])})
+	}
+
+	if invert {
+		for i := range segments {
+			segments[i].match = !segments[i].match
+		}
+	}
+
+	out := make([]string, 0, len(segments))
+
+	switch behavior {
+	case "removed":
+		for _, segment := range segments {
+			if !segment.match {
+				out = append(out, segment.text)
+			}
+		}
+	case "mergedwithprevious":
+		for _, segment := range segments {
+			if segment.match && len(out) > 0 {
+				out[len(out)-1] += segment.text
+				continue
+			}
+			out = append(out, segment.text)
+		}
+	case "mergedwithnext":
+		pending := ""
+		for _, segment := range segments {
+			if segment.match {
+				pending += segment.text
+				continue
+			}
+			out = append(out, pending+segment.text)
+			pending = ""
+		}
+		if pending != "" {
+			out = append(out, pending)
+		}
+	case "contiguous":
+		previousMatch := false
+		for _, segment := range segments {
+			if segment.match && previousMatch && len(out) > 0 {
+				out[len(out)-1] += segment.text
+				continue
+			}
+			out = append(out, segment.text)
+			previousMatch = segment.match
+		}
+	default:
+		for _, segment := range segments {
+			out = append(out, segment.text)
+		}
+	}
+
+	return out
+}
+
+var (
+	byteToRune [256]rune
+	runeToByte = make(map[rune]byte, 256)
+)
+
+// the byte to unicode table of gpt2, the printable bytes map to themselves
+// and the others are shifted past 255
+func init() {
+	n := 0
+	for b := range 256 {
+		if (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF) {
+			byteToRune[b] = rune(b)
+		} else {
+			byteToRune[b] = rune(256 + n)
+			n++
+		}
+
+		runeToByte[byteToRune[b]] = byte(b)
+	}
+}
+
+func byteLevelEncode(s string) string {
+	var builder strings.Builder
+	builder.Grow(len(s) * 2)
+
+	for i := range len(s) {
+		builder.WriteRune(byteToRune[s[i]])
+	}
+
+	return builder.String()
+}
+
+func (t *HFTokenizer) GetName() string {
+	return t.name
+}
+
+func (t *HFTokenizer) Count(text string) (int, error) {
+	ids, _, err := t.Encode(text)
+	return len(ids), err
+}
+
+func (t *HFTokenizer) Encode(text string) ([]uint, []string, error) {
+	var ids []uint
+
+	encodeSegment := func(segment string) {
+		if segment == "" {
+			return
+		}
+
+		for _, piece := range t.preTokenize([]string{t.normalize(segment)}) {
+			ids = append(ids, t.bpe(piece)...)
+		}
+	}
+
+	if t.addedPattern != nil {
+		last := 0
+		for _, loc := range t.addedPattern.FindAllStringIndex(text, -1) {
+			encodeSegment(text[last:loc[0]])
+			ids = append(ids, t.addedTokens[text[loc[0]:loc[1]]])
+			last = loc[1]
+		}
+
+		encodeSegment(text[last:])
+	} else {
+		encodeSegment(text)
+	}
+
+	tokens := make([]string, len(ids))
+	for i, id := range ids {
+		tokens[i] = t.idToToken[id]
+	}
+
+	return ids, tokens, nil
+}
+
+func (t *HFTokenizer) bpe(word string) []uint {
+	if word == "" {
+		return nil
+	}
+
+	t.cacheLock.RLock()
+	ids, ok := t.cache[word]
+	t.cacheLock.RUnlock()
+
+	if ok {
+		return ids
+	}
+
+	ids = t.mergeWord(word)
+
+	t.cacheLock.Lock()
+	if len(t.cache) >= maxBPECacheSize {
+		clear(t.cache)
+	}
+	t.cache[word] = ids
+	t.cacheLock.Unlock()
+
+	return ids
+}
+
+func (t *HFTokenizer) mergeWord(word string) []uint {
+	if id, ok := t.vocab[word]; ok && t.ignoreMerges {
+		return []uint{id}
+	}
+
+	symbols := make([]string, 0, len(word))
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+
+	for len(symbols) > 1 {
+		best, bestRank := -1, math.MaxInt
+		for i := range len(symbols) - 1 {
+			rank, ok := t.merges[bpePair{symbols[i], symbols[i+1]}]
+			if ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+
+		if best < 0 {
+			break
+		}
+
+		symbols[best] += symbols[best+1]
+		symbols = slices.Delete(symbols, best+1, best+2)
+	}
+
+	ids := make([]uint, 0, len(symbols))
+	for _, symbol := range symbols {
+		if id, ok := t.vocab[symbol]; ok {
+			ids = append(ids, id)
+			continue
+		}
+
+		if t.byteFallback {
+			fallback := true
+			for i := range len(symbol) {
+				id, ok := t.vocab[fmt.Sprintf("<0x%02X>", symbol[i])]
+				if !ok {
+					fallback = false
+					break
+				}
+				ids = append(ids, id)
+			}
+			if fallback {
+				continue
+			}
+		}
+
+		if t.hasUnk {
+			ids = append(ids, t.unkID)
+		}
+	}
+
+	return ids
+}
+
+func (t *HFTokenizer) Decode(ids []uint) (string, error) {
+	var buf []byte
+
+	for _, id := range ids {
+		token, ok := t.idToToken[id]
+		if !ok {
+			return "", fmt.Errorf("unknown token id: %d", id)
+		}
+
+		if _, ok := t.addedTokens[token]; ok {
+			buf = append(buf, token...)
+			continue
+		}
+
+		if t.byteLevel {
+			for _, r := range token {
+				if b, ok := runeToByte[r]; ok {
+					buf = append(buf, b)
+				} else {
+					buf = append(buf, string(r)...)
+				}
+			}
+			continue
+		}
+
+		if t.byteFallback && len(token) == 6 && strings.HasPrefix(token, "<0x") &&
+			strings.HasSuffix(token, ">") {
+			if b, err := strconv.ParseUint(token[3:5], 16, 8); err == nil {
+				buf = append(buf, byte(b))
+				continue
+			}
+		}
+
+		buf = append(buf, token...)
+	}
+
+	text := string(buf)
+	if t.metaspace != "" || !t.byteLevel {
+		text = strings.ReplaceAll(text, "▁", " ")
+		if t.metaspace != "" && t.metaspace != "▁" {
+			text = strings.ReplaceAll(text, t.metaspace, " ")
+		}
+
+		if t.metaPrepends || t.metaspace == "" {
+			text = strings.TrimPrefix(text, " ")
+		}
+	}
+
+	return text, nil
+}
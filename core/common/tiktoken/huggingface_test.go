@@ -0,0 +1,100 @@
+package tiktoken_test
+
+import (
+	"testing"
+
+	"github.com/wavespeed/llm-server/core/common/tiktoken"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+const byteLevelTokenizer = `{
+	"added_tokens": [{"id": 100, "content": "<|end|>", "special": true}],
+	"normalizer": null,
+	"pre_tokenizer": {"type": "ByteLevel", "add_prefix_space": false, "use_regex": true},
+	"model": {
+		"type": "BPE",
+		"vocab": {
+			"h": 0, "e": 1, "l": 2, "o": 3, "Ġ": 4, "w": 5, "r": 6, "d": 7,
+			"he": 8, "ll": 9, "hell": 10, "hello": 11, "Ġw": 12, "or": 13, "Ġwor": 14
+		},
+		"merges": ["h e", "l l", "he ll", "hell o", "Ġ w", "o r", ["Ġw", "or"]]
+	}
+}`
+
+const sentencePieceTokenizer = `{
+	"normalizer": {
+		"type": "Sequence",
+		"normalizers": [
+			{"type": "Prepend", "prepend": "▁"},
+			{"type": "Replace", "pattern": {"String": " "}, "content": "▁"}
+		]
+	},
+	"pre_tokenizer": null,
+	"model": {
+		"type": "BPE",
+		"byte_fallback": true,
+		"unk_token": "<unk>",
+		"vocab": {
+			"<unk>": 0, "▁": 1, "h": 2, "i": 3, "▁h": 4, "▁hi": 5,
+			"<0xE4>": 6, "<0xBD>": 7, "<0xA0>": 8
+		},
+		"merges": ["▁ h", "▁h i"]
+	}
+}`
+
+func TestHFTokenizer(t *testing.T) {
+	convey.Convey("HFTokenizer", t, func() {
+		convey.Convey("should encode byte level tokenizers", func() {
+			enc, err := tiktoken.ParseHFTokenizer("byte-level", []byte(byteLevelTokenizer))
+			convey.So(err, convey.ShouldBeNil)
+
+			ids, tokens, err := enc.Encode("hello world<|end|>")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(ids, convey.ShouldResemble, []uint{11, 14, 2, 7, 100})
+			convey.So(tokens, convey.ShouldResemble, []string{"hello", "Ġwor", "l", "d", "<|end|>"})
+
+			text, err := enc.Decode(ids)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(text, convey.ShouldEqual, "hello world<|end|>")
+		})
+
+		convey.Convey("should encode sentencepiece tokenizers with byte fallback", func() {
+			enc, err := tiktoken.ParseHFTokenizer("sentencepiece", []byte(sentencePieceTokenizer))
+			convey.So(err, convey.ShouldBeNil)
+
+			ids, _, err := enc.Encode("hi 你")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(ids, convey.ShouldResemble, []uint{5, 1, 6, 7, 8})
+
+			count, err := enc.Count("hi 你")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(count, convey.ShouldEqual, 5)
+
+			text, err := enc.Decode(ids)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(text, convey.ShouldEqual, "hi 你")
+		})
+
+		convey.Convey("should reject unsupported models", func() {
+			_, err := tiktoken.ParseHFTokenizer("unigram", []byte(`{"model": {"type": "Unigram"}}`))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+	})
+}
+
+func TestGetTokenizer(t *testing.T) {
+	convey.Convey("GetTokenizer", t, func() {
+		convey.Convey("should get tiktoken encodings", func() {
+			enc, err := tiktoken.GetTokenizer("cl100k_base")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(enc.GetName(), convey.ShouldEqual, "cl100k_base")
+		})
+
+		convey.Convey("should use the tokenizer of the model", func() {
+			tiktoken.SetModelTokenizers(map[string]string{"custom-model": "cl100k_base"})
+			defer tiktoken.SetModelTokenizers(nil)
+
+			convey.So(tiktoken.GetTokenEncoder("custom-model").GetName(), convey.ShouldEqual, "cl100k_base")
+		})
+	})
+}
@@ -0,0 +1,138 @@
+package tiktoken
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wavespeed/llm-server/core/common/env"
+	log "github.com/sirupsen/logrus"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// TOKENIZER_DIR holds the huggingface tokenizers, the tokenizer of a name is
+// either <dir>/<name>/tokenizer.json or <dir>/<name>.json
+var tokenizerDir = env.String("TOKENIZER_DIR", "")
+
+type namedTokenizer struct {
+	codec tokenizer.Codec
+	err   error
+}
+
+var (
+	namedTokenizers     = map[string]*namedTokenizer{}
+	namedTokenizersLock sync.Mutex
+
+	// map[model]tokenizer
+	modelTokenizers atomic.Pointer[map[string]string]
+)
+
+// SetModelTokenizers sets the tokenizers the models pick in their configs
+func SetModelTokenizers(tokenizers map[string]string) {
+	modelTokenizers.Store(&tokenizers)
+}
+
+// ModelTokenizer returns the tokenizer the model picks
+func ModelTokenizer(model string) (string, bool) {
+	tokenizers := modelTokenizers.Load()
+	if tokenizers == nil {
+		return "", false
+	}
+
+	name, ok := (*tokenizers)[model]
+
+	return name, ok && name != ""
+}
+
+func tokenizerPath(name string) (string, error) {
+	if tokenizerDir == "" {
+		return "", errors.New("TOKENIZER_DIR is not set")
+	}
+
+	if name == "" || name == "." || name == ".." ||
+		strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid tokenizer name: %s", name)
+	}
+
+	path := filepath.Join(tokenizerDir, name, "tokenizer.json")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	path = filepath.Join(tokenizerDir, name+".json")
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("tokenizer %s not found", name)
+	}
+
+	return path, nil
+}
+
+func loadTokenizer(name string) (tokenizer.Codec, error) {
+	if codec, err := tokenizer.Get(tokenizer.Encoding(name)); err == nil {
+		return codec, nil
+	}
+
+	path, err := tokenizerPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadHFTokenizer(name, path)
+}
+
+// GetTokenizer returns the tokenizer of the name, the name is a tokenizer of
+// TOKENIZER_DIR or a tiktoken encoding like cl100k_base, the tokenizers are
+// loaded once
+func GetTokenizer(name string) (tokenizer.Codec, error) {
+	namedTokenizersLock.Lock()
+	defer namedTokenizersLock.Unlock()
+
+	if t, ok := namedTokenizers[name]; ok {
+		return t.codec, t.err
+	}
+
+	codec, err := loadTokenizer(name)
+	if err != nil {
+		log.Errorf("failed to load tokenizer %s: %v", name, err)
+	} else {
+		log.Infof("loaded tokenizer %s", name)
+	}
+
+	namedTokenizers[name] = &namedTokenizer{codec: codec, err: err}
+
+	return codec, err
+}
+
+// Tokenizers returns the names of the tokenizers of TOKENIZER_DIR
+func Tokenizers() ([]string, error) {
+	if tokenizerDir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(tokenizerDir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		switch {
+		case entry.IsDir():
+			path := filepath.Join(tokenizerDir, entry.Name(), "tokenizer.json")
+			if _, err := os.Stat(path); err == nil {
+				names = append(names, entry.Name())
+			}
+		case strings.HasSuffix(entry.Name(), ".json"):
+			names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
@@ -24,7 +24,15 @@ func init() {
 	defaultTokenEncoder = gpt4oTokenEncoder
 }
 
+// GetTokenEncoder returns the tokenizer the model picks in its config, the
+// tiktoken encoder of the model otherwise
 func GetTokenEncoder(model string) tokenizer.Codec {
+	if name, ok := ModelTokenizer(model); ok {
+		if codec, err := GetTokenizer(name); err == nil {
+			return codec
+		}
+	}
+
 	tokenEncoderLock.RLock()
 
 	tokenEncoder, ok := tokenEncoderMap[model]
@@ -0,0 +1,3 @@
+package tracing
+
+var Setup = setup
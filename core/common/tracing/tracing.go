@@ -0,0 +1,133 @@
+// Package tracing exports the OpenTelemetry traces of the requests
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/wavespeed/llm-server/core/common/env"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName         = "github.com/wavespeed/llm-server/core"
+	defaultServiceName = "aiproxy"
+)
+
+// the attributes the spans of a request carry
+const (
+	RequestIDKey = attribute.Key("aiproxy.request_id")
+	GroupKey     = attribute.Key("aiproxy.group")
+	TokenNameKey = attribute.Key("aiproxy.token_name")
+	ChannelIDKey = attribute.Key("aiproxy.channel_id")
+	ModelKey     = attribute.Key("aiproxy.model")
+	ModeKey      = attribute.Key("aiproxy.mode")
+	RetryKey     = attribute.Key("aiproxy.retry")
+)
+
+var enabled atomic.Bool
+
+// Enabled reports whether the traces are exported
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Init sets up the OTLP http exporter when an OTLP endpoint is configured,
+// the exporter is configured by the standard OTEL_EXPORTER_OTLP_* envs and
+// the returned function flushes the pending spans
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	if env.Bool("OTEL_SDK_DISABLED", false) ||
+		(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" &&
+			os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "") {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", defaultServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	setup(provider)
+
+	return provider.Shutdown, nil
+}
+
+func setup(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	enabled.Store(true)
+}
+
+// Start starts a span, the span is a no-op when the tracing is disabled
+func Start(
+	ctx context.Context,
+	name string,
+	opts ...trace.SpanStartOption,
+) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// SetAttributes sets the attributes on the span of the context
+func SetAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.SetAttributes(attrs...)
+	}
+}
+
+// RecordError marks the span as failed
+func RecordError(span trace.Span, err error) {
+	if err == nil || !span.IsRecording() {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Extract returns the context with the trace context of the incoming headers
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject writes the trace context of ctx into the outgoing headers
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Headers returns the trace context headers of ctx, it fits the header
+// funcs of the MCP client transports
+func Headers(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	return carrier
+}
@@ -0,0 +1,49 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/wavespeed/llm-server/core/common/tracing"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracing.Setup(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, parent := tracing.Start(context.Background(), "request")
+
+	headers := tracing.Headers(ctx)
+	if headers["traceparent"] == "" {
+		t.Fatalf("Headers() = %v, want traceparent", headers)
+	}
+
+	// the upstream continues the trace from the injected headers
+	header := http.Header{}
+	tracing.Inject(ctx, header)
+
+	_, child := tracing.Start(tracing.Extract(context.Background(), header), "upstream")
+	tracing.RecordError(child, errors.New("upstream failed"))
+	child.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(spans))
+	}
+
+	upstream, request := spans[0], spans[1]
+	if upstream.Parent().SpanID() != request.SpanContext().SpanID() ||
+		upstream.SpanContext().TraceID() != request.SpanContext().TraceID() {
+		t.Errorf("upstream span is not a child of the request span")
+	}
+
+	if upstream.Status().Code != codes.Error {
+		t.Errorf("upstream status = %v, want error", upstream.Status())
+	}
+}
@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"gorm.io/gorm"
+)
+
+func adminKeyErrorStatus(err error) int {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+// GetAdminKeys godoc
+//
+//	@Summary		Get admin keys
+//	@Description	Returns all admin keys
+//	@Tags			admin_key
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	middleware.APIResponse{data=[]model.AdminKey}
+//	@Router			/api/admin_keys/ [get]
+func GetAdminKeys(c *gin.Context) {
+	keys, err := model.GetAdminKeys()
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, keys)
+}
+
+// GetAdminKey godoc
+//
+//	@Summary		Get an admin key
+//	@Description	Returns an admin key by its id
+//	@Tags			admin_key
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		int	true	"Admin key ID"
+//	@Success		200	{object}	middleware.APIResponse{data=model.AdminKey}
+//	@Router			/api/admin_keys/{id} [get]
+func GetAdminKey(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	key, err := model.GetAdminKey(id)
+	if err != nil {
+		middleware.ErrorResponse(c, adminKeyErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, key)
+}
+
+type SaveAdminKeyRequest struct {
+	Name   string               `json:"name"`
+	Role   model.Role           `json:"role"`
+	Groups []string             `json:"groups"`
+	Status model.AdminKeyStatus `json:"status"`
+}
+
+func (r *SaveAdminKeyRequest) adminKey(id int) *model.AdminKey {
+	return &model.AdminKey{
+		ID:     id,
+		Name:   r.Name,
+		Role:   r.Role,
+		Groups: r.Groups,
+		Status: r.Status,
+	}
+}
+
+// CreateAdminKey godoc
+//
+//	@Summary		Create an admin key
+//	@Description	Creates an admin key with a role, the key is limited to the groups when they are set
+//	@Tags			admin_key
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			admin_key	body		SaveAdminKeyRequest	true	"Admin key"
+//	@Success		200			{object}	middleware.APIResponse{data=model.AdminKey}
+//	@Router			/api/admin_keys/ [post]
+func CreateAdminKey(c *gin.Context) {
+	var req SaveAdminKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key := req.adminKey(0)
+	if err := model.CreateAdminKey(key); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, key)
+}
+
+// UpdateAdminKey godoc
+//
+//	@Summary		Update an admin key
+//	@Description	Updates the name, role and groups of an admin key
+//	@Tags			admin_key
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id			path		int					true	"Admin key ID"
+//	@Param			admin_key	body		SaveAdminKeyRequest	true	"Admin key"
+//	@Success		200			{object}	middleware.APIResponse{data=model.AdminKey}
+//	@Router			/api/admin_keys/{id} [put]
+func UpdateAdminKey(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req SaveAdminKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := model.UpdateAdminKey(req.adminKey(id)); err != nil {
+		middleware.ErrorResponse(c, adminKeyErrorStatus(err), err.Error())
+		return
+	}
+
+	key, err := model.GetAdminKey(id)
+	if err != nil {
+		middleware.ErrorResponse(c, adminKeyErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, key)
+}
+
+type UpdateAdminKeyStatusRequest struct {
+	Status model.AdminKeyStatus `json:"status"`
+}
+
+// UpdateAdminKeyStatus godoc
+//
+//	@Summary		Update the status of an admin key
+//	@Description	Enables or disables an admin key
+//	@Tags			admin_key
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id		path		int							true	"Admin key ID"
+//	@Param			status	body		UpdateAdminKeyStatusRequest	true	"Status"
+//	@Success		200		{object}	middleware.APIResponse
+//	@Router			/api/admin_keys/{id}/status [post]
+func UpdateAdminKeyStatus(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req UpdateAdminKeyStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := model.UpdateAdminKeyStatus(id, req.Status); err != nil {
+		middleware.ErrorResponse(c, adminKeyErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, nil)
+}
+
+// DeleteAdminKey godoc
+//
+//	@Summary		Delete an admin key
+//	@Description	Deletes an admin key
+//	@Tags			admin_key
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		int	true	"Admin key ID"
+//	@Success		200	{object}	middleware.APIResponse
+//	@Router			/api/admin_keys/{id} [delete]
+func DeleteAdminKey(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := model.DeleteAdminKey(id); err != nil {
+		middleware.ErrorResponse(c, adminKeyErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, nil)
+}
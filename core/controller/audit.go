@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/controller/utils"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+const maxAuditLogsExport = 10000
+
+// SearchAuditLogs godoc
+//
+//	@Summary		Search audit logs
+//	@Description	Returns the writes of the admin api with their caller and diff, newest first
+//	@Tags			audit
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			actor			query		string	false	"Admin key name"
+//	@Param			route			query		string	false	"Route, matches a part of the route"
+//	@Param			method			query		string	false	"HTTP method"
+//	@Param			group			query		string	false	"Group"
+//	@Param			start_timestamp	query		int		false	"Start timestamp (milliseconds)"
+//	@Param			end_timestamp	query		int		false	"End timestamp (milliseconds)"
+//	@Param			page			query		int		false	"Page number"
+//	@Param			per_page		query		int		false	"Items per page"
+//	@Success		200				{object}	middleware.APIResponse{data=map[string]any{logs=[]model.AuditLog,total=int}}
+//	@Router			/api/audit_logs/ [get]
+func SearchAuditLogs(c *gin.Context) {
+	page, perPage := utils.ParsePageParams(c)
+	startTime, endTime := utils.ParseTimeRange(c, -1)
+
+	logs, total, err := model.SearchAuditLogs(
+		c.Query("actor"),
+		c.Query("route"),
+		c.Query("method"),
+		c.Query("group"),
+		startTime,
+		endTime,
+		page,
+		perPage,
+	)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"logs":  logs,
+		"total": total,
+	})
+}
+
+// ExportAuditLogs godoc
+//
+//	@Summary		Export audit logs
+//	@Description	Exports at most 10000 audit logs of the filters as csv, newest first
+//	@Tags			audit
+//	@Produce		text/csv
+//	@Security		ApiKeyAuth
+//	@Param			actor			query	string	false	"Admin key name"
+//	@Param			route			query	string	false	"Route, matches a part of the route"
+//	@Param			method			query	string	false	"HTTP method"
+//	@Param			group			query	string	false	"Group"
+//	@Param			start_timestamp	query	int		false	"Start timestamp (milliseconds)"
+//	@Param			end_timestamp	query	int		false	"End timestamp (milliseconds)"
+//	@Success		200
+//	@Router			/api/audit_logs/export [get]
+func ExportAuditLogs(c *gin.Context) {
+	startTime, endTime := utils.ParseTimeRange(c, -1)
+
+	logs, err := model.ExportAuditLogs(
+		c.Query("actor"),
+		c.Query("route"),
+		c.Query("method"),
+		c.Query("group"),
+		startTime,
+		endTime,
+		maxAuditLogsExport,
+	)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header(
+		"Content-Disposition",
+		"attachment; filename=audit_logs_"+time.Now().Format("20060102150405")+".csv",
+	)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{
+		"id", "created_at", "actor", "role", "ip", "method", "route", "path", "group", "code", "diff",
+	})
+
+	for _, l := range logs {
+		diff, _ := sonic.MarshalString(l.Diff)
+		_ = w.Write([]string{
+			strconv.Itoa(l.ID),
+			l.CreatedAt.Format(time.RFC3339),
+			l.Actor,
+			string(l.Role),
+			l.IP,
+			l.Method,
+			l.Route,
+			l.Path,
+			l.GroupID,
+			strconv.Itoa(l.Code),
+			diff,
+		})
+	}
+
+	w.Flush()
+}
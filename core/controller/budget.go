@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"gorm.io/gorm"
+)
+
+func budgetErrorStatus(err error) int {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+// GetBudgets godoc
+//
+//	@Summary		Get budgets
+//	@Description	Returns the budgets of a group with the amounts of their current window
+//	@Tags			budget
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string	true	"Group ID"
+//	@Param			scope	query		string	false	"Scope, group, token or user"
+//	@Success		200		{object}	middleware.APIResponse{data=[]model.BudgetStatus}
+//	@Router			/api/budget/{group}/budgets [get]
+func GetBudgets(c *gin.Context) {
+	budgets, err := model.GetBudgets(c.Param("group"), model.BudgetScope(c.Query("scope")))
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, budgetStatuses(budgets))
+}
+
+// GetBudget godoc
+//
+//	@Summary		Get a budget
+//	@Description	Returns a budget of a group with the amounts of its current window
+//	@Tags			budget
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string	true	"Group ID"
+//	@Param			id		path		int		true	"Budget ID"
+//	@Success		200		{object}	middleware.APIResponse{data=model.BudgetStatus}
+//	@Router			/api/budget/{group}/budget/{id} [get]
+func GetBudget(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	budget, err := model.GetBudget(c.Param("group"), id)
+	if err != nil {
+		middleware.ErrorResponse(c, budgetErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, model.NewBudgetStatus(budget, time.Now()))
+}
+
+// GetRemainingBudgets godoc
+//
+//	@Summary		Get remaining budgets
+//	@Description	Returns the budgets a request of the token and end user is charged to, at every level, with the amount left in their current window
+//	@Tags			budget
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group		path		string	true	"Group ID"
+//	@Param			token_name	query		string	false	"Token name"
+//	@Param			user		query		string	false	"End user"
+//	@Success		200			{object}	middleware.APIResponse{data=[]model.BudgetStatus}
+//	@Router			/api/budget/{group}/remaining [get]
+func GetRemainingBudgets(c *gin.Context) {
+	budgets, err := model.GetMatchingBudgets(
+		c.Param("group"),
+		c.Query("token_name"),
+		c.Query("user"),
+	)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, budgetStatuses(budgets))
+}
+
+func budgetStatuses(budgets []*model.Budget) []model.BudgetStatus {
+	now := time.Now()
+
+	statuses := make([]model.BudgetStatus, 0, len(budgets))
+	for _, b := range budgets {
+		statuses = append(statuses, model.NewBudgetStatus(b, now))
+	}
+
+	return statuses
+}
+
+type SaveBudgetRequest struct {
+	Scope     model.BudgetScope `json:"scope"`
+	Subject   string            `json:"subject"`
+	Window    string            `json:"window"`
+	HardLimit float64           `json:"hard_limit"`
+	SoftLimit float64           `json:"soft_limit"`
+}
+
+// CreateBudget godoc
+//
+//	@Summary		Create a budget
+//	@Description	Creates a group, token or end user budget, the subject is the token name or the end user, the window is daily, weekly or monthly
+//	@Tags			budget
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string				true	"Group ID"
+//	@Param			budget	body		SaveBudgetRequest	true	"Budget"
+//	@Success		200		{object}	middleware.APIResponse{data=model.Budget}
+//	@Router			/api/budget/{group}/budgets [post]
+func CreateBudget(c *gin.Context) {
+	var req SaveBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	budget := &model.Budget{
+		GroupID:   c.Param("group"),
+		Scope:     req.Scope,
+		Subject:   req.Subject,
+		Window:    req.Window,
+		HardLimit: req.HardLimit,
+		SoftLimit: req.SoftLimit,
+	}
+	if err := model.CreateBudget(budget); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, budget)
+}
+
+// UpdateBudget godoc
+//
+//	@Summary		Update a budget
+//	@Description	Updates the limits of a budget, the scope, subject and window are not changed
+//	@Tags			budget
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string				true	"Group ID"
+//	@Param			id		path		int					true	"Budget ID"
+//	@Param			budget	body		SaveBudgetRequest	true	"Budget"
+//	@Success		200		{object}	middleware.APIResponse{data=model.BudgetStatus}
+//	@Router			/api/budget/{group}/budget/{id} [put]
+func UpdateBudget(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req SaveBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	group := c.Param("group")
+
+	err := model.UpdateBudget(&model.Budget{
+		ID:        id,
+		GroupID:   group,
+		HardLimit: req.HardLimit,
+		SoftLimit: req.SoftLimit,
+	})
+	if err != nil {
+		status := budgetErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+
+		middleware.ErrorResponse(c, status, err.Error())
+
+		return
+	}
+
+	budget, err := model.GetBudget(group, id)
+	if err != nil {
+		middleware.ErrorResponse(c, budgetErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, model.NewBudgetStatus(budget, time.Now()))
+}
+
+// DeleteBudget godoc
+//
+//	@Summary		Delete a budget
+//	@Description	Deletes a budget
+//	@Tags			budget
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string	true	"Group ID"
+//	@Param			id		path		int		true	"Budget ID"
+//	@Success		200		{object}	middleware.APIResponse
+//	@Router			/api/budget/{group}/budget/{id} [delete]
+func DeleteBudget(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := model.DeleteBudget(c.Param("group"), id); err != nil {
+		middleware.ErrorResponse(c, budgetErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, nil)
+}
@@ -214,16 +214,19 @@ func GetChannel(c *gin.Context) {
 
 // AddChannelRequest represents the request body for adding a channel
 type AddChannelRequest struct {
-	ModelMapping map[string]string    `json:"model_mapping"`
-	Configs      model.ChannelConfigs `json:"configs"`
-	Name         string               `json:"name"`
-	Key          string               `json:"key"`
-	BaseURL      string               `json:"base_url"`
-	Models       []string             `json:"models"`
-	Type         model.ChannelType    `json:"type"`
-	Priority     int32                `json:"priority"`
-	Status       int                  `json:"status"`
-	Sets         []string             `json:"sets"`
+	ModelMapping map[string]string      `json:"model_mapping"`
+	Configs      model.ChannelConfigs   `json:"configs"`
+	Name         string                 `json:"name"`
+	Key          string                 `json:"key"`
+	BaseURL      string                 `json:"base_url"`
+	Models       []string               `json:"models"`
+	Type         model.ChannelType      `json:"type"`
+	Priority     int32                  `json:"priority"`
+	Status       int                    `json:"status"`
+	Sets         []string               `json:"sets"`
+	Currency     string                 `json:"currency"`
+	CostPrices   map[string]model.Price `json:"cost_prices"`
+	CostRatio    float64                `json:"cost_ratio"`
 }
 
 func (r *AddChannelRequest) ToChannel() (*model.Channel, error) {
@@ -277,6 +280,9 @@ func (r *AddChannelRequest) ToChannel() (*model.Channel, error) {
 		Status:       r.Status,
 		Configs:      r.Configs,
 		Sets:         slices.Clone(r.Sets),
+		Currency:     strings.ToUpper(strings.TrimSpace(r.Currency)),
+		CostPrices:   maps.Clone(r.CostPrices),
+		CostRatio:    r.CostRatio,
 	}, nil
 }
 
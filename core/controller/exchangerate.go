@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/controller/utils"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"gorm.io/gorm"
+)
+
+func exchangeRateErrorStatus(err error) int {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+// GetExchangeRates godoc
+//
+//	@Summary		Get exchange rates
+//	@Description	Returns the history of the exchange rates, newest first
+//	@Tags			exchange_rate
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			from		query		string	false	"From currency"
+//	@Param			to			query		string	false	"To currency"
+//	@Param			page		query		int		false	"Page number"
+//	@Param			per_page	query		int		false	"Items per page"
+//	@Success		200			{object}	middleware.APIResponse{data=map[string]any{exchange_rates=[]model.ExchangeRate,total=int}}
+//	@Router			/api/exchange_rates/ [get]
+func GetExchangeRates(c *gin.Context) {
+	page, perPage := utils.ParsePageParams(c)
+
+	rates, total, err := model.GetExchangeRates(
+		c.Query("from"),
+		c.Query("to"),
+		page,
+		perPage,
+	)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"exchange_rates": rates,
+		"total":          total,
+	})
+}
+
+// GetExchangeRate godoc
+//
+//	@Summary		Get an exchange rate
+//	@Description	Returns an exchange rate of the history
+//	@Tags			exchange_rate
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		int	true	"Exchange rate ID"
+//	@Success		200	{object}	middleware.APIResponse{data=model.ExchangeRate}
+//	@Router			/api/exchange_rate/{id} [get]
+func GetExchangeRate(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	rate, err := model.GetExchangeRate(id)
+	if err != nil {
+		middleware.ErrorResponse(c, exchangeRateErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, rate)
+}
+
+// ConvertCurrency godoc
+//
+//	@Summary		Convert an amount
+//	@Description	Converts an amount at the exchange rate effective at the time
+//	@Tags			exchange_rate
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			amount		query		number	true	"Amount"
+//	@Param			from		query		string	false	"From currency"
+//	@Param			to			query		string	false	"To currency"
+//	@Param			timestamp	query		int		false	"Time of the rate (seconds), now by default"
+//	@Success		200			{object}	middleware.APIResponse{data=map[string]any{amount=number,rate=number}}
+//	@Router			/api/exchange_rates/convert [get]
+func ConvertCurrency(c *gin.Context) {
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "invalid amount")
+		return
+	}
+
+	at := time.Now()
+	if timestamp, _ := strconv.ParseInt(c.Query("timestamp"), 10, 64); timestamp > 0 {
+		at = time.Unix(timestamp, 0)
+	}
+
+	rate, err := model.ExchangeRateAt(c.Query("from"), c.Query("to"), at)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"amount": amount * rate,
+		"rate":   rate,
+	})
+}
+
+type CreateExchangeRateRequest struct {
+	From string  `json:"from"`
+	To   string  `json:"to"`
+	Rate float64 `json:"rate"`
+	// EffectiveAt is the unix seconds the rate applies from, now by default
+	EffectiveAt int64 `json:"effective_at"`
+}
+
+// CreateExchangeRate godoc
+//
+//	@Summary		Create an exchange rate
+//	@Description	Adds a rate to the history of a currency pair, the consumes after its effective time are converted at it
+//	@Tags			exchange_rate
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			data	body		CreateExchangeRateRequest	true	"Exchange rate"
+//	@Success		200		{object}	middleware.APIResponse{data=model.ExchangeRate}
+//	@Router			/api/exchange_rates/ [post]
+func CreateExchangeRate(c *gin.Context) {
+	var req CreateExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rate := &model.ExchangeRate{
+		From: req.From,
+		To:   req.To,
+		Rate: req.Rate,
+	}
+	if req.EffectiveAt > 0 {
+		rate.EffectiveAt = time.Unix(req.EffectiveAt, 0)
+	}
+
+	if err := model.CreateExchangeRate(rate); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, rate)
+}
+
+// DeleteExchangeRate godoc
+//
+//	@Summary		Delete an exchange rate
+//	@Description	Removes a rate from the history, the previous rate of the pair applies again
+//	@Tags			exchange_rate
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		int	true	"Exchange rate ID"
+//	@Success		200	{object}	middleware.APIResponse
+//	@Router			/api/exchange_rate/{id} [delete]
+func DeleteExchangeRate(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := model.DeleteExchangeRate(id); err != nil {
+		middleware.ErrorResponse(c, exchangeRateErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, nil)
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	mcpcontroller "github.com/wavespeed/llm-server/core/controller/mcp"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+)
+
+// Export for testing
+var (
+	RouteByLowestCost         = routeByLowestCost
+	RouteByLowestTTFB         = routeByLowestTTFB
+	RouteByLeastInFlight      = routeByLeastInFlight
+	RouteByWeightedRoundRobin = routeByWeightedRoundRobin
+)
+
+type ChannelRouting = channelRouting
+
+func NewChannelRouting(modelName string, mc model.ModelConfig) *ChannelRouting {
+	return newChannelRouting(modelName, mc)
+}
+
+var RecordChannelTTFBPenalty = recordChannelTTFBPenalty
+
+var (
+	ValidateBatchInput    = validateBatchInput
+	ForEachBatchInput     = forEachBatchInput
+	WriteBatchOutputs     = writeBatchOutputs
+	NewBatchRequestCursor = newBatchRequestCursor
+)
+
+const BatchRequestPageSize = batchRequestPageSize
+
+func BatchRequestCursorGet(c *batchRequestCursor, index int) (*model.BatchJobRequest, error) {
+	return c.get(index)
+}
+
+type FallbackModel struct {
+	Name   string
+	Config model.ModelConfig
+}
+
+func GetFallbackModels(c *gin.Context, m mode.Mode) []FallbackModel {
+	fallbacks := getFallbackModels(c, m)
+
+	models := make([]FallbackModel, 0, len(fallbacks))
+	for _, fallback := range fallbacks {
+		models = append(models, FallbackModel{Name: fallback.name, Config: fallback.config})
+	}
+
+	return models
+}
+
+func UseFallbackModel(c *gin.Context, fallback FallbackModel) {
+	useFallbackModel(c, fallbackModel{name: fallback.Name, config: fallback.Config})
+}
+
+// TakeFallbackModel returns the fallback model picked from models, budget is
+// the exhausted budget of the request
+func TakeFallbackModel(c *gin.Context, models []FallbackModel, budget *model.Budget) (FallbackModel, bool) {
+	fallbacks := &fallbackModels{
+		c: c,
+		exhaustedBudget: func(string, string, string) (*model.Budget, error) {
+			return budget, nil
+		},
+	}
+	for _, m := range models {
+		fallbacks.models = append(fallbacks.models, fallbackModel{name: m.Name, config: m.Config})
+	}
+
+	if !fallbacks.take() {
+		return FallbackModel{}, false
+	}
+
+	return FallbackModel{Name: fallbacks.next.name, Config: fallbacks.next.config}, true
+}
+
+var (
+	ParseRelayMCPTools     = parseRelayMCPTools
+	RelayChatMCPTools      = relayChatMCPTools
+	RelayResponsesMCPTools = relayResponsesMCPTools
+	SamplingModel          = samplingModel
+)
+
+const (
+	MaxMCPToolRounds         = maxMCPToolRounds
+	ErrMCPToolRoundsExceeded = errMCPToolRoundsExceeded
+)
+
+type (
+	RelayMCPTool  = relayMCPTool
+	RelayMCPTools = relayMCPTools
+)
+
+// NewRelayMCPTools returns the mcp tools of functions, call runs the tools
+// and relay returns the status code and the body of the model requests
+func NewRelayMCPTools(
+	functions []string,
+	otherTools []json.RawMessage,
+	call func(ctx context.Context, name, arguments string) (string, error),
+	relay func(m mode.Mode, req map[string]any) (int, []byte),
+) *RelayMCPTools {
+	r := &relayMCPTools{
+		labels:     make(map[string]string, len(functions)),
+		otherTools: otherTools,
+		call:       call,
+		relay: func(m mode.Mode, _ string, req map[string]any) (*internalRelayResponse, error) {
+			code, body := relay(m, req)
+			return &internalRelayResponse{StatusCode: code, Body: body}, nil
+		},
+	}
+
+	for _, name := range functions {
+		label, _, _ := cutMCPToolName(name)
+		r.functions = append(r.functions, mcpcontroller.MCPTool{Name: name})
+		r.labels[name] = label
+	}
+
+	return r
+}
@@ -350,9 +350,11 @@ func UpdateGroupsStatus(c *gin.Context) {
 }
 
 type CreateGroupRequest struct {
-	RPMRatio      float64  `json:"rpm_ratio"`
-	TPMRatio      float64  `json:"tpm_ratio"`
-	AvailableSets []string `json:"available_sets"`
+	RPMRatio      float64           `json:"rpm_ratio"`
+	TPMRatio      float64           `json:"tpm_ratio"`
+	AvailableSets []string          `json:"available_sets"`
+	MCPToolACLs   model.MCPToolACLs `json:"mcp_tool_acls"`
+	Currency      string            `json:"currency"`
 
 	BalanceAlertEnabled   bool    `json:"balance_alert_enabled"`
 	BalanceAlertThreshold float64 `json:"balance_alert_threshold"`
@@ -363,6 +365,8 @@ func (r *CreateGroupRequest) ToGroup() *model.Group {
 		RPMRatio:      r.RPMRatio,
 		TPMRatio:      r.TPMRatio,
 		AvailableSets: r.AvailableSets,
+		MCPToolACLs:   r.MCPToolACLs,
+		Currency:      strings.ToUpper(strings.TrimSpace(r.Currency)),
 
 		BalanceAlertEnabled:   r.BalanceAlertEnabled,
 		BalanceAlertThreshold: r.BalanceAlertThreshold,
@@ -396,6 +400,11 @@ func CreateGroup(c *gin.Context) {
 		return
 	}
 
+	if err := req.MCPToolACLs.Validate(); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "parameter error: "+err.Error())
+		return
+	}
+
 	g := req.ToGroup()
 
 	g.ID = group
@@ -436,6 +445,13 @@ func UpdateGroup(c *gin.Context) {
 		return
 	}
 
+	if req.MCPToolACLs != nil {
+		if err := req.MCPToolACLs.Validate(); err != nil {
+			middleware.ErrorResponse(c, http.StatusBadRequest, "parameter error: "+err.Error())
+			return
+		}
+	}
+
 	g, err := model.UpdateGroup(group, req)
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
@@ -463,6 +479,9 @@ type SaveGroupModelConfigRequest struct {
 
 	OverrideForceSaveDetail bool `json:"override_force_save_detail"`
 	ForceSaveDetail         bool `json:"force_save_detail"`
+
+	OverridePlugin bool                      `json:"override_plugin"`
+	Plugin         map[string]map[string]any `json:"plugin"`
 }
 
 func (r *SaveGroupModelConfigRequest) ToGroupModelConfig(groupID string) model.GroupModelConfig {
@@ -483,6 +502,9 @@ func (r *SaveGroupModelConfigRequest) ToGroupModelConfig(groupID string) model.G
 
 		OverrideForceSaveDetail: r.OverrideForceSaveDetail,
 		ForceSaveDetail:         r.ForceSaveDetail,
+
+		OverridePlugin: r.OverridePlugin,
+		Plugin:         r.Plugin,
 	}
 }
 
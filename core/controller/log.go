@@ -28,9 +28,9 @@ func parseCommonParams(c *gin.Context) (params struct {
 ) {
 	params.group = c.Query("group")
 
-	// Regular users: force filter to their own group
-	if forcedGroup, exists := c.Get("forced_group"); exists {
-		params.group = forcedGroup.(string)
+	// Scoped callers: force filter to their group
+	if forcedGroup, ok := middleware.GetForcedGroup(c); ok {
+		params.group = forcedGroup
 	}
 
 	params.tokenName = c.Query("token_name")
@@ -295,7 +295,15 @@ func SearchGroupLogs(c *gin.Context) {
 func GetLogDetail(c *gin.Context) {
 	logID, _ := strconv.Atoi(c.Param("log_id"))
 
-	log, err := model.GetLogDetail(logID)
+	var (
+		log *model.RequestDetail
+		err error
+	)
+	if forcedGroup, ok := middleware.GetForcedGroup(c); ok {
+		log, err = model.GetGroupLogDetail(logID, forcedGroup)
+	} else {
+		log, err = model.GetLogDetail(logID)
+	}
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -383,6 +391,9 @@ func DeleteHistoryLogs(c *gin.Context) {
 func SearchConsumeError(c *gin.Context) {
 	keyword := c.Query("keyword")
 	group := c.Query("group")
+	if forcedGroup, ok := middleware.GetForcedGroup(c); ok {
+		group = forcedGroup
+	}
 	tokenName := c.Query("token_name")
 	modelName := c.Query("model_name")
 	tokenID, _ := strconv.Atoi(c.Query("token_id"))
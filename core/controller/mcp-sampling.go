@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/config"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const mcpSamplingMetadata = "mcp_sampling"
+
+// MCPSampling answers the sampling requests of the mcps with the chat models
+// of the group of the request, the request is billed and logged like a chat
+// completion of the group
+func MCPSampling(
+	c *gin.Context,
+	params mcp.CreateMessageParams,
+) (*mcp.CreateMessageResult, error) {
+	group, token, modelCaches := internalRelayAuth(c)
+
+	modelName := samplingModel(&token, modelCaches, params.ModelPreferences)
+	if modelName == "" {
+		return nil, errors.New("no model available for sampling")
+	}
+
+	body, err := samplingRequest(modelName, params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := internalRelay(
+		c.Request.Context(),
+		mode.ChatCompletions,
+		"/v1/chat/completions",
+		body,
+		group,
+		token,
+		modelCaches,
+		map[string]string{mcpSamplingMetadata: "true"},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resp.Err(); err != nil {
+		return nil, err
+	}
+
+	var textResp relaymodel.TextResponse
+	if err := sonic.Unmarshal(resp.Body, &textResp); err != nil {
+		return nil, err
+	}
+
+	if len(textResp.Choices) == 0 || textResp.Choices[0] == nil {
+		return nil, errors.New("sampling response has no choices")
+	}
+
+	choice := textResp.Choices[0]
+
+	return &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{
+			Role:    mcp.RoleAssistant,
+			Content: mcp.NewTextContent(choice.Message.StringContent()),
+		},
+		Model:      textResp.Model,
+		StopReason: samplingStopReason(choice.FinishReason),
+	}, nil
+}
+
+// samplingModel returns the chat model of the first model hint the token
+// can use, the exact matches are preferred to the prefix matches, the other
+// types of models are never picked, MCP_SAMPLING_MODEL is the default
+func samplingModel(
+	token *model.TokenCache,
+	modelCaches *model.ModelCaches,
+	preferences *mcp.ModelPreferences,
+) string {
+	var candidates []string
+
+	token.Range(func(name string) bool {
+		if config, ok := modelCaches.ModelConfig.GetModelConfig(name); ok &&
+			config.Type == mode.ChatCompletions {
+			candidates = append(candidates, name)
+		}
+
+		return true
+	})
+
+	slices.Sort(candidates)
+
+	hints := make([]string, 0, 1)
+	if preferences != nil {
+		for _, hint := range preferences.Hints {
+			if hint.Name != "" {
+				hints = append(hints, hint.Name)
+			}
+		}
+	}
+
+	if config.MCPSamplingModel != "" {
+		hints = append(hints, config.MCPSamplingModel)
+	}
+
+	for _, hint := range hints {
+		for _, candidate := range candidates {
+			if strings.EqualFold(candidate, hint) {
+				return candidate
+			}
+		}
+
+		for _, candidate := range candidates {
+			if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(hint)) {
+				return candidate
+			}
+		}
+	}
+
+	return ""
+}
+
+// samplingRequest converts the sampling request to a chat completion request
+func samplingRequest(modelName string, params mcp.CreateMessageParams) ([]byte, error) {
+	messages := make([]relaymodel.Message, 0, len(params.Messages)+1)
+	if params.SystemPrompt != "" {
+		messages = append(messages, relaymodel.Message{
+			Role:    "system",
+			Content: params.SystemPrompt,
+		})
+	}
+
+	for _, message := range params.Messages {
+		content, err := samplingContent(message.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, relaymodel.Message{
+			Role:    string(message.Role),
+			Content: []relaymodel.MessageContent{content},
+		})
+	}
+
+	req := relaymodel.GeneralOpenAIRequest{
+		Model:     modelName,
+		Messages:  messages,
+		MaxTokens: samplingMaxTokens(params.MaxTokens),
+	}
+
+	if params.Temperature != 0 {
+		req.Temperature = &params.Temperature
+	}
+
+	if len(params.StopSequences) != 0 {
+		req.Stop = params.StopSequences
+	}
+
+	return sonic.Marshal(req)
+}
+
+// samplingMaxTokens caps the max tokens of the sampling request by
+// MCP_SAMPLING_MAX_TOKENS, a request without max tokens gets the cap
+func samplingMaxTokens(maxTokens int) int {
+	limit := int(config.MCPSamplingMaxTokens)
+	if limit <= 0 {
+		return maxTokens
+	}
+
+	if maxTokens <= 0 || maxTokens > limit {
+		return limit
+	}
+
+	return maxTokens
+}
+
+// samplingContent converts the text and the image contents of a sampling
+// message
+func samplingContent(content any) (relaymodel.MessageContent, error) {
+	data, err := sonic.Marshal(content)
+	if err != nil {
+		return relaymodel.MessageContent{}, err
+	}
+
+	var c struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Data     string `json:"data"`
+		MIMEType string `json:"mimeType"`
+	}
+	if err := sonic.Unmarshal(data, &c); err != nil {
+		return relaymodel.MessageContent{}, err
+	}
+
+	switch c.Type {
+	case "text":
+		return relaymodel.MessageContent{
+			Type: relaymodel.ContentTypeText,
+			Text: c.Text,
+		}, nil
+	case "image":
+		return relaymodel.MessageContent{
+			Type: relaymodel.ContentTypeImageURL,
+			ImageURL: &relaymodel.ImageURL{
+				URL: fmt.Sprintf("data:%s;base64,%s", c.MIMEType, c.Data),
+			},
+		}, nil
+	default:
+		return relaymodel.MessageContent{}, fmt.Errorf("unsupported sampling content type: %s", c.Type)
+	}
+}
+
+func samplingStopReason(finishReason relaymodel.FinishReason) string {
+	switch finishReason {
+	case relaymodel.FinishReasonStop:
+		return "endTurn"
+	case relaymodel.FinishReasonLength:
+		return "maxTokens"
+	default:
+		return finishReason
+	}
+}
@@ -0,0 +1,97 @@
+package controller_test
+
+import (
+	"testing"
+
+	"github.com/wavespeed/llm-server/core/common/config"
+	"github.com/wavespeed/llm-server/core/controller"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func newSamplingToken() (*model.TokenCache, *model.ModelCaches) {
+	configs := fallbackModelConfigs{
+		"gpt-4o":                 {Model: "gpt-4o", Type: mode.ChatCompletions},
+		"gpt-4o-mini":            {Model: "gpt-4o-mini", Type: mode.ChatCompletions},
+		"claude-sonnet":          {Model: "claude-sonnet", Type: mode.ChatCompletions},
+		"text-embedding-3-small": {Model: "text-embedding-3-small", Type: mode.Embeddings},
+	}
+
+	token := &model.TokenCache{Name: "sampling-token"}
+	token.SetAvailableSets([]string{model.ChannelDefaultSet})
+	token.SetModelsBySet(map[string][]string{
+		model.ChannelDefaultSet: {"gpt-4o-mini", "gpt-4o", "claude-sonnet", "text-embedding-3-small"},
+	})
+
+	return token, &model.ModelCaches{ModelConfig: configs}
+}
+
+func samplingPreferences(hints ...string) *mcp.ModelPreferences {
+	preferences := &mcp.ModelPreferences{}
+	for _, hint := range hints {
+		preferences.Hints = append(preferences.Hints, mcp.ModelHint{Name: hint})
+	}
+
+	return preferences
+}
+
+func TestSamplingModel(t *testing.T) {
+	defaultModel := config.MCPSamplingModel
+	t.Cleanup(func() { config.MCPSamplingModel = defaultModel })
+
+	config.MCPSamplingModel = "claude"
+
+	tests := []struct {
+		name        string
+		preferences *mcp.ModelPreferences
+		want        string
+	}{
+		{
+			name:        "exact match before prefix match",
+			preferences: samplingPreferences("gpt-4o"),
+			want:        "gpt-4o",
+		},
+		{
+			name:        "prefix match",
+			preferences: samplingPreferences("GPT-4O-M"),
+			want:        "gpt-4o-mini",
+		},
+		{
+			name:        "first usable hint",
+			preferences: samplingPreferences("gemini", "gpt-4o-mini"),
+			want:        "gpt-4o-mini",
+		},
+		{
+			name:        "other types are never picked",
+			preferences: samplingPreferences("text-embedding"),
+			want:        "claude-sonnet",
+		},
+		{
+			name: "default model without hints",
+			want: "claude-sonnet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, modelCaches := newSamplingToken()
+			if got := controller.SamplingModel(token, modelCaches, tt.preferences); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSamplingModelWithoutDefault(t *testing.T) {
+	defaultModel := config.MCPSamplingModel
+	t.Cleanup(func() { config.MCPSamplingModel = defaultModel })
+
+	config.MCPSamplingModel = ""
+
+	token, modelCaches := newSamplingToken()
+	if got := controller.SamplingModel(token, modelCaches, samplingPreferences("gemini")); got != "" {
+		t.Fatalf("expected no model, got %q", got)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"net/url"
 
 	"github.com/gin-gonic/gin"
-	"github.com/wavespeed/llm-server/core/mcpproxy"
+	"github.com/wavespeed/llm-server/core/common/tracing"
 	"github.com/wavespeed/llm-server/core/middleware"
 	"github.com/wavespeed/llm-server/core/model"
 	mcpservers "github.com/wavespeed/llm-server/mcp-servers"
@@ -47,11 +47,14 @@ func handleGroupSSEMCPServer(
 	groupMcp *model.GroupMCPCache,
 	endpoint EndpointProvider,
 ) {
+	setToolsCallRecorder(c, groupMcp.ID, model.MCPScopeGroup, model.MCPPrice{})
+
 	switch groupMcp.Type {
 	case model.GroupMCPTypeProxySSE:
 		client, err := transport.NewSSE(
 			groupMcp.ProxyConfig.URL,
 			transport.WithHeaders(groupMcp.ProxyConfig.Headers),
+			transport.WithHeaderFunc(tracing.Headers),
 		)
 		if err != nil {
 			http.Error(c.Writer, err.Error(), http.StatusBadRequest)
@@ -74,12 +77,15 @@ func handleGroupSSEMCPServer(
 		client, err := transport.NewStreamableHTTP(
 			groupMcp.ProxyConfig.URL,
 			transport.WithHTTPHeaders(groupMcp.ProxyConfig.Headers),
+			transport.WithHTTPHeaderFunc(tracing.Headers),
 		)
 		if err != nil {
 			http.Error(c.Writer, err.Error(), http.StatusBadRequest)
 			return
 		}
 
+		setSamplingHandler(c, client, groupMcp.SamplingEnabled)
+
 		err = client.Start(c.Request.Context())
 		if err != nil {
 			http.Error(c.Writer, err.Error(), http.StatusBadRequest)
@@ -176,6 +182,5 @@ func handleGroupProxyStreamable(c *gin.Context, config *model.GroupMCPProxyConfi
 	}
 
 	backendURL.RawQuery = backendQuery.Encode()
-	mcpproxy.NewStreamableProxy(backendURL.String(), headers, getStore()).
-		ServeHTTP(c.Writer, c.Request)
+	serveStreamableProxy(c, backendURL.String(), headers)
 }
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/config"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// SamplingFunc answers a sampling request of an mcp with the models of the
+// group of the request
+type SamplingFunc func(c *gin.Context, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error)
+
+var sampling SamplingFunc
+
+// SetSampling sets the answer of the sampling requests the mcps send to the
+// gateway, the mcps cannot sample when it is not set
+func SetSampling(f SamplingFunc) {
+	sampling = f
+}
+
+// samplingAllowed reports whether an mcp with sampling enabled can sample
+func samplingAllowed(enabled bool) bool {
+	return enabled && sampling != nil
+}
+
+// setSamplingHandler answers the sampling requests the mcp of the client
+// sends when the mcp has sampling enabled, the requests of a client are
+// capped by MCP_SAMPLING_MAX_REQUESTS, only the streamable clients receive
+// the requests of the mcps
+func setSamplingHandler(c *gin.Context, client transport.Interface, enabled bool) {
+	if !samplingAllowed(enabled) {
+		return
+	}
+
+	bidirectional, ok := client.(transport.BidirectionalInterface)
+	if !ok {
+		return
+	}
+
+	var requests atomic.Int64
+
+	bidirectional.SetRequestHandler(func(
+		_ context.Context,
+		req transport.JSONRPCRequest,
+	) (*transport.JSONRPCResponse, error) {
+		if req.Method != string(mcp.MethodSamplingCreateMessage) {
+			return nil, fmt.Errorf("unsupported request: %s", req.Method)
+		}
+
+		if config.MCPSamplingMaxRequests > 0 &&
+			requests.Add(1) > config.MCPSamplingMaxRequests {
+			return nil, errors.New("sampling requests limit reached")
+		}
+
+		data, err := sonic.Marshal(req.Params)
+		if err != nil {
+			return nil, err
+		}
+
+		var params mcp.CreateMessageParams
+		if err := sonic.Unmarshal(data, &params); err != nil {
+			return nil, err
+		}
+
+		result, err := sampling(c, params)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = sonic.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		return &transport.JSONRPCResponse{
+			JSONRPC: mcp.JSONRPC_VERSION,
+			ID:      req.ID,
+			Result:  data,
+		}, nil
+	})
+}
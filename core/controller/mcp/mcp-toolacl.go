@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/bytedance/sonic"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var errToolsCallForbidden = errors.New("tool is not allowed")
+
+// hasToolACLs is whether the group or the token of the request restricts
+// the tools of the mcps
+func (r *toolsCallRecorder) hasToolACLs() bool {
+	return len(r.group.MCPToolACLs) != 0 || len(r.token.MCPToolACLs) != 0
+}
+
+// toolAllowed is whether the acls of the group and the token allow the tool
+// of the mcp
+func (r *toolsCallRecorder) toolAllowed(tool string) bool {
+	return r.group.MCPToolACLs.Allowed(r.mcpID, tool) &&
+		r.token.MCPToolACLs.Allowed(r.mcpID, tool)
+}
+
+// isToolsList is whether the json-rpc message is a tools/list request
+func isToolsList(message []byte) bool {
+	var req struct {
+		Method string `json:"method"`
+	}
+
+	return sonic.Unmarshal(message, &req) == nil && req.Method == string(mcp.MethodToolsList)
+}
+
+// filterToolsList removes the tools not allowed from the json-rpc response
+// of a tools/list, the other messages are returned unchanged
+func (r *toolsCallRecorder) filterToolsList(message []byte) []byte {
+	var resp map[string]json.RawMessage
+	if err := sonic.Unmarshal(message, &resp); err != nil || resp["result"] == nil {
+		return message
+	}
+
+	var result map[string]json.RawMessage
+	if err := sonic.Unmarshal(resp["result"], &result); err != nil || result["tools"] == nil {
+		return message
+	}
+
+	var tools []json.RawMessage
+	if err := sonic.Unmarshal(result["tools"], &tools); err != nil {
+		return message
+	}
+
+	allowed := make([]json.RawMessage, 0, len(tools))
+	for _, tool := range tools {
+		var t struct {
+			Name string `json:"name"`
+		}
+		if err := sonic.Unmarshal(tool, &t); err != nil || !r.toolAllowed(t.Name) {
+			continue
+		}
+
+		allowed = append(allowed, tool)
+	}
+
+	if len(allowed) == len(tools) {
+		return message
+	}
+
+	var err error
+
+	result["tools"], err = sonic.Marshal(allowed)
+	if err != nil {
+		return message
+	}
+
+	resp["result"], err = sonic.Marshal(result)
+	if err != nil {
+		return message
+	}
+
+	filtered, err := sonic.Marshal(resp)
+	if err != nil {
+		return message
+	}
+
+	return filtered
+}
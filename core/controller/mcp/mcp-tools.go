@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MCPTool is a tool of the mcps of a relay request, the name is prefixed
+// with the namespace of its mcp
+type MCPTool struct {
+	Name        string
+	Description string
+	InputSchema json.RawMessage
+}
+
+// MCPTools runs the tools of the mcps referenced by a relay request for the
+// group of the request, the tools calls are charged and checked against the
+// tool acls like the calls of the mcp endpoints
+type MCPTools struct {
+	server *virtualMCPServer
+}
+
+func NewMCPTools(c *gin.Context, servers model.VirtualMCPServers) (*MCPTools, error) {
+	if err := servers.Validate(); err != nil {
+		return nil, err
+	}
+
+	group := middleware.GetGroup(c)
+
+	return &MCPTools{
+		server: newVirtualMCPServer(c, &model.VirtualMCPCache{
+			GroupID: group.ID,
+			Servers: servers,
+		}),
+	}, nil
+}
+
+// Close closes the clients of the connected mcps
+func (t *MCPTools) Close() {
+	t.server.Close()
+}
+
+// List returns the tools of all the mcps, an mcp failing to list fails the
+// list
+func (t *MCPTools) List(ctx context.Context) ([]MCPTool, error) {
+	var tools []MCPTool
+
+	for _, b := range t.server.backends {
+		items, err := t.server.listBackend(ctx, b, mcp.MethodToolsList, "tools")
+		if err != nil {
+			return nil, fmt.Errorf("list tools of mcp %s: %w", b.config.ID, err)
+		}
+
+		for _, item := range items {
+			var name, description string
+			if err := sonic.Unmarshal(item["name"], &name); err != nil {
+				continue
+			}
+
+			if item["description"] != nil {
+				_ = sonic.Unmarshal(item["description"], &description)
+			}
+
+			tools = append(tools, MCPTool{
+				Name:        b.config.GetNamespace() + model.VirtualMCPNamespaceSeparator + name,
+				Description: description,
+				InputSchema: item["inputSchema"],
+			})
+		}
+	}
+
+	return tools, nil
+}
+
+// Call calls the tool and returns the text of its result, a result flagged
+// as an error is returned as an error
+func (t *MCPTools) Call(ctx context.Context, name string, arguments string) (string, error) {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+
+	if !json.Valid([]byte(arguments)) {
+		return "", errors.New("tool arguments are not valid json")
+	}
+
+	nameBytes, err := sonic.Marshal(name)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := t.server.callNamespaced(ctx, mcp.MethodToolsCall, map[string]json.RawMessage{
+		"name":      nameBytes,
+		"arguments": json.RawMessage(arguments),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var callResult struct {
+		IsError bool              `json:"isError"`
+		Content []json.RawMessage `json:"content"`
+	}
+	if err := sonic.Unmarshal(result, &callResult); err != nil {
+		return "", err
+	}
+
+	texts := make([]string, 0, len(callResult.Content))
+	for _, content := range callResult.Content {
+		var c struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if err := sonic.Unmarshal(content, &c); err == nil && c.Type == "text" {
+			texts = append(texts, c.Text)
+		} else {
+			texts = append(texts, string(content))
+		}
+	}
+
+	text := strings.Join(texts, "\n")
+	if callResult.IsError {
+		if text == "" {
+			text = "tools call failed"
+		}
+
+		return "", errors.New(text)
+	}
+
+	return text, nil
+}
@@ -0,0 +1,446 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/common/balance"
+	"github.com/wavespeed/llm-server/core/common/consume"
+	"github.com/wavespeed/llm-server/core/mcpproxy"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	mcpservers "github.com/wavespeed/llm-server/mcp-servers"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// toolsCallRecorderKey is the gin key of the recorder of the tools calls
+	// of the mcp of the request
+	toolsCallRecorderKey = "mcp_tools_call_recorder"
+	// maxToolsCallResponseCapture bounds the response of a proxied tools call
+	// kept to find its error
+	maxToolsCallResponseCapture = 64 * 1024
+	// maxToolsCallErrorLength bounds the error of a tools call in the log
+	maxToolsCallErrorLength = 1024
+)
+
+var errToolsCallBalanceNotEnough = errors.New("group balance is not enough")
+
+// toolsCallRecorder charges and records the tools calls of an mcp for the
+// group of the request
+type toolsCallRecorder struct {
+	mcpID string
+	scope model.MCPScope
+	price model.MCPPrice
+	group model.GroupCache
+	token model.TokenCache
+	ip    string
+}
+
+// setToolsCallRecorder records the tools calls of the mcp of the request, the
+// requests without a group are not recorded
+func setToolsCallRecorder(
+	c *gin.Context,
+	mcpID string,
+	scope model.MCPScope,
+	price model.MCPPrice,
+) {
+	recorder := newToolsCallRecorder(c, mcpID, scope, price)
+	if recorder == nil {
+		return
+	}
+
+	c.Set(toolsCallRecorderKey, recorder)
+}
+
+// newToolsCallRecorder returns the recorder of the tools calls of the mcp for
+// the group of the request, nil when the request has no group
+func newToolsCallRecorder(
+	c *gin.Context,
+	mcpID string,
+	scope model.MCPScope,
+	price model.MCPPrice,
+) *toolsCallRecorder {
+	group, ok := c.Get(middleware.Group)
+	if !ok {
+		return nil
+	}
+
+	recorder := &toolsCallRecorder{
+		mcpID: mcpID,
+		scope: scope,
+		price: price,
+		ip:    c.ClientIP(),
+	}
+	recorder.group, _ = group.(model.GroupCache)
+
+	if token, ok := c.Get(middleware.Token); ok {
+		recorder.token, _ = token.(model.TokenCache)
+	}
+
+	return recorder
+}
+
+func getToolsCallRecorder(c *gin.Context) *toolsCallRecorder {
+	v, ok := c.Get(toolsCallRecorderKey)
+	if !ok {
+		return nil
+	}
+
+	recorder, _ := v.(*toolsCallRecorder)
+
+	return recorder
+}
+
+type toolsCall struct {
+	id            any
+	tool          string
+	argumentsSize int
+	price         float64
+	requestAt     time.Time
+	consumer      balance.PostGroupConsumer
+}
+
+// errBatchNotSupported rejects the json-rpc batches, the tools calls of a
+// batch would skip the tool acls and the charging
+const errBatchNotSupported = "json-rpc batches are not supported"
+
+// isBatchMessage reports whether the json-rpc message is a batch
+func isBatchMessage(message []byte) bool {
+	trimmed := bytes.TrimLeft(message, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// parseToolsCall returns the tools call of the json-rpc message, the other
+// messages are not tools calls
+func parseToolsCall(message []byte) (*toolsCall, bool) {
+	var req struct {
+		ID     mcp.RequestId `json:"id"`
+		Method string        `json:"method"`
+		Params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		} `json:"params"`
+	}
+
+	if err := sonic.Unmarshal(message, &req); err != nil ||
+		req.Method != string(mcp.MethodToolsCall) {
+		return nil, false
+	}
+
+	return &toolsCall{
+		id:            req.ID.Value(),
+		tool:          req.Params.Name,
+		argumentsSize: len(req.Params.Arguments),
+	}, true
+}
+
+// start checks the tool is allowed, prices the call and checks the balance
+// of the group can pay it
+func (r *toolsCallRecorder) start(ctx context.Context, call *toolsCall) error {
+	if !r.toolAllowed(call.tool) {
+		return errToolsCallForbidden
+	}
+
+	call.requestAt = time.Now()
+	call.price = r.price.ToolsCallPrice(call.tool)
+
+	if call.price <= 0 || r.group.Status == model.GroupStatusInternal {
+		return nil
+	}
+
+	if _, err := model.ExchangeRateAt("", r.group.Currency, call.requestAt); err != nil {
+		return err
+	}
+
+	remain, consumer, err := balance.GetGroupRemainBalance(ctx, r.group)
+	if err != nil {
+		return err
+	}
+
+	amount := consume.CalculateMCPAmount(consume.MCPToolsCall{
+		RequestAt: call.requestAt,
+		Group:     r.group,
+		Price:     call.price,
+	})
+	if remain < amount {
+		return errToolsCallBalanceNotEnough
+	}
+
+	call.consumer = consumer
+
+	return nil
+}
+
+// done charges the call and records it
+func (r *toolsCallRecorder) done(call *toolsCall, callErr string) {
+	consume.AsyncConsumeMCP(call.consumer, consume.MCPToolsCall{
+		RequestAt:     call.requestAt,
+		Latency:       time.Since(call.requestAt),
+		Group:         r.group,
+		Token:         r.token,
+		MCPID:         r.mcpID,
+		Scope:         r.scope,
+		Tool:          call.tool,
+		ArgumentsSize: call.argumentsSize,
+		Error:         common.TruncateByRune(callErr, maxToolsCallErrorLength),
+		Price:         call.price,
+		IP:            r.ip,
+	})
+}
+
+// toolsCallResponseError returns the error of the json-rpc response of a
+// tools call, a result flagged as an error is an error, ok is false when
+// the message is not a response
+func toolsCallResponseError(message []byte) (callErr string, ok bool) {
+	var resp struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Result *struct {
+			IsError bool `json:"isError"`
+			Content []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"result"`
+	}
+
+	if err := sonic.Unmarshal(message, &resp); err != nil {
+		return "", false
+	}
+
+	switch {
+	case resp.Error != nil:
+		if resp.Error.Message == "" {
+			return "tools call failed", true
+		}
+
+		return resp.Error.Message, true
+	case resp.Result != nil:
+		if !resp.Result.IsError {
+			return "", true
+		}
+
+		texts := make([]string, 0, len(resp.Result.Content))
+		for _, content := range resp.Result.Content {
+			if content.Type == "text" && content.Text != "" {
+				texts = append(texts, content.Text)
+			}
+		}
+
+		if len(texts) == 0 {
+			return "tools call failed", true
+		}
+
+		return strings.Join(texts, "\n"), true
+	default:
+		return "", false
+	}
+}
+
+// toolsCallServer records the tools calls handled by the server and hides
+// the tools not allowed
+type toolsCallServer struct {
+	mcpservers.Server
+	recorder *toolsCallRecorder
+}
+
+// wrapToolsCallServer records the tools calls of the server when the
+// request has a recorder
+func wrapToolsCallServer(c *gin.Context, s mcpservers.Server) mcpservers.Server {
+	recorder := getToolsCallRecorder(c)
+	if recorder == nil {
+		return s
+	}
+
+	return &toolsCallServer{
+		Server:   s,
+		recorder: recorder,
+	}
+}
+
+func (s *toolsCallServer) HandleMessage(
+	ctx context.Context,
+	message json.RawMessage,
+) mcp.JSONRPCMessage {
+	if isBatchMessage(message) {
+		return mcpservers.CreateMCPErrorResponse(
+			mcp.NewRequestId(nil),
+			mcp.INVALID_REQUEST,
+			errBatchNotSupported,
+		)
+	}
+
+	call, ok := parseToolsCall(message)
+	if !ok {
+		if s.recorder.hasToolACLs() && isToolsList(message) {
+			return s.filterToolsList(s.Server.HandleMessage(ctx, message))
+		}
+
+		return s.Server.HandleMessage(ctx, message)
+	}
+
+	if err := s.recorder.start(ctx, call); err != nil {
+		return mcpservers.CreateMCPErrorResponse(call.id, mcp.INVALID_REQUEST, err.Error())
+	}
+
+	resp := s.Server.HandleMessage(ctx, message)
+
+	var callErr string
+	if resp != nil {
+		respBytes, err := sonic.Marshal(resp)
+		if err != nil {
+			callErr = err.Error()
+		} else {
+			callErr, _ = toolsCallResponseError(respBytes)
+		}
+	}
+
+	s.recorder.done(call, callErr)
+
+	return resp
+}
+
+func (s *toolsCallServer) filterToolsList(resp mcp.JSONRPCMessage) mcp.JSONRPCMessage {
+	if resp == nil {
+		return nil
+	}
+
+	respBytes, err := sonic.Marshal(resp)
+	if err != nil {
+		return resp
+	}
+
+	return json.RawMessage(s.recorder.filterToolsList(respBytes))
+}
+
+// toolsCallResponseCapture keeps the beginning of the response of a proxied
+// tools call
+type toolsCallResponseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *toolsCallResponseCapture) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *toolsCallResponseCapture) Write(b []byte) (int, error) {
+	if remain := maxToolsCallResponseCapture - w.body.Len(); remain > 0 {
+		w.body.Write(b[:min(len(b), remain)])
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *toolsCallResponseCapture) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// callError returns the error of the captured response, the responses
+// streamed as sse carry the json-rpc response in a data line
+func (w *toolsCallResponseCapture) callError() string {
+	if w.status >= http.StatusBadRequest {
+		return http.StatusText(w.status)
+	}
+
+	body := w.body.Bytes()
+	if !strings.Contains(w.Header().Get("Content-Type"), "text/event-stream") {
+		callErr, _ := toolsCallResponseError(body)
+		return callErr
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, maxToolsCallResponseCapture)
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+
+		if callErr, ok := toolsCallResponseError([]byte(strings.TrimSpace(data))); ok {
+			return callErr
+		}
+	}
+
+	return ""
+}
+
+// serveStreamableProxy proxies the request to the streamable mcp, the posted
+// tools calls are recorded and the tools not allowed are hidden when the
+// request has a recorder
+func serveStreamableProxy(c *gin.Context, backend string, headers map[string]string) {
+	recorder := getToolsCallRecorder(c)
+
+	var opts []mcpproxy.StreamableProxyOption
+	if recorder != nil && recorder.hasToolACLs() {
+		opts = append(opts, mcpproxy.WithMessageFilter(recorder.filterToolsList))
+	}
+
+	proxy := mcpproxy.NewStreamableProxy(backend, headers, getStore(), opts...)
+
+	if recorder == nil || c.Request.Method != http.MethodPost {
+		proxy.ServeHTTP(c.Writer, c.Request)
+		return
+	}
+
+	body, err := common.GetRequestBodyReusable(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mcpservers.CreateMCPErrorResponse(
+			mcp.NewRequestId(nil),
+			mcp.PARSE_ERROR,
+			err.Error(),
+		))
+
+		return
+	}
+
+	if isBatchMessage(body) {
+		c.JSON(http.StatusBadRequest, mcpservers.CreateMCPErrorResponse(
+			mcp.NewRequestId(nil),
+			mcp.INVALID_REQUEST,
+			errBatchNotSupported,
+		))
+
+		return
+	}
+
+	call, ok := parseToolsCall(body)
+	if !ok {
+		proxy.ServeHTTP(c.Writer, c.Request)
+		return
+	}
+
+	if err := recorder.start(c.Request.Context(), call); err != nil {
+		c.JSON(http.StatusOK, mcpservers.CreateMCPErrorResponse(
+			call.id,
+			mcp.INVALID_REQUEST,
+			err.Error(),
+		))
+
+		return
+	}
+
+	w := &toolsCallResponseCapture{
+		ResponseWriter: c.Writer,
+		status:         http.StatusOK,
+	}
+	proxy.ServeHTTP(w, c.Request)
+
+	recorder.done(call, w.callError())
+}
@@ -33,7 +33,7 @@ func handleSSEMCPServer(
 
 	newEndpoint := endpoint.NewEndpoint(newSession)
 	server := mcpproxy.NewSSEServer(
-		s,
+		wrapToolsCallServer(c, s),
 		mcpproxy.WithMessageEndpoint(newEndpoint),
 	)
 
@@ -159,7 +159,7 @@ func handleStreamableMCPServer(c *gin.Context, s mcpservers.Server) {
 		return
 	}
 
-	respMessage := s.HandleMessage(c.Request.Context(), reqBody)
+	respMessage := wrapToolsCallServer(c, s).HandleMessage(c.Request.Context(), reqBody)
 	if respMessage == nil {
 		// For notifications, just send 202 Accepted with no body
 		c.Status(http.StatusAccepted)
@@ -170,6 +170,8 @@ func handleStreamableMCPServer(c *gin.Context, s mcpservers.Server) {
 }
 
 func handleGroupStreamable(c *gin.Context, groupMcp *model.GroupMCPCache) {
+	setToolsCallRecorder(c, groupMcp.ID, model.MCPScopeGroup, model.MCPPrice{})
+
 	switch groupMcp.Type {
 	case model.GroupMCPTypeProxyStreamable:
 		handleGroupProxyStreamable(c, groupMcp.ProxyConfig)
@@ -18,7 +18,8 @@ func IsHostedMCP(t model.PublicMCPType) bool {
 	return t == model.PublicMCPTypeEmbed ||
 		t == model.PublicMCPTypeOpenAPI ||
 		t == model.PublicMCPTypeProxySSE ||
-		t == model.PublicMCPTypeProxyStreamable
+		t == model.PublicMCPTypeProxyStreamable ||
+		t == model.PublicMCPTypeStdio
 }
 
 type GroupPublicMCPResponse struct {
@@ -60,6 +61,7 @@ func NewGroupPublicMCPResponse(mcp model.PublicMCP) GroupPublicMCPResponse {
 	r.ProxyConfig = nil
 	r.EmbedConfig = nil
 	r.OpenAPIConfig = nil
+	r.StdioConfig = nil
 	r.TestConfig = nil
 
 	return r
@@ -124,6 +126,7 @@ func NewGroupPublicMCPDetailResponse(
 	r.ProxyConfig = nil
 	r.EmbedConfig = nil
 	r.OpenAPIConfig = nil
+	r.StdioConfig = nil
 	r.TestConfig = nil
 
 	switch mcp.Type {
@@ -134,6 +137,8 @@ func NewGroupPublicMCPDetailResponse(
 		}
 	case model.PublicMCPTypeEmbed:
 		r.Reusing = mcp.EmbedConfig.Reusing
+	case model.PublicMCPTypeStdio:
+		r.Reusing = mcp.StdioConfig.Reusing
 	default:
 		return r, nil
 	}
@@ -7,6 +7,7 @@ import (
 	"net/url"
 
 	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/tracing"
 	"github.com/wavespeed/llm-server/core/mcpproxy"
 	"github.com/wavespeed/llm-server/core/middleware"
 	"github.com/wavespeed/llm-server/core/model"
@@ -50,6 +51,8 @@ func handlePublicSSEMCP(
 	paramsFunc ParamsFunc,
 	endpoint EndpointProvider,
 ) {
+	setToolsCallRecorder(c, publicMcp.ID, model.MCPScopePublic, publicMcp.Price)
+
 	switch publicMcp.Type {
 	case model.PublicMCPTypeProxySSE:
 		if err := handlePublicProxySSE(c, publicMcp, paramsFunc, endpoint); err != nil {
@@ -71,6 +74,14 @@ func handlePublicSSEMCP(
 		handleSSEMCPServer(c, server, string(model.PublicMCPTypeOpenAPI), endpoint)
 	case model.PublicMCPTypeEmbed:
 		handleEmbedSSEMCP(c, publicMcp.ID, publicMcp.EmbedConfig, paramsFunc, endpoint)
+	case model.PublicMCPTypeStdio:
+		server, err := newStdioMCPServer(publicMcp, paramsFunc)
+		if err != nil {
+			http.Error(c.Writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		handleSSEMCPServer(c, server, string(model.PublicMCPTypeStdio), endpoint)
 	default:
 		http.Error(c.Writer, "unknown mcp type", http.StatusBadRequest)
 	}
@@ -133,7 +144,11 @@ func createProxySSEClient(
 		return nil, err
 	}
 
-	client, err := transport.NewSSE(url, transport.WithHeaders(headers))
+	client, err := transport.NewSSE(
+		url,
+		transport.WithHeaders(headers),
+		transport.WithHeaderFunc(tracing.Headers),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -156,11 +171,17 @@ func createProxyStreamableClient(
 		return nil, err
 	}
 
-	client, err := transport.NewStreamableHTTP(url, transport.WithHTTPHeaders(headers))
+	client, err := transport.NewStreamableHTTP(
+		url,
+		transport.WithHTTPHeaders(headers),
+		transport.WithHTTPHeaderFunc(tracing.Headers),
+	)
 	if err != nil {
 		return nil, err
 	}
 
+	setSamplingHandler(c, client, publicMcp.SamplingEnabled)
+
 	if err := client.Start(c.Request.Context()); err != nil {
 		return nil, err
 	}
@@ -282,6 +303,8 @@ func handlePublicStreamable(
 	publicMcp *model.PublicMCPCache,
 	paramsFunc ParamsFunc,
 ) {
+	setToolsCallRecorder(c, publicMcp.ID, model.MCPScopePublic, publicMcp.Price)
+
 	switch publicMcp.Type {
 	case model.PublicMCPTypeProxySSE:
 		client, err := createProxySSEClient(c, publicMcp, paramsFunc)
@@ -292,7 +315,7 @@ func handlePublicStreamable(
 		defer client.Close()
 
 		mcpproxy.NewStatelessStreamableHTTPServer(
-			mcpservers.WrapMCPClient2Server(client),
+			wrapToolsCallServer(c, mcpservers.WrapMCPClient2Server(client)),
 		).ServeHTTP(c.Writer, c.Request)
 	case model.PublicMCPTypeProxyStreamable:
 		handlePublicProxyStreamable(c, paramsFunc, publicMcp.ProxyConfig)
@@ -311,6 +334,19 @@ func handlePublicStreamable(
 		handleStreamableMCPServer(c, server)
 	case model.PublicMCPTypeEmbed:
 		handlePublicEmbedStreamable(c, publicMcp.ID, paramsFunc, publicMcp.EmbedConfig)
+	case model.PublicMCPTypeStdio:
+		server, err := newStdioMCPServer(publicMcp, paramsFunc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, mcpservers.CreateMCPErrorResponse(
+				mcp.NewRequestId(nil),
+				mcp.INVALID_REQUEST,
+				err.Error(),
+			))
+
+			return
+		}
+
+		handleStreamableMCPServer(c, server)
 	default:
 		c.JSON(http.StatusBadRequest, mcpservers.CreateMCPErrorResponse(
 			mcp.NewRequestId(nil),
@@ -404,8 +440,7 @@ func handlePublicProxyStreamable(
 	}
 
 	backendURL.RawQuery = backendQuery.Encode()
-	mcpproxy.NewStreamableProxy(backendURL.String(), headers, getStore()).
-		ServeHTTP(c.Writer, c.Request)
+	serveStreamableProxy(c, backendURL.String(), headers)
 }
 
 // TestPublicMCPSSEServer godoc
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/wavespeed/llm-server/core/common/config"
+	"github.com/wavespeed/llm-server/core/mcpstdio"
+	"github.com/wavespeed/llm-server/core/model"
+	mcpservers "github.com/wavespeed/llm-server/mcp-servers"
+)
+
+// newStdioMCPServer returns the server of the processes of the stdio mcp run
+// for the group of the params, the reusing params are set as env vars
+func newStdioMCPServer(
+	publicMcp *model.PublicMCPCache,
+	paramsFunc ParamsFunc,
+) (mcpservers.Server, error) {
+	if !config.MCPStdioEnabled {
+		return nil, mcpstdio.ErrDisabled
+	}
+
+	stdioConfig := publicMcp.StdioConfig
+	if stdioConfig == nil {
+		return nil, errors.New("invalid stdio configuration")
+	}
+
+	params, err := NewReusingParamProcessor(publicMcp.ID, paramsFunc).
+		ProcessEmbedReusingParams(stdioConfig.Reusing)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := stdioDir(paramsFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := mcpstdio.NewCommand(stdioConfig, params, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcpstdio.NewServer(stdioPoolKey(publicMcp.ID, paramsFunc), cmd)
+}
+
+// stdioPoolKey is the pool of the processes of the mcp, each group has its
+// own processes
+func stdioPoolKey(mcpID string, paramsFunc ParamsFunc) string {
+	if params, ok := paramsFunc.(*groupParams); ok {
+		return mcpID + "/" + params.groupID
+	}
+
+	return mcpID
+}
+
+// stdioDir is the directory of the files of the group of the params, the
+// paths of the params of the group can not leave it
+func stdioDir(paramsFunc ParamsFunc) (string, error) {
+	params, ok := paramsFunc.(*groupParams)
+	if !ok {
+		return filepath.Join(config.MCPStdioDir, "public"), nil
+	}
+
+	if !filepath.IsLocal(params.groupID) || filepath.Base(params.groupID) != params.groupID {
+		return "", fmt.Errorf("invalid group id for a stdio mcp: %s", params.groupID)
+	}
+
+	return filepath.Join(config.MCPStdioDir, "groups", params.groupID), nil
+}
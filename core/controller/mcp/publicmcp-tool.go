@@ -9,6 +9,7 @@ import (
 	"github.com/bytedance/sonic"
 	"github.com/wavespeed/llm-server/core/common"
 	"github.com/wavespeed/llm-server/core/common/conv"
+	"github.com/wavespeed/llm-server/core/common/tracing"
 	"github.com/wavespeed/llm-server/core/model"
 	mcpservers "github.com/wavespeed/llm-server/mcp-servers"
 	"github.com/mark3labs/mcp-go/client/transport"
@@ -294,7 +295,11 @@ func getProxySSEMCPTools(
 		return nil, err
 	}
 
-	client, err := transport.NewSSE(url, transport.WithHeaders(headers))
+	client, err := transport.NewSSE(
+		url,
+		transport.WithHeaders(headers),
+		transport.WithHeaderFunc(tracing.Headers),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -339,6 +344,7 @@ func getProxyStreamableMCPTools(
 	client, err := transport.NewStreamableHTTP(
 		url,
 		transport.WithHTTPHeaders(headers),
+		transport.WithHTTPHeaderFunc(tracing.Headers),
 	)
 	if err != nil {
 		return nil, err
@@ -2,8 +2,10 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
 
 	"github.com/bytedance/sonic"
@@ -12,6 +14,7 @@ import (
 	"github.com/wavespeed/llm-server/core/controller/utils"
 	"github.com/wavespeed/llm-server/core/middleware"
 	"github.com/wavespeed/llm-server/core/model"
+	"gorm.io/gorm"
 )
 
 type MCPEndpoint struct {
@@ -95,6 +98,7 @@ func getHostedMCPTypes() []model.PublicMCPType {
 		model.PublicMCPTypeProxyStreamable,
 		model.PublicMCPTypeEmbed,
 		model.PublicMCPTypeOpenAPI,
+		model.PublicMCPTypeStdio,
 	}
 }
 
@@ -217,6 +221,11 @@ func CreatePublicMCP(c *gin.Context) {
 		return
 	}
 
+	if err := checkStdioConfig(c, &mcp); err != nil {
+		middleware.ErrorResponse(c, http.StatusForbidden, err.Error())
+		return
+	}
+
 	if err := model.CreatePublicMCP(&mcp); err != nil {
 		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -225,6 +234,33 @@ func CreatePublicMCP(c *gin.Context) {
 	middleware.SuccessResponse(c, NewPublicMCPResponse(c.Request.Host, mcp))
 }
 
+// checkStdioConfig only lets the unscoped super admins set or change the
+// stdio command of an mcp, the command is run on the gateway host, the other
+// callers must send the stdio config of the mcp unchanged
+func checkStdioConfig(c *gin.Context, mcp *model.PublicMCP) error {
+	if middleware.IsAdmin(c) {
+		return nil
+	}
+
+	var current *model.MCPStdioConfig
+
+	if mcp.ID != "" {
+		old, err := model.GetPublicMCPByID(mcp.ID)
+		switch {
+		case err == nil:
+			current = old.StdioConfig
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+	}
+
+	if !reflect.DeepEqual(current, mcp.StdioConfig) {
+		return errors.New("only super admins can set the stdio config")
+	}
+
+	return nil
+}
+
 type SavePublicMCPRequest struct {
 	model.PublicMCP
 	CreatedAt json.RawMessage `json:"created_at"`
@@ -258,6 +294,11 @@ func SavePublicMCP(c *gin.Context) {
 
 	mcp.ID = id
 
+	if err := checkStdioConfig(c, &mcp.PublicMCP); err != nil {
+		middleware.ErrorResponse(c, http.StatusForbidden, err.Error())
+		return
+	}
+
 	if err := model.SavePublicMCP(&mcp.PublicMCP); err != nil {
 		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -286,6 +327,11 @@ func SavePublicMCPs(c *gin.Context) {
 
 	pmcps := make([]model.PublicMCP, len(mcps))
 	for i, mcp := range mcps {
+		if err := checkStdioConfig(c, &mcp.PublicMCP); err != nil {
+			middleware.ErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+
 		pmcps[i] = mcp.PublicMCP
 	}
 
@@ -361,6 +407,11 @@ func UpdatePublicMCP(c *gin.Context) {
 
 	mcp.ID = id
 
+	if err := checkStdioConfig(c, &mcp); err != nil {
+		middleware.ErrorResponse(c, http.StatusForbidden, err.Error())
+		return
+	}
+
 	if err := model.UpdatePublicMCP(&mcp); err != nil {
 		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
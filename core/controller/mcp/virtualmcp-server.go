@@ -0,0 +1,700 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/tracing"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	mcpservers "github.com/wavespeed/llm-server/mcp-servers"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxVirtualMCPListPages bounds the pages of a list read from an mcp of a
+// virtual mcp
+const maxVirtualMCPListPages = 100
+
+// VirtualMCPSSEServer godoc
+//
+//	@Summary	Virtual MCP SSE Server
+//	@Security	ApiKeyAuth
+//	@Router		/mcp/virtual/{id}/sse [get]
+func VirtualMCPSSEServer(c *gin.Context) {
+	mcpID := c.Param("id")
+	if mcpID == "" {
+		http.Error(c.Writer, "mcp id is required", http.StatusBadRequest)
+		return
+	}
+
+	group := middleware.GetGroup(c)
+
+	virtualMcp, err := model.CacheGetVirtualMCP(group.ID, mcpID)
+	if err != nil {
+		http.Error(c.Writer, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if virtualMcp.Status != model.VirtualMCPStatusEnabled {
+		http.Error(c.Writer, "mcp is not enabled", http.StatusNotFound)
+		return
+	}
+
+	server := newVirtualMCPServer(c, virtualMcp)
+	defer server.Close()
+
+	handleSSEMCPServer(c, server, "virtual", sseEndpoint)
+}
+
+// VirtualMCPStreamable godoc
+//
+//	@Summary	Virtual MCP Streamable Server
+//	@Security	ApiKeyAuth
+//	@Router		/mcp/virtual/{id} [get]
+//	@Router		/mcp/virtual/{id} [post]
+//	@Router		/mcp/virtual/{id} [delete]
+func VirtualMCPStreamable(c *gin.Context) {
+	mcpID := c.Param("id")
+	if mcpID == "" {
+		c.JSON(http.StatusBadRequest, mcpservers.CreateMCPErrorResponse(
+			mcp.NewRequestId(nil),
+			mcp.INVALID_REQUEST,
+			"mcp id is required",
+		))
+
+		return
+	}
+
+	group := middleware.GetGroup(c)
+
+	virtualMcp, err := model.CacheGetVirtualMCP(group.ID, mcpID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, mcpservers.CreateMCPErrorResponse(
+			mcp.NewRequestId(nil),
+			mcp.INVALID_REQUEST,
+			err.Error(),
+		))
+
+		return
+	}
+
+	if virtualMcp.Status != model.VirtualMCPStatusEnabled {
+		c.JSON(http.StatusNotFound, mcpservers.CreateMCPErrorResponse(
+			mcp.NewRequestId(nil),
+			mcp.INVALID_REQUEST,
+			"mcp is not enabled",
+		))
+
+		return
+	}
+
+	server := newVirtualMCPServer(c, virtualMcp)
+	defer server.Close()
+
+	handleStreamableMCPServer(c, server)
+}
+
+// virtualMCPBackend is an mcp of a virtual mcp, it is connected and
+// initialized on its first message
+type virtualMCPBackend struct {
+	config model.VirtualMCPServer
+	once   sync.Once
+	server mcpservers.Server
+	closer io.Closer
+	err    error
+	// sampling is whether the mcp can sample
+	sampling bool
+}
+
+// virtualMCPServer combines the mcps of a virtual mcp, the tools and the
+// prompts are prefixed with the namespace of their mcp
+type virtualMCPServer struct {
+	c        *gin.Context
+	mcp      *model.VirtualMCPCache
+	backends []*virtualMCPBackend
+	nextID   atomic.Int64
+
+	resourcesMu sync.RWMutex
+	resources   map[string]*virtualMCPBackend
+}
+
+func newVirtualMCPServer(c *gin.Context, virtualMcp *model.VirtualMCPCache) *virtualMCPServer {
+	backends := make([]*virtualMCPBackend, len(virtualMcp.Servers))
+	for i, server := range virtualMcp.Servers {
+		backends[i] = &virtualMCPBackend{config: server}
+	}
+
+	return &virtualMCPServer{
+		c:         c,
+		mcp:       virtualMcp,
+		backends:  backends,
+		resources: make(map[string]*virtualMCPBackend),
+	}
+}
+
+// Close closes the clients of the connected mcps
+func (s *virtualMCPServer) Close() {
+	for _, b := range s.backends {
+		if b.closer != nil {
+			_ = b.closer.Close()
+		}
+	}
+}
+
+func (s *virtualMCPServer) backend(namespace string) *virtualMCPBackend {
+	for _, b := range s.backends {
+		if b.config.GetNamespace() == namespace {
+			return b
+		}
+	}
+
+	return nil
+}
+
+// connect creates and initializes the server of the mcp once, the tools
+// calls of the mcp are recorded as the calls of the mcp itself
+func (s *virtualMCPServer) connect(ctx context.Context, b *virtualMCPBackend) error {
+	b.once.Do(func() {
+		var (
+			server mcpservers.Server
+			price  model.MCPPrice
+		)
+
+		server, price, b.closer, b.err = s.newBackendServer(b)
+		if b.err != nil {
+			return
+		}
+
+		params := mcp.InitializeParams{
+			ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+			ClientInfo: mcp.Implementation{
+				Name:    "virtual-mcp",
+				Version: "1.0.0",
+			},
+		}
+		if samplingAllowed(b.sampling) {
+			params.Capabilities.Sampling = &struct{}{}
+		}
+
+		if _, b.err = s.call(ctx, server, mcp.MethodInitialize, params); b.err != nil {
+			return
+		}
+
+		_ = server.HandleMessage(ctx, json.RawMessage(
+			`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		))
+
+		recorder := newToolsCallRecorder(s.c, b.config.ID, b.config.Scope, price)
+		if recorder != nil {
+			server = &toolsCallServer{
+				Server:   server,
+				recorder: recorder,
+			}
+		}
+
+		b.server = server
+	})
+
+	return b.err
+}
+
+// newBackendServer returns the server of the mcp and the client to close
+// with the virtual mcp
+func (s *virtualMCPServer) newBackendServer(
+	b *virtualMCPBackend,
+) (mcpservers.Server, model.MCPPrice, io.Closer, error) {
+	switch b.config.Scope {
+	case model.MCPScopePublic:
+		return s.newPublicBackendServer(b)
+	case model.MCPScopeGroup:
+		server, closer, err := s.newGroupBackendServer(b)
+		return server, model.MCPPrice{}, closer, err
+	default:
+		return nil, model.MCPPrice{}, nil, fmt.Errorf("unknown mcp scope: %s", b.config.Scope)
+	}
+}
+
+func (s *virtualMCPServer) newPublicBackendServer(
+	b *virtualMCPBackend,
+) (mcpservers.Server, model.MCPPrice, io.Closer, error) {
+	mcpID := b.config.ID
+
+	publicMcp, err := model.CacheGetPublicMCP(mcpID)
+	if err != nil {
+		return nil, model.MCPPrice{}, nil, err
+	}
+
+	if publicMcp.Status != model.PublicMCPStatusEnabled {
+		return nil, model.MCPPrice{}, nil, fmt.Errorf("mcp %s is not enabled", mcpID)
+	}
+
+	b.sampling = publicMcp.SamplingEnabled && publicMcp.Type == model.PublicMCPTypeProxyStreamable
+
+	paramsFunc := newGroupParams(publicMcp.ID, s.mcp.GroupID)
+
+	var (
+		server mcpservers.Server
+		closer io.Closer
+	)
+
+	switch publicMcp.Type {
+	case model.PublicMCPTypeProxySSE:
+		var client transport.Interface
+
+		client, err = createProxySSEClient(s.c, publicMcp, paramsFunc)
+		if err == nil {
+			server, closer = mcpservers.WrapMCPClient2Server(client), client
+		}
+	case model.PublicMCPTypeProxyStreamable:
+		var client transport.Interface
+
+		client, err = createProxyStreamableClient(s.c, publicMcp, paramsFunc)
+		if err == nil {
+			server, closer = mcpservers.WrapMCPClient2Server(client), client
+		}
+	case model.PublicMCPTypeOpenAPI:
+		server, err = newOpenAPIMCPServer(publicMcp.OpenAPIConfig)
+	case model.PublicMCPTypeEmbed:
+		var reusingConfig map[string]string
+
+		reusingConfig, err = prepareEmbedReusingConfig(
+			publicMcp.ID,
+			paramsFunc,
+			publicMcp.EmbedConfig.Reusing,
+		)
+		if err == nil {
+			server, err = mcpservers.GetMCPServer(
+				publicMcp.ID,
+				publicMcp.EmbedConfig.Init,
+				reusingConfig,
+			)
+		}
+	case model.PublicMCPTypeStdio:
+		server, err = newStdioMCPServer(publicMcp, paramsFunc)
+	default:
+		err = errors.New("unknown mcp type")
+	}
+
+	return server, publicMcp.Price, closer, err
+}
+
+func (s *virtualMCPServer) newGroupBackendServer(
+	b *virtualMCPBackend,
+) (mcpservers.Server, io.Closer, error) {
+	mcpID := b.config.ID
+
+	groupMcp, err := model.CacheGetGroupMCP(s.mcp.GroupID, mcpID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if groupMcp.Status != model.GroupMCPStatusEnabled {
+		return nil, nil, fmt.Errorf("mcp %s is not enabled", mcpID)
+	}
+
+	b.sampling = groupMcp.SamplingEnabled && groupMcp.Type == model.GroupMCPTypeProxyStreamable
+
+	var client transport.Interface
+
+	switch groupMcp.Type {
+	case model.GroupMCPTypeProxySSE:
+		client, err = transport.NewSSE(
+			groupMcp.ProxyConfig.URL,
+			transport.WithHeaders(groupMcp.ProxyConfig.Headers),
+			transport.WithHeaderFunc(tracing.Headers),
+		)
+	case model.GroupMCPTypeProxyStreamable:
+		var backendURL *url.URL
+
+		backendURL, err = url.Parse(groupMcp.ProxyConfig.URL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		backendQuery := backendURL.Query()
+		for k, v := range groupMcp.ProxyConfig.Querys {
+			backendQuery.Set(k, v)
+		}
+
+		backendURL.RawQuery = backendQuery.Encode()
+
+		client, err = transport.NewStreamableHTTP(
+			backendURL.String(),
+			transport.WithHTTPHeaders(groupMcp.ProxyConfig.Headers),
+			transport.WithHTTPHeaderFunc(tracing.Headers),
+		)
+	case model.GroupMCPTypeOpenAPI:
+		server, err := newOpenAPIMCPServer(groupMcp.OpenAPIConfig)
+		return server, nil, err
+	default:
+		return nil, nil, errors.New("unsupported mcp type")
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	setSamplingHandler(s.c, client, groupMcp.SamplingEnabled)
+
+	if err := client.Start(s.c.Request.Context()); err != nil {
+		return nil, nil, err
+	}
+
+	return mcpservers.WrapMCPClient2Server(client), client, nil
+}
+
+// virtualMCPError is the json-rpc error of a request to an mcp
+type virtualMCPError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *virtualMCPError) Error() string {
+	return e.Message
+}
+
+// call sends the request to the server and returns the result, a json-rpc
+// error is returned as a *virtualMCPError
+func (s *virtualMCPServer) call(
+	ctx context.Context,
+	server mcpservers.Server,
+	method mcp.MCPMethod,
+	params any,
+) (json.RawMessage, error) {
+	message, err := sonic.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      s.nextID.Add(1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := server.HandleMessage(ctx, message)
+	if resp == nil {
+		return nil, fmt.Errorf("no response to %s", method)
+	}
+
+	respBytes, err := sonic.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result json.RawMessage  `json:"result"`
+		Error  *virtualMCPError `json:"error"`
+	}
+	if err := sonic.Unmarshal(respBytes, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return result.Result, nil
+}
+
+// callBackend connects the mcp and sends it the request
+func (s *virtualMCPServer) callBackend(
+	ctx context.Context,
+	b *virtualMCPBackend,
+	method mcp.MCPMethod,
+	params any,
+) (json.RawMessage, error) {
+	if err := s.connect(ctx, b); err != nil {
+		return nil, err
+	}
+
+	return s.call(ctx, b.server, method, params)
+}
+
+func (s *virtualMCPServer) HandleMessage(
+	ctx context.Context,
+	message json.RawMessage,
+) mcp.JSONRPCMessage {
+	var req struct {
+		ID     mcp.RequestId              `json:"id"`
+		Method string                     `json:"method"`
+		Params map[string]json.RawMessage `json:"params"`
+	}
+	if err := sonic.Unmarshal(message, &req); err != nil {
+		return mcpservers.CreateMCPErrorResponse(nil, mcp.PARSE_ERROR, err.Error())
+	}
+
+	if req.ID.IsNil() {
+		return nil
+	}
+
+	id := req.ID.Value()
+
+	var (
+		result any
+		err    error
+	)
+
+	switch mcp.MCPMethod(req.Method) {
+	case mcp.MethodInitialize:
+		result = s.initializeResult(req.Params)
+	case mcp.MethodPing:
+		result = struct{}{}
+	case mcp.MethodToolsList:
+		result, err = s.list(ctx, mcp.MethodToolsList, "tools", true)
+	case mcp.MethodPromptsList:
+		result, err = s.list(ctx, mcp.MethodPromptsList, "prompts", true)
+	case mcp.MethodResourcesList:
+		result, err = s.list(ctx, mcp.MethodResourcesList, "resources", false)
+	case mcp.MethodResourcesTemplatesList:
+		result, err = s.list(ctx, mcp.MethodResourcesTemplatesList, "resourceTemplates", false)
+	case mcp.MethodToolsCall, mcp.MethodPromptsGet:
+		result, err = s.callNamespaced(ctx, mcp.MCPMethod(req.Method), req.Params)
+	case mcp.MethodResourcesRead:
+		result, err = s.readResource(ctx, req.Params)
+	default:
+		return mcpservers.CreateMCPErrorResponse(
+			id,
+			mcp.METHOD_NOT_FOUND,
+			"method not found: "+req.Method,
+		)
+	}
+
+	if err != nil {
+		var rpcErr *virtualMCPError
+		if errors.As(err, &rpcErr) {
+			return mcpservers.CreateMCPErrorResponse(id, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+		}
+
+		return mcpservers.CreateMCPErrorResponse(id, mcp.INVALID_REQUEST, err.Error())
+	}
+
+	raw, ok := result.(json.RawMessage)
+	if !ok {
+		if raw, err = sonic.Marshal(result); err != nil {
+			return mcpservers.CreateMCPErrorResponse(id, mcp.INTERNAL_ERROR, err.Error())
+		}
+	}
+
+	return mcpservers.CreateMCPResultResponse(id, raw)
+}
+
+// initializeResult answers the initialize of the client with the protocol
+// version of the client when it is known
+func (s *virtualMCPServer) initializeResult(params map[string]json.RawMessage) any {
+	protocolVersion := mcp.LATEST_PROTOCOL_VERSION
+
+	var version string
+	if err := sonic.Unmarshal(params["protocolVersion"], &version); err == nil &&
+		slices.Contains(mcp.ValidProtocolVersions, version) {
+		protocolVersion = version
+	}
+
+	name := s.mcp.Name
+	if name == "" {
+		name = s.mcp.ID
+	}
+
+	return map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities": map[string]any{
+			"tools":     map[string]any{},
+			"prompts":   map[string]any{},
+			"resources": map[string]any{},
+		},
+		"serverInfo": mcp.Implementation{
+			Name:    name,
+			Version: "1.0.0",
+		},
+	}
+}
+
+// list merges the items of the list method of all the mcps, the mcps
+// failing to list are skipped
+func (s *virtualMCPServer) list(
+	ctx context.Context,
+	method mcp.MCPMethod,
+	key string,
+	namespaced bool,
+) (map[string]any, error) {
+	results := make([][]map[string]json.RawMessage, len(s.backends))
+
+	var wg sync.WaitGroup
+	for i, b := range s.backends {
+		wg.Go(func() {
+			items, err := s.listBackend(ctx, b, method, key)
+			if err != nil {
+				log.Debugf(
+					"virtual mcp %s: %s of mcp %s error: %s",
+					s.mcp.ID,
+					method,
+					b.config.ID,
+					err.Error(),
+				)
+
+				return
+			}
+
+			results[i] = items
+		})
+	}
+
+	wg.Wait()
+
+	merged := make([]map[string]json.RawMessage, 0)
+	for i, items := range results {
+		b := s.backends[i]
+
+		for _, item := range items {
+			if namespaced {
+				var name string
+				if err := sonic.Unmarshal(item["name"], &name); err != nil {
+					continue
+				}
+
+				item["name"], _ = sonic.Marshal(
+					b.config.GetNamespace() + model.VirtualMCPNamespaceSeparator + name,
+				)
+			}
+
+			if method == mcp.MethodResourcesList {
+				var uri string
+				if err := sonic.Unmarshal(item["uri"], &uri); err == nil {
+					s.resourcesMu.Lock()
+					s.resources[uri] = b
+					s.resourcesMu.Unlock()
+				}
+			}
+
+			merged = append(merged, item)
+		}
+	}
+
+	return map[string]any{key: merged}, nil
+}
+
+// listBackend reads all the pages of the list of the mcp
+func (s *virtualMCPServer) listBackend(
+	ctx context.Context,
+	b *virtualMCPBackend,
+	method mcp.MCPMethod,
+	key string,
+) ([]map[string]json.RawMessage, error) {
+	var (
+		items  []map[string]json.RawMessage
+		cursor string
+	)
+
+	for range maxVirtualMCPListPages {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		result, err := s.callBackend(ctx, b, method, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var page map[string]json.RawMessage
+		if err := sonic.Unmarshal(result, &page); err != nil {
+			return nil, err
+		}
+
+		var pageItems []map[string]json.RawMessage
+		if err := sonic.Unmarshal(page[key], &pageItems); err != nil {
+			return nil, err
+		}
+
+		items = append(items, pageItems...)
+
+		cursor = ""
+		if page["nextCursor"] != nil {
+			_ = sonic.Unmarshal(page["nextCursor"], &cursor)
+		}
+
+		if cursor == "" {
+			break
+		}
+	}
+
+	return items, nil
+}
+
+// callNamespaced routes the request to the mcp of the namespace of the name
+// of the tool or the prompt
+func (s *virtualMCPServer) callNamespaced(
+	ctx context.Context,
+	method mcp.MCPMethod,
+	params map[string]json.RawMessage,
+) (json.RawMessage, error) {
+	var name string
+	if err := sonic.Unmarshal(params["name"], &name); err != nil {
+		return nil, errors.New("name is required")
+	}
+
+	namespace, name, ok := strings.Cut(name, model.VirtualMCPNamespaceSeparator)
+	if !ok {
+		return nil, fmt.Errorf("name must be prefixed with a namespace and %s",
+			model.VirtualMCPNamespaceSeparator)
+	}
+
+	b := s.backend(namespace)
+	if b == nil {
+		return nil, fmt.Errorf("unknown namespace: %s", namespace)
+	}
+
+	var err error
+
+	params["name"], err = sonic.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.callBackend(ctx, b, method, params)
+}
+
+// readResource reads the resource from the mcp listing it, the resources not
+// listed yet are read from the first mcp having them
+func (s *virtualMCPServer) readResource(
+	ctx context.Context,
+	params map[string]json.RawMessage,
+) (json.RawMessage, error) {
+	var uri string
+	if err := sonic.Unmarshal(params["uri"], &uri); err != nil {
+		return nil, errors.New("uri is required")
+	}
+
+	s.resourcesMu.RLock()
+	b, ok := s.resources[uri]
+	s.resourcesMu.RUnlock()
+
+	if ok {
+		return s.callBackend(ctx, b, mcp.MethodResourcesRead, params)
+	}
+
+	var lastErr error
+	for _, b := range s.backends {
+		result, err := s.callBackend(ctx, b, mcp.MethodResourcesRead, params)
+		if err == nil {
+			return result, nil
+		}
+
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
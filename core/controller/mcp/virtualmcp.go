@@ -0,0 +1,301 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/controller/utils"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+type VirtualMCPResponse struct {
+	model.VirtualMCP
+	Endpoints MCPEndpoint `json:"endpoints"`
+}
+
+func (mcp *VirtualMCPResponse) MarshalJSON() ([]byte, error) {
+	type Alias VirtualMCPResponse
+
+	a := &struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+		UpdateAt  int64 `json:"update_at"`
+	}{
+		Alias:     (*Alias)(mcp),
+		CreatedAt: mcp.CreatedAt.UnixMilli(),
+		UpdateAt:  mcp.UpdateAt.UnixMilli(),
+	}
+
+	return sonic.Marshal(a)
+}
+
+func NewVirtualMCPResponse(host string, mcp model.VirtualMCP) VirtualMCPResponse {
+	return VirtualMCPResponse{
+		VirtualMCP: mcp,
+		Endpoints: MCPEndpoint{
+			Host:           host,
+			SSE:            fmt.Sprintf("/mcp/virtual/%s/sse", mcp.ID),
+			StreamableHTTP: "/mcp/virtual/" + mcp.ID,
+		},
+	}
+}
+
+func NewVirtualMCPResponses(host string, mcps []model.VirtualMCP) []VirtualMCPResponse {
+	responses := make([]VirtualMCPResponse, len(mcps))
+	for i, mcp := range mcps {
+		responses[i] = NewVirtualMCPResponse(host, mcp)
+	}
+
+	return responses
+}
+
+// validateVirtualMCPServers checks the mcps of a virtual mcp exist, the group
+// mcps must belong to the group of the virtual mcp
+func validateVirtualMCPServers(groupID string, servers model.VirtualMCPServers) error {
+	if err := servers.Validate(); err != nil {
+		return err
+	}
+
+	for _, server := range servers {
+		switch server.Scope {
+		case model.MCPScopePublic:
+			if _, err := model.GetPublicMCPByID(server.ID); err != nil {
+				return fmt.Errorf("public mcp %s: %w", server.ID, err)
+			}
+		case model.MCPScopeGroup:
+			if _, err := model.GetGroupMCPByID(server.ID, groupID); err != nil {
+				return fmt.Errorf("group mcp %s: %w", server.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetVirtualMCPs godoc
+//
+//	@Summary		Get Virtual MCPs
+//	@Description	Get a list of Virtual MCPs with pagination and filtering
+//	@Tags			mcp
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group		path		string	true	"Group ID"
+//	@Param			page		query		int		false	"Page number"
+//	@Param			per_page	query		int		false	"Items per page"
+//	@Param			keyword		query		string	false	"Search keyword"
+//	@Param			status		query		int		false	"MCP status"
+//	@Success		200			{object}	middleware.APIResponse{data=[]VirtualMCPResponse}
+//	@Router			/api/mcp/virtual/{group} [get]
+func GetVirtualMCPs(c *gin.Context) {
+	groupID := c.Param("group")
+	if groupID == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Group ID is required")
+		return
+	}
+
+	page, perPage := utils.ParsePageParams(c)
+	keyword := c.Query("keyword")
+	status, _ := strconv.Atoi(c.Query("status"))
+
+	mcps, total, err := model.GetVirtualMCPs(
+		groupID,
+		page,
+		perPage,
+		keyword,
+		model.VirtualMCPStatus(status),
+	)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"mcps":  NewVirtualMCPResponses(c.Request.Host, mcps),
+		"total": total,
+	})
+}
+
+// GetVirtualMCPByID godoc
+//
+//	@Summary		Get Virtual MCP by ID
+//	@Description	Get a specific Virtual MCP by its ID and Group ID
+//	@Tags			mcp
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id		path		string	true	"MCP ID"
+//	@Param			group	path		string	true	"Group ID"
+//	@Success		200		{object}	middleware.APIResponse{data=VirtualMCPResponse}
+//	@Router			/api/mcp/virtual/{group}/{id} [get]
+func GetVirtualMCPByID(c *gin.Context) {
+	id := c.Param("id")
+	groupID := c.Param("group")
+
+	if id == "" || groupID == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "MCP ID and Group ID are required")
+		return
+	}
+
+	mcp, err := model.GetVirtualMCPByID(id, groupID)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, NewVirtualMCPResponse(c.Request.Host, mcp))
+}
+
+// CreateVirtualMCP godoc
+//
+//	@Summary		Create Virtual MCP
+//	@Description	Create a new Virtual MCP
+//	@Tags			mcp
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string			true	"Group ID"
+//	@Param			mcp		body		model.VirtualMCP	true	"Virtual MCP object"
+//	@Success		200		{object}	middleware.APIResponse{data=VirtualMCPResponse}
+//	@Router			/api/mcp/virtual/{group} [post]
+func CreateVirtualMCP(c *gin.Context) {
+	groupID := c.Param("group")
+	if groupID == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Group ID is required")
+		return
+	}
+
+	var mcp model.VirtualMCP
+	if err := c.ShouldBindJSON(&mcp); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mcp.GroupID = groupID
+
+	if err := validateVirtualMCPServers(groupID, mcp.Servers); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := model.CreateVirtualMCP(&mcp); err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, NewVirtualMCPResponse(c.Request.Host, mcp))
+}
+
+// UpdateVirtualMCP godoc
+//
+//	@Summary		Update Virtual MCP
+//	@Description	Update an existing Virtual MCP
+//	@Tags			mcp
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id		path		string			true	"MCP ID"
+//	@Param			group	path		string			true	"Group ID"
+//	@Param			mcp		body		model.VirtualMCP	true	"Virtual MCP object"
+//	@Success		200		{object}	middleware.APIResponse{data=VirtualMCPResponse}
+//	@Router			/api/mcp/virtual/{group}/{id} [put]
+func UpdateVirtualMCP(c *gin.Context) {
+	id := c.Param("id")
+	groupID := c.Param("group")
+
+	if id == "" || groupID == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "MCP ID and Group ID are required")
+		return
+	}
+
+	var mcp model.VirtualMCP
+	if err := c.ShouldBindJSON(&mcp); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mcp.ID = id
+	mcp.GroupID = groupID
+
+	if err := validateVirtualMCPServers(groupID, mcp.Servers); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := model.UpdateVirtualMCP(&mcp); err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, NewVirtualMCPResponse(c.Request.Host, mcp))
+}
+
+type UpdateVirtualMCPStatusRequest struct {
+	Status model.VirtualMCPStatus `json:"status"`
+}
+
+// UpdateVirtualMCPStatus godoc
+//
+//	@Summary		Update Virtual MCP status
+//	@Description	Update the status of a Virtual MCP
+//	@Tags			mcp
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id		path		string						true	"MCP ID"
+//	@Param			group	path		string						true	"Group ID"
+//	@Param			status	body		UpdateVirtualMCPStatusRequest	true	"MCP status"
+//	@Success		200		{object}	middleware.APIResponse
+//	@Router			/api/mcp/virtual/{group}/{id}/status [post]
+func UpdateVirtualMCPStatus(c *gin.Context) {
+	id := c.Param("id")
+	groupID := c.Param("group")
+
+	if id == "" || groupID == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "MCP ID and Group ID are required")
+		return
+	}
+
+	var status UpdateVirtualMCPStatusRequest
+	if err := c.ShouldBindJSON(&status); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := model.UpdateVirtualMCPStatus(id, groupID, status.Status); err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, nil)
+}
+
+// DeleteVirtualMCP godoc
+//
+//	@Summary		Delete Virtual MCP
+//	@Description	Delete a Virtual MCP by ID and Group ID
+//	@Tags			mcp
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id		path		string	true	"MCP ID"
+//	@Param			group	path		string	true	"Group ID"
+//	@Success		200		{object}	middleware.APIResponse
+//	@Router			/api/mcp/virtual/{group}/{id} [delete]
+func DeleteVirtualMCP(c *gin.Context) {
+	id := c.Param("id")
+	groupID := c.Param("group")
+
+	if id == "" || groupID == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "MCP ID and Group ID are required")
+		return
+	}
+
+	if err := model.DeleteVirtualMCP(id, groupID); err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, nil)
+}
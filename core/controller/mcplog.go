@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/controller/utils"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func searchMCPLogs(c *gin.Context, group string) {
+	page, perPage := utils.ParsePageParams(c)
+	startTime, endTime := utils.ParseTimeRange(c, 0)
+
+	logs, total, err := model.SearchMCPLogs(
+		group,
+		c.Query("token_name"),
+		c.Query("mcp_id"),
+		c.Query("tool"),
+		startTime,
+		endTime,
+		c.Query("only_error") == "true",
+		page,
+		perPage,
+		c.Query("order"),
+	)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"logs":  logs,
+		"total": total,
+	})
+}
+
+// SearchMCPLogs godoc
+//
+//	@Summary		Search mcp logs
+//	@Description	Returns the tools calls of the mcp servers with their latency, error and charged amount
+//	@Tags			logs
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group			query		string	false	"Group"
+//	@Param			token_name		query		string	false	"Token name"
+//	@Param			mcp_id			query		string	false	"MCP ID"
+//	@Param			tool			query		string	false	"Tool name"
+//	@Param			only_error		query		bool	false	"Only the failed calls"
+//	@Param			start_timestamp	query		int		false	"Start timestamp (milliseconds)"
+//	@Param			end_timestamp	query		int		false	"End timestamp (milliseconds)"
+//	@Param			page			query		int		false	"Page number"
+//	@Param			per_page		query		int		false	"Items per page"
+//	@Param			order			query		string	false	"Order"
+//	@Success		200				{object}	middleware.APIResponse{data=map[string]any{logs=[]model.MCPLog,total=int}}
+//	@Router			/api/logs/mcp [get]
+func SearchMCPLogs(c *gin.Context) {
+	group := c.Query("group")
+	if forcedGroup, ok := middleware.GetForcedGroup(c); ok {
+		group = forcedGroup
+	}
+
+	searchMCPLogs(c, group)
+}
+
+// SearchGroupMCPLogs godoc
+//
+//	@Summary		Search group mcp logs
+//	@Description	Returns the tools calls of the mcp servers of a group
+//	@Tags			log
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group			path		string	true	"Group name"
+//	@Param			token_name		query		string	false	"Token name"
+//	@Param			mcp_id			query		string	false	"MCP ID"
+//	@Param			tool			query		string	false	"Tool name"
+//	@Param			only_error		query		bool	false	"Only the failed calls"
+//	@Param			start_timestamp	query		int		false	"Start timestamp (milliseconds)"
+//	@Param			end_timestamp	query		int		false	"End timestamp (milliseconds)"
+//	@Param			page			query		int		false	"Page number"
+//	@Param			per_page		query		int		false	"Items per page"
+//	@Param			order			query		string	false	"Order"
+//	@Success		200				{object}	middleware.APIResponse{data=map[string]any{logs=[]model.MCPLog,total=int}}
+//	@Router			/api/log/{group}/mcp [get]
+func SearchGroupMCPLogs(c *gin.Context) {
+	group := c.Param("group")
+	if group == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "invalid group parameter")
+		return
+	}
+
+	searchMCPLogs(c, group)
+}
+
+func getMCPUsage(c *gin.Context, group string) {
+	startTime, endTime := utils.ParseTimeRange(c, 0)
+
+	usages, err := model.GetMCPUsage(
+		group,
+		c.Query("token_name"),
+		c.Query("mcp_id"),
+		startTime,
+		endTime,
+	)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, usages)
+}
+
+// GetMCPUsage godoc
+//
+//	@Summary		Get mcp usage
+//	@Description	Returns the calls, errors and charged amount of the tools of the mcp servers summarized from the mcp logs
+//	@Tags			logs
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group			query		string	false	"Group"
+//	@Param			token_name		query		string	false	"Token name"
+//	@Param			mcp_id			query		string	false	"MCP ID"
+//	@Param			start_timestamp	query		int		false	"Start timestamp (milliseconds)"
+//	@Param			end_timestamp	query		int		false	"End timestamp (milliseconds)"
+//	@Success		200				{object}	middleware.APIResponse{data=[]model.MCPUsage}
+//	@Router			/api/logs/mcp/usage [get]
+func GetMCPUsage(c *gin.Context) {
+	group := c.Query("group")
+	if forcedGroup, ok := middleware.GetForcedGroup(c); ok {
+		group = forcedGroup
+	}
+
+	getMCPUsage(c, group)
+}
+
+// GetGroupMCPUsage godoc
+//
+//	@Summary		Get group mcp usage
+//	@Description	Returns the usage of the tools of the mcp servers of a group
+//	@Tags			log
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group			path		string	true	"Group name"
+//	@Param			token_name		query		string	false	"Token name"
+//	@Param			mcp_id			query		string	false	"MCP ID"
+//	@Param			start_timestamp	query		int		false	"Start timestamp (milliseconds)"
+//	@Param			end_timestamp	query		int		false	"End timestamp (milliseconds)"
+//	@Success		200				{object}	middleware.APIResponse{data=[]model.MCPUsage}
+//	@Router			/api/log/{group}/mcp/usage [get]
+func GetGroupMCPUsage(c *gin.Context) {
+	group := c.Param("group")
+	if group == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "invalid group parameter")
+		return
+	}
+
+	getMCPUsage(c, group)
+}
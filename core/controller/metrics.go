@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/consume"
+	"github.com/wavespeed/llm-server/core/common/metrics"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/monitor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	metrics.Registry.MustRegister(&stateCollector{})
+}
+
+var (
+	batchQueueDepthDesc = prometheus.NewDesc(
+		"aiproxy_batch_queue_depth",
+		"Pending batch updates of the summaries and the balances by kind.",
+		[]string{"kind"},
+		nil,
+	)
+	consumePendingDesc = prometheus.NewDesc(
+		"aiproxy_consume_pending",
+		"Consumes which are not recorded yet, the backlog consume.Wait waits for.",
+		nil,
+		nil,
+	)
+	bannedChannelsDesc = prometheus.NewDesc(
+		"aiproxy_monitor_banned_channels",
+		"Channels banned by the monitor by model.",
+		[]string{"model"},
+		nil,
+	)
+)
+
+// stateCollector reads the state of the batch queue, the consume backlog
+// and the monitor when scraped
+type stateCollector struct{}
+
+func (s *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- batchQueueDepthDesc
+	ch <- consumePendingDesc
+	ch <- bannedChannelsDesc
+}
+
+func (s *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	for kind, depth := range model.BatchQueueDepth() {
+		ch <- prometheus.MustNewConstMetric(
+			batchQueueDepthDesc,
+			prometheus.GaugeValue,
+			float64(depth),
+			kind,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		consumePendingDesc,
+		prometheus.GaugeValue,
+		float64(consume.Pending()),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	banned, err := monitor.GetAllBannedModelChannels(ctx)
+	if err != nil {
+		log.Errorf("get banned model channels for metrics failed: %v", err)
+		return
+	}
+
+	for modelName, channels := range banned {
+		ch <- prometheus.MustNewConstMetric(
+			bannedChannelsDesc,
+			prometheus.GaugeValue,
+			float64(len(channels)),
+			modelName,
+		)
+	}
+}
+
+var metricsHandler = promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+
+// Metrics godoc
+//
+//	@Summary		Prometheus metrics
+//	@Description	Returns the relay, billing and monitor metrics in the prometheus text format
+//	@Tags			misc
+//	@Produce		plain
+//	@Security		ApiKeyAuth
+//	@Success		200	{string}	string
+//	@Router			/metrics [get]
+func Metrics(c *gin.Context) {
+	metricsHandler.ServeHTTP(c.Writer, c.Request)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/bytedance/sonic"
 	"github.com/gin-gonic/gin"
 	"github.com/wavespeed/llm-server/core/common/config"
+	"github.com/wavespeed/llm-server/core/common/tiktoken"
 	"github.com/wavespeed/llm-server/core/middleware"
 	"github.com/wavespeed/llm-server/core/model"
 	"github.com/wavespeed/llm-server/core/relay/adaptors"
@@ -305,3 +306,22 @@ func EnabledModelSets(c *gin.Context) {
 
 	middleware.SuccessResponse(c, result)
 }
+
+// Tokenizers godoc
+//
+//	@Summary		Get tokenizers
+//	@Description	Returns the tokenizers of TOKENIZER_DIR the model configs can pick, the tiktoken encodings can be picked too
+//	@Tags			model
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	middleware.APIResponse{data=[]string}
+//	@Router			/api/models/tokenizers [get]
+func Tokenizers(c *gin.Context) {
+	tokenizers, err := tiktoken.Tokenizers()
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, tokenizers)
+}
@@ -0,0 +1,935 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/common/config"
+	"github.com/wavespeed/llm-server/core/common/notify"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxBatchRequests      = 50000
+	maxBatchInputErrors   = 100
+	batchWatchInterval    = time.Second * 5
+	batchStaleTimeout     = time.Minute * 5
+	batchRequestPageSize  = 500
+	batchMetadataID       = "batch_id"
+	batchMetadataCustomID = "batch_custom_id"
+)
+
+var (
+	errBatchCancelled = errors.New("batch cancelled")
+	errBatchExpired   = errors.New("batch expired")
+
+	runningBatches atomic.Int64
+	batchWG        sync.WaitGroup
+)
+
+// RunPendingBatches resumes the batches of stopped instances and claims
+// pending batches, then executes them in the background, the number of
+// batches running on this instance is limited by BATCH_MAX_RUNNING
+func RunPendingBatches(ctx context.Context) {
+	free := config.BatchMaxRunning - runningBatches.Load()
+	if free <= 0 {
+		return
+	}
+
+	stale, err := model.ClaimStaleBatchJobs(time.Now().Add(-batchStaleTimeout), int(free))
+	if err != nil {
+		notify.ErrorThrottle("claimStaleBatches", time.Minute, "claim stale batches failed", err.Error())
+	}
+
+	for _, batch := range stale {
+		log.Warnf("resume stale batch %s in status %s", batch.ID, batch.Status)
+		startBatch(ctx, batch)
+	}
+
+	free -= int64(len(stale))
+	if free <= 0 {
+		return
+	}
+
+	batches, err := model.GetPendingBatchJobs(int(free))
+	if err != nil {
+		notify.ErrorThrottle("getPendingBatches", time.Minute, "get pending batches failed", err.Error())
+		return
+	}
+
+	for _, batch := range batches {
+		claimed, err := model.ClaimBatchJob(batch)
+		if err != nil {
+			notify.ErrorThrottle("claimBatch", time.Minute, "claim batch failed", err.Error())
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		startBatch(ctx, batch)
+	}
+}
+
+func startBatch(ctx context.Context, batch *model.BatchJob) {
+	runningBatches.Add(1)
+	batchWG.Add(1)
+
+	go func() {
+		defer batchWG.Done()
+		defer runningBatches.Add(-1)
+
+		runBatch(ctx, batch)
+	}()
+}
+
+// WaitBatches waits for the batches running on this instance, they stop
+// dispatching requests when the RunPendingBatches context is done
+func WaitBatches() {
+	batchWG.Wait()
+}
+
+type batchRunner struct {
+	batch      *model.BatchJob
+	mode       mode.Mode
+	controller RelayController
+	tokenKey   string
+
+	// recorded are the indexes of the requests dispatched before the batch
+	// was resumed
+	recorded  map[int]struct{}
+	completed atomic.Int64
+	failed    atomic.Int64
+}
+
+func runBatch(ctx context.Context, batch *model.BatchJob) {
+	log := log.WithField("batch_id", batch.ID)
+
+	switch {
+	case batch.Status == model.BatchJobStatusCancelling:
+		finishBatch(batch, errBatchCancelled)
+		return
+	case batch.Status == model.BatchJobStatusFinalizing:
+		finishBatch(batch, nil)
+		return
+	case time.Now().After(batch.ExpiresAt):
+		finishBatch(batch, errBatchExpired)
+		return
+	}
+
+	m, ok := batchEndpointModes[batch.Endpoint]
+	if !ok {
+		failBatch(batch, "invalid_endpoint", "unsupported endpoint: "+batch.Endpoint)
+		return
+	}
+
+	token, err := model.GetGroupTokenByID(batch.GroupID, batch.TokenID)
+	if err != nil {
+		failBatch(batch, "invalid_token", "get token failed: "+err.Error())
+		return
+	}
+
+	total, inputErrors, err := validateBatchInputFile(ctx, batch)
+	if err != nil {
+		log.Errorf("read batch input failed: %v", err)
+		failBatch(batch, "invalid_input_file", err.Error())
+
+		return
+	}
+
+	if len(inputErrors) > 0 {
+		batch.Errors = inputErrors
+		finishBatch(batch, nil)
+
+		return
+	}
+
+	batch.TotalRequests = total
+
+	requests, err := model.GetBatchJobRequestStates(batch)
+	if err != nil {
+		// the batch is resumed once it is stale
+		log.Errorf("get batch requests failed: %v", err)
+		return
+	}
+
+	r := &batchRunner{
+		batch:      batch,
+		mode:       m,
+		controller: relayController(m),
+		tokenKey:   token.Key,
+		recorded:   make(map[int]struct{}, len(requests)),
+	}
+
+	for _, request := range requests {
+		r.recorded[request.RequestIndex] = struct{}{}
+
+		if request.Finished && request.Succeeded {
+			r.completed.Add(1)
+		} else {
+			r.failed.Add(1)
+		}
+	}
+
+	cause := r.run(ctx)
+
+	if cause != nil && ctx.Err() != nil &&
+		!errors.Is(cause, errBatchCancelled) && !errors.Is(cause, errBatchExpired) {
+		// the gateway shuts down, the batch is resumed once it is stale
+		log.Warn("batch is interrupted by the shutdown")
+		return
+	}
+
+	finishBatch(batch, cause)
+}
+
+// run executes the requests which are not recorded yet with bounded
+// concurrency, it stops dispatching when the batch is cancelled, expired or
+// the gateway shuts down
+func (r *batchRunner) run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	watchDone := make(chan struct{})
+	watchStopped := make(chan struct{})
+
+	go func() {
+		defer close(watchStopped)
+		r.watch(runCtx, cancel, watchDone)
+	}()
+
+	concurrency := config.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+
+	err := forEachBatchInputFile(runCtx, r.batch, func(index int, input *relaymodel.BatchRequestInput) error {
+		if _, ok := r.recorded[index]; ok {
+			return nil
+		}
+
+		select {
+		case <-runCtx.Done():
+			return context.Cause(runCtx)
+		case semaphore <- struct{}{}:
+		}
+
+		if runCtx.Err() != nil {
+			<-semaphore
+			return context.Cause(runCtx)
+		}
+
+		if err := model.StartBatchJobRequest(r.batch, index); err != nil {
+			<-semaphore
+			return fmt.Errorf("record batch request failed: %w", err)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			r.execute(index, input)
+		}()
+
+		return nil
+	})
+
+	wg.Wait()
+
+	close(watchDone)
+	<-watchStopped
+
+	if err == nil && runCtx.Err() != nil {
+		err = context.Cause(runCtx)
+	}
+
+	return err
+}
+
+// execute runs a request and records its output line
+func (r *batchRunner) execute(index int, input *relaymodel.BatchRequestInput) {
+	output := r.runRequest(input)
+
+	succeeded := isBatchOutputSucceeded(output)
+	if succeeded {
+		r.completed.Add(1)
+	} else {
+		r.failed.Add(1)
+	}
+
+	line, err := sonic.Marshal(output)
+	if err != nil {
+		log.Errorf("marshal batch %s output failed: %v", r.batch.ID, err)
+		return
+	}
+
+	if err := model.FinishBatchJobRequest(r.batch, index, string(line), succeeded); err != nil {
+		log.Errorf("record batch %s output failed: %v", r.batch.ID, err)
+	}
+}
+
+// watch reports the progress, which also keeps the batch from being taken
+// over by ClaimStaleBatchJobs, and cancels the run when the batch is
+// cancelled or expired
+func (r *batchRunner) watch(
+	ctx context.Context,
+	cancel context.CancelCauseFunc,
+	done <-chan struct{},
+) {
+	ticker := time.NewTicker(batchWatchInterval)
+	defer ticker.Stop()
+
+	expire := time.NewTimer(time.Until(r.batch.ExpiresAt))
+	defer expire.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-expire.C:
+			cancel(errBatchExpired)
+		case <-ticker.C:
+			r.batch.CompletedRequests = int(r.completed.Load())
+			r.batch.FailedRequests = int(r.failed.Load())
+
+			if err := model.UpdateBatchJobProgress(r.batch); err != nil {
+				log.Errorf("update batch %s progress failed: %v", r.batch.ID, err)
+			}
+
+			if ctx.Err() != nil {
+				continue
+			}
+
+			current, err := model.GetBatchJob(r.batch.GroupID, r.batch.TokenID, r.batch.ID)
+			if err != nil {
+				log.Errorf("get batch %s failed: %v", r.batch.ID, err)
+				continue
+			}
+
+			if current.Status == model.BatchJobStatusCancelling {
+				r.batch.CancellingAt = current.CancellingAt
+				cancel(errBatchCancelled)
+			}
+		}
+	}
+}
+
+// runRequest relays one batch line through the same pipeline as a normal
+// request, so it is distributed, retried, logged and billed the same way
+func (r *batchRunner) runRequest(input *relaymodel.BatchRequestInput) *relaymodel.BatchRequestOutput {
+	output := &relaymodel.BatchRequestOutput{
+		ID:       "batch_req_" + common.ShortUUID(),
+		CustomID: input.CustomID,
+	}
+
+	group, token, modelCaches, err := batchRequestAuth(r.tokenKey)
+	if err != nil {
+		output.Error = &relaymodel.BatchRequestError{
+			Code:    "invalid_token",
+			Message: err.Error(),
+		}
+
+		return output
+	}
+
+	resp, err := internalRelay(
+		context.Background(),
+		r.mode,
+		input.URL,
+		input.Body,
+		group,
+		token,
+		modelCaches,
+		map[string]string{
+			batchMetadataID:       r.batch.ID,
+			batchMetadataCustomID: input.CustomID,
+		},
+	)
+	if err != nil {
+		output.Error = &relaymodel.BatchRequestError{
+			Code:    "invalid_request",
+			Message: err.Error(),
+		}
+
+		return output
+	}
+
+	body := resp.Body
+	if !json.Valid(body) {
+		body, _ = sonic.Marshal(string(resp.Body))
+	}
+
+	output.Response = &relaymodel.BatchRequestResponse{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.RequestID,
+		Body:       body,
+	}
+
+	return output
+}
+
+// batchRequestAuth loads the group and token like TokenAuth, so quota and
+// status changes made while the batch runs are respected
+func batchRequestAuth(key string) (model.GroupCache, model.TokenCache, *model.ModelCaches, error) {
+	token, err := model.GetAndValidateToken(key)
+	if err != nil {
+		return model.GroupCache{}, model.TokenCache{}, nil, err
+	}
+
+	group, err := model.CacheGetGroup(token.Group)
+	if err != nil {
+		return model.GroupCache{}, model.TokenCache{}, nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	if group.Status != model.GroupStatusEnabled && group.Status != model.GroupStatusInternal {
+		return model.GroupCache{}, model.TokenCache{}, nil, errors.New("group is disabled")
+	}
+
+	modelCaches := model.LoadModelCaches()
+
+	tokenCache := *token
+	tokenCache.SetAvailableSets(group.GetAvailableSets())
+	tokenCache.SetModelsBySet(modelCaches.EnabledModelsBySet)
+
+	return *group, tokenCache, modelCaches, nil
+}
+
+func isBatchOutputSucceeded(output *relaymodel.BatchRequestOutput) bool {
+	return output.Error == nil &&
+		output.Response != nil &&
+		output.Response.StatusCode >= http.StatusOK &&
+		output.Response.StatusCode < http.StatusMultipleChoices
+}
+
+// validateBatchInputFile validates the batch input file without keeping the
+// requests in memory, it returns the number of requests
+func validateBatchInputFile(ctx context.Context, batch *model.BatchJob) (int, []model.BatchJobError, error) {
+	_, content, err := OpenFile(ctx, batch.GroupID, batch.TokenID, batch.InputFileID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("open input file %s failed: %w", batch.InputFileID, err)
+	}
+	defer content.Close()
+
+	total, inputErrors, err := validateBatchInput(content, batch.Endpoint)
+	if err != nil {
+		return 0, nil, fmt.Errorf("read input file %s failed: %w", batch.InputFileID, err)
+	}
+
+	return total, inputErrors, nil
+}
+
+// validateBatchInput checks every line is a request to the batch endpoint
+// with a unique custom id, only the custom ids are kept in memory
+func validateBatchInput(r io.Reader, endpoint string) (int, []model.BatchJobError, error) {
+	var (
+		total       int
+		inputErrors []model.BatchJobError
+		customIDs   = make(map[string]struct{})
+	)
+
+	addError := func(lineNumber int, code, message string) {
+		if len(inputErrors) < maxBatchInputErrors {
+			inputErrors = append(inputErrors, model.BatchJobError{
+				Code:    code,
+				Message: message,
+				Line:    lineNumber,
+			})
+		}
+	}
+
+	err := scanBatchInput(r, endpoint, func(
+		lineNumber int,
+		input *relaymodel.BatchRequestInput,
+		code, message string,
+	) error {
+		switch {
+		case input == nil:
+			addError(lineNumber, code, message)
+		case total >= maxBatchRequests:
+			addError(lineNumber, "too_many_requests",
+				fmt.Sprintf("a batch can contain at most %d requests", maxBatchRequests))
+		default:
+			if _, ok := customIDs[input.CustomID]; ok {
+				addError(lineNumber, "duplicate_custom_id",
+					fmt.Sprintf("duplicate custom_id: %q", input.CustomID))
+				break
+			}
+
+			customIDs[input.CustomID] = struct{}{}
+			total++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if total == 0 && len(inputErrors) == 0 {
+		addError(0, "empty_file", "the input file contains no requests")
+	}
+
+	return total, inputErrors, nil
+}
+
+func forEachBatchInputFile(
+	ctx context.Context,
+	batch *model.BatchJob,
+	fn func(index int, input *relaymodel.BatchRequestInput) error,
+) error {
+	_, content, err := OpenFile(ctx, batch.GroupID, batch.TokenID, batch.InputFileID)
+	if err != nil {
+		return fmt.Errorf("open input file %s failed: %w", batch.InputFileID, err)
+	}
+	defer content.Close()
+
+	return forEachBatchInput(content, batch.Endpoint, fn)
+}
+
+// forEachBatchInput calls fn with the requests of a validated input file,
+// the index of a request is its position among the requests
+func forEachBatchInput(
+	r io.Reader,
+	endpoint string,
+	fn func(index int, input *relaymodel.BatchRequestInput) error,
+) error {
+	index := 0
+
+	return scanBatchInput(r, endpoint, func(
+		_ int,
+		input *relaymodel.BatchRequestInput,
+		_, _ string,
+	) error {
+		if input == nil {
+			return nil
+		}
+
+		i := index
+		index++
+
+		return fn(i, input)
+	})
+}
+
+// scanBatchInput reads the input line by line and calls fn with the parsed
+// request or the reason the line is invalid, blank lines are skipped
+func scanBatchInput(
+	r io.Reader,
+	endpoint string,
+	fn func(lineNumber int, input *relaymodel.BatchRequestInput, code, message string) error,
+) error {
+	reader := bufio.NewReader(r)
+	lineNumber := 0
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		lineNumber++
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			input, code, message := parseBatchInput(trimmed, endpoint)
+			if err := fn(lineNumber, input, code, message); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
+
+func parseBatchInput(
+	line []byte,
+	endpoint string,
+) (input *relaymodel.BatchRequestInput, code, message string) {
+	input = &relaymodel.BatchRequestInput{}
+	if err := sonic.Unmarshal(line, input); err != nil {
+		return nil, "invalid_json_line", "invalid json: " + err.Error()
+	}
+
+	if input.CustomID == "" {
+		return nil, "missing_custom_id", "custom_id is required"
+	}
+
+	if input.Method != http.MethodPost {
+		return nil, "invalid_method", fmt.Sprintf("unsupported method: %q", input.Method)
+	}
+
+	if input.URL != endpoint {
+		return nil, "invalid_url", fmt.Sprintf("url %q does not match the batch endpoint %q", input.URL, endpoint)
+	}
+
+	var body struct {
+		Stream bool `json:"stream"`
+	}
+	if err := sonic.Unmarshal(input.Body, &body); err != nil {
+		return nil, "invalid_body", "body must be a json object: " + err.Error()
+	}
+
+	if body.Stream {
+		return nil, "invalid_body", "stream is not supported in batches"
+	}
+
+	return input, "", ""
+}
+
+// finishBatch writes the output and error files from the recorded requests
+// and saves the final state, requests not executed because of cause are
+// written to the error file
+func finishBatch(batch *model.BatchJob, cause error) {
+	now := time.Now()
+
+	if len(batch.Errors) > 0 {
+		batch.Status = model.BatchJobStatusFailed
+		batch.FailedAt = now
+		saveBatch(batch)
+
+		return
+	}
+
+	if err := model.UpdateBatchJobStatus(batch, model.BatchJobStatusFinalizing, now); err != nil {
+		log.Errorf("update batch %s status failed: %v", batch.ID, err)
+	}
+
+	notExecuted := &relaymodel.BatchRequestError{
+		Code:    "batch_interrupted",
+		Message: "the request was not executed because the batch was interrupted",
+	}
+
+	switch {
+	case errors.Is(cause, errBatchCancelled):
+		notExecuted.Code = "batch_cancelled"
+		notExecuted.Message = "the request was not executed because the batch was cancelled"
+	case errors.Is(cause, errBatchExpired):
+		notExecuted.Code = "batch_expired"
+		notExecuted.Message = "the request was not executed because the batch expired"
+	}
+
+	if err := storeBatchOutputs(batch, notExecuted); err != nil {
+		log.Errorf("store batch %s outputs failed: %v", batch.ID, err)
+	}
+
+	now = time.Now()
+
+	switch {
+	case len(batch.Errors) > 0:
+		batch.Status = model.BatchJobStatusFailed
+		batch.FailedAt = now
+	case errors.Is(cause, errBatchCancelled):
+		batch.Status = model.BatchJobStatusCancelled
+		batch.CancelledAt = now
+	case errors.Is(cause, errBatchExpired):
+		batch.Status = model.BatchJobStatusExpired
+		batch.ExpiredAt = now
+	case cause != nil:
+		batch.Status = model.BatchJobStatusFailed
+		batch.FailedAt = now
+		batch.Errors = append(batch.Errors, model.BatchJobError{
+			Code:    notExecuted.Code,
+			Message: notExecuted.Message,
+		})
+	default:
+		batch.Status = model.BatchJobStatusCompleted
+		batch.CompletedAt = now
+	}
+
+	if saveBatch(batch) {
+		if err := model.DeleteBatchJobRequests(batch); err != nil {
+			log.Errorf("delete batch %s requests failed: %v", batch.ID, err)
+		}
+	}
+}
+
+// storeBatchOutputs streams the outputs through temporary files into the
+// output and error files of the batch
+func storeBatchOutputs(batch *model.BatchJob, notExecuted *relaymodel.BatchRequestError) error {
+	ctx := context.Background()
+
+	output, err := os.CreateTemp("", "batch-output-*.jsonl")
+	if err != nil {
+		batch.Errors = append(batch.Errors, model.BatchJobError{
+			Code:    "output_file_failed",
+			Message: "save output file failed",
+		})
+
+		return err
+	}
+	defer os.Remove(output.Name())
+	defer output.Close()
+
+	errorOutput, err := os.CreateTemp("", "batch-error-*.jsonl")
+	if err != nil {
+		batch.Errors = append(batch.Errors, model.BatchJobError{
+			Code:    "error_file_failed",
+			Message: "save error file failed",
+		})
+
+		return err
+	}
+	defer os.Remove(errorOutput.Name())
+	defer errorOutput.Close()
+
+	requests := newBatchRequestCursor(func(after int) ([]*model.BatchJobRequest, error) {
+		// every page also keeps the finalizing batch from being taken over
+		if err := model.UpdateBatchJobProgress(batch); err != nil {
+			log.Errorf("update batch %s progress failed: %v", batch.ID, err)
+		}
+
+		return model.GetBatchJobRequests(batch, after, batchRequestPageSize)
+	})
+
+	_, content, err := OpenFile(ctx, batch.GroupID, batch.TokenID, batch.InputFileID)
+	if err != nil {
+		batch.Errors = append(batch.Errors, model.BatchJobError{
+			Code:    "output_file_failed",
+			Message: "save output file failed",
+		})
+
+		return fmt.Errorf("open input file %s failed: %w", batch.InputFileID, err)
+	}
+	defer content.Close()
+
+	outputWriter := bufio.NewWriter(output)
+	errorWriter := bufio.NewWriter(errorOutput)
+
+	completed, failed, err := writeBatchOutputs(
+		content,
+		batch.Endpoint,
+		requests,
+		notExecuted,
+		outputWriter,
+		errorWriter,
+	)
+	if err == nil {
+		err = outputWriter.Flush()
+	}
+
+	if err == nil {
+		err = errorWriter.Flush()
+	}
+
+	if err != nil {
+		batch.Errors = append(batch.Errors, model.BatchJobError{
+			Code:    "output_file_failed",
+			Message: "save output file failed",
+		})
+
+		return err
+	}
+
+	batch.TotalRequests = completed + failed
+	batch.CompletedRequests = completed
+	batch.FailedRequests = failed
+
+	file, err := storeBatchOutputFile(ctx, batch, output, batch.ID+"_output.jsonl")
+	if err != nil {
+		batch.Errors = append(batch.Errors, model.BatchJobError{
+			Code:    "output_file_failed",
+			Message: "save output file failed",
+		})
+
+		return err
+	}
+
+	if file != nil {
+		batch.OutputFileID = file.ID
+	}
+
+	file, err = storeBatchOutputFile(ctx, batch, errorOutput, batch.ID+"_error.jsonl")
+	if err != nil {
+		batch.Errors = append(batch.Errors, model.BatchJobError{
+			Code:    "error_file_failed",
+			Message: "save error file failed",
+		})
+
+		return err
+	}
+
+	if file != nil {
+		batch.ErrorFileID = file.ID
+	}
+
+	return nil
+}
+
+// storeBatchOutputFile stores a written temporary file, empty files are not
+// stored
+func storeBatchOutputFile(
+	ctx context.Context,
+	batch *model.BatchJob,
+	f *os.File,
+	filename string,
+) (*model.File, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return storeFile(ctx, batch.GroupID, batch.TokenID,
+		filename, relaymodel.FilePurposeBatchOut, f, size)
+}
+
+// writeBatchOutputs writes the recorded output of every request of the input
+// in the input order, the requests which are not recorded get notExecuted
+// and the requests which never finished are reported as interrupted
+func writeBatchOutputs(
+	input io.Reader,
+	endpoint string,
+	requests *batchRequestCursor,
+	notExecuted *relaymodel.BatchRequestError,
+	output, errorOutput io.Writer,
+) (completed, failed int, err error) {
+	err = forEachBatchInput(input, endpoint, func(index int, input *relaymodel.BatchRequestInput) error {
+		request, err := requests.get(index)
+		if err != nil {
+			return err
+		}
+
+		var line []byte
+
+		switch {
+		case request != nil && request.Finished:
+			line = []byte(request.Output)
+		default:
+			result := &relaymodel.BatchRequestOutput{
+				ID:       "batch_req_" + common.ShortUUID(),
+				CustomID: input.CustomID,
+				Error:    notExecuted,
+			}
+
+			if request != nil {
+				result.Error = &relaymodel.BatchRequestError{
+					Code: "batch_interrupted",
+					Message: "the request was interrupted by a restart of the gateway, " +
+						"it is not executed again to avoid billing it twice",
+				}
+			}
+
+			line, err = sonic.Marshal(result)
+			if err != nil {
+				return err
+			}
+		}
+
+		w := errorOutput
+		if request != nil && request.Finished && request.Succeeded {
+			completed++
+			w = output
+		} else {
+			failed++
+		}
+
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+
+		_, err = w.Write([]byte{'\n'})
+
+		return err
+	})
+
+	return completed, failed, err
+}
+
+// batchRequestCursor walks the recorded requests of a batch page by page,
+// the indexes passed to get must be increasing
+type batchRequestCursor struct {
+	next func(after int) ([]*model.BatchJobRequest, error)
+
+	page []*model.BatchJobRequest
+	last int
+	done bool
+}
+
+func newBatchRequestCursor(next func(after int) ([]*model.BatchJobRequest, error)) *batchRequestCursor {
+	return &batchRequestCursor{
+		next: next,
+		last: -1,
+	}
+}
+
+// get returns the recorded request with the index, or nil when the request
+// is not recorded
+func (c *batchRequestCursor) get(index int) (*model.BatchJobRequest, error) {
+	for {
+		for len(c.page) > 0 && c.page[0].RequestIndex < index {
+			c.page = c.page[1:]
+		}
+
+		if len(c.page) > 0 {
+			if c.page[0].RequestIndex == index {
+				return c.page[0], nil
+			}
+
+			return nil, nil
+		}
+
+		if c.done || index <= c.last {
+			return nil, nil
+		}
+
+		page, err := c.next(c.last)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(page) < batchRequestPageSize {
+			c.done = true
+		}
+
+		if len(page) > 0 {
+			c.last = page[len(page)-1].RequestIndex
+		}
+
+		c.page = page
+	}
+}
+
+func failBatch(batch *model.BatchJob, code, message string) {
+	batch.Errors = append(batch.Errors, model.BatchJobError{
+		Code:    code,
+		Message: message,
+	})
+	finishBatch(batch, nil)
+}
+
+func saveBatch(batch *model.BatchJob) bool {
+	if err := model.SaveBatchJob(batch); err != nil {
+		notify.ErrorThrottle("saveBatch", time.Minute, "save batch failed",
+			fmt.Sprintf("batch %s: %v", batch.ID, err))
+
+		return false
+	}
+
+	return true
+}
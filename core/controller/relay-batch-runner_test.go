@@ -0,0 +1,210 @@
+package controller_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/wavespeed/llm-server/core/controller"
+	"github.com/wavespeed/llm-server/core/model"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+)
+
+const batchEndpoint = "/v1/chat/completions"
+
+func batchLine(customID string) string {
+	return `{"custom_id":"` + customID + `","method":"POST","url":"` + batchEndpoint +
+		`","body":{"model":"gpt-4o"}}`
+}
+
+func TestValidateBatchInput(t *testing.T) {
+	input := strings.Join([]string{
+		batchLine("a"),
+		"",
+		"not json",
+		batchLine("a"),
+		`{"custom_id":"b","method":"POST","url":"/v1/embeddings","body":{}}`,
+		`{"custom_id":"c","method":"POST","url":"` + batchEndpoint + `","body":{"stream":true}}`,
+		batchLine("d"),
+	}, "\n")
+
+	total, inputErrors, err := controller.ValidateBatchInput(strings.NewReader(input), batchEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if total != 2 {
+		t.Errorf("Expected 2 requests, Got %d", total)
+	}
+
+	want := []model.BatchJobError{
+		{Code: "invalid_json_line", Line: 3},
+		{Code: "duplicate_custom_id", Line: 4},
+		{Code: "invalid_url", Line: 5},
+		{Code: "invalid_body", Line: 6},
+	}
+	if len(inputErrors) != len(want) {
+		t.Fatalf("Expected %d errors, Got %+v", len(want), inputErrors)
+	}
+
+	for i, w := range want {
+		if inputErrors[i].Code != w.Code || inputErrors[i].Line != w.Line {
+			t.Errorf("Expected error %s on line %d, Got %s on line %d",
+				w.Code, w.Line, inputErrors[i].Code, inputErrors[i].Line)
+		}
+	}
+}
+
+func TestValidateBatchInputEmpty(t *testing.T) {
+	total, inputErrors, err := controller.ValidateBatchInput(strings.NewReader("\n\n"), batchEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if total != 0 || len(inputErrors) != 1 || inputErrors[0].Code != "empty_file" {
+		t.Errorf("Expected an empty_file error, Got %d requests and %+v", total, inputErrors)
+	}
+}
+
+func TestForEachBatchInput(t *testing.T) {
+	input := batchLine("a") + "\n\n" + batchLine("b") + "\n" + batchLine("c")
+
+	var customIDs []string
+
+	err := controller.ForEachBatchInput(strings.NewReader(input), batchEndpoint,
+		func(index int, input *relaymodel.BatchRequestInput) error {
+			if index != len(customIDs) {
+				t.Errorf("Expected index %d, Got %d", len(customIDs), index)
+			}
+
+			customIDs = append(customIDs, input.CustomID)
+
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(customIDs, ",") != "a,b,c" {
+		t.Errorf("Expected a,b,c, Got %v", customIDs)
+	}
+}
+
+func TestBatchRequestCursor(t *testing.T) {
+	const recorded = controller.BatchRequestPageSize*2 + 200
+
+	var requests []*model.BatchJobRequest
+	for i := range recorded {
+		requests = append(requests, &model.BatchJobRequest{RequestIndex: i * 2})
+	}
+
+	calls := 0
+	cursor := controller.NewBatchRequestCursor(func(after int) ([]*model.BatchJobRequest, error) {
+		calls++
+
+		start := 0
+		for start < len(requests) && requests[start].RequestIndex <= after {
+			start++
+		}
+
+		end := min(start+controller.BatchRequestPageSize, len(requests))
+
+		return requests[start:end], nil
+	})
+
+	for index := range recorded*2 + 10 {
+		request, err := controller.BatchRequestCursorGet(cursor, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if found := request != nil; found != (index%2 == 0 && index < recorded*2) {
+			t.Fatalf("Unexpected request %v at index %d", request, index)
+		}
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected 3 pages, Got %d", calls)
+	}
+}
+
+func TestWriteBatchOutputs(t *testing.T) {
+	input := strings.Join([]string{
+		batchLine("succeeded"),
+		batchLine("failed"),
+		batchLine("interrupted"),
+		batchLine("cancelled"),
+	}, "\n")
+
+	requests := []*model.BatchJobRequest{
+		{
+			RequestIndex: 0,
+			Finished:     true,
+			Succeeded:    true,
+			Output:       `{"id":"batch_req_1","custom_id":"succeeded"}`,
+		},
+		{
+			RequestIndex: 1,
+			Finished:     true,
+			Output:       `{"id":"batch_req_2","custom_id":"failed"}`,
+		},
+		// dispatched before a restart and never finished
+		{RequestIndex: 2},
+	}
+
+	cursor := controller.NewBatchRequestCursor(func(after int) ([]*model.BatchJobRequest, error) {
+		if after != -1 {
+			return nil, nil
+		}
+
+		return requests, nil
+	})
+
+	var output, errorOutput bytes.Buffer
+
+	completed, failed, err := controller.WriteBatchOutputs(
+		strings.NewReader(input),
+		batchEndpoint,
+		cursor,
+		&relaymodel.BatchRequestError{Code: "batch_cancelled"},
+		&output,
+		&errorOutput,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if completed != 1 || failed != 3 {
+		t.Errorf("Expected 1 completed and 3 failed, Got %d and %d", completed, failed)
+	}
+
+	if output.String() != requests[0].Output+"\n" {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(errorOutput.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 error lines, Got %q", errorOutput.String())
+	}
+
+	if lines[0] != requests[1].Output {
+		t.Errorf("Unexpected error line: %q", lines[0])
+	}
+
+	wantCodes := map[string]string{
+		"interrupted": "batch_interrupted",
+		"cancelled":   "batch_cancelled",
+	}
+
+	for _, line := range lines[1:] {
+		var result relaymodel.BatchRequestOutput
+		if err := sonic.UnmarshalString(line, &result); err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Error == nil || result.Error.Code != wantCodes[result.CustomID] {
+			t.Errorf("Unexpected error line: %q", line)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+	"gorm.io/gorm"
+)
+
+// https://platform.openai.com/docs/api-reference/batch
+
+const (
+	defaultListBatchesLimit = 20
+	maxListBatchesLimit     = 100
+	maxBatchMetadata        = 16
+	batchCompletionWindow   = time.Hour * 24
+)
+
+// batchEndpointModes are the endpoints a batch can be created for
+var batchEndpointModes = map[string]mode.Mode{
+	"/v1/chat/completions": mode.ChatCompletions,
+	"/v1/completions":      mode.Completions,
+	"/v1/embeddings":       mode.Embeddings,
+	"/v1/responses":        mode.Responses,
+	"/v1/moderations":      mode.Moderations,
+	"/v1/messages":         mode.Anthropic,
+	"/v1/rerank":           mode.Rerank,
+}
+
+// CreateBatch godoc
+//
+//	@Summary		Create batch
+//	@Description	Create a batch from an uploaded jsonl file, the requests are executed by the gateway
+//	@Tags			relay
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		model.CreateBatchRequest	true	"Request"
+//	@Success		200		{object}	model.Batch
+//	@Router			/v1/batches [post]
+func CreateBatch() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewServe(mode.Batch),
+		createBatch,
+	}
+}
+
+// ListBatches godoc
+//
+//	@Summary		List batches
+//	@Description	List batches of the token
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			after	query		string	false	"Cursor batch id"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	model.BatchList
+//	@Router			/v1/batches [get]
+func ListBatches() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewServe(mode.Batch),
+		listBatches,
+	}
+}
+
+// RetrieveBatch godoc
+//
+//	@Summary		Retrieve batch
+//	@Description	Retrieve a batch by ID
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		string	true	"Batch ID"
+//	@Success		200	{object}	model.Batch
+//	@Router			/v1/batches/{id} [get]
+func RetrieveBatch() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewServe(mode.Batch),
+		retrieveBatch,
+	}
+}
+
+// CancelBatch godoc
+//
+//	@Summary		Cancel batch
+//	@Description	Cancel a batch, requests already running are still completed and billed
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		string	true	"Batch ID"
+//	@Success		200	{object}	model.Batch
+//	@Router			/v1/batches/{id}/cancel [post]
+func CancelBatch() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewServe(mode.Batch),
+		cancelBatch,
+	}
+}
+
+func unixOrNil(t time.Time) *int64 {
+	if t.IsZero() {
+		return nil
+	}
+
+	v := t.Unix()
+
+	return &v
+}
+
+func stringOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+func toBatchObject(b *model.BatchJob) relaymodel.Batch {
+	batch := relaymodel.Batch{
+		ID:               b.ID,
+		Object:           "batch",
+		Endpoint:         b.Endpoint,
+		InputFileID:      b.InputFileID,
+		CompletionWindow: b.CompletionWindow,
+		Status:           b.Status,
+		OutputFileID:     stringOrNil(b.OutputFileID),
+		ErrorFileID:      stringOrNil(b.ErrorFileID),
+		CreatedAt:        b.CreatedAt.Unix(),
+		InProgressAt:     unixOrNil(b.InProgressAt),
+		ExpiresAt:        unixOrNil(b.ExpiresAt),
+		FinalizingAt:     unixOrNil(b.FinalizingAt),
+		CompletedAt:      unixOrNil(b.CompletedAt),
+		FailedAt:         unixOrNil(b.FailedAt),
+		ExpiredAt:        unixOrNil(b.ExpiredAt),
+		CancellingAt:     unixOrNil(b.CancellingAt),
+		CancelledAt:      unixOrNil(b.CancelledAt),
+		RequestCounts: relaymodel.BatchRequestCounts{
+			Total:     b.TotalRequests,
+			Completed: b.CompletedRequests,
+			Failed:    b.FailedRequests,
+		},
+		Metadata: b.Metadata,
+	}
+
+	if len(b.Errors) > 0 {
+		batch.Errors = &relaymodel.BatchErrors{
+			Object: "list",
+			Data:   make([]relaymodel.BatchError, 0, len(b.Errors)),
+		}
+		for _, e := range b.Errors {
+			batchError := relaymodel.BatchError{
+				Code:    e.Code,
+				Message: e.Message,
+			}
+			if e.Line > 0 {
+				line := e.Line
+				batchError.Line = &line
+			}
+
+			batch.Errors.Data = append(batch.Errors.Data, batchError)
+		}
+	}
+
+	return batch
+}
+
+func createBatch(c *gin.Context) {
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+
+	if token.ID == 0 {
+		relayError(c, http.StatusBadRequest, "batches require a group token", "invalid_token")
+		return
+	}
+
+	var req relaymodel.CreateBatchRequest
+	if err := common.UnmarshalRequestReusable(c.Request, &req); err != nil {
+		relayError(c, http.StatusBadRequest, "invalid request body: "+err.Error(), "invalid_request")
+		return
+	}
+
+	if _, ok := batchEndpointModes[req.Endpoint]; !ok {
+		relayError(c, http.StatusBadRequest, fmt.Sprintf("unsupported endpoint: %q", req.Endpoint), "invalid_endpoint")
+		return
+	}
+
+	if req.CompletionWindow != relaymodel.BatchCompletionWindow24h {
+		relayError(c,
+			http.StatusBadRequest,
+			fmt.Sprintf("unsupported completion window: %q", req.CompletionWindow),
+			"invalid_completion_window",
+		)
+
+		return
+	}
+
+	if len(req.Metadata) > maxBatchMetadata {
+		relayError(c,
+			http.StatusBadRequest,
+			fmt.Sprintf("too many metadata keys: %d, max: %d", len(req.Metadata), maxBatchMetadata),
+			"invalid_metadata",
+		)
+
+		return
+	}
+
+	file, err := model.GetFile(group.ID, token.ID, req.InputFileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			relayError(c, http.StatusNotFound, fmt.Sprintf("no such file: %s", req.InputFileID), "file_not_found")
+			return
+		}
+
+		common.GetLogger(c).Errorf("get file failed: %v", err)
+		relayError(c, http.StatusInternalServerError, "get file failed", "get_file_failed")
+
+		return
+	}
+
+	if file.Purpose != relaymodel.FilePurposeBatch || file.StorageKey == "" {
+		relayError(c,
+			http.StatusBadRequest,
+			fmt.Sprintf("file %s must be uploaded to the gateway with purpose %q", file.ID, relaymodel.FilePurposeBatch),
+			"invalid_input_file",
+		)
+
+		return
+	}
+
+	batch := &model.BatchJob{
+		ID:               "batch_" + common.ShortUUID(),
+		GroupID:          group.ID,
+		TokenID:          token.ID,
+		Endpoint:         req.Endpoint,
+		InputFileID:      file.ID,
+		CompletionWindow: req.CompletionWindow,
+		Status:           model.BatchJobStatusValidating,
+		Metadata:         req.Metadata,
+		ExpiresAt:        time.Now().Add(batchCompletionWindow),
+	}
+
+	if err := model.CreateBatchJob(batch); err != nil {
+		common.GetLogger(c).Errorf("create batch failed: %v", err)
+		relayError(c, http.StatusInternalServerError, "create batch failed", "create_batch_failed")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, toBatchObject(batch))
+}
+
+func listBatches(c *gin.Context) {
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = defaultListBatchesLimit
+	} else if limit > maxListBatchesLimit {
+		limit = maxListBatchesLimit
+	}
+
+	batches, hasMore, err := model.GetBatchJobs(group.ID, token.ID, c.Query("after"), limit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			relayError(c, http.StatusNotFound, "cursor batch not found", "batch_not_found")
+			return
+		}
+
+		common.GetLogger(c).Errorf("list batches failed: %v", err)
+		relayError(c, http.StatusInternalServerError, "list batches failed", "list_batches_failed")
+
+		return
+	}
+
+	list := relaymodel.BatchList{
+		Object:  "list",
+		Data:    make([]relaymodel.Batch, 0, len(batches)),
+		HasMore: hasMore,
+	}
+	for _, b := range batches {
+		list.Data = append(list.Data, toBatchObject(b))
+	}
+
+	if len(list.Data) > 0 {
+		list.FirstID = list.Data[0].ID
+		list.LastID = list.Data[len(list.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func retrieveBatch(c *gin.Context) {
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+	id := c.Param("id")
+
+	batch, err := model.GetBatchJob(group.ID, token.ID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			relayError(c, http.StatusNotFound, fmt.Sprintf("no such batch: %s", id), "batch_not_found")
+			return
+		}
+
+		common.GetLogger(c).Errorf("get batch failed: %v", err)
+		relayError(c, http.StatusInternalServerError, "get batch failed", "get_batch_failed")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, toBatchObject(batch))
+}
+
+func cancelBatch(c *gin.Context) {
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+	id := c.Param("id")
+
+	batch, err := model.CancelBatchJob(group.ID, token.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			relayError(c, http.StatusNotFound, fmt.Sprintf("no such batch: %s", id), "batch_not_found")
+		case errors.Is(err, model.ErrBatchJobNotCancellable):
+			relayError(c,
+				http.StatusConflict,
+				fmt.Sprintf("batch %s with status %s can not be cancelled", id, batch.Status),
+				"batch_not_cancellable",
+			)
+		default:
+			common.GetLogger(c).Errorf("cancel batch failed: %v", err)
+			relayError(c, http.StatusInternalServerError, "cancel batch failed", "cancel_batch_failed")
+		}
+
+		return
+	}
+
+	c.JSON(http.StatusOK, toBatchObject(batch))
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand/v2"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -83,7 +82,14 @@ func needPinChannel(m mode.Mode) bool {
 		mode.ResponsesGet,
 		mode.ResponsesDelete,
 		mode.ResponsesCancel,
-		mode.ResponsesInputItems:
+		mode.ResponsesInputItems,
+		mode.FilesGet,
+		mode.FilesDelete,
+		mode.FilesContent,
+		mode.FineTuningJobsGet,
+		mode.FineTuningJobsCancel,
+		mode.FineTuningJobsEvents,
+		mode.FineTuningJobsCheckpoints:
 		return true
 	default:
 		return false
@@ -144,6 +150,7 @@ func getRandomChannel(
 	availableSet []string,
 	modelName string,
 	mode mode.Mode,
+	routing *channelRouting,
 	errorRates map[int64]float64,
 	maxErrorRate float64,
 	ignoreChannelMap ...map[int64]struct{},
@@ -190,6 +197,7 @@ func getRandomChannel(
 	channel, err := ignoreChannel(
 		migratedChannels,
 		mode,
+		routing,
 		errorRates,
 		maxErrorRate,
 		ignoreChannelMap...,
@@ -213,6 +221,7 @@ func getPriority(channel *model.Channel, errorRate float64) int32 {
 func ignoreChannel(
 	channels []*model.Channel,
 	mode mode.Mode,
+	routing *channelRouting,
 	errorRates map[int64]float64,
 	maxErrorRate float64,
 	ignoreChannelIDs ...map[int64]struct{},
@@ -226,32 +235,7 @@ func ignoreChannel(
 		return nil, ErrChannelsExhausted
 	}
 
-	if len(channels) == 1 {
-		return channels[0], nil
-	}
-
-	var totalWeight int32
-
-	cachedPrioritys := make([]int32, len(channels))
-	for i, ch := range channels {
-		priority := getPriority(ch, errorRates[int64(ch.ID)])
-		totalWeight += priority
-		cachedPrioritys[i] = priority
-	}
-
-	if totalWeight == 0 {
-		return channels[rand.IntN(len(channels))], nil
-	}
-
-	r := rand.Int32N(totalWeight)
-	for i, ch := range channels {
-		r -= cachedPrioritys[i]
-		if r < 0 {
-			return ch, nil
-		}
-	}
-
-	return channels[rand.IntN(len(channels))], nil
+	return routing.pick(channels, errorRates), nil
 }
 
 func getChannelWithFallback(
@@ -259,6 +243,7 @@ func getChannelWithFallback(
 	availableSet []string,
 	modelName string,
 	mode mode.Mode,
+	routing *channelRouting,
 	errorRates map[int64]float64,
 	ignoreChannelIDs map[int64]struct{},
 ) (*model.Channel, []*model.Channel, error) {
@@ -267,6 +252,7 @@ func getChannelWithFallback(
 		availableSet,
 		modelName,
 		mode,
+		routing,
 		errorRates,
 		maxRetryErrorRate,
 		ignoreChannelIDs,
@@ -284,6 +270,7 @@ func getChannelWithFallback(
 		availableSet,
 		modelName,
 		mode,
+		routing,
 		errorRates,
 		0,
 	)
@@ -295,6 +282,7 @@ type initialChannel struct {
 	ignoreChannelIDs  map[int64]struct{}
 	errorRates        map[int64]float64
 	migratedChannels  []*model.Channel
+	routing           *channelRouting
 }
 
 func getInitialChannel(c *gin.Context, modelName string, m mode.Mode) (*initialChannel, error) {
@@ -353,11 +341,14 @@ func getInitialChannel(c *gin.Context, modelName string, m mode.Mode) (*initialC
 		log.Errorf("get channel model error rates failed: %+v", err)
 	}
 
+	routing := newChannelRouting(modelName, middleware.GetModelConfig(c))
+
 	channel, migratedChannels, err := getChannelWithFallback(
 		mc,
 		availableSet,
 		modelName,
 		m,
+		routing,
 		errorRates,
 		ignoreChannelIDs,
 	)
@@ -365,11 +356,14 @@ func getInitialChannel(c *gin.Context, modelName string, m mode.Mode) (*initialC
 		return nil, err
 	}
 
+	log.Data["routing"] = routing.reason
+
 	return &initialChannel{
 		channel:          channel,
 		ignoreChannelIDs: ignoreChannelIDs,
 		errorRates:       errorRates,
 		migratedChannels: migratedChannels,
+		routing:          routing,
 	}, nil
 }
 
@@ -386,6 +380,7 @@ func getWebSearchChannel(
 		nil,
 		modelName,
 		mode.ChatCompletions,
+		nil,
 		errorRates,
 		ignoreChannelIDs)
 	if err != nil {
@@ -417,6 +412,7 @@ func getRetryChannel(state *retryState, currentRetry, totalRetries int) (*model.
 		newChannel, err := ignoreChannel(
 			state.migratedChannels,
 			state.meta.Mode,
+			state.routing,
 			state.errorRates,
 			maxRetryErrorRate,
 			state.ignoreChannelIDs,
@@ -431,6 +427,7 @@ func getRetryChannel(state *retryState, currentRetry, totalRetries int) (*model.
 	newChannel, err := ignoreChannel(
 		state.migratedChannels,
 		state.meta.Mode,
+		state.routing,
 		state.errorRates,
 		maxRetryErrorRate,
 		state.ignoreChannelIDs,
@@ -2,10 +2,10 @@ package controller
 
 import (
 	"bytes"
-	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"math/rand/v2"
 	"net/http"
 	"strconv"
@@ -18,8 +18,10 @@ import (
 	"github.com/wavespeed/llm-server/core/common/config"
 	"github.com/wavespeed/llm-server/core/common/consume"
 	"github.com/wavespeed/llm-server/core/common/conv"
+	"github.com/wavespeed/llm-server/core/common/tracing"
 	"github.com/wavespeed/llm-server/core/middleware"
 	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/monitor"
 	"github.com/wavespeed/llm-server/core/relay/adaptor"
 	"github.com/wavespeed/llm-server/core/relay/adaptors"
 	"github.com/wavespeed/llm-server/core/relay/controller"
@@ -28,12 +30,14 @@ import (
 	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
 	"github.com/wavespeed/llm-server/core/relay/plugin"
 	"github.com/wavespeed/llm-server/core/relay/plugin/cache"
+	"github.com/wavespeed/llm-server/core/relay/plugin/guardrail"
 	monitorplugin "github.com/wavespeed/llm-server/core/relay/plugin/monitor"
 	"github.com/wavespeed/llm-server/core/relay/plugin/patch"
 	"github.com/wavespeed/llm-server/core/relay/plugin/streamfake"
 	"github.com/wavespeed/llm-server/core/relay/plugin/thinksplit"
 	"github.com/wavespeed/llm-server/core/relay/plugin/timeout"
 	websearch "github.com/wavespeed/llm-server/core/relay/plugin/web-search"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // https://platform.openai.com/docs/api-reference/chat
@@ -48,6 +52,9 @@ type RelayController struct {
 	GetRequestUsage GetRequestUsage
 	GetRequestPrice GetRequestPrice
 	Handler         RelayHandler
+	// LocalHandler answers the request with the request usage when no
+	// channel of the model supports the mode
+	LocalHandler func(*gin.Context, model.Usage)
 }
 
 var adaptorStore adaptor.Store = &storeImpl{}
@@ -83,10 +90,37 @@ func (s *storeImpl) SaveStore(store adaptor.StoreCache) error {
 	return err
 }
 
-func wrapPlugin(ctx context.Context, mc *model.ModelCaches, a adaptor.Adaptor) adaptor.Adaptor {
+func (s *storeImpl) DeleteStore(group string, tokenID int, id string) error {
+	return model.DeleteStore(group, tokenID, id)
+}
+
+func (s *storeImpl) GetGroupStore(group, id string) (adaptor.StoreCache, error) {
+	store, err := model.GetGroupStore(group, id)
+	if err != nil {
+		return adaptor.StoreCache{}, err
+	}
+
+	return adaptor.StoreCache{
+		ID:        store.ID,
+		GroupID:   store.GroupID,
+		TokenID:   store.TokenID,
+		ChannelID: store.ChannelID,
+		Model:     store.Model,
+		ExpiresAt: store.ExpiresAt,
+	}, nil
+}
+
+func (s *storeImpl) MarkStoreBilled(group, id string) (bool, error) {
+	return model.MarkStoreBilled(group, id)
+}
+
+func wrapPlugin(c *gin.Context, mc *model.ModelCaches, a adaptor.Adaptor) adaptor.Adaptor {
+	ctx := c.Request.Context()
+
 	return plugin.WrapperAdaptor(a,
 		monitorplugin.NewGroupMonitorPlugin(),
-		cache.NewCachePlugin(common.RDB),
+		guardrail.NewGuardrailPlugin(guardrailCompleter(c, mc)),
+		cache.NewCachePlugin(common.RDB, cacheEmbedder(c, mc)),
 		streamfake.NewStreamFakePlugin(),
 		timeout.NewTimeoutPlugin(),
 		websearch.NewWebSearchPlugin(func(modelName string) (*model.Channel, error) {
@@ -113,7 +147,7 @@ func relayHandler(c *gin.Context, meta *meta.Meta, mc *model.ModelCaches) *contr
 		}
 	}
 
-	adaptor = wrapPlugin(c.Request.Context(), mc, adaptor)
+	adaptor = wrapPlugin(c, mc, adaptor)
 
 	return controller.Handle(adaptor, c, meta, adaptorStore)
 }
@@ -159,6 +193,23 @@ func relayController(m mode.Mode) RelayController {
 		c.GetRequestUsage = controller.GetVideoGenerationJobRequestUsage
 	case mode.Responses:
 		c.GetRequestUsage = controller.GetResponsesRequestUsage
+	case mode.FineTuningJobs,
+		mode.FineTuningJobsList,
+		mode.FineTuningJobsGet,
+		mode.FineTuningJobsCancel,
+		mode.FineTuningJobsEvents,
+		mode.FineTuningJobsCheckpoints:
+		c.GetRequestPrice = controller.GetFineTuningRequestPrice
+	case mode.AnthropicCountTokens:
+		c.GetRequestPrice = nil
+		c.GetRequestUsage = controller.GetAnthropicRequestUsage
+		c.LocalHandler = controller.AnthropicCountTokensLocal
+	case mode.GeminiCountTokens:
+		c.GetRequestPrice = nil
+		c.GetRequestUsage = controller.GetGeminiCountTokensRequestUsage
+		c.LocalHandler = controller.GeminiCountTokensLocal
+	case mode.Realtime:
+		c.Handler = realtimeHandler
 	}
 
 	return c
@@ -169,12 +220,67 @@ func RelayHelper(
 	meta *meta.Meta,
 	handel RelayHandler,
 ) (*controller.HandleResult, bool) {
+	// every attempt has its own span, the plugins and the upstream call
+	// are traced under it
+	ctx, span := tracing.Start(c.Request.Context(), "relay attempt",
+		trace.WithAttributes(
+			tracing.RequestIDKey.String(meta.RequestID),
+			tracing.GroupKey.String(meta.Group.ID),
+			tracing.TokenNameKey.String(meta.Token.Name),
+			tracing.ChannelIDKey.Int(meta.Channel.ID),
+			tracing.ModelKey.String(meta.OriginModel),
+			tracing.ModeKey.String(meta.Mode.String()),
+			tracing.RetryKey.Bool(!meta.RetryAt.IsZero()),
+		),
+	)
+	defer span.End()
+
+	meta.SetTraceContext(ctx)
+
+	start := time.Now()
+	done := monitor.StartInFlight(int64(meta.Channel.ID))
 	result := handel(c, meta)
+
+	done()
+
 	if result.Error == nil {
+		recordChannelTTFB(meta, result.Detail)
 		return result, false
 	}
 
-	return result, monitorplugin.ShouldRetry(result.Error)
+	tracing.RecordError(span, result.Error)
+
+	retry := monitorplugin.ShouldRetry(result.Error)
+	if retry {
+		recordChannelTTFBPenalty(meta, time.Since(start))
+	}
+
+	return result, retry
+}
+
+// recordChannelTTFB samples the ttfb of the channel for the latency routing
+func recordChannelTTFB(meta *meta.Meta, detail *controller.RequestDetail) {
+	if detail == nil || detail.FirstByteAt.IsZero() {
+		return
+	}
+
+	start := detail.UpstreamRequestAt
+	if start.IsZero() {
+		start = meta.RequestAt
+	}
+
+	monitor.AddTTFB(meta.OriginModel, int64(meta.Channel.ID), detail.FirstByteAt.Sub(start))
+}
+
+// recordChannelTTFBPenalty samples a failed attempt of the channel at the
+// request timeout, so the latency routing stops probing the failing channels
+func recordChannelTTFBPenalty(meta *meta.Meta, elapsed time.Duration) {
+	penalty := meta.RequestTimeout
+	if penalty <= 0 {
+		penalty = ttfbFailurePenalty
+	}
+
+	monitor.AddTTFB(meta.OriginModel, int64(meta.Channel.ID), max(penalty, elapsed))
 }
 
 func NewRelay(mode mode.Mode) func(c *gin.Context) {
@@ -194,18 +300,48 @@ func NewMetaByContext(
 }
 
 func relay(c *gin.Context, mode mode.Mode, relayController RelayController) {
+	fallbacks := &fallbackModels{
+		c:               c,
+		models:          getFallbackModels(c, mode),
+		exhaustedBudget: model.GetExhaustedBudget,
+	}
+
+	for relayModel(c, mode, relayController, fallbacks.take) {
+		useFallbackModel(c, fallbacks.next)
+	}
+}
+
+// relayModel relays the request to the channels of the request model, when
+// all the channels failed and canFallback picks a fallback model, the error
+// is not written and true is returned so that the fallback model is tried
+func relayModel(
+	c *gin.Context,
+	mode mode.Mode,
+	relayController RelayController,
+	canFallback func() bool,
+) (fallback bool) {
 	requestModel := middleware.GetRequestModel(c)
 	mc := middleware.GetModelConfig(c)
 
 	// Get initial channel
 	initialChannel, err := getInitialChannel(c, requestModel, mode)
 	if err != nil || initialChannel == nil || initialChannel.channel == nil {
+		if relayController.LocalHandler != nil {
+			relayLocal(c, mode, relayController, mc)
+			return false
+		}
+
+		if canFallback() {
+			common.GetLogger(c).Warnf("no channel available for model %s, fallback", requestModel)
+			return true
+		}
+
 		middleware.AbortLogWithMessageWithMode(mode, c,
 			http.StatusServiceUnavailable,
 			"the upstream load is saturated, please try again later",
 		)
 
-		return
+		return false
 	}
 
 	price := model.Price{}
@@ -217,11 +353,12 @@ func relay(c *gin.Context, mode mode.Mode, relayController RelayController) {
 				"get request price failed: "+err.Error(),
 			)
 
-			return
+			return false
 		}
 	}
 
 	meta := NewMetaByContext(c, initialChannel.channel, mode)
+	initialChannel.routing.setMeta(meta)
 
 	if relayController.GetRequestUsage != nil {
 		requestUsage, err := relayController.GetRequestUsage(c, mc)
@@ -231,22 +368,22 @@ func relay(c *gin.Context, mode mode.Mode, relayController RelayController) {
 				"get request usage failed: "+err.Error(),
 			)
 
-			return
+			return false
 		}
 
 		meta.RequestUsage = requestUsage
 	}
 
-	gbc := middleware.GetGroupBalanceConsumerFromContext(c)
-	if !gbc.CheckBalance(consume.CalculateAmount(http.StatusOK, meta.RequestUsage, price)) {
+	if !placeHold(c, meta, mc, price) {
 		middleware.AbortLogWithMessageWithMode(mode, c,
 			http.StatusForbidden,
-			fmt.Sprintf("group (%s) balance not enough", gbc.Group),
+			fmt.Sprintf("group (%s) balance not enough", meta.Group.ID),
 			relaymodel.WithType(middleware.GroupBalanceNotEnough),
 		)
 
-		return
+		return false
 	}
+	defer releaseHold(c)
 
 	// First attempt
 	result, retry := RelayHelper(c, meta, relayController.Handler)
@@ -256,6 +393,21 @@ func relay(c *gin.Context, mode mode.Mode, relayController RelayController) {
 		retryTimes = int(mc.RetryTimes)
 	}
 
+	if retryTimes == 0 && retry && c.Request.Context().Err() == nil && canFallback() {
+		recordResult(
+			c,
+			meta,
+			price,
+			result,
+			0,
+			false,
+			middleware.GetRequestUser(c),
+			middleware.GetRequestMetadata(c),
+		)
+
+		return true
+	}
+
 	if handleRelayResult(c, result.Error, retry, retryTimes) {
 		recordResult(
 			c,
@@ -268,7 +420,7 @@ func relay(c *gin.Context, mode mode.Mode, relayController RelayController) {
 			middleware.GetRequestMetadata(c),
 		)
 
-		return
+		return false
 	}
 
 	// Setup retry state
@@ -279,9 +431,35 @@ func relay(c *gin.Context, mode mode.Mode, relayController RelayController) {
 		result,
 		price,
 	)
+	retryState.canFallback = canFallback
 
 	// Retry loop
-	retryLoop(c, mode, retryState, relayController.Handler)
+	return retryLoop(c, mode, retryState, relayController.Handler)
+}
+
+// relayLocal answers the request without a channel, it is not billed
+func relayLocal(
+	c *gin.Context,
+	mode mode.Mode,
+	relayController RelayController,
+	mc model.ModelConfig,
+) {
+	var usage model.Usage
+	if relayController.GetRequestUsage != nil {
+		requestUsage, err := relayController.GetRequestUsage(c, mc)
+		if err != nil {
+			middleware.AbortLogWithMessageWithMode(mode, c,
+				http.StatusBadRequest,
+				"get request usage failed: "+err.Error(),
+			)
+
+			return
+		}
+
+		usage = requestUsage
+	}
+
+	relayController.LocalHandler(c, usage)
 }
 
 // recordResult records the consumption for the final result
@@ -320,16 +498,27 @@ func recordResult(
 
 	gbc := middleware.GetGroupBalanceConsumerFromContext(c)
 
-	amount := consume.CalculateAmount(
-		code,
-		result.Usage,
+	attachHold(c, meta)
+
+	amount := consume.ConvertAmount(
+		consume.CalculateAmount(code, result.Usage, price),
 		price,
+		meta,
 	)
 	if amount > 0 {
 		log := common.GetLogger(c)
 		log.Data["amount"] = strconv.FormatFloat(amount, 'f', -1, 64)
 	}
 
+	if reason := getRoutingReason(meta); reason != "" {
+		metadata = maps.Clone(metadata)
+		if metadata == nil {
+			metadata = make(map[string]string, 1)
+		}
+
+		metadata["routing"] = reason
+	}
+
 	consume.AsyncConsume(
 		gbc.Consumer,
 		code,
@@ -362,6 +551,27 @@ type retryState struct {
 	requestUsage     model.Usage
 	result           *controller.HandleResult
 	migratedChannels []*model.Channel
+	routing          *channelRouting
+	canFallback      func() bool
+
+	// the fallback is decided once, canFallback picks the fallback model
+	fallbackDecided bool
+	fallbackPicked  bool
+}
+
+// fallback reports whether the request should go on with the next fallback
+// model, which is when the last result still failed with a retryable error
+// and a fallback model is picked
+func (s *retryState) fallback(c *gin.Context) bool {
+	if !s.fallbackDecided {
+		s.fallbackDecided = true
+		s.fallbackPicked = s.result.Error != nil &&
+			c.Request.Context().Err() == nil &&
+			monitorplugin.ShouldRetry(s.result.Error) &&
+			s.canFallback()
+	}
+
+	return s.fallbackPicked
 }
 
 func handleRelayResult(
@@ -400,6 +610,7 @@ func initRetryState(
 		price:            price,
 		requestUsage:     meta.RequestUsage,
 		migratedChannels: channel.migratedChannels,
+		routing:          channel.routing,
 		failedChannelIDs: make(map[int64]struct{}),
 	}
 
@@ -423,7 +634,12 @@ func initRetryState(
 	return state
 }
 
-func retryLoop(c *gin.Context, mode mode.Mode, state *retryState, relayController RelayHandler) {
+func retryLoop(
+	c *gin.Context,
+	mode mode.Mode,
+	state *retryState,
+	relayController RelayHandler,
+) (fallback bool) {
 	log := common.GetLogger(c)
 
 	// do not use for i := range state.retryTimes, because the retryTimes is constant
@@ -450,7 +666,7 @@ func retryLoop(c *gin.Context, mode mode.Mode, state *retryState, relayControlle
 					state.price,
 					state.result,
 					i,
-					true,
+					!state.fallback(c),
 					middleware.GetRequestUser(c),
 					middleware.GetRequestMetadata(c),
 				)
@@ -495,6 +711,10 @@ func retryLoop(c *gin.Context, mode mode.Mode, state *retryState, relayControlle
 			meta.WithRequestUsage(state.requestUsage),
 			meta.WithRetryAt(time.Now()),
 		)
+		if state.routing != nil {
+			log.Data["routing"] = state.routing.reason
+			state.routing.setMeta(state.meta)
+		}
 
 		var retry bool
 
@@ -514,7 +734,7 @@ func retryLoop(c *gin.Context, mode mode.Mode, state *retryState, relayControlle
 				state.price,
 				state.result,
 				i+1,
-				true,
+				!state.fallback(c),
 				middleware.GetRequestUser(c),
 				middleware.GetRequestMetadata(c),
 			)
@@ -525,9 +745,15 @@ func retryLoop(c *gin.Context, mode mode.Mode, state *retryState, relayControlle
 		i++
 	}
 
+	if state.fallback(c) {
+		return true
+	}
+
 	if state.result.Error != nil {
 		ErrorWithRequestID(c, state.result.Error)
 	}
+
+	return false
 }
 
 func prepareRetry(c *gin.Context) error {
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+)
+
+const (
+	// AIProxyModelHeader is the model which actually answered the request
+	AIProxyModelHeader = "X-Aiproxy-Model"
+	// fallbackFromMetadataKey records the requested model in the log metadata
+	fallbackFromMetadataKey = "fallback_from"
+)
+
+type fallbackModel struct {
+	name   string
+	config model.ModelConfig
+}
+
+// getFallbackModels returns the fallback models of the request model which
+// can serve the request, in the order they are configured
+func getFallbackModels(c *gin.Context, m mode.Mode) []fallbackModel {
+	mc := middleware.GetModelConfig(c)
+	if len(mc.FallbackModels) == 0 {
+		return nil
+	}
+
+	// the channel is designated by the client, there is nothing to fall back to
+	if c.Request.Header.Get(AIProxyChannelHeader) != "" ||
+		middleware.GetChannelID(c) != 0 ||
+		needPinChannel(m) {
+		return nil
+	}
+
+	log := common.GetLogger(c)
+	requestModel := middleware.GetRequestModel(c)
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+	modelCaches := middleware.GetModelCaches(c)
+
+	seen := map[string]struct{}{strings.ToLower(requestModel): {}}
+	fallbacks := make([]fallbackModel, 0, len(mc.FallbackModels))
+
+	for _, name := range mc.FallbackModels {
+		if _, ok := seen[strings.ToLower(name)]; ok {
+			continue
+		}
+
+		seen[strings.ToLower(name)] = struct{}{}
+
+		findModel := token.FindModel(name)
+		if findModel == "" {
+			log.Debugf("fallback model %s of %s is not available to the token", name, requestModel)
+			continue
+		}
+
+		config, ok := modelCaches.ModelConfig.GetModelConfig(findModel)
+		if !ok {
+			log.Warnf("fallback model %s of %s has no model config", findModel, requestModel)
+			continue
+		}
+
+		if !middleware.CheckRelayMode(m, config.Type) {
+			continue
+		}
+
+		fallbacks = append(fallbacks, fallbackModel{
+			name:   findModel,
+			config: middleware.GetGroupAdjustedModelConfig(group, config),
+		})
+	}
+
+	return fallbacks
+}
+
+// fallbackModels are the fallback models of a request which are not tried
+// yet, next is the model picked by take
+type fallbackModels struct {
+	c      *gin.Context
+	models []fallbackModel
+	next   fallbackModel
+
+	exhaustedBudget func(group, tokenName, user string) (*model.Budget, error)
+}
+
+// take picks the next fallback model which passes the checks of the
+// distributor, its price must be charged in the currency of the group and
+// the budgets must not be used up, it is called once the request model
+// failed, so the request is counted to the picked model within its own rpm
+// and tpm limits like a direct request to it
+func (f *fallbackModels) take() bool {
+	if len(f.models) == 0 {
+		return false
+	}
+
+	log := common.GetLogger(f.c)
+	group := middleware.GetGroup(f.c)
+	token := middleware.GetToken(f.c)
+
+	budget, err := f.exhaustedBudget(group.ID, token.Name, middleware.GetRequestUser(f.c))
+	if err != nil {
+		log.Errorf("get budgets of the fallback models failed: %v", err)
+		f.models = nil
+
+		return false
+	}
+
+	if budget != nil {
+		log.Warnf("fallback models are skipped, %s %s budget exhausted", budget.Scope, budget.Window)
+		f.models = nil
+
+		return false
+	}
+
+	for len(f.models) > 0 {
+		fallback := f.models[0]
+		f.models = f.models[1:]
+
+		if err := middleware.CheckModelCurrency(group, fallback.config); err != nil {
+			log.Warnf("fallback model %s is skipped: %v", fallback.name, err)
+			continue
+		}
+
+		if err := middleware.CheckGroupModelRPMAndTPM(f.c, group, fallback.config, token.Name); err != nil {
+			log.Warnf("fallback model %s is skipped: %v", fallback.name, err)
+			continue
+		}
+
+		f.next = fallback
+
+		return true
+	}
+
+	return false
+}
+
+// useFallbackModel switches the request to the fallback model, the request
+// body is converted by the adaptors of its channels and it is billed with
+// the price of the fallback model
+func useFallbackModel(c *gin.Context, fallback fallbackModel) {
+	log := common.GetLogger(c)
+
+	requestModel := middleware.GetRequestModel(c)
+	log.Warnf("model %s is unavailable, fallback to %s", requestModel, fallback.name)
+
+	if err := prepareRetry(c); err != nil {
+		log.Errorf("prepare fallback failed: %+v", err)
+	}
+
+	metadata := middleware.GetRequestMetadata(c)
+	if _, ok := metadata[fallbackFromMetadataKey]; !ok {
+		metadata = maps.Clone(metadata)
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+
+		metadata[fallbackFromMetadataKey] = requestModel
+		c.Set(middleware.RequestMetadata, metadata)
+	}
+
+	c.Set(middleware.RequestModel, fallback.name)
+	c.Set(middleware.ModelConfig, fallback.config)
+	middleware.SetLogModelFields(log.Data, fallback.name)
+
+	c.Header(AIProxyModelHeader, fallback.name)
+}
@@ -0,0 +1,205 @@
+package controller_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/reqlimit"
+	"github.com/wavespeed/llm-server/core/controller"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+)
+
+type fallbackModelConfigs map[string]model.ModelConfig
+
+func (m fallbackModelConfigs) GetModelConfig(name string) (model.ModelConfig, bool) {
+	mc, ok := m[name]
+	return mc, ok
+}
+
+const fallbackGroup = "fallback-group"
+
+func newFallbackContext(requestModel string) *gin.Context {
+	configs := fallbackModelConfigs{
+		"gpt-4o": {
+			Model:          "gpt-4o",
+			Type:           mode.ChatCompletions,
+			FallbackModels: []string{"GPT-4O", "gpt-4o-mini", "hidden", "text-embedding-3-small", "gpt-4o-mini", "claude"},
+		},
+		"gpt-4o-mini": {
+			Model: "gpt-4o-mini",
+			Type:  mode.ChatCompletions,
+			Price: model.Price{InputPrice: 1, OutputPrice: 2},
+		},
+		"hidden": {
+			Model: "hidden",
+			Type:  mode.ChatCompletions,
+		},
+		"text-embedding-3-small": {
+			Model: "text-embedding-3-small",
+			Type:  mode.Embeddings,
+		},
+		"claude": {
+			Model: "claude",
+			Type:  mode.ChatCompletions,
+			Price: model.Price{InputPrice: 3, OutputPrice: 15},
+		},
+	}
+
+	group := model.GroupCache{
+		ID: fallbackGroup,
+		ModelConfigs: map[string]model.GroupModelConfig{
+			"claude": {
+				OverridePrice: true,
+				Price:         model.Price{InputPrice: 6, OutputPrice: 30},
+			},
+		},
+	}
+
+	token := model.TokenCache{Name: "fallback-token"}
+	token.SetAvailableSets([]string{model.ChannelDefaultSet})
+	token.SetModelsBySet(map[string][]string{
+		model.ChannelDefaultSet: {"gpt-4o", "gpt-4o-mini", "text-embedding-3-small", "claude"},
+	})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+
+	c.Set(middleware.Group, group)
+	c.Set(middleware.Token, token)
+	c.Set(middleware.ModelCaches, &model.ModelCaches{ModelConfig: configs})
+	c.Set(middleware.RequestModel, requestModel)
+	c.Set(middleware.ModelConfig, configs[requestModel])
+
+	return c
+}
+
+func fallbackNames(fallbacks []controller.FallbackModel) string {
+	names := make([]string, 0, len(fallbacks))
+	for _, fallback := range fallbacks {
+		names = append(names, fallback.Name)
+	}
+
+	return strings.Join(names, ",")
+}
+
+func TestGetFallbackModels(t *testing.T) {
+	c := newFallbackContext("gpt-4o")
+
+	// the request model and the duplicates are skipped, hidden is not
+	// available to the token and the embedding model can not serve the
+	// chat completions
+	fallbacks := controller.GetFallbackModels(c, mode.ChatCompletions)
+	if got := fallbackNames(fallbacks); got != "gpt-4o-mini,claude" {
+		t.Fatalf("Expected gpt-4o-mini,claude, Got %s", got)
+	}
+
+	// the group price of the fallback model is used
+	if price := fallbacks[1].Config.Price; price.InputPrice != 6 || price.OutputPrice != 30 {
+		t.Errorf("Expected the group price of claude, Got %+v", price)
+	}
+}
+
+func TestGetFallbackModelsChannelHeader(t *testing.T) {
+	c := newFallbackContext("gpt-4o")
+	c.Request.Header.Set(controller.AIProxyChannelHeader, "1")
+
+	if fallbacks := controller.GetFallbackModels(c, mode.ChatCompletions); len(fallbacks) != 0 {
+		t.Errorf("Expected no fallback models, Got %s", fallbackNames(fallbacks))
+	}
+
+	c = newFallbackContext("gpt-4o")
+	c.Set(middleware.ChannelID, 1)
+
+	if fallbacks := controller.GetFallbackModels(c, mode.ChatCompletions); len(fallbacks) != 0 {
+		t.Errorf("Expected no fallback models, Got %s", fallbackNames(fallbacks))
+	}
+}
+
+func TestUseFallbackModel(t *testing.T) {
+	c := newFallbackContext("gpt-4o")
+
+	fallbacks := controller.GetFallbackModels(c, mode.ChatCompletions)
+	controller.UseFallbackModel(c, fallbacks[1])
+
+	if got := middleware.GetRequestModel(c); got != "claude" {
+		t.Errorf("Expected request model claude, Got %s", got)
+	}
+
+	if got := c.Writer.Header().Get(controller.AIProxyModelHeader); got != "claude" {
+		t.Errorf("Expected model header claude, Got %s", got)
+	}
+
+	if got := middleware.GetRequestMetadata(c)["fallback_from"]; got != "gpt-4o" {
+		t.Errorf("Expected fallback_from gpt-4o, Got %s", got)
+	}
+
+	// the request is billed at the price of the fallback model
+	m := controller.NewMetaByContext(c, &model.Channel{ID: 1}, mode.ChatCompletions)
+	if m.OriginModel != "claude" {
+		t.Errorf("Expected meta model claude, Got %s", m.OriginModel)
+	}
+
+	if m.ModelConfig.Price.InputPrice != 6 || m.ModelConfig.Price.OutputPrice != 30 {
+		t.Errorf("Expected the price of claude, Got %+v", m.ModelConfig.Price)
+	}
+
+	// the requested model is kept when falling back again
+	controller.UseFallbackModel(c, fallbacks[0])
+
+	if got := middleware.GetRequestMetadata(c)["fallback_from"]; got != "gpt-4o" {
+		t.Errorf("Expected fallback_from gpt-4o, Got %s", got)
+	}
+}
+
+func TestTakeFallbackModelRPM(t *testing.T) {
+	c := newFallbackContext("gpt-4o")
+
+	limited := model.ModelConfig{Model: "fallback-rpm-limited", RPM: 1}
+	reqlimit.PushGroupModelRequest(context.Background(), fallbackGroup, limited.Model, limited.RPM)
+
+	fallback, ok := controller.TakeFallbackModel(c, []controller.FallbackModel{
+		{Name: limited.Model, Config: limited},
+		{Name: "fallback-rpm-free", Config: model.ModelConfig{Model: "fallback-rpm-free", RPM: 1}},
+	}, nil)
+	if !ok || fallback.Name != "fallback-rpm-free" {
+		t.Fatalf("Expected fallback-rpm-free, Got %s", fallback.Name)
+	}
+
+	if _, ok := controller.TakeFallbackModel(c, []controller.FallbackModel{
+		{Name: limited.Model, Config: limited},
+	}, nil); ok {
+		t.Error("Expected no fallback model within the rpm limit")
+	}
+}
+
+func TestTakeFallbackModelCurrency(t *testing.T) {
+	c := newFallbackContext("gpt-4o")
+
+	fallback, ok := controller.TakeFallbackModel(c, []controller.FallbackModel{
+		{
+			Name:   "fallback-no-rate",
+			Config: model.ModelConfig{Model: "fallback-no-rate", Price: model.Price{Currency: "XTS"}},
+		},
+		{Name: "fallback-rated", Config: model.ModelConfig{Model: "fallback-rated"}},
+	}, nil)
+	if !ok || fallback.Name != "fallback-rated" {
+		t.Fatalf("Expected fallback-rated, Got %s", fallback.Name)
+	}
+}
+
+func TestTakeFallbackModelBudgetExhausted(t *testing.T) {
+	c := newFallbackContext("gpt-4o")
+
+	budget := &model.Budget{GroupID: fallbackGroup, Scope: model.BudgetScopeGroup, Window: "day"}
+	if _, ok := controller.TakeFallbackModel(c, []controller.FallbackModel{
+		{Name: "fallback-budget", Config: model.ModelConfig{Model: "fallback-budget"}},
+	}, budget); ok {
+		t.Error("Expected no fallback model once the budget is exhausted")
+	}
+}
@@ -0,0 +1,462 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/common/filestorage"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/controller"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+	"gorm.io/gorm"
+)
+
+// https://platform.openai.com/docs/api-reference/files
+
+const (
+	defaultListFilesLimit = 10000
+	maxListFilesLimit     = 10000
+)
+
+// UploadFile godoc
+//
+//	@Summary		Upload file
+//	@Description	Upload a file to the gateway storage, or to an upstream channel when model is set
+//	@Tags			relay
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			file			formData	file	true	"File"
+//	@Param			purpose			formData	string	true	"Purpose"
+//	@Param			model			formData	string	false	"Upload to a channel of this model"
+//	@Param			Aiproxy-Channel	header		string	false	"Optional Aiproxy-Channel header"
+//	@Success		200				{object}	model.File
+//	@Router			/v1/files [post]
+func UploadFile() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewFileDistribute(mode.Files),
+		newFileRelay(mode.Files, uploadFile),
+	}
+}
+
+// ListFiles godoc
+//
+//	@Summary		List files
+//	@Description	List files of the token
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			purpose	query		string	false	"Purpose"
+//	@Param			after	query		string	false	"Cursor file id"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			order	query		string	false	"Order, asc or desc"
+//	@Success		200		{object}	model.FileList
+//	@Router			/v1/files [get]
+func ListFiles() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewFileDistribute(mode.FilesList),
+		listFiles,
+	}
+}
+
+// RetrieveFile godoc
+//
+//	@Summary		Retrieve file
+//	@Description	Retrieve a file by ID
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		string	true	"File ID"
+//	@Success		200	{object}	model.File
+//	@Router			/v1/files/{id} [get]
+func RetrieveFile() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewFileDistribute(mode.FilesGet),
+		newFileRelay(mode.FilesGet, retrieveFile),
+	}
+}
+
+// DeleteFile godoc
+//
+//	@Summary		Delete file
+//	@Description	Delete a file by ID
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		string	true	"File ID"
+//	@Success		200	{object}	model.FileDeleted
+//	@Router			/v1/files/{id} [delete]
+func DeleteFile() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewFileDistribute(mode.FilesDelete),
+		newFileRelay(mode.FilesDelete, deleteFile),
+	}
+}
+
+// RetrieveFileContent godoc
+//
+//	@Summary		Retrieve file content
+//	@Description	Retrieve the content of a file by ID
+//	@Tags			relay
+//	@Produce		octet-stream
+//	@Security		ApiKeyAuth
+//	@Param			id	path	string	true	"File ID"
+//	@Success		200	{file}	file	"file content"
+//	@Router			/v1/files/{id}/content [get]
+func RetrieveFileContent() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewFileDistribute(mode.FilesContent),
+		newFileRelay(mode.FilesContent, retrieveFileContent),
+	}
+}
+
+// newFileRelay relays distributed file requests to the upstream channel and
+// serves the others from the gateway storage
+func newFileRelay(m mode.Mode, local gin.HandlerFunc) gin.HandlerFunc {
+	relayController := fileRelayController(m)
+	return func(c *gin.Context) {
+		if middleware.GetRequestModel(c) == "" {
+			local(c)
+			return
+		}
+
+		relay(c, m, relayController)
+	}
+}
+
+// fileRelayController keeps the file records in sync with the upstream, so
+// upstream files are listed together with the gateway files
+func fileRelayController(m mode.Mode) RelayController {
+	rc := relayController(m)
+	handler := rc.Handler
+
+	rc.Handler = func(c *gin.Context, meta *meta.Meta) *controller.HandleResult {
+		result := handler(c, meta)
+		if result.Error != nil {
+			return result
+		}
+
+		log := common.GetLogger(c)
+
+		switch meta.Mode {
+		case mode.Files:
+			if result.Detail == nil {
+				break
+			}
+
+			var file relaymodel.File
+			if err := sonic.UnmarshalString(result.Detail.ResponseBody, &file); err != nil {
+				log.Errorf("unmarshal upstream file failed: %v", err)
+				break
+			}
+
+			err := model.CreateFile(&model.File{
+				ID:        file.ID,
+				GroupID:   meta.Group.ID,
+				TokenID:   meta.Token.ID,
+				Filename:  file.Filename,
+				Purpose:   file.Purpose,
+				Bytes:     file.Bytes,
+				Status:    file.Status,
+				ChannelID: meta.Channel.ID,
+				Model:     meta.OriginModel,
+			})
+			if err != nil {
+				log.Errorf("create upstream file record failed: %v", err)
+			}
+		case mode.FilesDelete:
+			err := model.DeleteFile(meta.Group.ID, meta.Token.ID, meta.FileID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Errorf("delete upstream file record failed: %v", err)
+			}
+		}
+
+		return result
+	}
+
+	return rc
+}
+
+func relayError(c *gin.Context, statusCode int, message, code string) {
+	ErrorWithRequestID(c, relaymodel.NewOpenAIError(statusCode, relaymodel.OpenAIError{
+		Message: message,
+		Type:    relaymodel.ErrorTypeAIPROXY,
+		Code:    code,
+	}))
+}
+
+func fileStorageKey(group string, tokenID int, id string) string {
+	return path.Join(group, strconv.Itoa(tokenID), id)
+}
+
+func toFileObject(f *model.File) relaymodel.File {
+	return relaymodel.File{
+		ID:        f.ID,
+		Object:    "file",
+		Bytes:     f.Bytes,
+		CreatedAt: f.CreatedAt.Unix(),
+		Filename:  f.Filename,
+		Purpose:   f.Purpose,
+		Status:    f.Status,
+	}
+}
+
+// OpenFile opens the content of a gateway stored file, so other endpoints can
+// read files uploaded through the files api
+func OpenFile(
+	ctx context.Context,
+	group string,
+	tokenID int,
+	id string,
+) (*model.File, io.ReadCloser, error) {
+	file, err := model.GetFile(group, tokenID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if file.StorageKey == "" {
+		return nil, nil, fmt.Errorf("file %s is stored on an upstream channel", id)
+	}
+
+	content, err := filestorage.Default().Get(ctx, file.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, content, nil
+}
+
+func uploadFile(c *gin.Context) {
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+
+	purpose := c.PostForm("purpose")
+	if !relaymodel.IsValidUploadFilePurpose(purpose) {
+		relayError(c, http.StatusBadRequest, fmt.Sprintf("invalid purpose: %q", purpose), "invalid_purpose")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		relayError(c, http.StatusBadRequest, "get form file failed: "+err.Error(), "invalid_file")
+		return
+	}
+
+	if maxSize := filestorage.GetMaxFileSize(); maxSize > 0 && fileHeader.Size > maxSize {
+		relayError(c,
+			http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("file too large: %d, max: %d", fileHeader.Size, maxSize),
+			"file_too_large",
+		)
+
+		return
+	}
+
+	f, err := fileHeader.Open()
+	if err != nil {
+		relayError(c, http.StatusBadRequest, "open form file failed: "+err.Error(), "invalid_file")
+		return
+	}
+	defer f.Close()
+
+	file, err := storeFile(
+		c.Request.Context(),
+		group.ID,
+		token.ID,
+		fileHeader.Filename,
+		purpose,
+		f,
+		fileHeader.Size,
+	)
+	if err != nil {
+		common.GetLogger(c).Errorf("store file failed: %v", err)
+		relayError(c, http.StatusInternalServerError, "save file failed", "save_file_failed")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, toFileObject(file))
+}
+
+// storeFile saves a file to the gateway storage and creates its record
+func storeFile(
+	ctx context.Context,
+	group string,
+	tokenID int,
+	filename, purpose string,
+	r io.Reader,
+	size int64,
+) (*model.File, error) {
+	id := "file-" + common.ShortUUID()
+	key := fileStorageKey(group, tokenID, id)
+
+	if err := filestorage.Default().Put(ctx, key, r, size); err != nil {
+		return nil, fmt.Errorf("put file to storage: %w", err)
+	}
+
+	file := &model.File{
+		ID:         id,
+		GroupID:    group,
+		TokenID:    tokenID,
+		Filename:   filename,
+		Purpose:    purpose,
+		Bytes:      size,
+		StorageKey: key,
+	}
+
+	if err := model.CreateFile(file); err != nil {
+		_ = filestorage.Default().Delete(context.Background(), key)
+		return nil, fmt.Errorf("create file record: %w", err)
+	}
+
+	return file, nil
+}
+
+func listFiles(c *gin.Context) {
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = defaultListFilesLimit
+	} else if limit > maxListFilesLimit {
+		limit = maxListFilesLimit
+	}
+
+	files, hasMore, err := model.GetFiles(
+		group.ID,
+		token.ID,
+		c.Query("purpose"),
+		c.Query("after"),
+		limit,
+		c.Query("order"),
+	)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			relayError(c, http.StatusNotFound, "cursor file not found", "file_not_found")
+			return
+		}
+
+		common.GetLogger(c).Errorf("list files failed: %v", err)
+		relayError(c, http.StatusInternalServerError, "list files failed", "list_files_failed")
+
+		return
+	}
+
+	list := relaymodel.FileList{
+		Object:  "list",
+		Data:    make([]relaymodel.File, 0, len(files)),
+		HasMore: hasMore,
+	}
+	for _, f := range files {
+		list.Data = append(list.Data, toFileObject(f))
+	}
+
+	if len(list.Data) > 0 {
+		list.FirstID = list.Data[0].ID
+		list.LastID = list.Data[len(list.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func getFileOrAbort(c *gin.Context) (*model.File, bool) {
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+	id := c.Param("id")
+
+	file, err := model.GetFile(group.ID, token.ID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			relayError(c, http.StatusNotFound, fmt.Sprintf("no such file: %s", id), "file_not_found")
+			return nil, false
+		}
+
+		common.GetLogger(c).Errorf("get file failed: %v", err)
+		relayError(c, http.StatusInternalServerError, "get file failed", "get_file_failed")
+
+		return nil, false
+	}
+
+	return file, true
+}
+
+func retrieveFile(c *gin.Context) {
+	file, ok := getFileOrAbort(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toFileObject(file))
+}
+
+func deleteFile(c *gin.Context) {
+	file, ok := getFileOrAbort(c)
+	if !ok {
+		return
+	}
+
+	if file.StorageKey != "" {
+		if err := filestorage.Default().Delete(c.Request.Context(), file.StorageKey); err != nil {
+			common.GetLogger(c).Errorf("delete file from storage failed: %v", err)
+			relayError(c, http.StatusInternalServerError, "delete file failed", "delete_file_failed")
+
+			return
+		}
+	}
+
+	err := model.DeleteFile(file.GroupID, file.TokenID, file.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		common.GetLogger(c).Errorf("delete file record failed: %v", err)
+		relayError(c, http.StatusInternalServerError, "delete file failed", "delete_file_failed")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, relaymodel.FileDeleted{
+		ID:      file.ID,
+		Object:  "file",
+		Deleted: true,
+	})
+}
+
+func retrieveFileContent(c *gin.Context) {
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+	id := c.Param("id")
+
+	file, content, err := OpenFile(c.Request.Context(), group.ID, token.ID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, filestorage.ErrNotFound) {
+			relayError(c, http.StatusNotFound, fmt.Sprintf("no such file: %s", id), "file_not_found")
+			return
+		}
+
+		common.GetLogger(c).Errorf("open file failed: %v", err)
+		relayError(c, http.StatusInternalServerError, "open file failed", "open_file_failed")
+
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(
+		http.StatusOK,
+		file.Bytes,
+		"application/octet-stream",
+		content,
+		map[string]string{
+			"Content-Disposition": fmt.Sprintf("attachment; filename=%q", file.Filename),
+		},
+	)
+}
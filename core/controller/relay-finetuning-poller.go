@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/notify"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// the jobs are not polled right after they are created
+	fineTuningPollDelay    = time.Minute * 10
+	fineTuningPollPageSize = 100
+)
+
+// PollFineTuningJobs retrieves the pinned fine-tuning jobs which are not
+// billed yet through the relay pipeline, so the jobs the clients never poll
+// are billed too, the jobs finished without trained tokens are only marked
+func PollFineTuningJobs(ctx context.Context) {
+	afterID := ""
+
+	for ctx.Err() == nil {
+		stores, lastID, err := model.GetUnbilledStores(
+			model.FineTuningJobIDPrefix,
+			afterID,
+			time.Now().Add(-fineTuningPollDelay),
+			fineTuningPollPageSize,
+		)
+		if err != nil {
+			notify.ErrorThrottle(
+				"pollFineTuningJobs",
+				time.Minute,
+				"get unbilled fine-tuning jobs failed",
+				err.Error(),
+			)
+
+			return
+		}
+
+		for _, store := range stores {
+			if ctx.Err() != nil {
+				return
+			}
+
+			pollFineTuningJob(ctx, store)
+		}
+
+		if lastID == "" {
+			return
+		}
+
+		afterID = lastID
+	}
+}
+
+func pollFineTuningJob(ctx context.Context, store *model.StoreV2) {
+	log := log.WithField("job_id", store.ID)
+
+	token, err := model.GetTokenByID(store.TokenID)
+	if err != nil {
+		log.Debugf("get fine-tuning job token failed: %v", err)
+		return
+	}
+
+	group, tokenCache, modelCaches, err := batchRequestAuth(token.Key)
+	if err != nil {
+		log.Debugf("fine-tuning job token is unavailable: %v", err)
+		return
+	}
+
+	resp, err := internalRelayGet(
+		ctx,
+		mode.FineTuningJobsGet,
+		"/v1/fine_tuning/jobs/"+store.ID,
+		gin.Params{{Key: "id", Value: store.ID}},
+		group,
+		tokenCache,
+		modelCaches,
+		nil,
+	)
+	if err != nil {
+		log.Errorf("poll fine-tuning job failed: %v", err)
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Debugf("poll fine-tuning job failed with status %d", resp.StatusCode)
+	}
+}
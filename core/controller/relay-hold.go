@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/bytedance/sonic/ast"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/common/consume"
+	"github.com/wavespeed/llm-server/core/common/hold"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+)
+
+const holdKey = "hold"
+
+// maxOutputTokensPaths are the request fields limiting the output tokens of
+// the modes, the first one set is used
+var maxOutputTokensPaths = map[mode.Mode][][]any{
+	mode.ChatCompletions: {{"max_completion_tokens"}, {"max_tokens"}},
+	mode.Completions:     {{"max_tokens"}},
+	mode.Anthropic:       {{"max_tokens"}},
+	mode.Responses:       {{"max_output_tokens"}},
+	mode.Gemini:          {{"generationConfig", "maxOutputTokens"}},
+}
+
+func requestMaxOutputTokens(c *gin.Context, m mode.Mode) int64 {
+	paths, ok := maxOutputTokensPaths[m]
+	if !ok {
+		return 0
+	}
+
+	body, err := common.GetRequestBodyReusable(c.Request)
+	if err != nil {
+		return 0
+	}
+
+	for _, path := range paths {
+		node, err := sonic.GetWithOptions(body, ast.SearchOptions{}, path...)
+		if err != nil {
+			if !errors.Is(err, ast.ErrNotExist) {
+				return 0
+			}
+
+			continue
+		}
+
+		maxTokens, err := node.Int64()
+		if err == nil && maxTokens > 0 {
+			return maxTokens
+		}
+	}
+
+	return 0
+}
+
+// holdUsage is the most the request can use, the output tokens are limited
+// by the max tokens of the request or of the model
+func holdUsage(c *gin.Context, m mode.Mode, mc model.ModelConfig, requestUsage model.Usage) model.Usage {
+	maxTokens := requestMaxOutputTokens(c, m)
+	if modelMaxTokens, ok := mc.MaxOutputTokens(); ok && modelMaxTokens > 0 {
+		if maxTokens == 0 || maxTokens > int64(modelMaxTokens) {
+			maxTokens = int64(modelMaxTokens)
+		}
+	}
+
+	usage := requestUsage
+	if maxTokens > int64(usage.OutputTokens) {
+		usage.OutputTokens = model.ZeroNullInt64(maxTokens)
+	}
+
+	return usage
+}
+
+// holdLimits are the balances the hold is placed against, the internal
+// groups have no balance and the tokens without quota are not limited
+func holdLimits(gbc *middleware.GroupBalanceConsumer, token model.TokenCache) []hold.Limit {
+	var limits []hold.Limit
+
+	if gbc.Consumer != nil {
+		limits = append(limits, hold.Limit{
+			Key:       hold.GroupKey(gbc.Group),
+			Available: gbc.Balance(),
+		})
+	}
+
+	if token.Quota <= 0 && token.PeriodQuota <= 0 {
+		return limits
+	}
+
+	available := -1.0
+	if token.Quota > 0 {
+		available = token.Quota - token.UsedAmount
+	}
+
+	if token.PeriodQuota > 0 {
+		periodAvailable := token.PeriodQuota - (token.UsedAmount - token.PeriodLastUpdateAmount)
+		if available < 0 || periodAvailable < available {
+			available = periodAvailable
+		}
+	}
+
+	return append(limits, hold.Limit{
+		Key:       hold.TokenKey(gbc.Group, token.ID),
+		Available: available,
+	})
+}
+
+// placeHold reserves the most the request can cost against the group
+// balance and the token quota, the hold is released when the consumes of
+// the request are recorded, false is returned when the balance is not enough
+func placeHold(c *gin.Context, meta *meta.Meta, mc model.ModelConfig, price model.Price) bool {
+	gbc := middleware.GetGroupBalanceConsumerFromContext(c)
+
+	amount := consume.ConvertAmount(
+		consume.CalculateAmount(
+			http.StatusOK,
+			holdUsage(c, meta.Mode, mc, meta.RequestUsage),
+			price,
+		),
+		price,
+		meta,
+	)
+	if !gbc.CheckBalance(amount) {
+		return false
+	}
+
+	h, ok := hold.Place(
+		c.Request.Context(),
+		amount,
+		hold.DefaultTTL,
+		holdLimits(gbc, middleware.GetToken(c))...,
+	)
+	if !ok {
+		return false
+	}
+
+	if h != nil {
+		common.GetLogger(c).Data["hold"] = strconv.FormatFloat(h.Amount, 'f', -1, 64)
+		c.Set(holdKey, h)
+	}
+
+	return true
+}
+
+func getHold(c *gin.Context) *hold.Hold {
+	v, ok := c.Get(holdKey)
+	if !ok {
+		return nil
+	}
+
+	h, _ := v.(*hold.Hold)
+
+	return h
+}
+
+// releaseHold drops the reference of the relay, the hold is kept until the
+// recorded consumes are done
+func releaseHold(c *gin.Context) {
+	getHold(c).Release()
+	c.Set(holdKey, nil)
+}
+
+// attachHold hands a reference of the hold to the consume of the meta
+func attachHold(c *gin.Context, meta *meta.Meta) {
+	h := getHold(c)
+	if h == nil {
+		return
+	}
+
+	h.Retain()
+	meta.Set(consume.MetaHold, h)
+}
@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+)
+
+// internalRelayResponse is the response of a request relayed by the gateway
+// itself
+type internalRelayResponse struct {
+	StatusCode int
+	RequestID  string
+	Body       []byte
+}
+
+// internalRelay relays the body through the same pipeline as a normal
+// request of the group and the token, so it is distributed, retried, logged
+// and billed the same way, the metadata is added to the request metadata
+func internalRelay(
+	ctx context.Context,
+	m mode.Mode,
+	url string,
+	body []byte,
+	group model.GroupCache,
+	token model.TokenCache,
+	modelCaches *model.ModelCaches,
+	metadata map[string]string,
+) (*internalRelayResponse, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		url,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	common.SetRequestBody(req, body)
+
+	return serveInternalRelay(req, nil, m, group, token, modelCaches, metadata), nil
+}
+
+// internalRelayGet relays a GET request with the route params through the
+// same pipeline as internalRelay, e.g. to poll a job by its id
+func internalRelayGet(
+	ctx context.Context,
+	m mode.Mode,
+	url string,
+	params gin.Params,
+	group model.GroupCache,
+	token model.TokenCache,
+	modelCaches *model.ModelCaches,
+	metadata map[string]string,
+) (*internalRelayResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return serveInternalRelay(req, params, m, group, token, modelCaches, metadata), nil
+}
+
+func serveInternalRelay(
+	req *http.Request,
+	params gin.Params,
+	m mode.Mode,
+	group model.GroupCache,
+	token model.TokenCache,
+	modelCaches *model.ModelCaches,
+	metadata map[string]string,
+) *internalRelayResponse {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = params
+
+	now := time.Now()
+	requestID := middleware.GenRequestID(now)
+
+	middleware.SetRequestAt(c, now)
+	middleware.SetRequestID(c, requestID)
+	c.Set(middleware.Group, group)
+	c.Set(middleware.Token, token)
+	c.Set(middleware.ModelCaches, modelCaches)
+
+	middleware.NewDistribute(m)(c)
+
+	if !c.IsAborted() {
+		addInternalRelayMetadata(c, metadata)
+		relay(c, m, relayController(m))
+	}
+
+	return &internalRelayResponse{
+		StatusCode: c.Writer.Status(),
+		RequestID:  requestID,
+		Body:       w.Body.Bytes(),
+	}
+}
+
+// internalRelayDistributed relays the body with the values the distributor
+// set on the request, e.g. the model requests of the mcp tools loop, so the
+// request already checked by the distributor is not counted again by the
+// rpm of the group, every relayed request is still logged and billed
+func internalRelayDistributed(
+	c *gin.Context,
+	m mode.Mode,
+	url string,
+	body []byte,
+	metadata map[string]string,
+) (*internalRelayResponse, error) {
+	req, err := http.NewRequestWithContext(
+		c.Request.Context(),
+		http.MethodPost,
+		url,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	common.SetRequestBody(req, body)
+
+	w := httptest.NewRecorder()
+	rc, _ := gin.CreateTestContext(w)
+	rc.Request = req
+	rc.Keys = maps.Clone(c.Keys)
+
+	now := time.Now()
+	requestID := middleware.GenRequestID(now)
+
+	middleware.SetRequestAt(rc, now)
+	middleware.SetRequestID(rc, requestID)
+	addInternalRelayMetadata(rc, metadata)
+
+	relay(rc, m, relayController(m))
+
+	return &internalRelayResponse{
+		StatusCode: rc.Writer.Status(),
+		RequestID:  requestID,
+		Body:       w.Body.Bytes(),
+	}, nil
+}
+
+func addInternalRelayMetadata(c *gin.Context, metadata map[string]string) {
+	if len(metadata) == 0 {
+		return
+	}
+
+	requestMetadata := maps.Clone(middleware.GetRequestMetadata(c))
+	if requestMetadata == nil {
+		requestMetadata = make(map[string]string, len(metadata))
+	}
+
+	maps.Copy(requestMetadata, metadata)
+	c.Set(middleware.RequestMetadata, requestMetadata)
+}
+
+// internalRelayAuth returns the group, the token and the model caches of the
+// request for an internal relay, the tokens authenticated by the mcp
+// endpoints are completed like TokenAuth
+func internalRelayAuth(c *gin.Context) (model.GroupCache, model.TokenCache, *model.ModelCaches) {
+	group := middleware.GetGroup(c)
+	token := middleware.GetToken(c)
+
+	if v, ok := c.Get(middleware.ModelCaches); ok {
+		if modelCaches, ok := v.(*model.ModelCaches); ok {
+			return group, token, modelCaches
+		}
+	}
+
+	modelCaches := model.LoadModelCaches()
+
+	if group.Status == model.GroupStatusInternal && len(group.AvailableSets) == 0 {
+		group.AvailableSets = slices.Collect(maps.Keys(modelCaches.EnabledModelsBySet))
+	}
+
+	token.SetAvailableSets(group.GetAvailableSets())
+	token.SetModelsBySet(modelCaches.EnabledModelsBySet)
+
+	return group, token, modelCaches
+}
+
+// Err returns the error of a failed response, the message of the openai
+// error is preferred over the status text
+func (r *internalRelayResponse) Err() error {
+	if r.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var errResp relaymodel.OpenAIErrorResponse
+	if err := sonic.Unmarshal(r.Body, &errResp); err == nil && errResp.Error.Message != "" {
+		return fmt.Errorf("%s (request id: %s)", errResp.Error.Message, r.RequestID)
+	}
+
+	return fmt.Errorf("%s (request id: %s)", http.StatusText(r.StatusCode), r.RequestID)
+}
@@ -0,0 +1,761 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	mcpcontroller "github.com/wavespeed/llm-server/core/controller/mcp"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+	"github.com/wavespeed/llm-server/core/relay/render"
+)
+
+const (
+	// maxMCPToolRounds bounds the model requests of a relay request running
+	// mcp tools
+	maxMCPToolRounds = 10
+	mcpToolType      = "mcp"
+	mcpToolsMetadata = "mcp_tools"
+
+	errMCPToolRoundsExceeded = "the model still calls mcp tools after the maximum rounds of mcp tools calls"
+)
+
+// relayMCPTool is an mcp tool of a relay request, like the mcp tool of the
+// openai responses api the mcp is referenced by its id instead of its url
+type relayMCPTool struct {
+	Type         string          `json:"type"`
+	ServerLabel  string          `json:"server_label"`
+	ServerID     string          `json:"server_id"`
+	ServerScope  model.MCPScope  `json:"server_scope"`
+	ServerURL    string          `json:"server_url"`
+	AllowedTools mcpAllowedTools `json:"allowed_tools"`
+}
+
+// mcpAllowedTools is a list of tool names or an object with the tool names
+type mcpAllowedTools []string
+
+func (a *mcpAllowedTools) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := sonic.Unmarshal(data, &names); err == nil {
+		*a = names
+		return nil
+	}
+
+	var filter struct {
+		ToolNames []string `json:"tool_names"`
+	}
+	if err := sonic.Unmarshal(data, &filter); err != nil {
+		return err
+	}
+
+	*a = filter.ToolNames
+
+	return nil
+}
+
+// relayMCPTools is the mcp tools of a relay request, the other tools are
+// passed to the model unchanged
+type relayMCPTools struct {
+	tools      *mcpcontroller.MCPTools
+	functions  []mcpcontroller.MCPTool
+	labels     map[string]string
+	otherTools []json.RawMessage
+
+	// call runs an mcp tool, relay relays a model request of the loop
+	call  func(ctx context.Context, name, arguments string) (string, error)
+	relay func(m mode.Mode, url string, req map[string]any) (*internalRelayResponse, error)
+}
+
+// parseRelayMCPTools returns the mcp tools of the tools of the request, nil
+// when the request has no mcp tool
+func parseRelayMCPTools(rawTools []json.RawMessage) ([]relayMCPTool, []json.RawMessage, error) {
+	var (
+		mcpTools   []relayMCPTool
+		otherTools []json.RawMessage
+	)
+
+	for _, raw := range rawTools {
+		var tool relayMCPTool
+		if err := sonic.Unmarshal(raw, &tool); err != nil {
+			return nil, nil, err
+		}
+
+		if tool.Type != mcpToolType {
+			otherTools = append(otherTools, raw)
+			continue
+		}
+
+		if tool.ServerURL != "" {
+			return nil, nil, errors.New("mcp server_url is not supported, reference the mcp by server_id")
+		}
+
+		if tool.ServerLabel == "" {
+			return nil, nil, errors.New("mcp server_label is required")
+		}
+
+		if tool.ServerID == "" {
+			tool.ServerID = tool.ServerLabel
+		}
+
+		if tool.ServerScope == "" {
+			tool.ServerScope = model.MCPScopePublic
+		}
+
+		mcpTools = append(mcpTools, tool)
+	}
+
+	return mcpTools, otherTools, nil
+}
+
+// newRelayMCPTools connects the mcps of the tools and lists their tools
+func newRelayMCPTools(
+	c *gin.Context,
+	mcpTools []relayMCPTool,
+	otherTools []json.RawMessage,
+) (*relayMCPTools, error) {
+	servers := make(model.VirtualMCPServers, 0, len(mcpTools))
+	allowed := make(map[string][]string, len(mcpTools))
+
+	for _, tool := range mcpTools {
+		servers = append(servers, model.VirtualMCPServer{
+			Scope:     tool.ServerScope,
+			ID:        tool.ServerID,
+			Namespace: tool.ServerLabel,
+		})
+
+		allowed[tool.ServerLabel] = tool.AllowedTools
+	}
+
+	tools, err := mcpcontroller.NewMCPTools(c, servers)
+	if err != nil {
+		return nil, err
+	}
+
+	functions, err := tools.List(c.Request.Context())
+	if err != nil {
+		tools.Close()
+		return nil, err
+	}
+
+	r := &relayMCPTools{
+		tools:      tools,
+		labels:     make(map[string]string, len(functions)),
+		otherTools: otherTools,
+		call:       tools.Call,
+		relay: func(m mode.Mode, url string, req map[string]any) (*internalRelayResponse, error) {
+			return relayMCPToolsRound(c, m, url, req)
+		},
+	}
+
+	for _, function := range functions {
+		label, name, _ := cutMCPToolName(function.Name)
+		if names := allowed[label]; len(names) != 0 && !slices.Contains(names, name) {
+			continue
+		}
+
+		r.functions = append(r.functions, function)
+		r.labels[function.Name] = label
+	}
+
+	return r, nil
+}
+
+func cutMCPToolName(name string) (label, tool string, ok bool) {
+	return strings.Cut(name, model.VirtualMCPNamespaceSeparator)
+}
+
+func (r *relayMCPTools) Close() {
+	if r.tools != nil {
+		r.tools.Close()
+	}
+}
+
+func (r *relayMCPTools) isMCPTool(name string) bool {
+	_, ok := r.labels[name]
+	return ok
+}
+
+// chatTools returns the tools of a chat completion request, the mcp tools
+// are function tools
+func (r *relayMCPTools) chatTools() []any {
+	tools := make([]any, 0, len(r.otherTools)+len(r.functions))
+	for _, tool := range r.otherTools {
+		tools = append(tools, tool)
+	}
+
+	for _, function := range r.functions {
+		tools = append(tools, relaymodel.Tool{
+			Type: "function",
+			Function: relaymodel.Function{
+				Name:        function.Name,
+				Description: function.Description,
+				Parameters:  function.InputSchema,
+			},
+		})
+	}
+
+	return tools
+}
+
+// responsesTools returns the tools of a responses request, the mcp tools are
+// function tools
+func (r *relayMCPTools) responsesTools() []any {
+	tools := make([]any, 0, len(r.otherTools)+len(r.functions))
+	for _, tool := range r.otherTools {
+		tools = append(tools, tool)
+	}
+
+	for _, function := range r.functions {
+		tools = append(tools, relaymodel.ResponseTool{
+			Type:        "function",
+			Name:        function.Name,
+			Description: function.Description,
+			Parameters:  function.InputSchema,
+		})
+	}
+
+	return tools
+}
+
+// MCPTools runs the tools calls of the mcp tools of the chat completion and
+// the responses requests on the gateway, it runs after the distributor so
+// the request passed its checks once, each model request of the loop is
+// relayed, logged and billed like a request of the group, the requests
+// without mcp tools are passed to the next handlers
+func MCPTools(m mode.Mode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := common.GetRequestBodyReusable(c.Request)
+		if err != nil || !bytes.Contains(body, []byte(`"`+mcpToolType+`"`)) {
+			return
+		}
+
+		var req map[string]json.RawMessage
+		if err := sonic.Unmarshal(body, &req); err != nil || req["tools"] == nil {
+			return
+		}
+
+		var rawTools []json.RawMessage
+		if err := sonic.Unmarshal(req["tools"], &rawTools); err != nil {
+			return
+		}
+
+		mcpTools, otherTools, err := parseRelayMCPTools(rawTools)
+		if err != nil {
+			middleware.AbortLogWithMessageWithMode(m, c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if len(mcpTools) == 0 {
+			return
+		}
+
+		tools, err := newRelayMCPTools(c, mcpTools, otherTools)
+		if err != nil {
+			middleware.AbortLogWithMessageWithMode(m, c, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer tools.Close()
+
+		var stream bool
+		if req["stream"] != nil {
+			_ = sonic.Unmarshal(req["stream"], &stream)
+		}
+
+		delete(req, "stream")
+
+		switch m {
+		case mode.ChatCompletions:
+			relayChatMCPTools(c, req, tools, stream)
+		case mode.Responses:
+			relayResponsesMCPTools(c, req, tools, stream)
+		default:
+			middleware.AbortLogWithMessageWithMode(m, c, http.StatusBadRequest,
+				"mcp tools are not supported by "+m.String())
+
+			return
+		}
+
+		c.Abort()
+	}
+}
+
+// relayMCPToolsRound relays one model request of the loop with the values
+// the distributor set on the request
+func relayMCPToolsRound(
+	c *gin.Context,
+	m mode.Mode,
+	url string,
+	req map[string]any,
+) (*internalRelayResponse, error) {
+	body, err := sonic.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return internalRelayDistributed(c, m, url, body, map[string]string{mcpToolsMetadata: "true"})
+}
+
+func rawRequest(req map[string]json.RawMessage) map[string]any {
+	r := make(map[string]any, len(req))
+	for k, v := range req {
+		r[k] = v
+	}
+
+	return r
+}
+
+func relayChatMCPTools(
+	c *gin.Context,
+	req map[string]json.RawMessage,
+	tools *relayMCPTools,
+	stream bool,
+) {
+	var messages []json.RawMessage
+	if err := sonic.Unmarshal(req["messages"], &messages); err != nil {
+		middleware.AbortLogWithMessageWithMode(mode.ChatCompletions, c, http.StatusBadRequest,
+			"invalid messages: "+err.Error())
+
+		return
+	}
+
+	var includeUsage bool
+	if req["stream_options"] != nil {
+		var options relaymodel.StreamOptions
+		if err := sonic.Unmarshal(req["stream_options"], &options); err == nil {
+			includeUsage = options.IncludeUsage
+		}
+	}
+
+	delete(req, "stream_options")
+
+	body := rawRequest(req)
+	body["tools"] = tools.chatTools()
+
+	var usage relaymodel.ChatUsage
+
+	for round := 1; ; round++ {
+		body["messages"] = messages
+
+		resp, err := tools.relay(mode.ChatCompletions, "/v1/chat/completions", body)
+		if err != nil {
+			middleware.AbortLogWithMessageWithMode(mode.ChatCompletions, c,
+				http.StatusInternalServerError, err.Error())
+
+			return
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			c.Data(resp.StatusCode, "application/json", resp.Body)
+			return
+		}
+
+		var textResp relaymodel.TextResponse
+		if err := sonic.Unmarshal(resp.Body, &textResp); err != nil {
+			middleware.AbortLogWithMessageWithMode(mode.ChatCompletions, c,
+				http.StatusInternalServerError, err.Error())
+
+			return
+		}
+
+		usage.Add(&textResp.Usage)
+
+		calls, clientCalls := splitChatToolCalls(&textResp, tools)
+		if len(calls) == 0 || len(clientCalls) != 0 {
+			if len(calls) != 0 {
+				// the client can not run the mcp tools calls
+				textResp.Choices[0].Message.ToolCalls = clientCalls
+			}
+
+			textResp.Usage = usage
+			writeChatMCPToolsResponse(c, &textResp, stream, includeUsage)
+
+			return
+		}
+
+		if round == maxMCPToolRounds {
+			middleware.AbortLogWithMessageWithMode(mode.ChatCompletions, c,
+				http.StatusInternalServerError, errMCPToolRoundsExceeded)
+
+			return
+		}
+
+		assistant, err := sonic.Marshal(textResp.Choices[0].Message)
+		if err != nil {
+			middleware.AbortLogWithMessageWithMode(mode.ChatCompletions, c,
+				http.StatusInternalServerError, err.Error())
+
+			return
+		}
+
+		messages = append(messages, assistant)
+
+		for _, call := range calls {
+			output, err := tools.call(c.Request.Context(), call.Function.Name, call.Function.Arguments)
+			if err != nil {
+				output = "error: " + err.Error()
+			}
+
+			message, err := sonic.Marshal(relaymodel.Message{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    output,
+			})
+			if err != nil {
+				middleware.AbortLogWithMessageWithMode(mode.ChatCompletions, c,
+					http.StatusInternalServerError, err.Error())
+
+				return
+			}
+
+			messages = append(messages, message)
+		}
+	}
+}
+
+// splitChatToolCalls splits the tools calls of the response into the mcp
+// tools calls and the calls of the client tools, the mcp tools calls of a
+// response which also calls client tools are not run, they are removed from
+// the response returned to the client
+func splitChatToolCalls(
+	resp *relaymodel.TextResponse,
+	tools *relayMCPTools,
+) (mcpCalls, clientCalls []relaymodel.ToolCall) {
+	if len(resp.Choices) == 0 || resp.Choices[0] == nil {
+		return nil, nil
+	}
+
+	for _, call := range resp.Choices[0].Message.ToolCalls {
+		if tools.isMCPTool(call.Function.Name) {
+			mcpCalls = append(mcpCalls, call)
+		} else {
+			clientCalls = append(clientCalls, call)
+		}
+	}
+
+	return mcpCalls, clientCalls
+}
+
+// writeChatMCPToolsResponse writes the last response of the loop, the rounds
+// are not streamed so a stream request receives the whole answer as one
+// chunk once all the rounds are done
+func writeChatMCPToolsResponse(
+	c *gin.Context,
+	resp *relaymodel.TextResponse,
+	stream bool,
+	includeUsage bool,
+) {
+	if !stream {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	chunk := relaymodel.ChatCompletionsStreamResponse{
+		ID:      resp.ID,
+		Object:  relaymodel.ChatCompletionChunkObject,
+		Model:   resp.Model,
+		Created: resp.Created,
+		Choices: make([]*relaymodel.ChatCompletionsStreamResponseChoice, 0, len(resp.Choices)),
+	}
+
+	for _, choice := range resp.Choices {
+		if choice == nil {
+			continue
+		}
+
+		delta := choice.Message
+		for i := range delta.ToolCalls {
+			delta.ToolCalls[i].Index = i
+		}
+
+		chunk.Choices = append(chunk.Choices, &relaymodel.ChatCompletionsStreamResponseChoice{
+			Index:        choice.Index,
+			Delta:        delta,
+			FinishReason: choice.FinishReason,
+		})
+	}
+
+	_ = render.OpenaiObjectData(c, chunk)
+
+	if includeUsage {
+		usage := resp.Usage
+
+		_ = render.OpenaiObjectData(c, relaymodel.ChatCompletionsStreamResponse{
+			ID:      resp.ID,
+			Object:  relaymodel.ChatCompletionChunkObject,
+			Model:   resp.Model,
+			Created: resp.Created,
+			Choices: []*relaymodel.ChatCompletionsStreamResponseChoice{},
+			Usage:   &usage,
+		})
+	}
+
+	render.OpenaiDone(c)
+}
+
+// responsesFunctionCall is a function call output item of a response
+type responsesFunctionCall struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	CallID    string `json:"call_id"`
+	Arguments string `json:"arguments"`
+}
+
+func relayResponsesMCPTools(
+	c *gin.Context,
+	req map[string]json.RawMessage,
+	tools *relayMCPTools,
+	stream bool,
+) {
+	input, err := responsesInputItems(req["input"])
+	if err != nil {
+		middleware.AbortLogWithMessageWithMode(mode.Responses, c, http.StatusBadRequest,
+			"invalid input: "+err.Error())
+
+		return
+	}
+
+	body := rawRequest(req)
+	body["tools"] = tools.responsesTools()
+
+	var (
+		usage    relaymodel.ResponseUsage
+		mcpCalls []map[string]any
+	)
+
+	for round := 1; ; round++ {
+		body["input"] = input
+
+		resp, err := tools.relay(mode.Responses, "/v1/responses", body)
+		if err != nil {
+			middleware.AbortLogWithMessageWithMode(mode.Responses, c,
+				http.StatusInternalServerError, err.Error())
+
+			return
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			c.Data(resp.StatusCode, "application/json", resp.Body)
+			return
+		}
+
+		var response map[string]json.RawMessage
+		if err := sonic.Unmarshal(resp.Body, &response); err != nil {
+			middleware.AbortLogWithMessageWithMode(mode.Responses, c,
+				http.StatusInternalServerError, err.Error())
+
+			return
+		}
+
+		var output []json.RawMessage
+		if response["output"] != nil {
+			if err := sonic.Unmarshal(response["output"], &output); err != nil {
+				middleware.AbortLogWithMessageWithMode(mode.Responses, c,
+					http.StatusInternalServerError, err.Error())
+
+				return
+			}
+		}
+
+		if response["usage"] != nil {
+			var roundUsage relaymodel.ResponseUsage
+			if err := sonic.Unmarshal(response["usage"], &roundUsage); err == nil {
+				usage.InputTokens += roundUsage.InputTokens
+				usage.OutputTokens += roundUsage.OutputTokens
+				usage.TotalTokens += roundUsage.TotalTokens
+			}
+		}
+
+		calls, clientOutput := splitResponsesToolCalls(output, tools)
+		if clientCalls := hasResponsesFunctionCall(clientOutput); len(calls) == 0 || clientCalls {
+			if clientCalls {
+				// the client can not run the mcp tools calls
+				output = clientOutput
+			}
+
+			writeResponsesMCPToolsResponse(c, response, output, mcpCalls, usage, stream)
+
+			return
+		}
+
+		if round == maxMCPToolRounds {
+			middleware.AbortLogWithMessageWithMode(mode.Responses, c,
+				http.StatusInternalServerError, errMCPToolRoundsExceeded)
+
+			return
+		}
+
+		input = append(input, output...)
+
+		for _, call := range calls {
+			label, name, _ := cutMCPToolName(call.Name)
+			mcpCall := map[string]any{
+				"id":           "mcp_" + common.ShortUUID(),
+				"type":         "mcp_call",
+				"server_label": label,
+				"name":         name,
+				"arguments":    call.Arguments,
+			}
+
+			callOutput, err := tools.call(c.Request.Context(), call.Name, call.Arguments)
+			if err != nil {
+				mcpCall["error"] = err.Error()
+				callOutput = "error: " + err.Error()
+			} else {
+				mcpCall["output"] = callOutput
+			}
+
+			mcpCalls = append(mcpCalls, mcpCall)
+
+			item, err := sonic.Marshal(map[string]any{
+				"type":    "function_call_output",
+				"call_id": call.CallID,
+				"output":  callOutput,
+			})
+			if err != nil {
+				middleware.AbortLogWithMessageWithMode(mode.Responses, c,
+					http.StatusInternalServerError, err.Error())
+
+				return
+			}
+
+			input = append(input, item)
+		}
+	}
+}
+
+// responsesInputItems returns the input of a responses request as items, a
+// text input is a user message
+func responsesInputItems(raw json.RawMessage) ([]json.RawMessage, error) {
+	if raw == nil {
+		return nil, errors.New("input is required")
+	}
+
+	var text string
+	if err := sonic.Unmarshal(raw, &text); err == nil {
+		item, err := sonic.Marshal(map[string]any{
+			"type":    relaymodel.InputItemTypeMessage,
+			"role":    "user",
+			"content": text,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return []json.RawMessage{item}, nil
+	}
+
+	var items []json.RawMessage
+	if err := sonic.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// splitResponsesToolCalls splits the output into the mcp tools calls and the
+// other items, the mcp tools calls of an output which also calls client
+// tools are not run, they are removed from the response returned to the
+// client
+func splitResponsesToolCalls(
+	output []json.RawMessage,
+	tools *relayMCPTools,
+) (mcpCalls []responsesFunctionCall, clientOutput []json.RawMessage) {
+	for _, item := range output {
+		var call responsesFunctionCall
+		if err := sonic.Unmarshal(item, &call); err == nil &&
+			call.Type == relaymodel.InputItemTypeFunctionCall &&
+			tools.isMCPTool(call.Name) {
+			mcpCalls = append(mcpCalls, call)
+			continue
+		}
+
+		clientOutput = append(clientOutput, item)
+	}
+
+	return mcpCalls, clientOutput
+}
+
+func hasResponsesFunctionCall(output []json.RawMessage) bool {
+	for _, item := range output {
+		var call responsesFunctionCall
+		if err := sonic.Unmarshal(item, &call); err == nil &&
+			call.Type == relaymodel.InputItemTypeFunctionCall {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeResponsesMCPToolsResponse writes the last response of the loop with
+// the mcp calls before its output, the rounds are not streamed so a stream
+// request receives the events of the completed response once all the rounds
+// are done
+func writeResponsesMCPToolsResponse(
+	c *gin.Context,
+	response map[string]json.RawMessage,
+	output []json.RawMessage,
+	mcpCalls []map[string]any,
+	usage relaymodel.ResponseUsage,
+	stream bool,
+) {
+	items := make([]any, 0, len(mcpCalls)+len(output))
+	for _, call := range mcpCalls {
+		items = append(items, call)
+	}
+
+	for _, item := range output {
+		items = append(items, item)
+	}
+
+	result := make(map[string]any, len(response))
+	for k, v := range response {
+		result[k] = v
+	}
+
+	result["output"] = items
+	result["usage"] = usage
+
+	if !stream {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	sequence := 0
+	event := func(eventType string, data map[string]any) {
+		data["type"] = eventType
+		data["sequence_number"] = sequence
+		sequence++
+
+		_ = render.ResponsesEventObjectData(c, eventType, data)
+	}
+
+	created := make(map[string]any, len(result))
+	for k, v := range result {
+		created[k] = v
+	}
+
+	created["status"] = relaymodel.ResponseStatusInProgress
+	created["output"] = []any{}
+	created["usage"] = nil
+
+	event(relaymodel.EventResponseCreated, map[string]any{"response": created})
+	event(relaymodel.EventResponseInProgress, map[string]any{"response": created})
+
+	for i, item := range items {
+		event(relaymodel.EventOutputItemAdded, map[string]any{"output_index": i, "item": item})
+		event(relaymodel.EventOutputItemDone, map[string]any{"output_index": i, "item": item})
+	}
+
+	event(relaymodel.EventResponseCompleted, map[string]any{"response": result})
+}
@@ -0,0 +1,367 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/controller"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+)
+
+func TestParseRelayMCPTools(t *testing.T) {
+	weather := json.RawMessage(`{"type":"function","function":{"name":"get_weather"}}`)
+
+	mcpTools, otherTools, err := controller.ParseRelayMCPTools([]json.RawMessage{
+		json.RawMessage(`{"type":"mcp","server_label":"docs","allowed_tools":{"tool_names":["search"]}}`),
+		weather,
+		json.RawMessage(`{"type":"mcp","server_label":"wiki","server_id":"wiki-mcp","server_scope":"group","allowed_tools":["read"]}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mcpTools) != 2 {
+		t.Fatalf("expected 2 mcp tools, got %d", len(mcpTools))
+	}
+
+	docs := mcpTools[0]
+	if docs.ServerID != "docs" || docs.ServerScope != model.MCPScopePublic ||
+		len(docs.AllowedTools) != 1 || docs.AllowedTools[0] != "search" {
+		t.Fatalf("unexpected docs tool: %+v", docs)
+	}
+
+	wiki := mcpTools[1]
+	if wiki.ServerID != "wiki-mcp" || wiki.ServerScope != "group" ||
+		len(wiki.AllowedTools) != 1 || wiki.AllowedTools[0] != "read" {
+		t.Fatalf("unexpected wiki tool: %+v", wiki)
+	}
+
+	if len(otherTools) != 1 || string(otherTools[0]) != string(weather) {
+		t.Fatalf("expected the function tool to be kept, got %s", otherTools)
+	}
+}
+
+func TestParseRelayMCPToolsWithoutMCP(t *testing.T) {
+	mcpTools, otherTools, err := controller.ParseRelayMCPTools([]json.RawMessage{
+		json.RawMessage(`{"type":"function","function":{"name":"get_weather"}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mcpTools != nil || len(otherTools) != 1 {
+		t.Fatalf("expected no mcp tool, got %+v %s", mcpTools, otherTools)
+	}
+}
+
+func TestParseRelayMCPToolsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		tool string
+		want string
+	}{
+		{
+			name: "server url",
+			tool: `{"type":"mcp","server_label":"docs","server_url":"https://mcp.example.com"}`,
+			want: "server_url",
+		},
+		{
+			name: "no server label",
+			tool: `{"type":"mcp","server_id":"docs"}`,
+			want: "server_label",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := controller.ParseRelayMCPTools([]json.RawMessage{json.RawMessage(tt.tool)})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected an error about %s, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// mcpToolsRecorder records the model requests and the tools calls of a loop
+type mcpToolsRecorder struct {
+	requests []map[string]any
+	calls    []string
+}
+
+func (r *mcpToolsRecorder) call(_ context.Context, name, arguments string) (string, error) {
+	r.calls = append(r.calls, name+" "+arguments)
+	return "result of " + name, nil
+}
+
+func (r *mcpToolsRecorder) tools(t *testing.T, responses ...string) *controller.RelayMCPTools {
+	t.Helper()
+
+	return controller.NewRelayMCPTools(
+		[]string{"docs__search"},
+		[]json.RawMessage{json.RawMessage(`{"type":"function","function":{"name":"get_weather"}}`)},
+		r.call,
+		func(_ mode.Mode, req map[string]any) (int, []byte) {
+			body, err := json.Marshal(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var request map[string]any
+			if err := json.Unmarshal(body, &request); err != nil {
+				t.Fatal(err)
+			}
+
+			r.requests = append(r.requests, request)
+
+			return http.StatusOK, []byte(responses[min(len(r.requests), len(responses))-1])
+		},
+	)
+}
+
+func newMCPToolsContext() (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	return c, w
+}
+
+func chatRequest(t *testing.T, body string) map[string]json.RawMessage {
+	t.Helper()
+
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	return req
+}
+
+const (
+	chatMCPCall = `{"id":"chat-1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,` +
+		`"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function",` +
+		`"function":{"name":"docs__search","arguments":"{\"q\":\"go\"}"}}]},"finish_reason":"tool_calls"}],` +
+		`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`
+	chatAnswer = `{"id":"chat-2","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,` +
+		`"message":{"role":"assistant","content":"done"},"finish_reason":"stop"}],` +
+		`"usage":{"prompt_tokens":20,"completion_tokens":3,"total_tokens":23}}`
+	chatMixedCalls = `{"id":"chat-3","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,` +
+		`"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function",` +
+		`"function":{"name":"docs__search","arguments":"{}"}},{"id":"call_2","type":"function",` +
+		`"function":{"name":"get_weather","arguments":"{}"}}]},"finish_reason":"tool_calls"}],` +
+		`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`
+)
+
+func TestRelayChatMCPTools(t *testing.T) {
+	recorder := &mcpToolsRecorder{}
+	tools := recorder.tools(t, chatMCPCall, chatAnswer)
+
+	c, w := newMCPToolsContext()
+	controller.RelayChatMCPTools(c, chatRequest(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`), tools, false)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(recorder.requests) != 2 {
+		t.Fatalf("expected 2 model requests, got %d", len(recorder.requests))
+	}
+
+	if len(recorder.calls) != 1 || recorder.calls[0] != `docs__search {"q":"go"}` {
+		t.Fatalf("unexpected tools calls: %v", recorder.calls)
+	}
+
+	requestTools, _ := recorder.requests[0]["tools"].([]any)
+	if len(requestTools) != 2 {
+		t.Fatalf("expected the client tool and the mcp tool, got %v", requestTools)
+	}
+
+	messages, _ := recorder.requests[1]["messages"].([]any)
+	if len(messages) != 3 {
+		t.Fatalf("expected the user, assistant and tool messages, got %v", messages)
+	}
+
+	toolMessage, _ := messages[2].(map[string]any)
+	if toolMessage["role"] != "tool" || toolMessage["tool_call_id"] != "call_1" ||
+		toolMessage["content"] != "result of docs__search" {
+		t.Fatalf("unexpected tool message: %v", toolMessage)
+	}
+
+	var resp relaymodel.TextResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Choices[0].Message.StringContent() != "done" {
+		t.Fatalf("expected the answer of the last round, got %s", w.Body.String())
+	}
+
+	if resp.Usage.PromptTokens != 30 || resp.Usage.CompletionTokens != 8 || resp.Usage.TotalTokens != 38 {
+		t.Fatalf("expected the usage of all the rounds, got %+v", resp.Usage)
+	}
+}
+
+func TestRelayChatMCPToolsRoundsExceeded(t *testing.T) {
+	recorder := &mcpToolsRecorder{}
+	tools := recorder.tools(t, chatMCPCall)
+
+	c, w := newMCPToolsContext()
+	controller.RelayChatMCPTools(c, chatRequest(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`), tools, false)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), controller.ErrMCPToolRoundsExceeded) {
+		t.Fatalf("expected the rounds error, got %s", w.Body.String())
+	}
+
+	if len(recorder.requests) != controller.MaxMCPToolRounds {
+		t.Fatalf("expected %d model requests, got %d", controller.MaxMCPToolRounds, len(recorder.requests))
+	}
+
+	if len(recorder.calls) != controller.MaxMCPToolRounds-1 {
+		t.Fatalf("expected %d tools calls, got %d", controller.MaxMCPToolRounds-1, len(recorder.calls))
+	}
+}
+
+func TestRelayChatMCPToolsMixedCalls(t *testing.T) {
+	recorder := &mcpToolsRecorder{}
+	tools := recorder.tools(t, chatMixedCalls)
+
+	c, w := newMCPToolsContext()
+	controller.RelayChatMCPTools(c, chatRequest(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`), tools, false)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(recorder.requests) != 1 || len(recorder.calls) != 0 {
+		t.Fatalf("expected no mcp tool call, got %d requests and calls %v", len(recorder.requests), recorder.calls)
+	}
+
+	var resp relaymodel.TextResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Name != "get_weather" {
+		t.Fatalf("expected only the client tool call, got %+v", calls)
+	}
+}
+
+const (
+	responsesMCPCall = `{"id":"resp-1","object":"response","model":"gpt-4o","output":[` +
+		`{"type":"function_call","name":"docs__search","call_id":"call_1","arguments":"{}"}],` +
+		`"usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}`
+	responsesAnswer = `{"id":"resp-2","object":"response","model":"gpt-4o","output":[` +
+		`{"type":"message","role":"assistant","content":[{"type":"output_text","text":"done"}]}],` +
+		`"usage":{"input_tokens":20,"output_tokens":3,"total_tokens":23}}`
+	responsesMixedCalls = `{"id":"resp-3","object":"response","model":"gpt-4o","output":[` +
+		`{"type":"function_call","name":"docs__search","call_id":"call_1","arguments":"{}"},` +
+		`{"type":"function_call","name":"get_weather","call_id":"call_2","arguments":"{}"}],` +
+		`"usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}`
+)
+
+type responsesMCPToolsResult struct {
+	Output []struct {
+		Type   string `json:"type"`
+		Name   string `json:"name"`
+		Output string `json:"output"`
+	} `json:"output"`
+	Usage relaymodel.ResponseUsage `json:"usage"`
+}
+
+func TestRelayResponsesMCPTools(t *testing.T) {
+	recorder := &mcpToolsRecorder{}
+	tools := recorder.tools(t, responsesMCPCall, responsesAnswer)
+
+	c, w := newMCPToolsContext()
+	controller.RelayResponsesMCPTools(c, chatRequest(t, `{"model":"gpt-4o","input":"hi"}`), tools, false)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(recorder.requests) != 2 || len(recorder.calls) != 1 {
+		t.Fatalf("expected 2 model requests and 1 tool call, got %d and %v", len(recorder.requests), recorder.calls)
+	}
+
+	input, _ := recorder.requests[1]["input"].([]any)
+	if len(input) != 3 {
+		t.Fatalf("expected the message, the function call and its output, got %v", input)
+	}
+
+	callOutput, _ := input[2].(map[string]any)
+	if callOutput["type"] != "function_call_output" || callOutput["call_id"] != "call_1" ||
+		callOutput["output"] != "result of docs__search" {
+		t.Fatalf("unexpected function call output: %v", callOutput)
+	}
+
+	var result responsesMCPToolsResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Output) != 2 || result.Output[0].Type != "mcp_call" || result.Output[0].Name != "search" ||
+		result.Output[0].Output != "result of docs__search" || result.Output[1].Type != "message" {
+		t.Fatalf("expected the mcp call before the answer, got %s", w.Body.String())
+	}
+
+	if result.Usage.InputTokens != 30 || result.Usage.OutputTokens != 8 || result.Usage.TotalTokens != 38 {
+		t.Fatalf("expected the usage of all the rounds, got %+v", result.Usage)
+	}
+}
+
+func TestRelayResponsesMCPToolsRoundsExceeded(t *testing.T) {
+	recorder := &mcpToolsRecorder{}
+	tools := recorder.tools(t, responsesMCPCall)
+
+	c, w := newMCPToolsContext()
+	controller.RelayResponsesMCPTools(c, chatRequest(t, `{"model":"gpt-4o","input":"hi"}`), tools, false)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), controller.ErrMCPToolRoundsExceeded) {
+		t.Fatalf("expected the rounds error, got %s", w.Body.String())
+	}
+
+	if len(recorder.requests) != controller.MaxMCPToolRounds {
+		t.Fatalf("expected %d model requests, got %d", controller.MaxMCPToolRounds, len(recorder.requests))
+	}
+}
+
+func TestRelayResponsesMCPToolsMixedCalls(t *testing.T) {
+	recorder := &mcpToolsRecorder{}
+	tools := recorder.tools(t, responsesMixedCalls)
+
+	c, w := newMCPToolsContext()
+	controller.RelayResponsesMCPTools(c, chatRequest(t, `{"model":"gpt-4o","input":"hi"}`), tools, false)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(recorder.requests) != 1 || len(recorder.calls) != 0 {
+		t.Fatalf("expected no mcp tool call, got %d requests and calls %v", len(recorder.requests), recorder.calls)
+	}
+
+	var result responsesMCPToolsResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Output) != 1 || result.Output[0].Name != "get_weather" {
+		t.Fatalf("expected only the client function call, got %s", w.Body.String())
+	}
+}
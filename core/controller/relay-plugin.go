@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/common/balance"
+	"github.com/wavespeed/llm-server/core/common/consume"
+	"github.com/wavespeed/llm-server/core/common/conv"
+	"github.com/wavespeed/llm-server/core/common/tracing"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/monitor"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/adaptors"
+	"github.com/wavespeed/llm-server/core/relay/controller"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+	"github.com/wavespeed/llm-server/core/relay/plugin/cache"
+	"github.com/wavespeed/llm-server/core/relay/plugin/guardrail"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cacheEmbedder embeds the semantic cache queries through the channels of the
+// gateway, the embedding is kept in the context so retries do not bill it again
+func cacheEmbedder(c *gin.Context, mc *model.ModelCaches) cache.Embed {
+	return func(m *meta.Meta, store adaptor.Store, modelName, input string) ([]float64, error) {
+		contextKey := "cache_embedding:" + modelName + ":" + input
+		if v, ok := c.Get(contextKey); ok {
+			if embedding, ok := v.([]float64); ok {
+				return embedding, nil
+			}
+		}
+
+		body, err := sonic.Marshal(map[string]any{
+			"model": modelName,
+			"input": input,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		respBody, err := pluginRelay(c, mc, m, store, mode.Embeddings, modelName, body, "cache")
+		if err != nil {
+			return nil, err
+		}
+
+		var resp relaymodel.EmbeddingResponse
+		if err := sonic.Unmarshal(respBody, &resp); err != nil {
+			return nil, err
+		}
+
+		if len(resp.Data) == 0 || resp.Data[0] == nil {
+			return nil, errors.New("embedding response is empty")
+		}
+
+		c.Set(contextKey, resp.Data[0].Embedding)
+
+		return resp.Data[0].Embedding, nil
+	}
+}
+
+// guardrailCompleter runs the guardrail classifier through the channels of the gateway
+func guardrailCompleter(c *gin.Context, mc *model.ModelCaches) guardrail.Complete {
+	return func(m *meta.Meta, store adaptor.Store, modelName string, body []byte) ([]byte, error) {
+		return pluginRelay(c, mc, m, store, mode.ChatCompletions, modelName, body, guardrail.PluginName)
+	}
+}
+
+var pluginRelayEndpoints = map[mode.Mode]string{
+	mode.ChatCompletions: "/v1/chat/completions",
+	mode.Embeddings:      "/v1/embeddings",
+}
+
+// pluginRelay runs a request made by a plugin through the channels of the
+// gateway and returns the response body, it is checked and billed with the
+// price of the group like a request of the group and logged with the same
+// request id
+func pluginRelay(
+	c *gin.Context,
+	mc *model.ModelCaches,
+	m *meta.Meta,
+	store adaptor.Store,
+	relayMode mode.Mode,
+	modelName string,
+	body []byte,
+	pluginName string,
+) ([]byte, error) {
+	modelConfig, ok := mc.ModelConfig.GetModelConfig(modelName)
+	if !ok {
+		return nil, fmt.Errorf("model not found: %s", modelName)
+	}
+
+	modelConfig = middleware.GetGroupAdjustedModelConfig(m.Group, modelConfig)
+
+	// traced under the span of the plugin hook which made the call
+	ctx, span := tracing.Start(
+		trace.ContextWithSpan(c.Request.Context(), trace.SpanFromContext(m.TraceContext())),
+		"plugin relay "+pluginName,
+		trace.WithAttributes(tracing.ModelKey.String(modelName)),
+	)
+	defer span.End()
+
+	ignoreChannelIDs, _ := monitor.GetBannedChannelsMapWithModel(ctx, modelName)
+	errorRates, _ := monitor.GetModelChannelErrorRate(ctx, modelName)
+
+	channel, _, err := getChannelWithFallback(
+		mc,
+		m.Group.GetAvailableSets(),
+		modelName,
+		relayMode,
+		nil,
+		errorRates,
+		ignoreChannelIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(tracing.ChannelIDKey.Int(channel.ID))
+
+	a, ok := adaptors.GetAdaptor(channel.Type)
+	if !ok {
+		return nil, fmt.Errorf("invalid channel type: %d", channel.Type)
+	}
+
+	endpoint := pluginRelayEndpoints[relayMode]
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		endpoint,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	common.SetRequestBody(req, body)
+	// the checks of the plugin model do not change the log of the request
+	common.SetLogger(req, common.NewLogger())
+
+	w := httptest.NewRecorder()
+	newc, _ := gin.CreateTestContext(w)
+	newc.Request = req
+	middleware.SetRequestID(newc, m.RequestID)
+
+	if err := checkPluginModel(newc, m, modelConfig, middleware.GetRequestUser(c)); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	newMeta := meta.NewMeta(
+		channel,
+		relayMode,
+		modelName,
+		modelConfig,
+		meta.WithRequestID(m.RequestID),
+		meta.WithGroup(m.Group),
+		meta.WithToken(m.Token),
+		meta.WithEndpoint(endpoint),
+	)
+	newMeta.SetTraceContext(ctx)
+
+	result := controller.Handle(a, newc, newMeta, store)
+
+	recordPluginResult(c, newMeta, modelConfig.Price, result, pluginName)
+
+	if result.Error != nil {
+		tracing.RecordError(span, result.Error)
+		return nil, result.Error
+	}
+
+	return w.Body.Bytes(), nil
+}
+
+// checkPluginModel runs the checks of the distributor on the model called by
+// a plugin, the call is counted to the rpm and tpm of the model like a
+// request of the group
+func checkPluginModel(c *gin.Context, m *meta.Meta, mc model.ModelConfig, user string) error {
+	if err := middleware.CheckModelCurrency(m.Group, mc); err != nil {
+		return fmt.Errorf("the price of the model can not be charged: %w", err)
+	}
+
+	budget, err := model.GetExhaustedBudget(m.Group.ID, m.Token.Name, user)
+	if err != nil {
+		return err
+	}
+
+	if budget != nil {
+		return fmt.Errorf("%s %s budget exhausted", budget.Scope, budget.Window)
+	}
+
+	return middleware.CheckGroupModelRPMAndTPM(c, m.Group, mc, m.Token.Name)
+}
+
+// recordPluginResult bills the request made by a plugin to the group of the request
+func recordPluginResult(
+	c *gin.Context,
+	m *meta.Meta,
+	price model.Price,
+	result *controller.HandleResult,
+	pluginName string,
+) {
+	code := http.StatusOK
+
+	content := ""
+	if result.Error != nil {
+		code = result.Error.StatusCode()
+		respBody, _ := result.Error.MarshalJSON()
+		content = conv.BytesToString(respBody)
+	}
+
+	var postGroupConsumer balance.PostGroupConsumer
+	if gbc := middleware.GetGroupBalanceConsumerFromContext(c); gbc != nil {
+		postGroupConsumer = gbc.Consumer
+	}
+
+	detail := result.Detail
+	if detail == nil {
+		detail = &controller.RequestDetail{}
+	}
+
+	consume.AsyncConsume(
+		postGroupConsumer,
+		code,
+		detail.FirstByteAt,
+		detail.UpstreamRequestAt,
+		detail.UpstreamResponseAt,
+		m,
+		result.Usage,
+		price,
+		content,
+		c.ClientIP(),
+		0,
+		nil,
+		false,
+		middleware.GetRequestUser(c),
+		map[string]string{"plugin": pluginName},
+	)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/consume"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/adaptor/openai"
+	"github.com/wavespeed/llm-server/core/relay/controller"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+)
+
+func realtimeHandler(c *gin.Context, meta *meta.Meta) *controller.HandleResult {
+	meta.Set(openai.MetaRealtimeUsageHandler, realtimeUsageHandler(c, meta))
+	return relayHandler(c, meta, middleware.GetModelCaches(c))
+}
+
+// realtimeUsageHandler bills every response of a realtime session when it is
+// done, the session is closed when the group balance can not pay for the
+// session so far
+func realtimeUsageHandler(c *gin.Context, meta *meta.Meta) func(model.Usage) bool {
+	gbc := middleware.GetGroupBalanceConsumerFromContext(c)
+	price := middleware.GetModelConfig(c).Price
+	user := middleware.GetRequestUser(c)
+	metadata := middleware.GetRequestMetadata(c)
+	ip := c.ClientIP()
+
+	var spent float64
+
+	return func(usage model.Usage) bool {
+		consume.AsyncConsume(
+			gbc.Consumer,
+			http.StatusOK,
+			time.Time{},
+			time.Time{},
+			time.Time{},
+			meta,
+			usage,
+			price,
+			"",
+			ip,
+			0,
+			nil,
+			true,
+			user,
+			metadata,
+		)
+
+		spent += consume.ConvertAmount(
+			consume.CalculateAmount(http.StatusOK, usage, price),
+			price,
+			meta,
+		)
+
+		return gbc.CheckBalance(spent)
+	}
+}
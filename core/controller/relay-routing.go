@@ -0,0 +1,316 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/common/consume"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/monitor"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+)
+
+const (
+	// metaRoutingReason is why the channel of the meta was picked, it is
+	// stored in the metadata of the log
+	metaRoutingReason = "routing_reason"
+	// ttfbFailurePenalty is the ttfb sampled for a failed attempt without a
+	// request timeout
+	ttfbFailurePenalty = time.Minute
+)
+
+// channelRouter picks one of the available channels and tells why
+type channelRouter func(
+	r *channelRouting,
+	channels []*model.Channel,
+	errorRates map[int64]float64,
+) (*model.Channel, string)
+
+// channelRouters are the routers of the routing strategies, the strategies
+// without a router route by priority
+var channelRouters = map[model.RoutingStrategy]channelRouter{
+	model.RoutingStrategyPriority:           routeByPriority,
+	model.RoutingStrategyLowestCost:         routeByLowestCost,
+	model.RoutingStrategyLowestTTFB:         routeByLowestTTFB,
+	model.RoutingStrategyLeastInFlight:      routeByLeastInFlight,
+	model.RoutingStrategyWeightedRoundRobin: routeByWeightedRoundRobin,
+}
+
+// channelRouting is the routing of the requests of a model, it keeps the
+// reason of the last pick
+type channelRouting struct {
+	strategy model.RoutingStrategy
+	model    string
+	price    model.Price
+	reason   string
+}
+
+func newChannelRouting(modelName string, mc model.ModelConfig) *channelRouting {
+	strategy := mc.RoutingStrategy
+	if strategy == "" {
+		strategy = model.RoutingStrategyPriority
+	}
+
+	return &channelRouting{
+		strategy: strategy,
+		model:    modelName,
+		price:    mc.Price,
+	}
+}
+
+// pick picks a channel with the router of the strategy, a nil routing picks
+// by priority
+func (r *channelRouting) pick(
+	channels []*model.Channel,
+	errorRates map[int64]float64,
+) *model.Channel {
+	if r == nil {
+		channel, _ := routeByPriority(r, channels, errorRates)
+		return channel
+	}
+
+	router, ok := channelRouters[r.strategy]
+	if !ok {
+		router = routeByPriority
+	}
+
+	channel, reason := router(r, channels, errorRates)
+	r.reason = fmt.Sprintf("%s: channel %d, %s", r.strategy, channel.ID, reason)
+
+	return channel
+}
+
+// setMeta records the reason of the last pick on the meta
+func (r *channelRouting) setMeta(meta *meta.Meta) {
+	if r == nil || r.reason == "" {
+		return
+	}
+
+	meta.Set(metaRoutingReason, r.reason)
+}
+
+func getRoutingReason(meta *meta.Meta) string {
+	v, ok := meta.Get(metaRoutingReason)
+	if !ok {
+		return ""
+	}
+
+	reason, _ := v.(string)
+
+	return reason
+}
+
+func pickByPriority(channels []*model.Channel, errorRates map[int64]float64) *model.Channel {
+	if len(channels) == 1 {
+		return channels[0]
+	}
+
+	var totalWeight int32
+
+	cachedPrioritys := make([]int32, len(channels))
+	for i, ch := range channels {
+		priority := getPriority(ch, errorRates[int64(ch.ID)])
+		totalWeight += priority
+		cachedPrioritys[i] = priority
+	}
+
+	if totalWeight == 0 {
+		return channels[rand.IntN(len(channels))]
+	}
+
+	r := rand.Int32N(totalWeight)
+	for i, ch := range channels {
+		r -= cachedPrioritys[i]
+		if r < 0 {
+			return ch
+		}
+	}
+
+	return channels[rand.IntN(len(channels))]
+}
+
+func routeByPriority(
+	_ *channelRouting,
+	channels []*model.Channel,
+	errorRates map[int64]float64,
+) (*model.Channel, string) {
+	return pickByPriority(channels, errorRates), fmt.Sprintf(
+		"weighted by priority and error rate of %d channels",
+		len(channels),
+	)
+}
+
+// pickLowest picks the channel with the lowest score, the ties are picked by
+// priority
+func pickLowest(
+	channels []*model.Channel,
+	errorRates map[int64]float64,
+	score func(ch *model.Channel) float64,
+) (*model.Channel, float64) {
+	lowest := math.Inf(1)
+
+	var candidates []*model.Channel
+
+	for _, ch := range channels {
+		s := score(ch)
+
+		switch {
+		case s < lowest:
+			lowest = s
+			candidates = append(candidates[:0], ch)
+		case s == lowest:
+			candidates = append(candidates, ch)
+		}
+	}
+
+	return pickByPriority(candidates, errorRates), lowest
+}
+
+// routingCostUsage is the usage the costs of the channels are compared with
+var routingCostUsage = model.Usage{
+	InputTokens:  1000,
+	OutputTokens: 1000,
+}
+
+// channelCost is the cost of the routing usage on the channel in the default
+// currency, the channels without a cost are at the model price, the channels
+// without an exchange rate of their cost are the most expensive
+func (r *channelRouting) channelCost(ch *model.Channel) float64 {
+	channelMeta := meta.ChannelMeta{
+		Currency:   ch.Currency,
+		CostPrices: ch.CostPrices,
+		CostRatio:  ch.CostRatio,
+	}
+	if _, ok := ch.CostPrices[r.model]; !ok && ch.CostRatio <= 0 {
+		channelMeta.CostRatio = 1
+	}
+
+	cost, currency := consume.CalculateChannelCost(
+		http.StatusOK,
+		routingCostUsage,
+		r.model,
+		r.price,
+		channelMeta,
+	)
+
+	converted, err := model.ConvertCurrency(cost, currency, "", time.Now())
+	if err != nil {
+		return math.Inf(1)
+	}
+
+	return converted
+}
+
+func routeByLowestCost(
+	r *channelRouting,
+	channels []*model.Channel,
+	errorRates map[int64]float64,
+) (*model.Channel, string) {
+	channel, cost := pickLowest(channels, errorRates, r.channelCost)
+
+	return channel, fmt.Sprintf(
+		"lowest cost %s per 1k input and output tokens of %d channels",
+		strconv.FormatFloat(cost, 'f', -1, 64),
+		len(channels),
+	)
+}
+
+func routeByLowestTTFB(
+	r *channelRouting,
+	channels []*model.Channel,
+	errorRates map[int64]float64,
+) (*model.Channel, string) {
+	p50 := monitor.GetModelChannelTTFBP50(r.model)
+
+	// the channels without recent samples are tried first to sample them
+	var unsampled []*model.Channel
+
+	for _, ch := range channels {
+		if _, ok := p50[int64(ch.ID)]; !ok {
+			unsampled = append(unsampled, ch)
+		}
+	}
+
+	if len(unsampled) > 0 {
+		return pickByPriority(unsampled, errorRates), fmt.Sprintf(
+			"no recent ttfb of %d channels",
+			len(unsampled),
+		)
+	}
+
+	channel, ttfb := pickLowest(channels, errorRates, func(ch *model.Channel) float64 {
+		return float64(p50[int64(ch.ID)])
+	})
+
+	return channel, fmt.Sprintf(
+		"lowest p50 ttfb %s of %d channels",
+		time.Duration(ttfb).Round(time.Millisecond),
+		len(channels),
+	)
+}
+
+func routeByLeastInFlight(
+	_ *channelRouting,
+	channels []*model.Channel,
+	errorRates map[int64]float64,
+) (*model.Channel, string) {
+	channel, inFlight := pickLowest(channels, errorRates, func(ch *model.Channel) float64 {
+		return float64(monitor.GetInFlight(int64(ch.ID)))
+	})
+
+	return channel, fmt.Sprintf(
+		"least in flight %d of %d channels",
+		int64(inFlight),
+		len(channels),
+	)
+}
+
+// roundRobinWeights are the current weights of the smooth weighted round
+// robin of the channels of the models
+var roundRobinWeights = struct {
+	sync.Mutex
+	models map[string]map[int]int64
+}{
+	models: make(map[string]map[int]int64),
+}
+
+func routeByWeightedRoundRobin(
+	r *channelRouting,
+	channels []*model.Channel,
+	errorRates map[int64]float64,
+) (*model.Channel, string) {
+	roundRobinWeights.Lock()
+	defer roundRobinWeights.Unlock()
+
+	current, ok := roundRobinWeights.models[r.model]
+	if !ok {
+		current = make(map[int]int64)
+		roundRobinWeights.models[r.model] = current
+	}
+
+	var (
+		total    int64
+		selected *model.Channel
+		weight   int64
+	)
+
+	for _, ch := range channels {
+		w := max(int64(getPriority(ch, errorRates[int64(ch.ID)])), 1)
+		total += w
+		current[ch.ID] += w
+
+		if selected == nil || current[ch.ID] > current[selected.ID] {
+			selected = ch
+			weight = w
+		}
+	}
+
+	current[selected.ID] -= total
+
+	return selected, fmt.Sprintf("weight %d of %d", weight, total)
+}
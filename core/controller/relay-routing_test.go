@@ -0,0 +1,105 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/controller"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/monitor"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+)
+
+func TestRouteByLowestCost(t *testing.T) {
+	const modelName = "routing-cost-model"
+
+	routing := controller.NewChannelRouting(modelName, model.ModelConfig{
+		Price: model.Price{
+			InputPrice:  1,
+			OutputPrice: 1,
+		},
+	})
+
+	channels := []*model.Channel{
+		// at the model price
+		{ID: 1},
+		{ID: 2, CostRatio: 0.5},
+		{ID: 3, CostPrices: map[string]model.Price{
+			modelName: {InputPrice: 0.1, OutputPrice: 0.1},
+		}},
+		// no exchange rate of the currency, the most expensive
+		{ID: 4, CostPrices: map[string]model.Price{
+			modelName: {Currency: "XYZ", InputPrice: 0.01, OutputPrice: 0.01},
+		}},
+	}
+
+	channel, reason := controller.RouteByLowestCost(routing, channels, nil)
+	if channel.ID != 3 {
+		t.Fatalf("Expected channel 3, Got %d: %s", channel.ID, reason)
+	}
+}
+
+func TestRouteByLowestTTFB(t *testing.T) {
+	const modelName = "routing-ttfb-model"
+
+	routing := controller.NewChannelRouting(modelName, model.ModelConfig{})
+	channels := []*model.Channel{{ID: 101}, {ID: 102}}
+
+	monitor.AddTTFB(modelName, 101, 500*time.Millisecond)
+
+	channel, reason := controller.RouteByLowestTTFB(routing, channels, nil)
+	if channel.ID != 102 {
+		t.Fatalf("Expected the unsampled channel 102, Got %d: %s", channel.ID, reason)
+	}
+
+	// the failed attempt samples the penalty, so the channel is not probed
+	// again
+	controller.RecordChannelTTFBPenalty(&meta.Meta{
+		OriginModel: modelName,
+		Channel:     meta.ChannelMeta{ID: 102},
+	}, time.Second)
+
+	channel, reason = controller.RouteByLowestTTFB(routing, channels, nil)
+	if channel.ID != 101 {
+		t.Fatalf("Expected channel 101, Got %d: %s", channel.ID, reason)
+	}
+}
+
+func TestRouteByLeastInFlight(t *testing.T) {
+	routing := controller.NewChannelRouting("routing-in-flight-model", model.ModelConfig{})
+	channels := []*model.Channel{{ID: 201}, {ID: 202}, {ID: 203}}
+
+	done := []func(){
+		monitor.StartInFlight(201),
+		monitor.StartInFlight(201),
+		monitor.StartInFlight(202),
+		monitor.StartInFlight(203),
+		monitor.StartInFlight(203),
+	}
+
+	channel, reason := controller.RouteByLeastInFlight(routing, channels, nil)
+	if channel.ID != 202 {
+		t.Fatalf("Expected channel 202, Got %d: %s", channel.ID, reason)
+	}
+
+	for _, d := range done {
+		d()
+	}
+}
+
+func TestRouteByWeightedRoundRobin(t *testing.T) {
+	routing := controller.NewChannelRouting("routing-round-robin-model", model.ModelConfig{})
+	channels := []*model.Channel{
+		{ID: 301, Priority: 30},
+		{ID: 302, Priority: 10},
+	}
+
+	expected := []int{301, 301, 302, 301, 301, 301, 302, 301}
+
+	for i, id := range expected {
+		channel, reason := controller.RouteByWeightedRoundRobin(routing, channels, nil)
+		if channel.ID != id {
+			t.Fatalf("Expected channel %d at pick %d, Got %d: %s", id, i, channel.ID, reason)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/tiktoken"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/relay/adaptor/openai"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+)
+
+// TokenizeRequest is the text or the messages to tokenize, the input is a
+// string or a list of strings
+type TokenizeRequest struct {
+	Model    string               `json:"model"`
+	Input    any                  `json:"input,omitempty"`
+	Messages []relaymodel.Message `json:"messages,omitempty"`
+}
+
+type TokenizeResponse struct {
+	Model     string `json:"model"`
+	Tokenizer string `json:"tokenizer"`
+	Tokens    []uint `json:"tokens"`
+	Count     int    `json:"count"`
+}
+
+type CountTokensResponse struct {
+	Model       string `json:"model"`
+	Tokenizer   string `json:"tokenizer"`
+	InputTokens int64  `json:"input_tokens"`
+}
+
+func invalidTokenizeRequest(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"error": &relaymodel.OpenAIError{
+			Message: message,
+			Type:    "invalid_request_error",
+			Param:   "model",
+			Code:    code,
+		},
+	})
+}
+
+// bindTokenizeRequest binds the request and checks the model is available
+// for the token, the model of the request is resolved to the name of its
+// model config, the tokenizers are mapped by the real model names
+func bindTokenizeRequest(c *gin.Context) (*TokenizeRequest, bool) {
+	var req TokenizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidTokenizeRequest(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return nil, false
+	}
+
+	if req.Model == "" {
+		invalidTokenizeRequest(c, http.StatusBadRequest, "invalid_request", "model is required")
+		return nil, false
+	}
+
+	token := middleware.GetToken(c)
+
+	findModel := token.FindModel(req.Model)
+	if _, ok := middleware.GetModelCaches(c).EnabledModelConfigsMap[findModel]; !ok {
+		invalidTokenizeRequest(
+			c,
+			http.StatusNotFound,
+			"model_not_found",
+			fmt.Sprintf("the model '%s' does not exist", req.Model),
+		)
+
+		return nil, false
+	}
+
+	req.Model = findModel
+
+	return &req, true
+}
+
+// Tokenize godoc
+//
+//	@Summary		Tokenize
+//	@Description	Returns the tokens of the input with the tokenizer of the model
+//	@Tags			relay
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		TokenizeRequest	true	"Request"
+//	@Success		200		{object}	TokenizeResponse
+//	@Router			/v1/tokenize [post]
+func Tokenize(c *gin.Context) {
+	req, ok := bindTokenizeRequest(c)
+	if !ok {
+		return
+	}
+
+	var text string
+	switch input := req.Input.(type) {
+	case string:
+		text = input
+	case nil:
+	default:
+		invalidTokenizeRequest(c, http.StatusBadRequest, "invalid_request", "input must be a string")
+		return
+	}
+
+	encoder := tiktoken.GetTokenEncoder(req.Model)
+
+	tokens, _, err := encoder.Encode(text)
+	if err != nil {
+		invalidTokenizeRequest(c, http.StatusInternalServerError, "tokenize_failed", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, &TokenizeResponse{
+		Model:     req.Model,
+		Tokenizer: encoder.GetName(),
+		Tokens:    tokens,
+		Count:     len(tokens),
+	})
+}
+
+// CountTokens godoc
+//
+//	@Summary		Count tokens
+//	@Description	Counts the prompt tokens of the input or the messages with the tokenizer of the model
+//	@Tags			relay
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		TokenizeRequest	true	"Request"
+//	@Success		200		{object}	CountTokensResponse
+//	@Router			/v1/count_tokens [post]
+func CountTokens(c *gin.Context) {
+	req, ok := bindTokenizeRequest(c)
+	if !ok {
+		return
+	}
+
+	var count int64
+	if len(req.Messages) > 0 {
+		count = openai.CountTokenMessages(req.Messages, req.Model)
+	}
+
+	if req.Input != nil {
+		count += openai.CountTokenInput(req.Input, req.Model)
+	}
+
+	c.JSON(http.StatusOK, &CountTokensResponse{
+		Model:       req.Model,
+		Tokenizer:   tiktoken.GetTokenEncoder(req.Model).GetName(),
+		InputTokens: count,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wavespeed/llm-server/core/middleware"
 	"github.com/wavespeed/llm-server/core/relay/mode"
+	"github.com/wavespeed/llm-server/core/relay/utils"
 	// relay model used by swagger
 	_ "github.com/wavespeed/llm-server/core/relay/model"
 )
@@ -56,6 +57,41 @@ func Anthropic() []gin.HandlerFunc {
 	}
 }
 
+// Realtime godoc
+//
+//	@Summary		Realtime
+//	@Description	Upgrades to a websocket proxied to the realtime api of the channel, every response is billed when it is done
+//	@Tags			relay
+//	@Security		ApiKeyAuth
+//	@Param			model			query		string	true	"Model"
+//	@Param			Aiproxy-Channel	header		string	false	"Optional Aiproxy-Channel header"
+//	@Success		101
+//	@Router			/v1/realtime [get]
+func Realtime() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewDistribute(mode.Realtime),
+		NewRelay(mode.Realtime),
+	}
+}
+
+// AnthropicCountTokens godoc
+//
+//	@Summary		AnthropicCountTokens
+//	@Description	Counts the input tokens of a messages request, it is counted locally when no channel supports it and not billed
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request			body		model.AnthropicMessageRequest	true	"Request"
+//	@Param			Aiproxy-Channel	header		string							false	"Optional Aiproxy-Channel header"
+//	@Success		200				{object}	object{input_tokens=int}
+//	@Router			/v1/messages/count_tokens [post]
+func AnthropicCountTokens() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewDistribute(mode.AnthropicCountTokens),
+		NewRelay(mode.AnthropicCountTokens),
+	}
+}
+
 // ChatCompletions godoc
 //
 //	@Summary		ChatCompletions
@@ -76,6 +112,7 @@ func Anthropic() []gin.HandlerFunc {
 func ChatCompletions() []gin.HandlerFunc {
 	return []gin.HandlerFunc{
 		middleware.NewDistribute(mode.ChatCompletions),
+		MCPTools(mode.ChatCompletions),
 		NewRelay(mode.ChatCompletions),
 	}
 }
@@ -376,6 +413,7 @@ func VideoGenerationsContent() []gin.HandlerFunc {
 func CreateResponse() []gin.HandlerFunc {
 	return []gin.HandlerFunc{
 		middleware.NewDistribute(mode.Responses),
+		MCPTools(mode.Responses),
 		NewRelay(mode.Responses),
 	}
 }
@@ -473,8 +511,137 @@ func GetResponseInputItems() []gin.HandlerFunc {
 //	@Router			/{version}/models/{model} [post]
 
 func Gemini() []gin.HandlerFunc {
+	distribute := middleware.NewDistribute(mode.Gemini)
+	relay := NewRelay(mode.Gemini)
+	countTokensDistribute := middleware.NewDistribute(mode.GeminiCountTokens)
+	countTokensRelay := NewRelay(mode.GeminiCountTokens)
+
+	// the actions share the model path, :countTokens is not billed
+	return []gin.HandlerFunc{
+		func(c *gin.Context) {
+			if utils.IsGeminiCountTokensRequest(c.Request.URL.Path) {
+				countTokensDistribute(c)
+			} else {
+				distribute(c)
+			}
+		},
+		func(c *gin.Context) {
+			if utils.IsGeminiCountTokensRequest(c.Request.URL.Path) {
+				countTokensRelay(c)
+			} else {
+				relay(c)
+			}
+		},
+	}
+}
+
+// CreateFineTuningJob godoc
+//
+//	@Summary		Create fine-tuning job
+//	@Description	Create a fine-tuning job on an upstream channel, the job is billed by trained tokens once it succeeded
+//	@Tags			relay
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request			body		model.CreateFineTuningJobRequest	true	"Request"
+//	@Param			Aiproxy-Channel	header		string								false	"Optional Aiproxy-Channel header"
+//	@Success		200				{object}	model.FineTuningJob
+//	@Router			/v1/fine_tuning/jobs [post]
+func CreateFineTuningJob() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewDistribute(mode.FineTuningJobs),
+		NewRelay(mode.FineTuningJobs),
+	}
+}
+
+// ListFineTuningJobs godoc
+//
+//	@Summary		List fine-tuning jobs
+//	@Description	List fine-tuning jobs of the upstream channel selected by model, or of the latest job of the token without model, the succeeded jobs of the group pinned to the model are billed the first time they are seen
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			model			query		string	false	"Model used to select the upstream channel"
+//	@Param			after			query		string	false	"Cursor job id"
+//	@Param			limit			query		int		false	"Limit"
+//	@Param			Aiproxy-Channel	header		string	false	"Optional Aiproxy-Channel header"
+//	@Success		200				{object}	model.FineTuningJobList
+//	@Router			/v1/fine_tuning/jobs [get]
+func ListFineTuningJobs() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewDistribute(mode.FineTuningJobsList),
+		NewRelay(mode.FineTuningJobsList),
+	}
+}
+
+// RetrieveFineTuningJob godoc
+//
+//	@Summary		Retrieve fine-tuning job
+//	@Description	Retrieve a fine-tuning job by ID, the trained tokens are billed the first time the job is seen succeeded
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		string	true	"Fine-tuning job ID"
+//	@Success		200	{object}	model.FineTuningJob
+//	@Router			/v1/fine_tuning/jobs/{id} [get]
+func RetrieveFineTuningJob() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewDistribute(mode.FineTuningJobsGet),
+		NewRelay(mode.FineTuningJobsGet),
+	}
+}
+
+// CancelFineTuningJob godoc
+//
+//	@Summary		Cancel fine-tuning job
+//	@Description	Cancel a fine-tuning job by ID
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		string	true	"Fine-tuning job ID"
+//	@Success		200	{object}	model.FineTuningJob
+//	@Router			/v1/fine_tuning/jobs/{id}/cancel [post]
+func CancelFineTuningJob() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewDistribute(mode.FineTuningJobsCancel),
+		NewRelay(mode.FineTuningJobsCancel),
+	}
+}
+
+// ListFineTuningJobEvents godoc
+//
+//	@Summary		List fine-tuning job events
+//	@Description	List the events of a fine-tuning job
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id		path		string	true	"Fine-tuning job ID"
+//	@Param			after	query		string	false	"Cursor event id"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	object
+//	@Router			/v1/fine_tuning/jobs/{id}/events [get]
+func ListFineTuningJobEvents() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.NewDistribute(mode.FineTuningJobsEvents),
+		NewRelay(mode.FineTuningJobsEvents),
+	}
+}
+
+// ListFineTuningJobCheckpoints godoc
+//
+//	@Summary		List fine-tuning job checkpoints
+//	@Description	List the checkpoints of a fine-tuning job
+//	@Tags			relay
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id		path		string	true	"Fine-tuning job ID"
+//	@Param			after	query		string	false	"Cursor checkpoint id"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	object
+//	@Router			/v1/fine_tuning/jobs/{id}/checkpoints [get]
+func ListFineTuningJobCheckpoints() []gin.HandlerFunc {
 	return []gin.HandlerFunc{
-		middleware.NewDistribute(mode.Gemini),
-		NewRelay(mode.Gemini),
+		middleware.NewDistribute(mode.FineTuningJobsCheckpoints),
+		NewRelay(mode.FineTuningJobsCheckpoints),
 	}
 }
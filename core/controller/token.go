@@ -38,13 +38,14 @@ func (t *TokenResponse) MarshalJSON() ([]byte, error) {
 
 type (
 	AddTokenRequest struct {
-		Name                 string   `json:"name"`
-		Subnets              []string `json:"subnets"`
-		Models               []string `json:"models"`
-		Quota                float64  `json:"quota"`
-		PeriodQuota          float64  `json:"period_quota"`
-		PeriodType           string   `json:"period_type"`
-		PeriodLastUpdateTime int64    `json:"period_last_update_time"`
+		Name                 string            `json:"name"`
+		Subnets              []string          `json:"subnets"`
+		Models               []string          `json:"models"`
+		MCPToolACLs          model.MCPToolACLs `json:"mcp_tool_acls"`
+		Quota                float64           `json:"quota"`
+		PeriodQuota          float64           `json:"period_quota"`
+		PeriodType           string            `json:"period_type"`
+		PeriodLastUpdateTime int64             `json:"period_last_update_time"`
 	}
 
 	UpdateTokenStatusRequest struct {
@@ -61,6 +62,7 @@ func (at *AddTokenRequest) ToToken() *model.Token {
 		Name:        model.EmptyNullString(at.Name),
 		Subnets:     at.Subnets,
 		Models:      at.Models,
+		MCPToolACLs: at.MCPToolACLs,
 		Quota:       at.Quota,
 		PeriodQuota: at.PeriodQuota,
 		PeriodType:  model.EmptyNullString(at.PeriodType),
@@ -78,6 +80,10 @@ func validateToken(token AddTokenRequest) error {
 		return fmt.Errorf("invalid subnet: %w", err)
 	}
 
+	if err := token.MCPToolACLs.Validate(); err != nil {
+		return fmt.Errorf("invalid mcp tool acls: %w", err)
+	}
+
 	return nil
 }
 
@@ -126,10 +132,9 @@ func GetTokens(c *gin.Context) {
 	order := c.Query("order")
 	status, _ := strconv.Atoi(c.Query("status"))
 
-	// Regular users can only view their own tokens
-	if !middleware.IsAdmin(c) {
-		token := middleware.GetToken(c)
-		group = token.Group
+	// Scoped callers can only view the tokens of their group
+	if forcedGroup, ok := middleware.GetForcedGroup(c); ok {
+		group = forcedGroup
 	}
 
 	tokens, total, err := model.GetTokens(group, page, perPage, order, status)
@@ -207,10 +212,9 @@ func SearchTokens(c *gin.Context) {
 	status, _ := strconv.Atoi(c.Query("status"))
 	group := c.Query("group")
 
-	// Regular users can only search their own tokens
-	if !middleware.IsAdmin(c) {
-		token := middleware.GetToken(c)
-		group = token.Group
+	// Scoped callers can only search the tokens of their group
+	if forcedGroup, ok := middleware.GetForcedGroup(c); ok {
+		group = forcedGroup
 	}
 
 	tokens, total, err := model.SearchTokens(
@@ -309,6 +313,11 @@ func GetToken(c *gin.Context) {
 		return
 	}
 
+	if forcedGroup, ok := middleware.GetForcedGroup(c); ok && token.GroupID != forcedGroup {
+		middleware.ErrorResponse(c, http.StatusNotFound, "token not found")
+		return
+	}
+
 	middleware.SuccessResponse(c, buildTokenResponse(token))
 }
 
@@ -523,6 +532,13 @@ func UpdateToken(c *gin.Context) {
 		}
 	}
 
+	if req.MCPToolACLs != nil {
+		if err := req.MCPToolACLs.Validate(); err != nil {
+			middleware.ErrorResponse(c, http.StatusBadRequest, "parameter error: "+err.Error())
+			return
+		}
+	}
+
 	token, err := model.UpdateToken(id, req)
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
@@ -567,6 +583,13 @@ func UpdateGroupToken(c *gin.Context) {
 		}
 	}
 
+	if req.MCPToolACLs != nil {
+		if err := req.MCPToolACLs.Validate(); err != nil {
+			middleware.ErrorResponse(c, http.StatusBadRequest, "parameter error: "+err.Error())
+			return
+		}
+	}
+
 	token, err := model.UpdateGroupToken(id, group, req)
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
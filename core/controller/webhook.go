@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/controller/utils"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+	"gorm.io/gorm"
+)
+
+func webhookErrorStatus(err error) int {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+// GetWebhookSubscriptions godoc
+//
+//	@Summary		Get webhook subscriptions
+//	@Description	Returns the webhook subscriptions of a group
+//	@Tags			webhook
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string	true	"Group ID"
+//	@Success		200		{object}	middleware.APIResponse{data=[]model.WebhookSubscription}
+//	@Router			/api/webhook/{group}/subscriptions [get]
+func GetWebhookSubscriptions(c *gin.Context) {
+	subscriptions, err := model.GetWebhookSubscriptions(c.Param("group"))
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, subscriptions)
+}
+
+// GetWebhookSubscription godoc
+//
+//	@Summary		Get a webhook subscription
+//	@Description	Returns a webhook subscription of a group
+//	@Tags			webhook
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string	true	"Group ID"
+//	@Param			id		path		int		true	"Subscription ID"
+//	@Success		200		{object}	middleware.APIResponse{data=model.WebhookSubscription}
+//	@Router			/api/webhook/{group}/subscription/{id} [get]
+func GetWebhookSubscription(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	subscription, err := model.GetWebhookSubscription(c.Param("group"), id)
+	if err != nil {
+		middleware.ErrorResponse(c, webhookErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, subscription)
+}
+
+type SaveWebhookSubscriptionRequest struct {
+	URL        string                          `json:"url"`
+	Secret     string                          `json:"secret"`
+	Events     []model.WebhookEvent            `json:"events"`
+	TokenNames []string                        `json:"token_names"`
+	Status     model.WebhookSubscriptionStatus `json:"status"`
+}
+
+func (r *SaveWebhookSubscriptionRequest) subscription(
+	group string,
+	id int,
+) *model.WebhookSubscription {
+	return &model.WebhookSubscription{
+		ID:         id,
+		GroupID:    group,
+		URL:        r.URL,
+		Secret:     r.Secret,
+		Events:     r.Events,
+		TokenNames: r.TokenNames,
+		Status:     r.Status,
+	}
+}
+
+// CreateWebhookSubscription godoc
+//
+//	@Summary		Create a webhook subscription
+//	@Description	Subscribes a webhook url to the events of a group, a secret is generated when it is empty
+//	@Tags			webhook
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group			path		string							true	"Group ID"
+//	@Param			subscription	body		SaveWebhookSubscriptionRequest	true	"Subscription"
+//	@Success		200				{object}	middleware.APIResponse{data=model.WebhookSubscription}
+//	@Router			/api/webhook/{group}/subscriptions [post]
+func CreateWebhookSubscription(c *gin.Context) {
+	var req SaveWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	subscription := req.subscription(c.Param("group"), 0)
+	if err := model.CreateWebhookSubscription(subscription); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, subscription)
+}
+
+// UpdateWebhookSubscription godoc
+//
+//	@Summary		Update a webhook subscription
+//	@Description	Updates a webhook subscription, an empty secret keeps the current one
+//	@Tags			webhook
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group			path		string							true	"Group ID"
+//	@Param			id				path		int								true	"Subscription ID"
+//	@Param			subscription	body		SaveWebhookSubscriptionRequest	true	"Subscription"
+//	@Success		200				{object}	middleware.APIResponse{data=model.WebhookSubscription}
+//	@Router			/api/webhook/{group}/subscription/{id} [put]
+func UpdateWebhookSubscription(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req SaveWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	group := c.Param("group")
+	if err := model.UpdateWebhookSubscription(req.subscription(group, id)); err != nil {
+		middleware.ErrorResponse(c, webhookErrorStatus(err), err.Error())
+		return
+	}
+
+	subscription, err := model.GetWebhookSubscription(group, id)
+	if err != nil {
+		middleware.ErrorResponse(c, webhookErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, subscription)
+}
+
+// DeleteWebhookSubscription godoc
+//
+//	@Summary		Delete a webhook subscription
+//	@Description	Deletes a webhook subscription, its pending deliveries are failed
+//	@Tags			webhook
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string	true	"Group ID"
+//	@Param			id		path		int		true	"Subscription ID"
+//	@Success		200		{object}	middleware.APIResponse
+//	@Router			/api/webhook/{group}/subscription/{id} [delete]
+func DeleteWebhookSubscription(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := model.DeleteWebhookSubscription(c.Param("group"), id); err != nil {
+		middleware.ErrorResponse(c, webhookErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, nil)
+}
+
+// GetWebhookDeliveries godoc
+//
+//	@Summary		Get webhook deliveries
+//	@Description	Returns the delivery log of the webhooks of a group, newest first
+//	@Tags			webhook
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group			path		string	true	"Group ID"
+//	@Param			subscription_id	query		int		false	"Subscription ID"
+//	@Param			event			query		string	false	"Event"
+//	@Param			status			query		string	false	"Status, pending, success or failed"
+//	@Param			page			query		int		false	"Page number"
+//	@Param			per_page		query		int		false	"Items per page"
+//	@Success		200				{object}	middleware.APIResponse{data=map[string]any{deliveries=[]model.WebhookDelivery,total=int}}
+//	@Router			/api/webhook/{group}/deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	page, perPage := utils.ParsePageParams(c)
+	subscriptionID, _ := strconv.Atoi(c.Query("subscription_id"))
+
+	deliveries, total, err := model.GetWebhookDeliveries(
+		c.Param("group"),
+		subscriptionID,
+		model.WebhookEvent(c.Query("event")),
+		model.WebhookDeliveryStatus(c.Query("status")),
+		page,
+		perPage,
+	)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+	})
+}
+
+// RedeliverWebhookDelivery godoc
+//
+//	@Summary		Redeliver a webhook delivery
+//	@Description	Queues a delivery again, it is sent by the retry task with fresh attempts
+//	@Tags			webhook
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			group	path		string	true	"Group ID"
+//	@Param			id		path		int		true	"Delivery ID"
+//	@Success		200		{object}	middleware.APIResponse
+//	@Router			/api/webhook/{group}/delivery/{id}/redeliver [post]
+func RedeliverWebhookDelivery(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := model.RedeliverWebhookDelivery(c.Param("group"), id); err != nil {
+		middleware.ErrorResponse(c, webhookErrorStatus(err), err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(c, nil)
+}
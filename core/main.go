@@ -13,6 +13,7 @@ import (
 	"github.com/wavespeed/llm-server/core/common/config"
 	"github.com/wavespeed/llm-server/core/common/consume"
 	"github.com/wavespeed/llm-server/core/controller"
+	"github.com/wavespeed/llm-server/core/mcpstdio"
 	"github.com/wavespeed/llm-server/core/model"
 	"github.com/wavespeed/llm-server/core/task"
 	log "github.com/sirupsen/logrus"
@@ -54,6 +55,8 @@ func main() {
 		log.Fatal("failed to initialize services: " + err.Error())
 	}
 
+	shutdownTracing := initializeTracing()
+
 	defer func() {
 		if err := model.CloseDB(); err != nil {
 			log.Fatal("failed to close database: " + err.Error())
@@ -84,6 +87,18 @@ func main() {
 
 	go task.UsageAlertTask(ctx)
 
+	log.Info("batch task started")
+
+	go task.BatchTask(ctx)
+
+	log.Info("webhook retry task started")
+
+	go task.WebhookRetryTask(ctx)
+
+	log.Info("fine-tuning task started")
+
+	go task.FineTuningTask(ctx)
+
 	log.Info("update channels balance task started")
 
 	go controller.UpdateChannelsBalance(time.Minute * 10)
@@ -113,6 +128,12 @@ func main() {
 		log.Info("server shutdown successfully")
 	}
 
+	log.Info("shutting down stdio mcp processes...")
+	mcpstdio.Close()
+
+	log.Info("shutting down batches...")
+	controller.WaitBatches()
+
 	log.Info("shutting down consumer...")
 	consume.Wait()
 
@@ -129,5 +150,8 @@ func main() {
 
 	model.CleanBatchUpdatesSummary(cleanCtx)
 
+	log.Info("shutting down tracing...")
+	shutdownTracing(cleanCtx)
+
 	log.Info("server exiting")
 }
@@ -2,12 +2,15 @@ package mcpproxy
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/wavespeed/llm-server/core/common/tracing"
 )
 
 const (
@@ -19,6 +22,18 @@ type StreamableProxy struct {
 	store   SessionManager
 	backend string
 	headers map[string]string
+	filter  func(message []byte) []byte
+}
+
+// StreamableProxyOption defines a function type for configuring StreamableProxy
+type StreamableProxyOption func(*StreamableProxy)
+
+// WithMessageFilter rewrites the json-rpc messages responded by the backend
+// to the posted requests
+func WithMessageFilter(filter func(message []byte) []byte) StreamableProxyOption {
+	return func(p *StreamableProxy) {
+		p.filter = filter
+	}
 }
 
 // NewStreamableProxy creates a new proxy for the Streamable HTTP transport
@@ -26,12 +41,59 @@ func NewStreamableProxy(
 	backend string,
 	headers map[string]string,
 	store SessionManager,
+	opts ...StreamableProxyOption,
 ) *StreamableProxy {
-	return &StreamableProxy{
+	p := &StreamableProxy{
 		store:   store,
 		backend: backend,
 		headers: headers,
 	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// filterEvent rewrites the message in the data line of the sse event
+func (p *StreamableProxy) filterEvent(line string) string {
+	if p.filter == nil {
+		return line
+	}
+
+	data, ok := strings.CutPrefix(line, "data:")
+	if !ok {
+		return line
+	}
+
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return line
+	}
+
+	return "data: " + string(p.filter([]byte(data))) + "\n"
+}
+
+// copyBody copies the json response of the backend, its message is
+// rewritten by the filter
+func (p *StreamableProxy) copyBody(w io.Writer, body io.Reader) {
+	if p.filter == nil {
+		_, _ = io.Copy(w, body)
+		return
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		_, _ = w.Write(data)
+		return
+	}
+
+	_, _ = w.Write(p.filter(data))
 }
 
 // ServeHTTP handles both GET and POST requests for the Streamable HTTP transport
@@ -104,6 +166,8 @@ func (p *StreamableProxy) handleGetRequest(w http.ResponseWriter, r *http.Reques
 
 	req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
 
+	tracing.Inject(req.Context(), req.Header)
+
 	//nolint:bodyclose
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -206,6 +270,8 @@ func (p *StreamableProxy) handlePostRequest(w http.ResponseWriter, r *http.Reque
 	req.Header.Set("Accept", "application/json, text/event-stream")
 	req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
 
+	tracing.Inject(req.Context(), req.Header)
+
 	//nolint:bodyclose
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -255,13 +321,13 @@ func (p *StreamableProxy) handlePostRequest(w http.ResponseWriter, r *http.Reque
 			}
 
 			// Write the line to the client
-			_, _ = fmt.Fprint(w, line)
+			_, _ = fmt.Fprint(w, p.filterEvent(line))
 
 			flusher.Flush()
 		}
 	} else {
 		// Copy regular response body
-		_, _ = io.Copy(w, resp.Body)
+		p.copyBody(w, resp.Body)
 	}
 }
 
@@ -304,6 +370,8 @@ func (p *StreamableProxy) handleDeleteRequest(w http.ResponseWriter, r *http.Req
 		Timeout: time.Second * 10,
 	}
 
+	tracing.Inject(req.Context(), req.Header)
+
 	resp, err := client.Do(req)
 	if err != nil {
 		http.Error(w, "Failed to connect to backend", http.StatusInternalServerError)
@@ -341,6 +409,8 @@ func (p *StreamableProxy) proxyInitialOrNoSessionRequest(w http.ResponseWriter,
 	req.Header.Set("Accept", "application/json, text/event-stream")
 	req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
 
+	tracing.Inject(req.Context(), req.Header)
+
 	//nolint:bodyclose
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -403,11 +473,11 @@ func (p *StreamableProxy) proxyInitialOrNoSessionRequest(w http.ResponseWriter,
 			}
 
 			// Write the line to the client
-			fmt.Fprint(w, line)
+			fmt.Fprint(w, p.filterEvent(line))
 			flusher.Flush()
 		}
 	} else {
 		// Copy regular response body
-		_, _ = io.Copy(w, resp.Body)
+		p.copyBody(w, resp.Body)
 	}
 }
@@ -0,0 +1,308 @@
+package mcpstdio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+const (
+	defaultCallTimeout = time.Minute
+	defaultIdleTimeout = 5 * time.Minute
+	defaultProcesses   = 2
+	defaultCPUSeconds  = 600
+	defaultMemoryMB    = 4096
+	defaultOpenFiles   = 1024
+	// processWaitDelay bounds the wait of the pipes of a killed process
+	// kept open by its children
+	processWaitDelay = 5 * time.Second
+)
+
+// baseEnv is the env of the gateway passed to the processes, the other env
+// of the gateway is never leaked to the commands
+var baseEnv = []string{
+	"PATH",
+	"HOME",
+	"USER",
+	"LANG",
+	"LC_ALL",
+	"TZ",
+	"TMPDIR",
+}
+
+// Command is the command run by the processes of a pool
+type Command struct {
+	Command string
+	Args    []string
+	// Env is the env of the processes as KEY=VALUE
+	Env    []string
+	Limits model.MCPStdioLimits
+	// Dir is the working directory of the processes
+	Dir string
+}
+
+// NewCommand builds the command of the stdio mcp config run in the dir, the
+// params are set as env vars and the ${NAME} in the env and the args are
+// expanded, the path params are resolved in the dir
+func NewCommand(
+	config *model.MCPStdioConfig,
+	params map[string]string,
+	dir string,
+) (Command, error) {
+	if config == nil || config.Command == "" {
+		return Command{}, errors.New("stdio command is empty")
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return Command{}, err
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return Command{}, err
+	}
+
+	params, err = resolvePaths(config, params, dir)
+	if err != nil {
+		return Command{}, err
+	}
+
+	vars := make(map[string]string, len(baseEnv)+len(params)+len(config.Env))
+	for _, key := range baseEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			vars[key] = value
+		}
+	}
+
+	for key, value := range params {
+		vars[key] = value
+	}
+
+	expand := func(s string) string {
+		return os.Expand(s, func(key string) string {
+			return vars[key]
+		})
+	}
+
+	configEnv := make(map[string]string, len(config.Env))
+	for key, value := range config.Env {
+		configEnv[key] = expand(value)
+	}
+
+	for key, value := range configEnv {
+		vars[key] = value
+	}
+
+	args := make([]string, len(config.Args))
+	for i, arg := range config.Args {
+		args[i] = expand(arg)
+	}
+
+	env := make([]string, 0, len(vars))
+	for key, value := range vars {
+		env = append(env, key+"="+value)
+	}
+
+	slices.Sort(env)
+
+	return Command{
+		Command: expand(config.Command),
+		Args:    args,
+		Env:     env,
+		Limits:  config.Limits,
+		Dir:     dir,
+	}, nil
+}
+
+// resolvePaths returns the params with the path params resolved in the dir,
+// the directories and the parent directories of the files are created
+func resolvePaths(
+	config *model.MCPStdioConfig,
+	params map[string]string,
+	dir string,
+) (map[string]string, error) {
+	if len(config.Dirs) == 0 && len(config.Files) == 0 {
+		return params, nil
+	}
+
+	resolved := make(map[string]string, len(params))
+	for key, value := range params {
+		resolved[key] = value
+	}
+
+	for _, name := range config.Dirs {
+		path, err := ResolvePath(dir, params[name], true)
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", name, err)
+		}
+
+		resolved[name] = path
+	}
+
+	for _, name := range config.Files {
+		path, err := ResolvePath(dir, params[name], false)
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", name, err)
+		}
+
+		resolved[name] = path
+	}
+
+	return resolved, nil
+}
+
+// ResolvePath returns the path in the dir, the absolute paths, the paths
+// with .. and the symlinks leaving the dir are rejected, the directory of
+// the path, or its parent for a file, is created
+func ResolvePath(dir, path string, isDir bool) (string, error) {
+	if filepath.IsAbs(path) ||
+		slices.Contains(strings.Split(filepath.ToSlash(path), "/"), "..") {
+		return "", fmt.Errorf("path %q must be relative and inside the directory of the group", path)
+	}
+
+	path = filepath.Clean(path)
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("path %q must be relative and inside the directory of the group", path)
+	}
+
+	resolved := filepath.Join(dir, path)
+
+	parent := resolved
+	if !isDir {
+		parent = filepath.Dir(resolved)
+	}
+
+	if err := os.MkdirAll(parent, 0o750); err != nil {
+		return "", err
+	}
+
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+
+	real, err := filepath.EvalSymlinks(resolved)
+	if errors.Is(err, os.ErrNotExist) {
+		real, err = filepath.EvalSymlinks(parent)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if rel, err := filepath.Rel(realDir, real); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %q leaves the directory of the group", path)
+	}
+
+	return resolved, nil
+}
+
+// hash identifies the command in the pools, the processes of a changed
+// command are never reused
+func (c Command) hash() string {
+	data, _ := sonic.Marshal(c)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:8])
+}
+
+func (c Command) callTimeout() time.Duration {
+	if c.Limits.CallTimeoutSeconds > 0 {
+		return time.Duration(c.Limits.CallTimeoutSeconds) * time.Second
+	}
+
+	return defaultCallTimeout
+}
+
+func (c Command) idleTimeout() time.Duration {
+	if c.Limits.IdleTimeoutSeconds > 0 {
+		return time.Duration(c.Limits.IdleTimeoutSeconds) * time.Second
+	}
+
+	return defaultIdleTimeout
+}
+
+func (c Command) maxProcesses() int {
+	if c.Limits.MaxProcesses > 0 {
+		return c.Limits.MaxProcesses
+	}
+
+	return defaultProcesses
+}
+
+// DefaultLimits returns the limits with the defaults of the unset cpu,
+// memory and open files limits, the processes are never run unbounded by
+// default
+func DefaultLimits(limits model.MCPStdioLimits) model.MCPStdioLimits {
+	if limits.CPUSeconds == 0 {
+		limits.CPUSeconds = defaultCPUSeconds
+	}
+
+	if limits.MemoryMB == 0 {
+		limits.MemoryMB = defaultMemoryMB
+	}
+
+	if limits.OpenFiles == 0 {
+		limits.OpenFiles = defaultOpenFiles
+	}
+
+	return limits
+}
+
+// LimitScript returns the shell script setting the cpu, memory and open
+// files limits of the command, the script execs the command given as its
+// arguments, it is empty without limits
+func LimitScript(limits model.MCPStdioLimits) string {
+	var ulimits []string
+	if limits.CPUSeconds > 0 {
+		ulimits = append(ulimits, "ulimit -t "+strconv.FormatInt(limits.CPUSeconds, 10))
+	}
+
+	if limits.MemoryMB > 0 {
+		ulimits = append(ulimits, "ulimit -v "+strconv.FormatInt(limits.MemoryMB*1024, 10))
+	}
+
+	if limits.OpenFiles > 0 {
+		ulimits = append(ulimits, "ulimit -n "+strconv.FormatInt(limits.OpenFiles, 10))
+	}
+
+	if len(ulimits) == 0 {
+		return ""
+	}
+
+	return strings.Join(ulimits, " && ") + ` && exec "$0" "$@"`
+}
+
+// exec builds the process of the command, the limits are set by a shell
+// execing the command
+func (c Command) exec(
+	ctx context.Context,
+	command string,
+	env []string,
+	args []string,
+) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	if script := LimitScript(DefaultLimits(c.Limits)); script != "" {
+		cmd = exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", script, command}, args...)...)
+	} else {
+		cmd = exec.CommandContext(ctx, command, args...)
+	}
+
+	cmd.Env = env
+	cmd.Dir = c.Dir
+	cmd.WaitDelay = processWaitDelay
+
+	return cmd, nil
+}
@@ -0,0 +1,202 @@
+package mcpstdio_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wavespeed/llm-server/core/common/config"
+	"github.com/wavespeed/llm-server/core/mcpstdio"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testServerEnv = "MCPSTDIO_TEST_SERVER"
+
+// TestMain runs the test binary as a stdio mcp server echoing the env and
+// the method of the requests
+func TestMain(m *testing.M) {
+	if os.Getenv(testServerEnv) == "" {
+		os.Exit(m.Run())
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || req.ID == nil {
+			continue
+		}
+
+		result, _ := json.Marshal(map[string]any{
+			"method": req.Method,
+			"secret": os.Getenv("SECRET"),
+			"token":  os.Getenv("TOKEN"),
+		})
+		fmt.Printf(`{"jsonrpc":"2.0","id":%s,"result":%s}`+"\n", req.ID, result)
+	}
+
+	os.Exit(0)
+}
+
+func TestNewCommand(t *testing.T) {
+	t.Setenv("SECRET", "gateway")
+
+	cmd, err := mcpstdio.NewCommand(&model.MCPStdioConfig{
+		Command: "server",
+		Args:    []string{"--token", "${TOKEN}", "--dir", "${DIR}"},
+		Env:     map[string]string{"DIR": "/data/${TOKEN}"},
+	}, map[string]string{"TOKEN": "group"}, t.TempDir())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"--token", "group", "--dir", "/data/group"}, cmd.Args)
+	assert.Contains(t, cmd.Env, "TOKEN=group")
+	assert.Contains(t, cmd.Env, "DIR=/data/group")
+
+	for _, env := range cmd.Env {
+		assert.False(t, strings.HasPrefix(env, "SECRET="), "gateway env leaked: %s", env)
+	}
+
+	_, err = mcpstdio.NewCommand(&model.MCPStdioConfig{}, nil, t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestNewCommandPaths(t *testing.T) {
+	dir := t.TempDir()
+	stdioConfig := &model.MCPStdioConfig{
+		Command: "server",
+		Args:    []string{"${ROOT}", "${DB}"},
+		Dirs:    []string{"ROOT"},
+		Files:   []string{"DB"},
+	}
+
+	cmd, err := mcpstdio.NewCommand(
+		stdioConfig,
+		map[string]string{"ROOT": "docs", "DB": "data/app.db"},
+		dir,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "docs"),
+		filepath.Join(dir, "data", "app.db"),
+	}, cmd.Args)
+	assert.Equal(t, dir, cmd.Dir)
+	assert.DirExists(t, filepath.Join(dir, "docs"))
+	assert.DirExists(t, filepath.Join(dir, "data"))
+
+	for _, path := range []string{"/", "/etc", "../other", "docs/../../other"} {
+		_, err := mcpstdio.NewCommand(
+			stdioConfig,
+			map[string]string{"ROOT": path, "DB": "app.db"},
+			dir,
+		)
+		assert.Error(t, err, path)
+	}
+
+	require.NoError(t, os.Symlink("/", filepath.Join(dir, "root")))
+
+	_, err = mcpstdio.NewCommand(
+		stdioConfig,
+		map[string]string{"ROOT": "root/etc", "DB": "app.db"},
+		dir,
+	)
+	assert.Error(t, err)
+}
+
+func TestLimitScript(t *testing.T) {
+	assert.Empty(t, mcpstdio.LimitScript(model.MCPStdioLimits{CallTimeoutSeconds: 10}))
+	assert.Equal(
+		t,
+		`ulimit -t 10 && ulimit -v 524288 && exec "$0" "$@"`,
+		mcpstdio.LimitScript(model.MCPStdioLimits{CPUSeconds: 10, MemoryMB: 512}),
+	)
+}
+
+func TestDefaultLimits(t *testing.T) {
+	assert.Equal(
+		t,
+		`ulimit -t 600 && ulimit -v 4194304 && ulimit -n 1024 && exec "$0" "$@"`,
+		mcpstdio.LimitScript(mcpstdio.DefaultLimits(model.MCPStdioLimits{})),
+	)
+	assert.Equal(
+		t,
+		`ulimit -t 10 && ulimit -n 1024 && exec "$0" "$@"`,
+		mcpstdio.LimitScript(mcpstdio.DefaultLimits(model.MCPStdioLimits{
+			CPUSeconds: 10,
+			MemoryMB:   -1,
+		})),
+	)
+}
+
+func TestServer(t *testing.T) {
+	t.Setenv("SECRET", "gateway")
+
+	config.MCPStdioEnabled = false
+
+	cmd, err := mcpstdio.NewCommand(&model.MCPStdioConfig{
+		Command: os.Args[0],
+		Env:     map[string]string{testServerEnv: "1"},
+		Limits:  model.MCPStdioLimits{CPUSeconds: 60, MaxProcesses: 2},
+	}, map[string]string{"TOKEN": "group"}, t.TempDir())
+	require.NoError(t, err)
+
+	_, err = mcpstdio.NewServer("test/group", cmd)
+	require.ErrorIs(t, err, mcpstdio.ErrDisabled)
+
+	config.MCPStdioEnabled = true
+	defer func() {
+		config.MCPStdioEnabled = false
+
+		mcpstdio.Close()
+	}()
+
+	server, err := mcpstdio.NewServer("test/group", cmd)
+	require.NoError(t, err)
+
+	handle := func(message string) map[string]any {
+		resp := server.HandleMessage(context.Background(), json.RawMessage(message))
+		if resp == nil {
+			return nil
+		}
+
+		data, err := json.Marshal(resp)
+		require.NoError(t, err)
+
+		var m map[string]any
+		require.NoError(t, json.Unmarshal(data, &m))
+
+		return m
+	}
+
+	resp := handle(`{"jsonrpc":"2.0","id":"init","method":"initialize","params":{}}`)
+	assert.Equal(t, "init", resp["id"])
+	assert.Equal(t, "initialize", resp["result"].(map[string]any)["method"])
+
+	assert.Nil(t, handle(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			resp := handle(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/list"}`, i))
+
+			assert.InDelta(t, float64(i), resp["id"], 0)
+
+			result, _ := resp["result"].(map[string]any)
+			assert.Equal(t, "tools/list", result["method"])
+			assert.Equal(t, "group", result["token"])
+			assert.Empty(t, result["secret"])
+		})
+	}
+
+	wg.Wait()
+}
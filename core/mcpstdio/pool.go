@@ -0,0 +1,321 @@
+package mcpstdio
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	initializeTimeout = 30 * time.Second
+	cleanInterval     = 30 * time.Second
+	// acquireInterval is the wait for a process of a pool with all its
+	// processes starting
+	acquireInterval = 100 * time.Millisecond
+)
+
+var (
+	ErrDisabled   = errors.New("stdio mcp is disabled")
+	errPoolClosed = errors.New("stdio mcp pool is closed")
+)
+
+// process is a running command, its requests are sent with ids of the
+// process so the sessions sharing it never collide
+type process struct {
+	client *transport.Stdio
+	cancel context.CancelFunc
+	// init is the result of the initialize of the process, the sessions are
+	// initialized with it
+	init     json.RawMessage
+	inFlight int
+	lastUsed time.Time
+	broken   bool
+	nextID   atomic.Int64
+}
+
+func startProcess(cmd Command) (*process, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	client := transport.NewStdioWithOptions(
+		cmd.Command,
+		cmd.Env,
+		cmd.Args,
+		transport.WithCommandFunc(cmd.exec),
+	)
+	if err := client.Start(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	p := &process{
+		client:   client,
+		cancel:   cancel,
+		lastUsed: time.Now(),
+	}
+
+	go p.logStderr(cmd.Command)
+
+	initCtx, initCancel := context.WithTimeout(ctx, initializeTimeout)
+	defer initCancel()
+
+	resp, err := client.SendRequest(initCtx, transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(p.nextID.Add(1)),
+		Method:  string(mcp.MethodInitialize),
+		Params: mcp.InitializeParams{
+			ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+			ClientInfo: mcp.Implementation{
+				Name:    "llm-server",
+				Version: "1.0.0",
+			},
+		},
+	})
+	if err != nil {
+		p.close()
+		return nil, fmt.Errorf("failed to initialize stdio mcp: %w", err)
+	}
+
+	if resp.Error != nil {
+		p.close()
+		return nil, fmt.Errorf("failed to initialize stdio mcp: %s", resp.Error.Message)
+	}
+
+	err = client.SendNotification(initCtx, mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: "notifications/initialized",
+		},
+	})
+	if err != nil {
+		p.close()
+		return nil, fmt.Errorf("failed to initialize stdio mcp: %w", err)
+	}
+
+	p.init = resp.Result
+
+	return p, nil
+}
+
+func (p *process) logStderr(command string) {
+	scanner := bufio.NewScanner(p.client.Stderr())
+	for scanner.Scan() {
+		log.Debugf("stdio mcp %s: %s", command, scanner.Text())
+	}
+}
+
+func (p *process) close() {
+	p.cancel()
+	_ = p.client.Close()
+}
+
+// pool is the processes of a command of a group
+type pool struct {
+	cmd       Command
+	mu        sync.Mutex
+	processes []*process
+	starting  int
+	closed    bool
+}
+
+var (
+	poolsMu   sync.Mutex
+	pools     = make(map[string]*pool)
+	cleanOnce sync.Once
+)
+
+func getPool(key string, cmd Command) *pool {
+	cleanOnce.Do(func() {
+		go cleanPools()
+	})
+
+	key = key + "@" + cmd.hash()
+
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	p, ok := pools[key]
+	if !ok {
+		p = &pool{cmd: cmd}
+		pools[key] = p
+	}
+
+	return p
+}
+
+// acquire returns the least busy process of the pool, a process is started
+// while the busy pool is below its max processes
+func (p *pool) acquire(ctx context.Context) (*process, error) {
+	for {
+		proc, start, err := p.pick()
+		if err != nil {
+			return nil, err
+		}
+
+		if proc != nil {
+			return proc, nil
+		}
+
+		if start {
+			return p.start()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(acquireInterval):
+		}
+	}
+}
+
+func (p *pool) pick() (proc *process, start bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, false, errPoolClosed
+	}
+
+	for _, candidate := range p.processes {
+		if proc == nil || candidate.inFlight < proc.inFlight {
+			proc = candidate
+		}
+	}
+
+	if (proc == nil || proc.inFlight > 0) &&
+		len(p.processes)+p.starting < p.cmd.maxProcesses() {
+		p.starting++
+		return nil, true, nil
+	}
+
+	if proc != nil {
+		proc.inFlight++
+	}
+
+	return proc, false, nil
+}
+
+func (p *pool) start() (*process, error) {
+	proc, err := startProcess(p.cmd)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.starting--
+
+	if err != nil {
+		return nil, err
+	}
+
+	proc.inFlight++
+
+	// the process started while the pool closed only serves its request
+	if p.closed {
+		proc.broken = true
+	} else {
+		p.processes = append(p.processes, proc)
+	}
+
+	return proc, nil
+}
+
+// release returns the process to the pool, a broken process is closed once
+// its requests are done
+func (p *pool) release(proc *process, broken bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proc.inFlight--
+	proc.lastUsed = time.Now()
+
+	if broken && !proc.broken {
+		proc.broken = true
+		p.remove(proc)
+	}
+
+	if proc.broken && proc.inFlight == 0 {
+		go proc.close()
+	}
+}
+
+func (p *pool) remove(proc *process) {
+	for i, candidate := range p.processes {
+		if candidate == proc {
+			p.processes = append(p.processes[:i], p.processes[i+1:]...)
+			return
+		}
+	}
+}
+
+// clean closes the idle processes, the pool is closed when it has no
+// process left
+func (p *pool) clean(now time.Time) (empty bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idleTimeout := p.cmd.idleTimeout()
+
+	processes := p.processes[:0]
+	for _, proc := range p.processes {
+		if proc.inFlight == 0 && now.Sub(proc.lastUsed) > idleTimeout {
+			go proc.close()
+			continue
+		}
+
+		processes = append(processes, proc)
+	}
+
+	p.processes = processes
+
+	if len(p.processes) == 0 && p.starting == 0 {
+		p.closed = true
+		return true
+	}
+
+	return false
+}
+
+func cleanPools() {
+	ticker := time.NewTicker(cleanInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		poolsMu.Lock()
+
+		for key, p := range pools {
+			if p.clean(now) {
+				delete(pools, key)
+			}
+		}
+
+		poolsMu.Unlock()
+	}
+}
+
+// Close closes the processes of all the pools
+func Close() {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	for key, p := range pools {
+		p.mu.Lock()
+
+		for _, proc := range p.processes {
+			proc.close()
+		}
+
+		p.processes = nil
+		p.closed = true
+		p.mu.Unlock()
+
+		delete(pools, key)
+	}
+}
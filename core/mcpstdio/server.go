@@ -0,0 +1,113 @@
+package mcpstdio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/bytedance/sonic"
+	"github.com/wavespeed/llm-server/core/common/config"
+	mcpservers "github.com/wavespeed/llm-server/mcp-servers"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Server is an mcp server backed by the processes of the pool of a command,
+// the sessions share the processes of the pool
+type Server struct {
+	key string
+	cmd Command
+}
+
+var _ mcpservers.Server = (*Server)(nil)
+
+// NewServer returns the server of the command in the pool of the key, the
+// key is usually the mcp and the group
+func NewServer(key string, cmd Command) (*Server, error) {
+	if !config.MCPStdioEnabled {
+		return nil, ErrDisabled
+	}
+
+	return &Server{
+		key: key,
+		cmd: cmd,
+	}, nil
+}
+
+func (s *Server) acquire(ctx context.Context) (*pool, *process, error) {
+	for {
+		p := getPool(s.key, s.cmd)
+
+		proc, err := p.acquire(ctx)
+		if errors.Is(err, errPoolClosed) {
+			continue
+		}
+
+		return p, proc, err
+	}
+}
+
+// HandleMessage forwards the message to a process of the pool, the
+// processes are initialized by the pool so the initialize of the session
+// is answered by the result of the process and its notifications are
+// dropped
+func (s *Server) HandleMessage(ctx context.Context, message json.RawMessage) mcp.JSONRPCMessage {
+	var req struct {
+		ID     mcp.RequestId   `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+
+	if err := sonic.Unmarshal(message, &req); err != nil {
+		return mcpservers.CreateMCPErrorResponse(nil, mcp.PARSE_ERROR, err.Error())
+	}
+
+	if req.ID.IsNil() {
+		return nil
+	}
+
+	if req.Method == string(mcp.MethodPing) {
+		return mcpservers.CreateMCPResultResponse(req.ID.Value(), json.RawMessage("{}"))
+	}
+
+	p, proc, err := s.acquire(ctx)
+	if err != nil {
+		return mcpservers.CreateMCPErrorResponse(req.ID.Value(), mcp.INTERNAL_ERROR, err.Error())
+	}
+
+	if req.Method == string(mcp.MethodInitialize) {
+		p.release(proc, false)
+		return mcpservers.CreateMCPResultResponse(req.ID.Value(), proc.init)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, s.cmd.callTimeout())
+	defer cancel()
+
+	forward := transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(proc.nextID.Add(1)),
+		Method:  req.Method,
+	}
+	if len(req.Params) != 0 {
+		forward.Params = req.Params
+	}
+
+	resp, err := proc.client.SendRequest(callCtx, forward)
+	// a process failing a request the session did not cancel is recycled
+	p.release(proc, err != nil && ctx.Err() == nil)
+
+	if err != nil {
+		return mcpservers.CreateMCPErrorResponse(req.ID.Value(), mcp.INTERNAL_ERROR, err.Error())
+	}
+
+	if resp.Error != nil {
+		return mcpservers.CreateMCPErrorResponse(
+			req.ID.Value(),
+			resp.Error.Code,
+			resp.Error.Message,
+			resp.Error.Data,
+		)
+	}
+
+	return mcpservers.CreateMCPResultResponse(req.ID.Value(), resp.Result)
+}
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+type auditSnapshot func(c *gin.Context) (any, error)
+
+func channelSnapshot(c *gin.Context) (any, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	return model.GetChannelByID(id)
+}
+
+func tokenSnapshot(c *gin.Context) (any, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	return model.GetTokenByID(id)
+}
+
+func groupTokenSnapshot(c *gin.Context) (any, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	return model.GetGroupTokenByID(c.Param("group"), id)
+}
+
+func optionsSnapshot(_ *gin.Context) (any, error) {
+	options, err := model.GetAllOption()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(options))
+	for _, option := range options {
+		values[option.Key] = option.Value
+	}
+
+	return values, nil
+}
+
+func modelConfigSnapshot(c *gin.Context) (any, error) {
+	return model.GetModelConfig(strings.TrimPrefix(c.Param("model"), "/"))
+}
+
+func groupSnapshot(c *gin.Context) (any, error) {
+	return model.GetGroupByID(c.Param("group"), false)
+}
+
+func groupModelConfigSnapshot(c *gin.Context) (any, error) {
+	return model.GetGroupModelConfig(
+		c.Param("group"),
+		strings.TrimPrefix(c.Param("model"), "/"),
+	)
+}
+
+func publicMCPSnapshot(c *gin.Context) (any, error) {
+	return model.GetPublicMCPByID(c.Param("id"))
+}
+
+func groupMCPSnapshot(c *gin.Context) (any, error) {
+	return model.GetGroupMCPByID(c.Param("id"), c.Param("group"))
+}
+
+func webhookSubscriptionSnapshot(c *gin.Context) (any, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	return model.GetWebhookSubscription(c.Param("group"), id)
+}
+
+func budgetSnapshot(c *gin.Context) (any, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	return model.GetBudget(c.Param("group"), id)
+}
+
+func exchangeRateSnapshot(c *gin.Context) (any, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	return model.GetExchangeRate(id)
+}
+
+func adminKeySnapshot(c *gin.Context) (any, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	return model.GetAdminKey(id)
+}
+
+// auditSnapshots load the resource of the write routes before and after the
+// write, the request body is recorded as the new state of the other routes
+var auditSnapshots = map[string]auditSnapshot{
+	"/api/channel/:id":                      channelSnapshot,
+	"/api/channel/:id/status":               channelSnapshot,
+	"/api/tokens/:id":                       tokenSnapshot,
+	"/api/tokens/:id/status":                tokenSnapshot,
+	"/api/tokens/:id/name":                  tokenSnapshot,
+	"/api/token/:group/:id":                 groupTokenSnapshot,
+	"/api/token/:group/:id/status":          groupTokenSnapshot,
+	"/api/token/:group/:id/name":            groupTokenSnapshot,
+	"/api/option/":                          optionsSnapshot,
+	"/api/option/:key":                      optionsSnapshot,
+	"/api/option/batch":                     optionsSnapshot,
+	"/api/model_config/*model":              modelConfigSnapshot,
+	"/api/group/:group":                     groupSnapshot,
+	"/api/group/:group/status":              groupSnapshot,
+	"/api/group/:group/rpm_ratio":           groupSnapshot,
+	"/api/group/:group/tpm_ratio":           groupSnapshot,
+	"/api/group/:group/model_config/*model": groupModelConfigSnapshot,
+	"/api/mcp/public/:id":                   publicMCPSnapshot,
+	"/api/mcp/public/:id/status":            publicMCPSnapshot,
+	"/api/mcp/group/:group/:id":             groupMCPSnapshot,
+	"/api/mcp/group/:group/:id/status":      groupMCPSnapshot,
+	"/api/webhook/:group/subscription/:id":  webhookSubscriptionSnapshot,
+	"/api/budget/:group/budget/:id":         budgetSnapshot,
+	"/api/exchange_rate/:id":                exchangeRateSnapshot,
+	"/api/admin_keys/:id":                   adminKeySnapshot,
+	"/api/admin_keys/:id/status":            adminKeySnapshot,
+}
+
+// auditSkipRoutes are the non-GET routes that change nothing
+var auditSkipRoutes = []string{
+	"/api/model_configs/contains",
+	"/api/test-embedmcp/",
+	"/api/test-publicmcp/",
+}
+
+func skipAudit(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	route := c.FullPath()
+	if route == "" {
+		return true
+	}
+
+	for _, skip := range auditSkipRoutes {
+		if strings.HasPrefix(route, skip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func takeAuditSnapshot(c *gin.Context, snapshot auditSnapshot) any {
+	value, err := snapshot(c)
+	if err != nil {
+		return nil
+	}
+
+	return value
+}
+
+// Audit is a middleware that records the successful writes of the admin api
+// with the caller and the diff of the changed resource
+func Audit(c *gin.Context) {
+	if skipAudit(c) {
+		c.Next()
+		return
+	}
+
+	var (
+		before any
+		body   []byte
+	)
+
+	snapshot := auditSnapshots[c.FullPath()]
+	if snapshot != nil {
+		before = takeAuditSnapshot(c, snapshot)
+	} else {
+		body, _ = common.GetRequestBodyReusable(c.Request)
+	}
+
+	c.Next()
+
+	code := c.Writer.Status()
+	if code >= http.StatusBadRequest {
+		return
+	}
+
+	var after any
+	if snapshot != nil {
+		after = takeAuditSnapshot(c, snapshot)
+	} else if len(body) > 0 {
+		if err := sonic.Unmarshal(body, &after); err != nil {
+			after = string(body)
+		}
+	}
+
+	log := common.GetLogger(c)
+
+	diff, err := model.AuditDiff(before, after)
+	if err != nil {
+		log.Errorf("audit diff failed: %v", err)
+	}
+
+	principal := GetPrincipal(c)
+
+	err = model.RecordAuditLog(&model.AuditLog{
+		Actor:   principal.Name,
+		Role:    principal.Role,
+		IP:      c.ClientIP(),
+		Method:  c.Request.Method,
+		Route:   c.FullPath(),
+		Path:    c.Request.URL.Path,
+		GroupID: c.Param("group"),
+		Code:    code,
+		Diff:    diff,
+	})
+	if err != nil {
+		log.Errorf("record audit log failed: %v", err)
+	}
+}
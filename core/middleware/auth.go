@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"maps"
 	"net/http"
@@ -8,13 +9,17 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/wavespeed/llm-server/core/common"
 	"github.com/wavespeed/llm-server/core/common/config"
 	"github.com/wavespeed/llm-server/core/common/network"
+	"github.com/wavespeed/llm-server/core/common/tracing"
 	"github.com/wavespeed/llm-server/core/model"
 	"github.com/wavespeed/llm-server/core/relay/meta"
 	"github.com/wavespeed/llm-server/core/relay/mode"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
 )
 
 type APIResponse struct {
@@ -52,21 +57,32 @@ func AdminAuth(c *gin.Context) {
 		return
 	}
 
-	var userType string
-	var tokenCache *model.TokenCache
+	log := common.GetLogger(c)
 
 	// Check if admin key
 	if config.AdminKey != "" && accessToken == config.AdminKey {
-		userType = "admin"
-		tokenCache = &model.TokenCache{
-			Key: config.AdminKey,
+		c.Set(Token, model.TokenCache{Key: config.AdminKey}) // Store value type, not pointer
+		setPrincipal(c, &Principal{Name: "admin", Role: model.RoleSuperAdmin})
+
+		if group := c.Param("group"); group != "" {
+			log.Data["gid"] = group
 		}
-		c.Set(Token, *tokenCache)  // Store value type, not pointer
-		c.Set("user_type", userType)
 
-		group := c.Param("group")
-		if group != "" {
-			log := common.GetLogger(c)
+		c.Next()
+		return
+	}
+
+	// Check if scoped admin key
+	adminKey, err := model.GetEnabledAdminKeyByKey(accessToken)
+	if err == nil {
+		c.Set(Token, model.TokenCache{Key: adminKey.Key, Name: adminKey.Name})
+		setPrincipal(c, &Principal{
+			Name:   adminKey.Name,
+			Role:   model.Role(adminKey.Role),
+			Groups: adminKey.Groups,
+		})
+
+		if group := c.Param("group"); group != "" {
 			log.Data["gid"] = group
 		}
 
@@ -74,6 +90,10 @@ func AdminAuth(c *gin.Context) {
 		return
 	}
 
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Errorf("get admin key failed: %v", err)
+	}
+
 	// Try to validate as regular user token
 	tc, err := model.GetAndValidateToken(accessToken)
 	if err != nil {
@@ -82,16 +102,20 @@ func AdminAuth(c *gin.Context) {
 		return
 	}
 
-	// Get user type from token
-	userType = tc.UserType
-	if userType == "" {
-		userType = "regular" // default for old records
-	}
+	c.Set(Token, *tc) // Store value type, not pointer
 
-	c.Set(Token, *tc)  // Store value type, not pointer
-	c.Set("user_type", userType)
+	// tokens of the admin user type keep the full access, the regular tokens
+	// are limited to their own group
+	if tc.UserType == "admin" {
+		setPrincipal(c, &Principal{Name: tc.Name, Role: model.RoleSuperAdmin})
+	} else {
+		setPrincipal(c, &Principal{
+			Name:   tc.Name,
+			Role:   model.RoleUser,
+			Groups: []string{tc.Group},
+		})
+	}
 
-	log := common.GetLogger(c)
 	if tc.Group != "" {
 		log.Data["gid"] = tc.Group
 	}
@@ -99,9 +123,46 @@ func AdminAuth(c *gin.Context) {
 	c.Next()
 }
 
+// setPrincipal sets the caller of the admin api, the admin callers are logged
+// with the request so the admin actions can be traced back to their key
+func setPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(principalKey, principal)
+
+	userType := "admin"
+	if principal.Role == model.RoleUser {
+		userType = "regular"
+	}
+
+	c.Set("user_type", userType)
+
+	if principal.Role != model.RoleUser {
+		log := common.GetLogger(c)
+		log.Data["admin"] = principal.Name
+		log.Data["role"] = principal.Role
+	}
+}
+
+const websocketProtocolKeyPrefix = "openai-insecure-api-key."
+
+// websocketProtocolKey returns the key browsers send as a websocket
+// subprotocol, as they can not set the headers of a websocket
+func websocketProtocolKey(r *http.Request) string {
+	for _, protocol := range websocket.Subprotocols(r) {
+		if key, ok := strings.CutPrefix(protocol, websocketProtocolKeyPrefix); ok {
+			return key
+		}
+	}
+
+	return ""
+}
+
 func TokenAuth(c *gin.Context) {
 	log := common.GetLogger(c)
 
+	_, span := tracing.Start(c.Request.Context(), "token_auth")
+	// gin runs the next handlers once it returns, so they are not in the span
+	defer span.End()
+
 	key := c.Request.Header.Get("Authorization")
 	if key == "" {
 		key = c.Request.Header.Get("X-Api-Key")
@@ -111,6 +172,10 @@ func TokenAuth(c *gin.Context) {
 		key = c.Request.Header.Get("X-Goog-Api-Key")
 	}
 
+	if key == "" {
+		key = websocketProtocolKey(c.Request)
+	}
+
 	key = strings.TrimPrefix(
 		strings.TrimPrefix(key, "Bearer "),
 		"sk-",
@@ -191,7 +256,12 @@ func TokenAuth(c *gin.Context) {
 	c.Set(Token, token)
 	c.Set(ModelCaches, modelCaches)
 
-	c.Next()
+	attrs := []attribute.KeyValue{
+		tracing.GroupKey.String(group.ID),
+		tracing.TokenNameKey.String(token.Name),
+	}
+	span.SetAttributes(attrs...)
+	tracing.SetAttributes(c.Request.Context(), attrs...)
 }
 
 func GetGroup(c *gin.Context) model.GroupCache {
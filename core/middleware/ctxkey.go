@@ -16,4 +16,5 @@ const (
 	JobID           = "job_id"
 	GenerationID    = "generation_id"
 	ResponseID      = "response_id"
+	FileID          = "file_id"
 )
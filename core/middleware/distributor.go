@@ -17,11 +17,15 @@ import (
 	"github.com/wavespeed/llm-server/core/common/consume"
 	"github.com/wavespeed/llm-server/core/common/notify"
 	"github.com/wavespeed/llm-server/core/common/reqlimit"
+	"github.com/wavespeed/llm-server/core/common/tracing"
+	"github.com/wavespeed/llm-server/core/common/trylock"
 	"github.com/wavespeed/llm-server/core/model"
 	"github.com/wavespeed/llm-server/core/relay/meta"
 	"github.com/wavespeed/llm-server/core/relay/mode"
 	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
 	monitorplugin "github.com/wavespeed/llm-server/core/relay/plugin/monitor"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
 func calculateGroupConsumeLevelRatio(usedAmount float64) float64 {
@@ -109,7 +113,9 @@ func setTpmHeaders(c *gin.Context, tpm, remainingRequests int64) {
 	c.Header(XRateLimitResetTokens, "1m0s")
 }
 
-func checkGroupModelRPMAndTPM(
+// CheckGroupModelRPMAndTPM counts the request to the model of the group and
+// checks the rpm and tpm limits of the model config
+func CheckGroupModelRPMAndTPM(
 	c *gin.Context,
 	group model.GroupCache,
 	mc model.ModelConfig,
@@ -193,6 +199,11 @@ type GroupBalanceConsumer struct {
 	Consumer     balance.PostGroupConsumer
 }
 
+// Balance is the remaining balance of the group when the request started
+func (g *GroupBalanceConsumer) Balance() float64 {
+	return g.balance
+}
+
 func GetGroupBalanceConsumerFromContext(c *gin.Context) *GroupBalanceConsumer {
 	gbcI, ok := c.Get(GroupBalance)
 	if ok {
@@ -294,6 +305,18 @@ func checkGroupBalance(c *gin.Context, group model.GroupCache) bool {
 				gbc.balance,
 			),
 		)
+
+		if trylock.Lock("webhookGroupBalanceLow:"+group.ID, time.Minute*30) {
+			model.EmitWebhookEvent(
+				group.ID,
+				model.WebhookEventGroupBalanceLow,
+				"",
+				map[string]any{
+					"balance":   gbc.balance,
+					"threshold": group.BalanceAlertThreshold,
+				},
+			)
+		}
 	}
 
 	if !gbc.CheckBalance(0) {
@@ -310,12 +333,136 @@ func checkGroupBalance(c *gin.Context, group model.GroupCache) bool {
 	return true
 }
 
+// CheckModelCurrency checks the price of the model can be converted to the
+// currency of the group, the internal groups are not charged
+func CheckModelCurrency(group model.GroupCache, mc model.ModelConfig) error {
+	if group.Status == model.GroupStatusInternal {
+		return nil
+	}
+
+	_, err := model.ExchangeRateAt(mc.Price.Currency, group.Currency, time.Now())
+
+	return err
+}
+
+const (
+	BudgetExhausted = "budget_exhausted"
+)
+
+// checkBudgets rejects the request when a hard limit of the group, token or
+// end user budgets is used up in the current window
+func checkBudgets(c *gin.Context, group model.GroupCache, token model.TokenCache, user string) bool {
+	budget, err := model.GetExhaustedBudget(group.ID, token.Name, user)
+	if err != nil {
+		notify.ErrorThrottle(
+			"getBudgetsError",
+			time.Minute*3,
+			fmt.Sprintf("Get group `%s` budgets error", group.ID),
+			err.Error(),
+		)
+		AbortWithMessage(
+			c,
+			http.StatusInternalServerError,
+			fmt.Sprintf("get group `%s` budgets error", group.ID),
+		)
+
+		return false
+	}
+
+	if budget == nil {
+		return true
+	}
+
+	message := fmt.Sprintf("group `%s` %s budget exhausted", group.ID, budget.Window)
+	if budget.Scope != model.BudgetScopeGroup {
+		message = fmt.Sprintf(
+			"%s `%s` %s budget exhausted",
+			budget.Scope,
+			budget.Subject,
+			budget.Window,
+		)
+	}
+
+	AbortLogWithMessage(
+		c,
+		http.StatusForbidden,
+		message,
+		relaymodel.WithType(BudgetExhausted),
+	)
+
+	return false
+}
+
 func NewDistribute(mode mode.Mode) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		distribute(c, mode)
 	}
 }
 
+// NewFileDistribute only distributes file requests that target an upstream
+// channel: uploads naming a model and files pinned in the store. Other file
+// requests are served from the gateway file storage and skip distribution.
+func NewFileDistribute(mode mode.Mode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		upstream, err := isUpstreamFileRequest(c, mode)
+		if err != nil {
+			c.Set(Mode, mode)
+			AbortLogWithMessage(c, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+
+		if upstream {
+			distribute(c, mode)
+			return
+		}
+
+		serve(c, mode)
+	}
+}
+
+// NewServe is used by requests served by the gateway itself, which do not
+// need a model and a channel
+func NewServe(mode mode.Mode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serve(c, mode)
+	}
+}
+
+func serve(c *gin.Context, mode mode.Mode) {
+	c.Set(Mode, mode)
+
+	if config.GetDisableServe() {
+		AbortLogWithMessage(c, http.StatusServiceUnavailable, "service is under maintenance")
+		return
+	}
+
+	c.Next()
+}
+
+func isUpstreamFileRequest(c *gin.Context, m mode.Mode) (bool, error) {
+	switch m {
+	case mode.Files:
+		return c.Request.FormValue("model") != "", nil
+	case mode.FilesGet, mode.FilesDelete, mode.FilesContent:
+		group := GetGroup(c)
+		token := GetToken(c)
+
+		_, err := model.CacheGetStore(group.ID, token.ID, c.Param("id"))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("get file store failed: %w", err)
+		}
+
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
 func CheckRelayMode(requestMode, modelMode mode.Mode) bool {
 	if modelMode == mode.Unknown {
 		return true
@@ -323,6 +470,7 @@ func CheckRelayMode(requestMode, modelMode mode.Mode) bool {
 
 	switch requestMode {
 	case mode.ChatCompletions, mode.Completions, mode.Anthropic, mode.Gemini,
+		mode.AnthropicCountTokens, mode.GeminiCountTokens, mode.Realtime,
 		mode.Responses, mode.ResponsesGet, mode.ResponsesDelete, mode.ResponsesCancel, mode.ResponsesInputItems:
 		return modelMode == mode.ChatCompletions ||
 			modelMode == mode.Completions ||
@@ -340,14 +488,34 @@ func CheckRelayMode(requestMode, modelMode mode.Mode) bool {
 		return modelMode == mode.VideoGenerationsJobs ||
 			modelMode == mode.VideoGenerationsGetJobs ||
 			modelMode == mode.VideoGenerationsContent
+	case mode.Files, mode.FilesGet, mode.FilesDelete, mode.FilesContent,
+		mode.FineTuningJobs, mode.FineTuningJobsList, mode.FineTuningJobsGet,
+		mode.FineTuningJobsCancel, mode.FineTuningJobsEvents, mode.FineTuningJobsCheckpoints:
+		// files and fine-tuning jobs are not bound to a model type, the model
+		// only selects the upstream
+		return true
 	default:
 		return requestMode == modelMode
 	}
 }
 
+func noModelMessage(m mode.Mode) string {
+	if m == mode.FineTuningJobsList {
+		return "no model provided and the token has no fine-tuning jobs, " +
+			"set the model query to select the upstream"
+	}
+
+	return "no model provided"
+}
+
 func distribute(c *gin.Context, mode mode.Mode) {
 	c.Set(Mode, mode)
 
+	_, span := tracing.Start(c.Request.Context(), "distribute",
+		trace.WithAttributes(tracing.ModeKey.String(mode.String())))
+	// ended on return, the next handlers are run by gin after the checks
+	defer span.End()
+
 	if config.GetDisableServe() {
 		AbortLogWithMessage(c, http.StatusServiceUnavailable, "service is under maintenance")
 		return
@@ -374,7 +542,7 @@ func distribute(c *gin.Context, mode mode.Mode) {
 	}
 
 	if requestModel == "" {
-		AbortLogWithMessage(c, http.StatusBadRequest, "no model provided")
+		AbortLogWithMessage(c, http.StatusBadRequest, noModelMessage(mode))
 		return
 	}
 
@@ -414,6 +582,9 @@ func distribute(c *gin.Context, mode mode.Mode) {
 	c.Set(RequestModel, findModel)
 	c.Set(ModelConfig, mc)
 
+	span.SetAttributes(tracing.ModelKey.String(findModel))
+	tracing.SetAttributes(c.Request.Context(), tracing.ModelKey.String(findModel))
+
 	if !CheckRelayMode(mode, mc.Type) {
 		AbortLogWithMessage(
 			c,
@@ -427,6 +598,16 @@ func distribute(c *gin.Context, mode mode.Mode) {
 		return
 	}
 
+	if err := CheckModelCurrency(group, mc); err != nil {
+		AbortLogWithMessage(
+			c,
+			http.StatusInternalServerError,
+			"the price of the model can not be charged: "+err.Error(),
+		)
+
+		return
+	}
+
 	user, err := getRequestUser(c, mode)
 	if err != nil {
 		AbortLogWithMessage(
@@ -440,6 +621,10 @@ func distribute(c *gin.Context, mode mode.Mode) {
 
 	c.Set(RequestUser, user)
 
+	if !checkBudgets(c, group, token, user) {
+		return
+	}
+
 	metadata, err := getRequestMetadata(c, mode)
 	if err != nil {
 		AbortLogWithMessage(
@@ -453,7 +638,7 @@ func distribute(c *gin.Context, mode mode.Mode) {
 
 	c.Set(RequestMetadata, metadata)
 
-	if err := checkGroupModelRPMAndTPM(c, group, mc, token.Name); err != nil {
+	if err := CheckGroupModelRPMAndTPM(c, group, mc, token.Name); err != nil {
 		errMsg := err.Error()
 
 		consume.Summary(
@@ -468,8 +653,6 @@ func distribute(c *gin.Context, mode mode.Mode) {
 
 		return
 	}
-
-	c.Next()
 }
 
 func GetRequestModel(c *gin.Context) string {
@@ -496,6 +679,10 @@ func GetResponseID(c *gin.Context) string {
 	return c.GetString(ResponseID)
 }
 
+func GetFileID(c *gin.Context) string {
+	return c.GetString(FileID)
+}
+
 func GetRequestMetadata(c *gin.Context) map[string]string {
 	return c.GetStringMapString(RequestMetadata)
 }
@@ -523,6 +710,7 @@ func NewMetaByContext(c *gin.Context,
 	jobID := GetJobID(c)
 	generationID := GetGenerationID(c)
 	responseID := GetResponseID(c)
+	fileID := GetFileID(c)
 
 	opts = append(
 		opts,
@@ -534,6 +722,7 @@ func NewMetaByContext(c *gin.Context,
 		meta.WithJobID(jobID),
 		meta.WithGenerationID(generationID),
 		meta.WithResponseID(responseID),
+		meta.WithFileID(fileID),
 	)
 
 	return meta.NewMeta(
@@ -631,7 +820,88 @@ func getRequestModel(c *gin.Context, m mode.Mode, group string, tokenID int) (st
 		}
 
 		return modelName, nil
-	case m == mode.Gemini:
+	case m == mode.Files:
+		return c.Request.FormValue("model"), nil
+	case m == mode.FilesGet || m == mode.FilesDelete || m == mode.FilesContent:
+		fileID := c.Param("id")
+
+		store, err := model.CacheGetStore(group, tokenID, fileID)
+		if err != nil {
+			return "", fmt.Errorf("get request model failed: %w", err)
+		}
+
+		c.Set(FileID, store.ID)
+		c.Set(ChannelID, store.ChannelID)
+
+		return store.Model, nil
+	case m == mode.FineTuningJobs:
+		body, err := common.GetRequestBodyReusable(c.Request)
+		if err != nil {
+			return "", fmt.Errorf("get request model failed: %w", err)
+		}
+
+		modelName, err := GetModelFromJSON(body)
+		if err != nil {
+			return "", err
+		}
+
+		trainingFile, err := GetTrainingFileFromJSON(body)
+		if err != nil {
+			return "", fmt.Errorf("get request training file failed: %w", err)
+		}
+
+		// the training file must be on the same upstream account as the job
+		if trainingFile != "" {
+			store, err := model.CacheGetStore(group, tokenID, trainingFile)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", fmt.Errorf("get training file store failed: %w", err)
+			}
+
+			if err == nil {
+				c.Set(ChannelID, store.ChannelID)
+			}
+		}
+
+		return modelName, nil
+	case m == mode.FineTuningJobsList:
+		if modelName := c.Query("model"); modelName != "" {
+			return modelName, nil
+		}
+
+		// list the upstream account of the latest job of the token
+		store, err := model.GetLatestStore(group, tokenID, model.FineTuningJobIDPrefix)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", nil
+			}
+
+			return "", fmt.Errorf("get latest fine-tuning job failed: %w", err)
+		}
+
+		c.Set(ChannelID, store.ChannelID)
+
+		return store.Model, nil
+	case m == mode.Realtime:
+		// azure clients name the deployment
+		if modelName := c.Query("model"); modelName != "" {
+			return modelName, nil
+		}
+
+		return c.Query("deployment"), nil
+	case m == mode.FineTuningJobsGet || m == mode.FineTuningJobsCancel ||
+		m == mode.FineTuningJobsEvents || m == mode.FineTuningJobsCheckpoints:
+		jobID := c.Param("id")
+
+		store, err := model.CacheGetStore(group, tokenID, jobID)
+		if err != nil {
+			return "", fmt.Errorf("get request model failed: %w", err)
+		}
+
+		c.Set(JobID, store.ID)
+		c.Set(ChannelID, store.ChannelID)
+
+		return store.Model, nil
+	case m == mode.Gemini || m == mode.GeminiCountTokens:
 		modelName := strings.TrimPrefix(c.Param("model"), "/")
 		modelName, _, _ = strings.Cut(modelName, ":")
 
@@ -658,6 +928,18 @@ func GetModelFromJSON(body []byte) (string, error) {
 	return node.String()
 }
 
+func GetTrainingFileFromJSON(body []byte) (string, error) {
+	node, err := sonic.GetWithOptions(body, ast.SearchOptions{}, "training_file")
+	if err != nil {
+		if errors.Is(err, ast.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("get request training file failed: %w", err)
+	}
+
+	return node.String()
+}
+
 func GetPreviousResponseIDFromJSON(body []byte) (string, error) {
 	node, err := sonic.GetWithOptions(body, ast.SearchOptions{}, "previous_response_id")
 	if err != nil {
@@ -0,0 +1,4 @@
+package middleware
+
+// Export for testing
+var SetPrincipal = setPrincipal
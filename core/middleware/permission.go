@@ -2,10 +2,49 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/model"
 )
 
+const (
+	principalKey   = "admin_principal"
+	forcedGroupKey = "forced_group"
+)
+
+// sharedPermissions are the permissions of the data shared by all groups,
+// scoped callers use them without a group
+var sharedPermissions = []model.Permission{
+	model.PermissionModelRead,
+}
+
+// Principal is the caller of the admin api, a principal with groups is
+// limited to the groups
+type Principal struct {
+	Name   string
+	Role   model.Role
+	Groups []string
+}
+
+func (p *Principal) Scoped() bool {
+	return len(p.Groups) > 0
+}
+
+func (p *Principal) CanAccessGroup(group string) bool {
+	return !p.Scoped() || slices.Contains(p.Groups, group)
+}
+
+// GetPrincipal returns the caller set by AdminAuth
+func GetPrincipal(c *gin.Context) *Principal {
+	principal, ok := c.Get(principalKey)
+	if !ok {
+		return &Principal{Role: model.RoleUser}
+	}
+
+	return principal.(*Principal)
+}
+
 // GetUserType retrieves the user type from context
 func GetUserType(c *gin.Context) string {
 	userType, exists := c.Get("user_type")
@@ -15,35 +54,78 @@ func GetUserType(c *gin.Context) string {
 	return userType.(string)
 }
 
-// IsAdmin checks if the current user is an admin
+// IsAdmin checks if the current user has full access to the admin api
 func IsAdmin(c *gin.Context) bool {
-	return GetUserType(c) == "admin"
+	principal := GetPrincipal(c)
+	return principal.Role == model.RoleSuperAdmin && !principal.Scoped()
 }
 
-// RequireAdmin is a middleware that requires admin privileges
-// Returns 403 Forbidden if user is not admin
-func RequireAdmin(c *gin.Context) {
-	if !IsAdmin(c) {
-		ErrorResponse(c, http.StatusForbidden, "admin access required")
-		c.Abort()
-		return
+// RequirePermission is a middleware that requires the role of the caller to
+// have the permission, a scoped caller can only use the routes of its groups,
+// either by the group path param or by the group forced by FilterByUserGroup
+func RequirePermission(permission model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := GetPrincipal(c)
+		if !principal.Role.Can(permission) {
+			ErrorResponse(c, http.StatusForbidden, "permission "+string(permission)+" required")
+			c.Abort()
+			return
+		}
+
+		if principal.Scoped() && !slices.Contains(sharedPermissions, permission) {
+			if group := c.Param("group"); group != "" {
+				if !principal.CanAccessGroup(group) {
+					ErrorResponse(c, http.StatusForbidden, "no access to group "+group)
+					c.Abort()
+					return
+				}
+			} else if _, ok := GetForcedGroup(c); !ok {
+				ErrorResponse(c, http.StatusForbidden, "the key is limited to its groups")
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
 	}
-	c.Next()
+}
+
+// GetForcedGroup returns the group the queries of a scoped caller are
+// limited to
+func GetForcedGroup(c *gin.Context) (string, bool) {
+	group, ok := c.Get(forcedGroupKey)
+	if !ok {
+		return "", false
+	}
+
+	return group.(string), true
 }
 
 // FilterByUserGroup is a middleware that filters queries to user's group
-// Admin users bypass this filter, regular users are restricted to their own group
+// Unscoped admins bypass this filter, scoped callers are restricted to the
+// requested group of their groups, or to their only group
 func FilterByUserGroup(c *gin.Context) {
-	if IsAdmin(c) {
-		// Admins can access all groups
+	principal := GetPrincipal(c)
+	if !principal.Scoped() {
 		c.Next()
 		return
 	}
 
-	// Get user's token to find their group
-	token := GetToken(c)
+	group := c.Param("group")
+	if group == "" {
+		group = c.Query("group")
+	}
+
+	if group == "" && len(principal.Groups) == 1 {
+		group = principal.Groups[0]
+	}
+
+	if !principal.CanAccessGroup(group) {
+		ErrorResponse(c, http.StatusForbidden, "no access to group "+group)
+		c.Abort()
+		return
+	}
 
-	// Set forced group for regular users
-	c.Set("forced_group", token.Group)
+	c.Set(forcedGroupKey, group)
 	c.Next()
 }
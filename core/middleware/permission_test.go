@@ -0,0 +1,135 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func TestRoleCan(t *testing.T) {
+	tests := []struct {
+		role       model.Role
+		permission model.Permission
+		want       bool
+	}{
+		{model.RoleSuperAdmin, model.PermissionAdminKeyWrite, true},
+		{model.RoleViewer, model.PermissionLogRead, true},
+		{model.RoleViewer, model.PermissionTokenWrite, false},
+		{model.RoleViewer, model.PermissionChannelRead, false},
+		{model.RoleBilling, model.PermissionTokenWrite, true},
+		{model.RoleBilling, model.PermissionChannelWrite, false},
+		{model.RoleChannelOperator, model.PermissionChannelWrite, true},
+		{model.RoleChannelOperator, model.PermissionGroupWrite, false},
+		{model.RoleGroupAdmin, model.PermissionMCPWrite, true},
+		{model.RoleGroupAdmin, model.PermissionOptionWrite, false},
+		{model.RoleUser, model.PermissionTokenRead, true},
+		{model.RoleUser, model.PermissionGroupRead, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Can(tt.permission); got != tt.want {
+			t.Errorf("%s.Can(%s) = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		principal *middleware.Principal
+		path      string
+		want      int
+	}{
+		{
+			name:      "super admin",
+			principal: &middleware.Principal{Role: model.RoleSuperAdmin},
+			path:      "/group/a",
+			want:      http.StatusOK,
+		},
+		{
+			name:      "missing permission",
+			principal: &middleware.Principal{Role: model.RoleViewer},
+			path:      "/channels",
+			want:      http.StatusForbidden,
+		},
+		{
+			name: "scoped in group",
+			principal: &middleware.Principal{
+				Role:   model.RoleGroupAdmin,
+				Groups: []string{"a"},
+			},
+			path: "/group/a",
+			want: http.StatusOK,
+		},
+		{
+			name: "scoped other group",
+			principal: &middleware.Principal{
+				Role:   model.RoleGroupAdmin,
+				Groups: []string{"a"},
+			},
+			path: "/group/b",
+			want: http.StatusForbidden,
+		},
+		{
+			name: "scoped global route",
+			principal: &middleware.Principal{
+				Role:   model.RoleGroupAdmin,
+				Groups: []string{"a"},
+			},
+			path: "/groups",
+			want: http.StatusForbidden,
+		},
+		{
+			name: "scoped filtered route",
+			principal: &middleware.Principal{
+				Role:   model.RoleViewer,
+				Groups: []string{"a"},
+			},
+			path: "/logs",
+			want: http.StatusOK,
+		},
+		{
+			name: "scoped shared route",
+			principal: &middleware.Principal{
+				Role:   model.RoleUser,
+				Groups: []string{"a"},
+			},
+			path: "/models",
+			want: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				middleware.SetPrincipal(c, tt.principal)
+			})
+
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			router.GET("/group/:group", middleware.RequirePermission(model.PermissionGroupRead), ok)
+			router.GET("/groups", middleware.RequirePermission(model.PermissionGroupRead), ok)
+			router.GET("/channels", middleware.RequirePermission(model.PermissionChannelRead), ok)
+			router.GET("/models", middleware.RequirePermission(model.PermissionModelRead), ok)
+			router.GET(
+				"/logs",
+				middleware.FilterByUserGroup,
+				middleware.RequirePermission(model.PermissionLogRead),
+				ok,
+			)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
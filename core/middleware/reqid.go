@@ -6,6 +6,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/common/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func GenRequestID(t time.Time) string {
@@ -27,8 +31,39 @@ func GetRequestID(c *gin.Context) string {
 	return c.GetString(RequestID)
 }
 
+// RequestIDMiddleware sets the request id and starts the root span of the
+// request, the span continues the trace of the incoming traceparent header
 func RequestIDMiddleware(c *gin.Context) {
 	now := GetRequestAt(c)
 	id := GenRequestID(now)
+
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+
+	ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+	ctx, span := tracing.Start(ctx, c.Request.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithTimestamp(now),
+		trace.WithAttributes(
+			tracing.RequestIDKey.String(id),
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+		),
+	)
+	defer span.End()
+
+	*c.Request = *c.Request.WithContext(ctx)
+
 	SetRequestID(c, id)
+
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+	if status >= 500 {
+		span.SetStatus(codes.Error, strconv.Itoa(status))
+	}
 }
@@ -0,0 +1,294 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	ErrAdminKeyNotFound = "admin key"
+)
+
+type Role string
+
+const (
+	// RoleUser is the role of the regular tokens, they are limited to their
+	// own group
+	RoleUser            Role = "user"
+	RoleViewer          Role = "viewer"
+	RoleBilling         Role = "billing"
+	RoleChannelOperator Role = "channel-operator"
+	RoleGroupAdmin      Role = "group-admin"
+	RoleSuperAdmin      Role = "super-admin"
+)
+
+type Permission string
+
+const (
+	PermissionDashboardRead     Permission = "dashboard:read"
+	PermissionLogRead           Permission = "log:read"
+	PermissionLogWrite          Permission = "log:write"
+	PermissionGroupRead         Permission = "group:read"
+	PermissionGroupWrite        Permission = "group:write"
+	PermissionTokenRead         Permission = "token:read"
+	PermissionTokenWrite        Permission = "token:write"
+	PermissionChannelRead       Permission = "channel:read"
+	PermissionChannelWrite      Permission = "channel:write"
+	PermissionModelRead         Permission = "model:read"
+	PermissionModelWrite        Permission = "model:write"
+	PermissionOptionRead        Permission = "option:read"
+	PermissionOptionWrite       Permission = "option:write"
+	PermissionMonitorRead       Permission = "monitor:read"
+	PermissionMonitorWrite      Permission = "monitor:write"
+	PermissionMCPRead           Permission = "mcp:read"
+	PermissionMCPWrite          Permission = "mcp:write"
+	PermissionWebhookRead       Permission = "webhook:read"
+	PermissionWebhookWrite      Permission = "webhook:write"
+	PermissionAdminKeyWrite     Permission = "admin_key:write"
+	PermissionAuditRead         Permission = "audit:read"
+	PermissionBudgetRead        Permission = "budget:read"
+	PermissionBudgetWrite       Permission = "budget:write"
+	PermissionExchangeRateWrite Permission = "exchange_rate:write"
+)
+
+var viewerPermissions = []Permission{
+	PermissionDashboardRead,
+	PermissionLogRead,
+	PermissionGroupRead,
+	PermissionTokenRead,
+	PermissionModelRead,
+	PermissionMonitorRead,
+	PermissionMCPRead,
+	PermissionWebhookRead,
+	PermissionBudgetRead,
+}
+
+// rolePermissions are the permissions of the roles, the super admin has
+// all permissions
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {
+		PermissionDashboardRead,
+		PermissionLogRead,
+		PermissionTokenRead,
+		PermissionModelRead,
+	},
+	RoleViewer: viewerPermissions,
+	RoleBilling: append(slices.Clone(viewerPermissions),
+		PermissionGroupWrite,
+		PermissionTokenWrite,
+		PermissionWebhookWrite,
+		PermissionBudgetWrite,
+		PermissionExchangeRateWrite,
+	),
+	RoleChannelOperator: append(slices.Clone(viewerPermissions),
+		PermissionChannelRead,
+		PermissionChannelWrite,
+		PermissionModelWrite,
+		PermissionMonitorWrite,
+	),
+	RoleGroupAdmin: append(slices.Clone(viewerPermissions),
+		PermissionGroupWrite,
+		PermissionTokenWrite,
+		PermissionMCPWrite,
+		PermissionWebhookWrite,
+		PermissionBudgetWrite,
+	),
+}
+
+// AdminKeyRoles are the roles an admin key can have
+var AdminKeyRoles = []Role{
+	RoleViewer,
+	RoleBilling,
+	RoleChannelOperator,
+	RoleGroupAdmin,
+	RoleSuperAdmin,
+}
+
+func (r Role) Can(p Permission) bool {
+	if r == RoleSuperAdmin {
+		return true
+	}
+
+	return slices.Contains(rolePermissions[r], p)
+}
+
+type AdminKeyStatus int
+
+const (
+	AdminKeyStatusEnabled AdminKeyStatus = iota + 1
+	AdminKeyStatusDisabled
+)
+
+// AdminKey is a key of the admin api with a role, the key is limited to the
+// groups when they are set
+type AdminKey struct {
+	ID         int            `gorm:"primaryKey"                    json:"id"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime"                json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime"                json:"updated_at"`
+	Name       string         `gorm:"size:64;uniqueIndex"           json:"name"`
+	Key        string         `gorm:"size:64;uniqueIndex"           json:"key"`
+	Role       Role           `gorm:"size:32"                       json:"role"`
+	Groups     []string       `gorm:"serializer:fastjson;type:text" json:"groups,omitempty"`
+	Status     AdminKeyStatus `gorm:"default:1;index"               json:"status"`
+	LastUsedAt time.Time      `                                     json:"last_used_at"`
+}
+
+func (k *AdminKey) BeforeSave(_ *gorm.DB) error {
+	if k.Name == "" {
+		return errors.New("admin key name is empty")
+	}
+
+	if !slices.Contains(AdminKeyRoles, k.Role) {
+		return fmt.Errorf("unknown role: %s", k.Role)
+	}
+
+	if k.Status == 0 {
+		k.Status = AdminKeyStatusEnabled
+	}
+
+	return nil
+}
+
+func CreateAdminKey(k *AdminKey) error {
+	if k.Key == "" {
+		k.Key = generateKey()
+	}
+
+	err := DB.Create(k).Error
+	if err != nil && errors.Is(err, gorm.ErrDuplicatedKey) {
+		return errors.New("admin key name already exists")
+	}
+
+	if err == nil {
+		// the key may be cached as not an admin key
+		if err := CacheDeleteAdminKey(k.Key); err != nil {
+			log.Error("delete admin key from cache failed: " + err.Error())
+		}
+	}
+
+	return err
+}
+
+func UpdateAdminKey(k *AdminKey) error {
+	selects := []string{
+		"name",
+		"role",
+		"groups",
+	}
+	if k.Status != 0 {
+		selects = append(selects, "status")
+	}
+
+	result := DB.
+		Select(selects).
+		Where("id = ?", k.ID).
+		Updates(k)
+	if err := HandleUpdateResult(result, ErrAdminKeyNotFound); err != nil {
+		return err
+	}
+
+	return cacheDeleteAdminKeyByID(k.ID)
+}
+
+func UpdateAdminKeyStatus(id int, status AdminKeyStatus) error {
+	result := DB.Model(&AdminKey{}).
+		Where("id = ?", id).
+		UpdateColumn("status", status)
+	if err := HandleUpdateResult(result, ErrAdminKeyNotFound); err != nil {
+		return err
+	}
+
+	return cacheDeleteAdminKeyByID(id)
+}
+
+func DeleteAdminKey(id int) error {
+	k, err := GetAdminKey(id)
+	if err != nil {
+		return err
+	}
+
+	result := DB.Where("id = ?", id).Delete(&AdminKey{})
+	if err := HandleUpdateResult(result, ErrAdminKeyNotFound); err != nil {
+		return err
+	}
+
+	if err := CacheDeleteAdminKey(k.Key); err != nil {
+		log.Error("delete admin key from cache failed: " + err.Error())
+	}
+
+	return nil
+}
+
+// cacheDeleteAdminKeyByID drops the cached admin key after it is changed
+func cacheDeleteAdminKeyByID(id int) error {
+	k, err := GetAdminKey(id)
+	if err != nil {
+		return err
+	}
+
+	if err := CacheDeleteAdminKey(k.Key); err != nil {
+		log.Error("delete admin key from cache failed: " + err.Error())
+	}
+
+	return nil
+}
+
+func GetAdminKey(id int) (*AdminKey, error) {
+	var k AdminKey
+
+	err := DB.Where("id = ?", id).First(&k).Error
+
+	return &k, HandleNotFound(err, ErrAdminKeyNotFound)
+}
+
+func GetAdminKeys() ([]*AdminKey, error) {
+	var keys []*AdminKey
+
+	err := DB.Order("id asc").Find(&keys).Error
+
+	return keys, err
+}
+
+func getAdminKeyCacheByKey(key string) (*AdminKeyCache, error) {
+	var k AdminKey
+
+	err := DB.Where("`key` = ?", key).First(&k).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &AdminKeyCache{Key: key}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return k.ToAdminKeyCache(), nil
+}
+
+// GetEnabledAdminKeyByKey returns the enabled admin key of the key from the
+// cache and records its use
+func GetEnabledAdminKeyByKey(key string) (*AdminKeyCache, error) {
+	k, err := CacheGetAdminKeyByKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if k.ID == 0 || AdminKeyStatus(k.Status) != AdminKeyStatusEnabled {
+		return nil, NotFoundError(ErrAdminKeyNotFound)
+	}
+
+	now := time.Now()
+	if now.Sub(time.Time(k.LastUsedAt)) > time.Minute {
+		DB.Model(&AdminKey{}).Where("id = ?", k.ID).UpdateColumn("last_used_at", now)
+
+		if err := CacheUpdateAdminKeyLastUsedAt(key, now); err != nil {
+			log.Error("update admin key last used at in cache failed: " + err.Error())
+		}
+	}
+
+	return k, nil
+}
@@ -0,0 +1,239 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"gorm.io/gorm"
+)
+
+// AuditLog is a write of the admin api, the diff holds the changed fields of
+// the resource with their values before and after the write
+type AuditLog struct {
+	ID        int                    `gorm:"primaryKey"                    json:"id"`
+	CreatedAt time.Time              `gorm:"autoCreateTime;index"          json:"created_at"`
+	Actor     string                 `gorm:"size:64;index"                 json:"actor"`
+	Role      Role                   `gorm:"size:32"                       json:"role"`
+	IP        string                 `gorm:"size:45"                       json:"ip"`
+	Method    string                 `gorm:"size:8"                        json:"method"`
+	Route     string                 `gorm:"size:128;index"                json:"route"`
+	Path      string                 `gorm:"size:256"                      json:"path"`
+	GroupID   string                 `gorm:"size:64;index"                 json:"group,omitempty"`
+	Code      int                    `                                     json:"code"`
+	Diff      map[string]AuditChange `gorm:"serializer:fastjson;type:text" json:"diff,omitempty"`
+}
+
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+const auditRedacted = "******"
+
+// auditValueKey is the diff key of the values that are not json objects
+const auditValueKey = "value"
+
+// isAuditSecret reports whether the field holds a secret, the secrets are
+// redacted from the diff
+func isAuditSecret(field string) bool {
+	field = strings.ToLower(field)
+	switch field {
+	case "key", "secret", "password", "authorization", "access_token":
+		return true
+	}
+
+	return strings.HasSuffix(field, "_key") ||
+		strings.HasSuffix(field, "_secret") ||
+		strings.HasSuffix(field, "_password")
+}
+
+// redactAuditSecret keeps the last chars of the long secrets so a rotated
+// secret still shows up in the diff
+func redactAuditSecret(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return v
+		}
+
+		if len(v) >= 16 {
+			return auditRedacted + v[len(v)-4:]
+		}
+	}
+
+	return auditRedacted
+}
+
+func redactAudit(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for field, value := range v {
+			if isAuditSecret(field) {
+				v[field] = redactAuditSecret(value)
+				continue
+			}
+
+			v[field] = redactAudit(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactAudit(value)
+		}
+	}
+
+	return v
+}
+
+func auditValue(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	b, err := sonic.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := sonic.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+
+	return redactAudit(value), nil
+}
+
+// AuditDiff returns the changed fields of before and after, the values are
+// compared by their json, a nil value is a created or deleted resource
+func AuditDiff(before, after any) (map[string]AuditChange, error) {
+	beforeValue, err := auditValue(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterValue, err := auditValue(after)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeFields, beforeIsObject := beforeValue.(map[string]any)
+	afterFields, afterIsObject := afterValue.(map[string]any)
+
+	if (!beforeIsObject && beforeValue != nil) || (!afterIsObject && afterValue != nil) {
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			return nil, nil
+		}
+
+		return map[string]AuditChange{
+			auditValueKey: {Before: beforeValue, After: afterValue},
+		}, nil
+	}
+
+	diff := make(map[string]AuditChange)
+
+	for field, value := range beforeFields {
+		afterField := afterFields[field]
+		if !reflect.DeepEqual(value, afterField) {
+			diff[field] = AuditChange{Before: value, After: afterField}
+		}
+	}
+
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			diff[field] = AuditChange{After: value}
+		}
+	}
+
+	return diff, nil
+}
+
+func RecordAuditLog(l *AuditLog) error {
+	return LogDB.Create(l).Error
+}
+
+func buildAuditLogsQuery(
+	actor string,
+	route string,
+	method string,
+	group string,
+	startTimestamp time.Time,
+	endTimestamp time.Time,
+) *gorm.DB {
+	tx := LogDB.Model(&AuditLog{})
+
+	if actor != "" {
+		tx = tx.Where("actor = ?", actor)
+	}
+
+	if route != "" {
+		tx = tx.Where("route LIKE ?", "%"+route+"%")
+	}
+
+	if method != "" {
+		tx = tx.Where("method = ?", strings.ToUpper(method))
+	}
+
+	if group != "" {
+		tx = tx.Where("group_id = ?", group)
+	}
+
+	if !startTimestamp.IsZero() {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+
+	if !endTimestamp.IsZero() {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+
+	return tx
+}
+
+// SearchAuditLogs returns the audit logs of the filters, newest first
+func SearchAuditLogs(
+	actor string,
+	route string,
+	method string,
+	group string,
+	startTimestamp time.Time,
+	endTimestamp time.Time,
+	page, perPage int,
+) (logs []*AuditLog, total int64, err error) {
+	tx := buildAuditLogsQuery(actor, route, method, group, startTimestamp, endTimestamp)
+
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if total <= 0 {
+		return nil, 0, nil
+	}
+
+	limit, offset := toLimitOffset(page, perPage)
+
+	err = tx.Order("id desc").Limit(limit).Offset(offset).Find(&logs).Error
+
+	return logs, total, err
+}
+
+// ExportAuditLogs returns at most limit audit logs of the filters, newest
+// first
+func ExportAuditLogs(
+	actor string,
+	route string,
+	method string,
+	group string,
+	startTimestamp time.Time,
+	endTimestamp time.Time,
+	limit int,
+) (logs []*AuditLog, err error) {
+	err = buildAuditLogsQuery(actor, route, method, group, startTimestamp, endTimestamp).
+		Order("id desc").
+		Limit(limit).
+		Find(&logs).
+		Error
+
+	return logs, err
+}
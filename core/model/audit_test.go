@@ -0,0 +1,79 @@
+package model_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func TestAuditDiff(t *testing.T) {
+	type channel struct {
+		Name         string            `json:"name"`
+		Key          string            `json:"key"`
+		ModelMapping map[string]string `json:"model_mapping"`
+	}
+
+	tests := []struct {
+		name   string
+		before any
+		after  any
+		want   map[string]model.AuditChange
+	}{
+		{
+			name:   "changed field",
+			before: channel{Name: "a", Key: "sk-1", ModelMapping: map[string]string{"gpt-4": "gpt-4o"}},
+			after:  channel{Name: "a", Key: "sk-1", ModelMapping: map[string]string{"gpt-4": "gpt-4.1"}},
+			want: map[string]model.AuditChange{
+				"model_mapping": {
+					Before: map[string]any{"gpt-4": "gpt-4o"},
+					After:  map[string]any{"gpt-4": "gpt-4.1"},
+				},
+			},
+		},
+		{
+			name:   "redacted secret",
+			before: channel{Name: "a", Key: "sk-0123456789abcdef"},
+			after:  channel{Name: "a", Key: "sk-0123456789abcdeg"},
+			want: map[string]model.AuditChange{
+				"key": {Before: "******cdef", After: "******cdeg"},
+			},
+		},
+		{
+			name:   "created",
+			before: nil,
+			after:  map[string]any{"name": "a", "api_key": "sk-1"},
+			want: map[string]model.AuditChange{
+				"name":    {After: "a"},
+				"api_key": {After: "******"},
+			},
+		},
+		{
+			name:   "not an object",
+			before: nil,
+			after:  []int{1, 2},
+			want: map[string]model.AuditChange{
+				"value": {After: []any{float64(1), float64(2)}},
+			},
+		},
+		{
+			name:   "unchanged",
+			before: map[string]string{"a": "1"},
+			after:  map[string]string{"a": "1"},
+			want:   map[string]model.AuditChange{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := model.AuditDiff(tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("AuditDiff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -39,6 +39,22 @@ func (b *batchUpdateData) isCleanLocked() bool {
 		len(b.GroupSummariesMinute) == 0
 }
 
+// BatchQueueDepth returns the number of the pending batch updates by kind
+func BatchQueueDepth() map[string]int {
+	batchData.Lock()
+	defer batchData.Unlock()
+
+	return map[string]int{
+		"groups":                 len(batchData.Groups),
+		"tokens":                 len(batchData.Tokens),
+		"channels":               len(batchData.Channels),
+		"summaries":              len(batchData.Summaries),
+		"group_summaries":        len(batchData.GroupSummaries),
+		"summaries_minute":       len(batchData.SummariesMinute),
+		"group_summaries_minute": len(batchData.GroupSummariesMinute),
+	}
+}
+
 type GroupUpdate struct {
 	Amount decimal.Decimal
 	Count  int
@@ -319,6 +335,9 @@ func BatchRecordLogs(
 	usage Usage,
 	modelPrice Price,
 	amount float64,
+	originalAmount float64,
+	costAmount float64,
+	currency string,
 	user string,
 	metadata map[string]string,
 ) (err error) {
@@ -357,6 +376,9 @@ func BatchRecordLogs(
 				usage,
 				modelPrice,
 				amount,
+				originalAmount,
+				costAmount,
+				currency,
 				user,
 				metadata,
 			)
@@ -394,6 +416,8 @@ func BatchRecordLogs(
 		downstreamResult,
 		usage,
 		amount,
+		originalAmount,
+		costAmount,
 	)
 
 	return err
@@ -412,12 +436,16 @@ func BatchUpdateSummary(
 	downstreamResult bool,
 	usage Usage,
 	amount float64,
+	originalAmount float64,
+	costAmount float64,
 ) {
 	if now.IsZero() {
 		now = time.Now()
 	}
 
 	amountDecimal := decimal.NewFromFloat(amount)
+	originalAmountDecimal := decimal.NewFromFloat(originalAmount)
+	costAmountDecimal := decimal.NewFromFloat(costAmount)
 
 	batchData.Lock()
 	defer batchData.Unlock()
@@ -433,6 +461,8 @@ func BatchUpdateSummary(
 			firstByteAt,
 			code,
 			amountDecimal,
+			originalAmountDecimal,
+			costAmountDecimal,
 			usage,
 			!downstreamResult,
 		)
@@ -445,6 +475,8 @@ func BatchUpdateSummary(
 			firstByteAt,
 			code,
 			amountDecimal,
+			originalAmountDecimal,
+			costAmountDecimal,
 			usage,
 			!downstreamResult,
 		)
@@ -469,6 +501,8 @@ func BatchUpdateSummary(
 			firstByteAt,
 			code,
 			amountDecimal,
+			originalAmountDecimal,
+			costAmountDecimal,
 			usage,
 		)
 
@@ -481,6 +515,8 @@ func BatchUpdateSummary(
 			firstByteAt,
 			code,
 			amountDecimal,
+			originalAmountDecimal,
+			costAmountDecimal,
 			usage,
 		)
 	}
@@ -552,6 +588,8 @@ func updateGroupSummaryData(
 	firstByteAt time.Time,
 	code int,
 	amountDecimal decimal.Decimal,
+	originalAmountDecimal decimal.Decimal,
+	costAmountDecimal decimal.Decimal,
 	usage Usage,
 ) {
 	if createAt.IsZero() {
@@ -584,6 +622,12 @@ func updateGroupSummaryData(
 	groupSummary.UsedAmount = amountDecimal.
 		Add(decimal.NewFromFloat(groupSummary.UsedAmount)).
 		InexactFloat64()
+	groupSummary.OriginalAmount = originalAmountDecimal.
+		Add(decimal.NewFromFloat(groupSummary.OriginalAmount)).
+		InexactFloat64()
+	groupSummary.CostAmount = costAmountDecimal.
+		Add(decimal.NewFromFloat(groupSummary.CostAmount)).
+		InexactFloat64()
 
 	groupSummary.TotalTimeMilliseconds += createAt.Sub(requestAt).Milliseconds()
 	groupSummary.TotalTTFBMilliseconds += firstByteAt.Sub(requestAt).Milliseconds()
@@ -603,6 +647,8 @@ func updateGroupSummaryDataMinute(
 	firstByteAt time.Time,
 	code int,
 	amountDecimal decimal.Decimal,
+	originalAmountDecimal decimal.Decimal,
+	costAmountDecimal decimal.Decimal,
 	usage Usage,
 ) {
 	if createAt.IsZero() {
@@ -635,6 +681,12 @@ func updateGroupSummaryDataMinute(
 	groupSummary.UsedAmount = amountDecimal.
 		Add(decimal.NewFromFloat(groupSummary.UsedAmount)).
 		InexactFloat64()
+	groupSummary.OriginalAmount = originalAmountDecimal.
+		Add(decimal.NewFromFloat(groupSummary.OriginalAmount)).
+		InexactFloat64()
+	groupSummary.CostAmount = costAmountDecimal.
+		Add(decimal.NewFromFloat(groupSummary.CostAmount)).
+		InexactFloat64()
 
 	groupSummary.TotalTimeMilliseconds += createAt.Sub(requestAt).Milliseconds()
 	groupSummary.TotalTTFBMilliseconds += firstByteAt.Sub(requestAt).Milliseconds()
@@ -655,6 +707,8 @@ func updateSummaryData(
 	firstByteAt time.Time,
 	code int,
 	amountDecimal decimal.Decimal,
+	originalAmountDecimal decimal.Decimal,
+	costAmountDecimal decimal.Decimal,
 	usage Usage,
 	isRetry bool,
 ) {
@@ -687,6 +741,12 @@ func updateSummaryData(
 	summary.UsedAmount = amountDecimal.
 		Add(decimal.NewFromFloat(summary.UsedAmount)).
 		InexactFloat64()
+	summary.OriginalAmount = originalAmountDecimal.
+		Add(decimal.NewFromFloat(summary.OriginalAmount)).
+		InexactFloat64()
+	summary.CostAmount = costAmountDecimal.
+		Add(decimal.NewFromFloat(summary.CostAmount)).
+		InexactFloat64()
 
 	summary.TotalTimeMilliseconds += createAt.Sub(requestAt).Milliseconds()
 	summary.TotalTTFBMilliseconds += firstByteAt.Sub(requestAt).Milliseconds()
@@ -707,6 +767,8 @@ func updateSummaryDataMinute(
 	firstByteAt time.Time,
 	code int,
 	amountDecimal decimal.Decimal,
+	originalAmountDecimal decimal.Decimal,
+	costAmountDecimal decimal.Decimal,
 	usage Usage,
 	isRetry bool,
 ) {
@@ -739,6 +801,12 @@ func updateSummaryDataMinute(
 	summary.UsedAmount = amountDecimal.
 		Add(decimal.NewFromFloat(summary.UsedAmount)).
 		InexactFloat64()
+	summary.OriginalAmount = originalAmountDecimal.
+		Add(decimal.NewFromFloat(summary.OriginalAmount)).
+		InexactFloat64()
+	summary.CostAmount = costAmountDecimal.
+		Add(decimal.NewFromFloat(summary.CostAmount)).
+		InexactFloat64()
 
 	summary.TotalTimeMilliseconds += createAt.Sub(requestAt).Milliseconds()
 	summary.TotalTTFBMilliseconds += firstByteAt.Sub(requestAt).Milliseconds()
@@ -0,0 +1,377 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ErrBatchJobNotFound = "batch"
+)
+
+const (
+	BatchJobStatusValidating = "validating"
+	BatchJobStatusFailed     = "failed"
+	BatchJobStatusInProgress = "in_progress"
+	BatchJobStatusFinalizing = "finalizing"
+	BatchJobStatusCompleted  = "completed"
+	BatchJobStatusExpired    = "expired"
+	BatchJobStatusCancelling = "cancelling"
+	BatchJobStatusCancelled  = "cancelled"
+)
+
+var ErrBatchJobNotCancellable = errors.New("batch can not be cancelled")
+
+type BatchJobError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// BatchJob is a batch created through the batch api and executed by the
+// gateway, every line of the input file is relayed as a normal request
+type BatchJob struct {
+	ID                string            `gorm:"size:128;primaryKey:3"`
+	GroupID           string            `gorm:"size:64;primaryKey:1"`
+	TokenID           int               `gorm:"primaryKey:2"`
+	CreatedAt         time.Time         `gorm:"autoCreateTime;index"`
+	UpdatedAt         time.Time         `gorm:"autoUpdateTime"`
+	Endpoint          string            `gorm:"size:64"`
+	InputFileID       string            `gorm:"size:128"`
+	OutputFileID      string            `gorm:"size:128"`
+	ErrorFileID       string            `gorm:"size:128"`
+	CompletionWindow  string            `gorm:"size:16"`
+	Status            string            `gorm:"size:32;index"`
+	Errors            []BatchJobError   `gorm:"serializer:fastjson;type:text"`
+	Metadata          map[string]string `gorm:"serializer:fastjson;type:text"`
+	TotalRequests     int
+	CompletedRequests int
+	FailedRequests    int
+	ExpiresAt         time.Time
+	InProgressAt      time.Time
+	FinalizingAt      time.Time
+	CompletedAt       time.Time
+	FailedAt          time.Time
+	ExpiredAt         time.Time
+	CancellingAt      time.Time
+	CancelledAt       time.Time
+}
+
+func (b *BatchJob) BeforeCreate(_ *gorm.DB) error {
+	if b.ID == "" {
+		return errors.New("batch id is required")
+	}
+
+	if b.GroupID == "" || b.TokenID == 0 {
+		return errors.New("group and token id are required")
+	}
+
+	if b.Status == "" {
+		b.Status = BatchJobStatusValidating
+	}
+
+	return nil
+}
+
+func CreateBatchJob(b *BatchJob) error {
+	return LogDB.Create(b).Error
+}
+
+func GetBatchJob(group string, tokenID int, id string) (*BatchJob, error) {
+	var b BatchJob
+
+	err := LogDB.Where("group_id = ? and token_id = ? and id = ?", group, tokenID, id).
+		First(&b).
+		Error
+
+	return &b, HandleNotFound(err, ErrBatchJobNotFound)
+}
+
+// GetBatchJobs lists batches newest first with the openai cursor pagination,
+// after is the id of the last batch of the previous page
+func GetBatchJobs(
+	group string,
+	tokenID int,
+	after string,
+	limit int,
+) (batches []*BatchJob, hasMore bool, err error) {
+	tx := LogDB.Model(&BatchJob{}).
+		Where("group_id = ? and token_id = ?", group, tokenID)
+
+	if after != "" {
+		cursor, err := GetBatchJob(group, tokenID, after)
+		if err != nil {
+			return nil, false, err
+		}
+
+		tx = tx.Where("created_at < ? or (created_at = ? and id < ?)",
+			cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+
+	err = tx.Order("created_at desc, id desc").Limit(limit + 1).Find(&batches).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(batches) > limit {
+		return batches[:limit], true, nil
+	}
+
+	return batches, false, nil
+}
+
+// GetPendingBatchJobs returns the oldest batches waiting to be executed
+func GetPendingBatchJobs(limit int) ([]*BatchJob, error) {
+	var batches []*BatchJob
+
+	err := LogDB.
+		Where("status = ?", BatchJobStatusValidating).
+		Order("created_at asc").
+		Limit(limit).
+		Find(&batches).
+		Error
+
+	return batches, err
+}
+
+// ClaimBatchJob moves a validating batch to in progress, only one instance
+// can claim a batch
+func ClaimBatchJob(b *BatchJob) (bool, error) {
+	now := time.Now()
+
+	result := LogDB.Model(&BatchJob{}).
+		Where("group_id = ? and token_id = ? and id = ? and status = ?",
+			b.GroupID, b.TokenID, b.ID, BatchJobStatusValidating).
+		Updates(map[string]any{
+			"status":         BatchJobStatusInProgress,
+			"in_progress_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	b.Status = BatchJobStatusInProgress
+	b.InProgressAt = now
+
+	return true, nil
+}
+
+// CancelBatchJob cancels a batch, a running batch is moved to cancelling and
+// is cancelled by the instance executing it
+func CancelBatchJob(group string, tokenID int, id string) (*BatchJob, error) {
+	now := time.Now()
+
+	result := LogDB.Model(&BatchJob{}).
+		Where("group_id = ? and token_id = ? and id = ? and status = ?",
+			group, tokenID, id, BatchJobStatusValidating).
+		Updates(map[string]any{
+			"status":        BatchJobStatusCancelled,
+			"cancelling_at": now,
+			"cancelled_at":  now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		result = LogDB.Model(&BatchJob{}).
+			Where("group_id = ? and token_id = ? and id = ? and status in ?",
+				group, tokenID, id,
+				[]string{BatchJobStatusInProgress, BatchJobStatusFinalizing}).
+			Updates(map[string]any{
+				"status":        BatchJobStatusCancelling,
+				"cancelling_at": now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+	}
+
+	b, err := GetBatchJob(group, tokenID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 0 && b.Status != BatchJobStatusCancelling &&
+		b.Status != BatchJobStatusCancelled {
+		return b, ErrBatchJobNotCancellable
+	}
+
+	return b, nil
+}
+
+// UpdateBatchJobProgress updates the request counts, it also refreshes
+// UpdatedAt which is used to find batches of dead instances
+func UpdateBatchJobProgress(b *BatchJob) error {
+	return LogDB.Model(&BatchJob{}).
+		Where("group_id = ? and token_id = ? and id = ?", b.GroupID, b.TokenID, b.ID).
+		Updates(map[string]any{
+			"completed_requests": b.CompletedRequests,
+			"failed_requests":    b.FailedRequests,
+			"updated_at":         time.Now(),
+		}).
+		Error
+}
+
+func UpdateBatchJobStatus(b *BatchJob, status string, at time.Time) error {
+	values := map[string]any{
+		"status": status,
+	}
+
+	switch status {
+	case BatchJobStatusFinalizing:
+		values["finalizing_at"] = at
+	case BatchJobStatusFailed:
+		values["failed_at"] = at
+	}
+
+	err := LogDB.Model(&BatchJob{}).
+		Where("group_id = ? and token_id = ? and id = ?", b.GroupID, b.TokenID, b.ID).
+		Updates(values).
+		Error
+	if err != nil {
+		return err
+	}
+
+	b.Status = status
+
+	switch status {
+	case BatchJobStatusFinalizing:
+		b.FinalizingAt = at
+	case BatchJobStatusFailed:
+		b.FailedAt = at
+	}
+
+	return nil
+}
+
+// SaveBatchJob saves the final state of a batch
+func SaveBatchJob(b *BatchJob) error {
+	return LogDB.Save(b).Error
+}
+
+// ClaimStaleBatchJobs takes over the running batches whose instance stopped
+// updating them, they are resumed from the recorded requests, only one
+// instance can take over a batch
+func ClaimStaleBatchJobs(before time.Time, limit int) ([]*BatchJob, error) {
+	var stale []*BatchJob
+
+	err := LogDB.
+		Where("status in ? and updated_at < ?",
+			[]string{
+				BatchJobStatusInProgress,
+				BatchJobStatusFinalizing,
+				BatchJobStatusCancelling,
+			}, before).
+		Order("updated_at asc").
+		Limit(limit).
+		Find(&stale).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	batches := make([]*BatchJob, 0, len(stale))
+
+	for _, b := range stale {
+		now := time.Now()
+
+		result := LogDB.Model(&BatchJob{}).
+			Where("group_id = ? and token_id = ? and id = ? and status = ? and updated_at < ?",
+				b.GroupID, b.TokenID, b.ID, b.Status, before).
+			Update("updated_at", now)
+		if result.Error != nil {
+			return batches, result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		b.UpdatedAt = now
+		batches = append(batches, b)
+	}
+
+	return batches, nil
+}
+
+// BatchJobRequest records a request of a batch, it is created before the
+// request is dispatched and finished with its output line, so a resumed
+// batch never executes a request twice
+type BatchJobRequest struct {
+	GroupID      string    `gorm:"size:64;primaryKey:1"`
+	TokenID      int       `gorm:"primaryKey:2"`
+	BatchID      string    `gorm:"size:128;primaryKey:3"`
+	RequestIndex int       `gorm:"primaryKey:4;autoIncrement:false"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	Finished     bool
+	Succeeded    bool
+	Output       string `gorm:"type:text"`
+}
+
+func StartBatchJobRequest(b *BatchJob, index int) error {
+	return LogDB.Create(&BatchJobRequest{
+		GroupID:      b.GroupID,
+		TokenID:      b.TokenID,
+		BatchID:      b.ID,
+		RequestIndex: index,
+	}).Error
+}
+
+func FinishBatchJobRequest(b *BatchJob, index int, output string, succeeded bool) error {
+	return LogDB.Model(&BatchJobRequest{}).
+		Where("group_id = ? and token_id = ? and batch_id = ? and request_index = ?",
+			b.GroupID, b.TokenID, b.ID, index).
+		Updates(map[string]any{
+			"finished":  true,
+			"succeeded": succeeded,
+			"output":    output,
+		}).
+		Error
+}
+
+// GetBatchJobRequestStates returns the recorded requests of a batch without
+// their outputs
+func GetBatchJobRequestStates(b *BatchJob) ([]*BatchJobRequest, error) {
+	var requests []*BatchJobRequest
+
+	err := LogDB.
+		Select("request_index", "finished", "succeeded").
+		Where("group_id = ? and token_id = ? and batch_id = ?", b.GroupID, b.TokenID, b.ID).
+		Find(&requests).
+		Error
+
+	return requests, err
+}
+
+// GetBatchJobRequests returns a page of the recorded requests of a batch
+// ordered by their index, after is the index of the last request of the
+// previous page
+func GetBatchJobRequests(b *BatchJob, after, limit int) ([]*BatchJobRequest, error) {
+	var requests []*BatchJobRequest
+
+	err := LogDB.
+		Where("group_id = ? and token_id = ? and batch_id = ? and request_index > ?",
+			b.GroupID, b.TokenID, b.ID, after).
+		Order("request_index asc").
+		Limit(limit).
+		Find(&requests).
+		Error
+
+	return requests, err
+}
+
+// DeleteBatchJobRequests deletes the recorded requests once the outputs of
+// the batch are stored in its files
+func DeleteBatchJobRequests(b *BatchJob) error {
+	return LogDB.
+		Where("group_id = ? and token_id = ? and batch_id = ?", b.GroupID, b.TokenID, b.ID).
+		Delete(&BatchJobRequest{}).
+		Error
+}
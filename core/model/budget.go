@@ -0,0 +1,416 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	ErrBudgetNotFound = "budget"
+)
+
+// BudgetScope is the level a budget is attached to, the group is the top
+// level, the token and end user budgets are charged with the group budget,
+// there is no organization level as the group is the top tenant of the
+// gateway and the organization budgets belong to the external balance
+type BudgetScope string
+
+const (
+	BudgetScopeGroup BudgetScope = "group"
+	BudgetScopeToken BudgetScope = "token"
+	BudgetScopeUser  BudgetScope = "user"
+)
+
+var BudgetScopes = []BudgetScope{
+	BudgetScopeGroup,
+	BudgetScopeToken,
+	BudgetScopeUser,
+}
+
+var BudgetWindows = []string{
+	PeriodTypeDaily,
+	PeriodTypeWeekly,
+	PeriodTypeMonthly,
+}
+
+// Budget limits the amount spent in a calendar window by a group, a token
+// of the group or an end user of the group, the subject is the token name
+// or the `user` field of the requests, the requests are rejected when the
+// hard limit is used up, the soft limit only sends a webhook
+type Budget struct {
+	ID        int         `gorm:"primaryKey"                                       json:"id"`
+	GroupID   string      `gorm:"size:64;uniqueIndex:idx_budget_subject,priority:1" json:"group_id"`
+	Scope     BudgetScope `gorm:"size:16;uniqueIndex:idx_budget_subject,priority:2" json:"scope"`
+	Subject   string      `gorm:"size:128;uniqueIndex:idx_budget_subject,priority:3" json:"subject,omitempty"`
+	Window    string      `gorm:"size:20;uniqueIndex:idx_budget_subject,priority:4" json:"window"`
+	CreatedAt time.Time   `gorm:"autoCreateTime"                                   json:"created_at"`
+	UpdatedAt time.Time   `gorm:"autoUpdateTime"                                   json:"updated_at"`
+	HardLimit float64     `                                                        json:"hard_limit"`
+	SoftLimit float64     `                                                        json:"soft_limit"`
+	// UsedAmount is the amount spent in the window starting at WindowStart
+	UsedAmount  float64 `json:"used_amount"`
+	WindowStart int64   `json:"window_start"`
+}
+
+func (b *Budget) BeforeCreate(_ *gorm.DB) error {
+	if b.GroupID == "" {
+		return errors.New("group id is empty")
+	}
+
+	if !slices.Contains(BudgetScopes, b.Scope) {
+		return fmt.Errorf("unknown budget scope: %s", b.Scope)
+	}
+
+	if b.Scope == BudgetScopeGroup {
+		b.Subject = ""
+	} else if b.Subject == "" {
+		return fmt.Errorf("subject of the %s budget is empty", b.Scope)
+	}
+
+	if b.Window == "" {
+		b.Window = PeriodTypeMonthly
+	}
+
+	if !slices.Contains(BudgetWindows, b.Window) {
+		return fmt.Errorf("unknown budget window: %s", b.Window)
+	}
+
+	if b.HardLimit < 0 || b.SoftLimit < 0 {
+		return errors.New("budget limit is negative")
+	}
+
+	if b.HardLimit == 0 && b.SoftLimit == 0 {
+		return errors.New("budget has no limit")
+	}
+
+	return nil
+}
+
+// BudgetWindowStart returns the start of the calendar window containing
+// now, the weeks start on monday
+func BudgetWindowStart(window string, now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch window {
+	case PeriodTypeDaily:
+		return day
+	case PeriodTypeWeekly:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+}
+
+// BudgetWindowEnd returns the start of the window after the one containing now
+func BudgetWindowEnd(window string, now time.Time) time.Time {
+	start := BudgetWindowStart(window, now)
+
+	switch window {
+	case PeriodTypeDaily:
+		return start.AddDate(0, 0, 1)
+	case PeriodTypeWeekly:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// Used returns the amount spent in the current window, the stored amount
+// belongs to a past window when the window has rolled over
+func (b *Budget) Used(now time.Time) float64 {
+	if b.WindowStart != BudgetWindowStart(b.Window, now).Unix() {
+		return 0
+	}
+
+	return b.UsedAmount
+}
+
+// Exhausted reports whether the hard limit of the current window is used up
+func (b *Budget) Exhausted(now time.Time) bool {
+	return b.HardLimit > 0 && b.Used(now) >= b.HardLimit
+}
+
+func (b *Budget) SoftLimitReached(now time.Time) bool {
+	return b.SoftLimit > 0 && b.Used(now) >= b.SoftLimit
+}
+
+func CreateBudget(b *Budget) error {
+	b.UsedAmount = 0
+	b.WindowStart = 0
+
+	if err := DB.Create(b).Error; err != nil {
+		return err
+	}
+
+	if err := CacheDeleteBudgets(b.GroupID); err != nil {
+		log.Error("delete budgets from cache failed: " + err.Error())
+	}
+
+	return nil
+}
+
+// UpdateBudget updates the limits of a budget, the scope, subject and window
+// are the identity of a budget and are not changed
+func UpdateBudget(b *Budget) error {
+	if b.HardLimit < 0 || b.SoftLimit < 0 {
+		return errors.New("budget limit is negative")
+	}
+
+	if b.HardLimit == 0 && b.SoftLimit == 0 {
+		return errors.New("budget has no limit")
+	}
+
+	result := DB.
+		Model(&Budget{}).
+		Where("id = ? and group_id = ?", b.ID, b.GroupID).
+		Updates(map[string]any{
+			"hard_limit": b.HardLimit,
+			"soft_limit": b.SoftLimit,
+		})
+	if err := HandleUpdateResult(result, ErrBudgetNotFound); err != nil {
+		return err
+	}
+
+	if err := CacheDeleteBudgets(b.GroupID); err != nil {
+		log.Error("delete budgets from cache failed: " + err.Error())
+	}
+
+	return nil
+}
+
+func DeleteBudget(group string, id int) error {
+	result := DB.
+		Where("id = ? and group_id = ?", id, group).
+		Delete(&Budget{})
+	if err := HandleUpdateResult(result, ErrBudgetNotFound); err != nil {
+		return err
+	}
+
+	if err := CacheDeleteBudgets(group); err != nil {
+		log.Error("delete budgets from cache failed: " + err.Error())
+	}
+
+	return nil
+}
+
+func GetBudget(group string, id int) (*Budget, error) {
+	var b Budget
+
+	err := DB.Where("id = ? and group_id = ?", id, group).First(&b).Error
+
+	return &b, HandleNotFound(err, ErrBudgetNotFound)
+}
+
+func GetBudgets(group string, scope BudgetScope) ([]*Budget, error) {
+	var budgets []*Budget
+
+	tx := DB.Where("group_id = ?", group)
+	if scope != "" {
+		tx = tx.Where("scope = ?", scope)
+	}
+
+	err := tx.Order("id asc").Find(&budgets).Error
+
+	return budgets, err
+}
+
+// GetMatchingBudgets returns the budgets a request of the token and end
+// user is charged to, at every level
+func GetMatchingBudgets(group, tokenName, user string) ([]*Budget, error) {
+	var budgets []*Budget
+
+	cond := DB.Where("scope = ?", BudgetScopeGroup)
+	if tokenName != "" {
+		cond = cond.Or("scope = ? and subject = ?", BudgetScopeToken, tokenName)
+	}
+
+	if user != "" {
+		cond = cond.Or("scope = ? and subject = ?", BudgetScopeUser, user)
+	}
+
+	err := DB.
+		Where("group_id = ?", group).
+		Where(cond).
+		Order("id asc").
+		Find(&budgets).Error
+
+	return budgets, err
+}
+
+// matchBudgets filters the budgets of a group a request of the token and end
+// user is charged to
+func matchBudgets(budgets []*Budget, tokenName, user string) []*Budget {
+	var matched []*Budget
+
+	for _, b := range budgets {
+		switch {
+		case b.Scope == BudgetScopeGroup,
+			b.Scope == BudgetScopeToken && tokenName != "" && b.Subject == tokenName,
+			b.Scope == BudgetScopeUser && user != "" && b.Subject == user:
+			matched = append(matched, b)
+		}
+	}
+
+	return matched
+}
+
+// GetExhaustedBudget returns the first budget of the request whose hard
+// limit is used up, nil when the request is within all budgets, the budgets
+// are read from the cache
+func GetExhaustedBudget(group, tokenName, user string) (*Budget, error) {
+	budgets, err := CacheGetBudgets(group)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, b := range matchBudgets(budgets, tokenName, user) {
+		if b.Exhausted(now) {
+			return b, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ChargeBudgets adds the amount to every budget of the request, the amount
+// of a window that has rolled over is replaced in the same statement so
+// concurrent charges are never lost, the groups without budgets only read
+// the cache
+func ChargeBudgets(group, tokenName, user string, amount float64) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	cached, err := CacheGetBudgets(group)
+	if err != nil {
+		return err
+	}
+
+	budgets := matchBudgets(cached, tokenName, user)
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	byWindow := make(map[string][]int)
+	for _, b := range budgets {
+		byWindow[b.Window] = append(byWindow[b.Window], b.ID)
+	}
+
+	for window, ids := range byWindow {
+		windowStart := BudgetWindowStart(window, now).Unix()
+
+		err := DB.
+			Model(&Budget{}).
+			Where("id IN ? and group_id = ?", ids, group).
+			Updates(map[string]any{
+				"used_amount": gorm.Expr(
+					"CASE WHEN window_start = ? THEN used_amount + ? ELSE ? END",
+					windowStart,
+					amount,
+					amount,
+				),
+				"window_start": windowStart,
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// read the budgets again to see the amounts of the concurrent charges
+	charged, err := GetBudgets(group, "")
+	if err != nil {
+		log.Errorf("get charged budgets failed: %v", err)
+		return nil
+	}
+
+	if err := CacheSetBudgets(group, charged); err != nil {
+		log.Error("redis set budgets error: " + err.Error())
+	}
+
+	emitBudgetWebhooks(matchBudgets(charged, tokenName, user), amount, now)
+
+	return nil
+}
+
+type BudgetWebhookData struct {
+	BudgetID    int         `json:"budget_id"`
+	Scope       BudgetScope `json:"scope"`
+	Subject     string      `json:"subject,omitempty"`
+	Window      string      `json:"window"`
+	HardLimit   float64     `json:"hard_limit"`
+	SoftLimit   float64     `json:"soft_limit"`
+	UsedAmount  float64     `json:"used_amount"`
+	WindowStart int64       `json:"window_start"`
+}
+
+// emitBudgetWebhooks sends the events of the limits crossed by the charge,
+// the budgets are read after the charge to see the concurrent charges
+func emitBudgetWebhooks(budgets []*Budget, amount float64, now time.Time) {
+	for _, b := range budgets {
+		used := b.Used(now)
+		before := used - amount
+
+		var event WebhookEvent
+
+		switch {
+		case b.HardLimit > 0 && before < b.HardLimit && used >= b.HardLimit:
+			event = WebhookEventBudgetExhausted
+		case b.SoftLimit > 0 && before < b.SoftLimit && used >= b.SoftLimit:
+			event = WebhookEventBudgetSoftLimitReached
+		default:
+			continue
+		}
+
+		var tokenName string
+		if b.Scope == BudgetScopeToken {
+			tokenName = b.Subject
+		}
+
+		EmitWebhookEvent(b.GroupID, event, tokenName, BudgetWebhookData{
+			BudgetID:    b.ID,
+			Scope:       b.Scope,
+			Subject:     b.Subject,
+			Window:      b.Window,
+			HardLimit:   b.HardLimit,
+			SoftLimit:   b.SoftLimit,
+			UsedAmount:  used,
+			WindowStart: b.WindowStart,
+		})
+	}
+}
+
+// BudgetStatus is a budget with the amounts of its current window,
+// Remaining is the amount left before the hard limit, or the soft limit
+// when there is no hard limit
+type BudgetStatus struct {
+	*Budget
+	Used      float64 `json:"used"`
+	Remaining float64 `json:"remaining"`
+	Exhausted bool    `json:"exhausted"`
+	ResetAt   int64   `json:"reset_at"`
+}
+
+func NewBudgetStatus(b *Budget, now time.Time) BudgetStatus {
+	used := b.Used(now)
+
+	limit := b.HardLimit
+	if limit == 0 {
+		limit = b.SoftLimit
+	}
+
+	return BudgetStatus{
+		Budget:    b,
+		Used:      used,
+		Remaining: max(limit-used, 0),
+		Exhausted: b.Exhausted(now),
+		ResetAt:   BudgetWindowEnd(b.Window, now).Unix(),
+	}
+}
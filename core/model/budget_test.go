@@ -0,0 +1,210 @@
+package model_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func TestBudgetBeforeCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  model.Budget
+		wantErr bool
+	}{
+		{
+			name: "group budget",
+			budget: model.Budget{
+				GroupID:   "group",
+				Scope:     model.BudgetScopeGroup,
+				Subject:   "ignored",
+				HardLimit: 100,
+			},
+		},
+		{
+			name: "end user budget",
+			budget: model.Budget{
+				GroupID:   "group",
+				Scope:     model.BudgetScopeUser,
+				Subject:   "user-1",
+				Window:    model.PeriodTypeDaily,
+				SoftLimit: 5,
+			},
+		},
+		{
+			name: "token budget without subject",
+			budget: model.Budget{
+				GroupID:   "group",
+				Scope:     model.BudgetScopeToken,
+				HardLimit: 100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown scope",
+			budget: model.Budget{
+				GroupID:   "group",
+				Scope:     "organisation",
+				HardLimit: 100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown window",
+			budget: model.Budget{
+				GroupID:   "group",
+				Scope:     model.BudgetScopeGroup,
+				Window:    "yearly",
+				HardLimit: 100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "no limit",
+			budget: model.Budget{
+				GroupID: "group",
+				Scope:   model.BudgetScopeGroup,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.budget.BeforeCreate(nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BeforeCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if tt.budget.Scope == model.BudgetScopeGroup && tt.budget.Subject != "" {
+				t.Errorf("Subject = %q, want empty", tt.budget.Subject)
+			}
+
+			if tt.budget.Window == "" {
+				t.Error("Window is empty, want the default window")
+			}
+		})
+	}
+}
+
+func TestBudgetWindowStart(t *testing.T) {
+	// a thursday
+	now := time.Date(2026, 10, 15, 13, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		window string
+		start  time.Time
+		end    time.Time
+	}{
+		{
+			window: model.PeriodTypeDaily,
+			start:  time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			window: model.PeriodTypeWeekly,
+			start:  time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			window: model.PeriodTypeMonthly,
+			start:  time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			if got := model.BudgetWindowStart(tt.window, now); !got.Equal(tt.start) {
+				t.Errorf("BudgetWindowStart() = %v, want %v", got, tt.start)
+			}
+
+			if got := model.BudgetWindowEnd(tt.window, now); !got.Equal(tt.end) {
+				t.Errorf("BudgetWindowEnd() = %v, want %v", got, tt.end)
+			}
+		})
+	}
+
+	sunday := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	if got := model.BudgetWindowStart(model.PeriodTypeWeekly, sunday); !got.Equal(tests[1].start) {
+		t.Errorf("BudgetWindowStart(sunday) = %v, want %v", got, tests[1].start)
+	}
+}
+
+func TestBudgetStatus(t *testing.T) {
+	now := time.Now()
+
+	budget := &model.Budget{
+		Window:      model.PeriodTypeDaily,
+		HardLimit:   10,
+		SoftLimit:   8,
+		UsedAmount:  9,
+		WindowStart: model.BudgetWindowStart(model.PeriodTypeDaily, now).Unix(),
+	}
+
+	status := model.NewBudgetStatus(budget, now)
+	if status.Used != 9 || status.Remaining != 1 || status.Exhausted {
+		t.Errorf("status = %+v, want used 9, remaining 1, not exhausted", status)
+	}
+
+	if !budget.SoftLimitReached(now) {
+		t.Error("SoftLimitReached() = false, want true")
+	}
+
+	budget.UsedAmount = 10
+	if !budget.Exhausted(now) {
+		t.Error("Exhausted() = false, want true")
+	}
+
+	// the amount of the past window is not counted
+	budget.WindowStart = model.BudgetWindowStart(model.PeriodTypeDaily, now.AddDate(0, 0, -1)).Unix()
+	if budget.Exhausted(now) || budget.Used(now) != 0 {
+		t.Errorf("Used() = %v, want 0 after the window rolled over", budget.Used(now))
+	}
+}
+
+func TestMatchBudgets(t *testing.T) {
+	budgets := []*model.Budget{
+		{ID: 1, Scope: model.BudgetScopeGroup},
+		{ID: 2, Scope: model.BudgetScopeToken, Subject: "token-a"},
+		{ID: 3, Scope: model.BudgetScopeToken, Subject: "token-b"},
+		{ID: 4, Scope: model.BudgetScopeUser, Subject: "user-a"},
+	}
+
+	tests := []struct {
+		name      string
+		tokenName string
+		user      string
+		want      []int
+	}{
+		{name: "group only", want: []int{1}},
+		{name: "token", tokenName: "token-a", want: []int{1, 2}},
+		{name: "token and user", tokenName: "token-b", user: "user-a", want: []int{1, 3, 4}},
+		{name: "unknown user", tokenName: "token-c", user: "user-b", want: []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched := model.MatchBudgets(budgets, tt.tokenName, tt.user)
+
+			ids := make([]int, 0, len(matched))
+			for _, b := range matched {
+				ids = append(ids, b.ID)
+			}
+
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("MatchBudgets() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	if matched := model.MatchBudgets(nil, "token-a", "user-a"); len(matched) != 0 {
+		t.Errorf("MatchBudgets() = %v, want none without budgets", matched)
+	}
+}
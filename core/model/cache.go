@@ -17,6 +17,7 @@ import (
 	"github.com/wavespeed/llm-server/core/common/config"
 	"github.com/wavespeed/llm-server/core/common/conv"
 	"github.com/wavespeed/llm-server/core/common/notify"
+	"github.com/wavespeed/llm-server/core/common/tiktoken"
 	"github.com/maruel/natural"
 	"github.com/redis/go-redis/v9"
 	log "github.com/sirupsen/logrus"
@@ -70,6 +71,8 @@ type TokenCache struct {
 	Status     int              `json:"status"      redis:"st"`
 	UsedAmount float64          `json:"used_amount" redis:"u"`
 
+	MCPToolACLs MCPToolACLs `json:"mcp_tool_acls" redis:"mta"`
+
 	// Quota system
 	Quota                  float64   `json:"quota"                     redis:"q"`
 	PeriodQuota            float64   `json:"period_quota"              redis:"pq"`
@@ -173,6 +176,8 @@ func (t *Token) ToTokenCache() *TokenCache {
 		Status:     t.Status,
 		UsedAmount: t.UsedAmount,
 
+		MCPToolACLs: t.MCPToolACLs,
+
 		Quota:                  t.Quota,
 		PeriodQuota:            t.PeriodQuota,
 		PeriodType:             string(t.PeriodType),
@@ -243,6 +248,119 @@ func CacheGetTokenByKey(key string) (*TokenCache, error) {
 	return tc, nil
 }
 
+const (
+	AdminKeyCacheKey = "admin_key:%s"
+)
+
+// AdminKeyCache is the admin key of a key, the keys which are not admin keys
+// are cached with a zero ID, so the user tokens of the admin api skip the
+// database too
+type AdminKeyCache struct {
+	ID         int              `json:"id"           redis:"i"`
+	Key        string           `json:"-"            redis:"-"`
+	Name       string           `json:"name"         redis:"n"`
+	Role       string           `json:"role"         redis:"r"`
+	Groups     redisStringSlice `json:"groups"       redis:"g"`
+	Status     int              `json:"status"       redis:"s"`
+	LastUsedAt redisTime        `json:"last_used_at" redis:"lu"`
+}
+
+func (k *AdminKey) ToAdminKeyCache() *AdminKeyCache {
+	return &AdminKeyCache{
+		ID:         k.ID,
+		Key:        k.Key,
+		Name:       k.Name,
+		Role:       string(k.Role),
+		Groups:     k.Groups,
+		Status:     int(k.Status),
+		LastUsedAt: redisTime(k.LastUsedAt),
+	}
+}
+
+func CacheDeleteAdminKey(key string) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+
+	return common.RDB.Del(context.Background(), common.RedisKeyf(AdminKeyCacheKey, key)).Err()
+}
+
+func CacheSetAdminKey(adminKey *AdminKeyCache) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+
+	key := common.RedisKeyf(AdminKeyCacheKey, adminKey.Key)
+	pipe := common.RDB.Pipeline()
+	pipe.HSet(context.Background(), key, adminKey)
+
+	expireTime := SyncFrequency + time.Duration(rand.Int64N(60)-30)*time.Second
+	pipe.Expire(context.Background(), key, expireTime)
+	_, err := pipe.Exec(context.Background())
+
+	return err
+}
+
+// CacheGetAdminKeyByKey returns the admin key of the key, the returned key
+// has a zero ID when the key is not an admin key
+func CacheGetAdminKeyByKey(key string) (*AdminKeyCache, error) {
+	if !common.RedisEnabled {
+		return getAdminKeyCacheByKey(key)
+	}
+
+	cacheKey := common.RedisKeyf(AdminKeyCacheKey, key)
+
+	cmd := common.RDB.HGetAll(context.Background(), cacheKey)
+
+	values, err := cmd.Result()
+	if err == nil && len(values) != 0 {
+		adminKeyCache := &AdminKeyCache{}
+
+		err = cmd.Scan(adminKeyCache)
+		if err == nil {
+			adminKeyCache.Key = key
+			return adminKeyCache, nil
+		}
+	}
+
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Errorf("get admin key from redis error: %s", err.Error())
+	}
+
+	akc, err := getAdminKeyCacheByKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CacheSetAdminKey(akc); err != nil {
+		log.Error("redis set admin key error: " + err.Error())
+	}
+
+	return akc, nil
+}
+
+// CacheUpdateAdminKeyLastUsedAt updates the last use of a cached admin key,
+// the missing keys are not created
+func CacheUpdateAdminKeyLastUsedAt(key string, lastUsedAt time.Time) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+
+	return updateAdminKeyLastUsedAtScript.Run(
+		context.Background(),
+		common.RDB,
+		[]string{common.RedisKeyf(AdminKeyCacheKey, key)},
+		redisTime(lastUsedAt),
+	).Err()
+}
+
+var updateAdminKeyLastUsedAtScript = redis.NewScript(`
+	if redis.call("HExists", KEYS[1], "lu") == 1 then
+		redis.call("HSet", KEYS[1], "lu", ARGV[1])
+	end
+	return redis.status_reply("ok")
+`)
+
 var updateTokenUsedAmountOnlyIncreaseScript = redis.NewScript(`
 	local used_amount = redis.call("HGet", KEYS[1], "ua")
 	if used_amount == false then
@@ -343,6 +461,8 @@ type GroupCache struct {
 	TPMRatio      float64                  `json:"tpm_ratio"      redis:"tpm_r"`
 	AvailableSets redisStringSlice         `json:"available_sets" redis:"ass"`
 	ModelConfigs  redisGroupModelConfigMap `json:"model_configs"  redis:"mc"`
+	MCPToolACLs   MCPToolACLs              `json:"mcp_tool_acls"  redis:"mta"`
+	Currency      string                   `json:"currency"       redis:"cur"`
 
 	BalanceAlertEnabled   bool    `json:"balance_alert_enabled"   redis:"bae"`
 	BalanceAlertThreshold float64 `json:"balance_alert_threshold" redis:"bat"`
@@ -368,7 +488,9 @@ func (g *Group) ToGroupCache() *GroupCache {
 		RPMRatio:      g.RPMRatio,
 		TPMRatio:      g.TPMRatio,
 		AvailableSets: g.AvailableSets,
+		MCPToolACLs:   g.MCPToolACLs,
 		ModelConfigs:  modelConfigs,
+		Currency:      g.Currency,
 
 		BalanceAlertEnabled:   g.BalanceAlertEnabled,
 		BalanceAlertThreshold: g.BalanceAlertThreshold,
@@ -509,6 +631,8 @@ type GroupMCPCache struct {
 	Type          GroupMCPType         `json:"type"           redis:"t"`
 	ProxyConfig   *GroupMCPProxyConfig `json:"proxy_config"   redis:"pc"`
 	OpenAPIConfig *MCPOpenAPIConfig    `json:"openapi_config" redis:"oc"`
+
+	SamplingEnabled bool `json:"sampling_enabled" redis:"se"`
 }
 
 func (g *GroupMCP) ToGroupMCPCache() *GroupMCPCache {
@@ -519,6 +643,8 @@ func (g *GroupMCP) ToGroupMCPCache() *GroupMCPCache {
 		Type:          g.Type,
 		ProxyConfig:   g.ProxyConfig,
 		OpenAPIConfig: g.OpenAPIConfig,
+
+		SamplingEnabled: g.SamplingEnabled,
 	}
 }
 
@@ -609,6 +735,9 @@ type PublicMCPCache struct {
 	ProxyConfig   *PublicMCPProxyConfig `json:"proxy_config"   redis:"pc"`
 	OpenAPIConfig *MCPOpenAPIConfig     `json:"openapi_config" redis:"oc"`
 	EmbedConfig   *MCPEmbeddingConfig   `json:"embed_config"   redis:"ec"`
+	StdioConfig   *MCPStdioConfig       `json:"stdio_config"   redis:"sc"`
+
+	SamplingEnabled bool `json:"sampling_enabled" redis:"se"`
 }
 
 func (p *PublicMCP) ToPublicMCPCache() *PublicMCPCache {
@@ -620,6 +749,9 @@ func (p *PublicMCP) ToPublicMCPCache() *PublicMCPCache {
 		ProxyConfig:   p.ProxyConfig,
 		OpenAPIConfig: p.OpenAPIConfig,
 		EmbedConfig:   p.EmbedConfig,
+		StdioConfig:   p.StdioConfig,
+
+		SamplingEnabled: p.SamplingEnabled,
 	}
 }
 
@@ -825,6 +957,14 @@ func CacheSetStore(store *StoreCache) error {
 	return err
 }
 
+func CacheDeleteStore(group string, tokenID int, id string) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+
+	return common.RDB.Del(context.Background(), common.RedisKeyf(StoreCacheKey, group, tokenID, id)).Err()
+}
+
 func CacheGetStore(group string, tokenID int, id string) (*StoreCache, error) {
 	if !common.RedisEnabled {
 		store, err := GetStore(group, tokenID, id)
@@ -857,6 +997,73 @@ func CacheGetStore(group string, tokenID int, id string) (*StoreCache, error) {
 	return sc, nil
 }
 
+const (
+	BudgetsCacheKey = "budgets:%s" // group_id
+)
+
+// CacheDeleteBudgets drops the cached budgets of the group after its budgets
+// are created, updated or deleted
+func CacheDeleteBudgets(group string) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+
+	return common.RDB.Del(context.Background(), common.RedisKeyf(BudgetsCacheKey, group)).Err()
+}
+
+// CacheSetBudgets caches all the budgets of the group, an empty list is
+// cached too so the groups without budgets skip the database
+func CacheSetBudgets(group string, budgets []*Budget) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+
+	data, err := sonic.Marshal(budgets)
+	if err != nil {
+		return err
+	}
+
+	expireTime := SyncFrequency + time.Duration(rand.Int64N(60)-30)*time.Second
+
+	return common.RDB.Set(
+		context.Background(),
+		common.RedisKeyf(BudgetsCacheKey, group),
+		data,
+		expireTime,
+	).Err()
+}
+
+// CacheGetBudgets returns all the budgets of the group, the used amounts are
+// refreshed by the charges like the used amount of the group cache
+func CacheGetBudgets(group string) ([]*Budget, error) {
+	if !common.RedisEnabled {
+		return GetBudgets(group, "")
+	}
+
+	cacheKey := common.RedisKeyf(BudgetsCacheKey, group)
+
+	data, err := common.RDB.Get(context.Background(), cacheKey).Bytes()
+	if err == nil {
+		var budgets []*Budget
+		if err := sonic.Unmarshal(data, &budgets); err == nil {
+			return budgets, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		log.Errorf("get group (%s) budgets from redis error: %s", group, err.Error())
+	}
+
+	budgets, err := GetBudgets(group, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CacheSetBudgets(group, budgets); err != nil {
+		log.Error("redis set budgets error: " + err.Error())
+	}
+
+	return budgets, nil
+}
+
 type ModelConfigCache interface {
 	GetModelConfig(model string) (ModelConfig, bool)
 }
@@ -927,6 +1134,12 @@ func InitModelConfigAndChannelCache() error {
 	// Build disabled model to channels map by set
 	disabledModel2ChannelsBySet := buildModelToChannelsBySetMap(disabledChannels)
 
+	tiktoken.SetModelTokenizers(buildModelTokenizers(enabledModelConfigsMap))
+
+	if err := LoadExchangeRates(); err != nil {
+		return err
+	}
+
 	// Update global cache atomically
 	modelCaches.Store(&ModelCaches{
 		ModelConfig: modelConfig,
@@ -942,6 +1155,17 @@ func InitModelConfigAndChannelCache() error {
 	return nil
 }
 
+func buildModelTokenizers(modelConfigs map[string]ModelConfig) map[string]string {
+	tokenizers := make(map[string]string)
+	for model, config := range modelConfigs {
+		if config.Tokenizer != "" {
+			tokenizers[model] = config.Tokenizer
+		}
+	}
+
+	return tokenizers
+}
+
 func LoadEnabledChannels() ([]*Channel, error) {
 	var channels []*Channel
 
@@ -53,6 +53,15 @@ type Channel struct {
 	BalanceThreshold        float64           `                                          json:"balance_threshold"          yaml:"balance_threshold,omitempty"`
 	Configs                 ChannelConfigs    `gorm:"serializer:fastjson;type:text"      json:"configs,omitempty"          yaml:"configs,omitempty"`
 	Sets                    []string          `gorm:"serializer:fastjson;type:text"      json:"sets,omitempty"             yaml:"sets,omitempty"`
+	// Currency is the currency the upstream bills the channel in, empty is
+	// the default currency
+	Currency string `gorm:"size:8" json:"currency,omitempty" yaml:"currency,omitempty"`
+	// CostPrices are the upstream prices of the models of the channel, the
+	// prices without a currency are in the channel currency
+	CostPrices map[string]Price `gorm:"serializer:fastjson;type:text" json:"cost_prices,omitempty" yaml:"cost_prices,omitempty"`
+	// CostRatio is the upstream cost of the models without a cost price
+	// relative to the model price, zero is an unknown cost
+	CostRatio float64 `json:"cost_ratio,omitempty" yaml:"cost_ratio,omitempty"`
 }
 
 func (c *Channel) GetSets() []string {
@@ -408,6 +417,9 @@ func UpdateChannel(channel *Channel) (err error) {
 		"enabled_auto_balance_check",
 		"balance_threshold",
 		"sets",
+		"currency",
+		"cost_prices",
+		"cost_ratio",
 	}
 	if channel.Type != 0 {
 		selects = append(selects, "type")
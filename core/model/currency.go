@@ -0,0 +1,231 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/common/config"
+	"gorm.io/gorm"
+)
+
+const (
+	ErrExchangeRateNotFound = "exchange rate"
+)
+
+// NormalizeCurrency returns the upper case currency code, empty is the
+// default currency
+func NormalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return strings.ToUpper(config.DefaultCurrency)
+	}
+
+	return currency
+}
+
+// ExchangeRate is the rate of a currency pair from EffectiveAt until the
+// next rate of the pair, the rates are kept as the history of the pair,
+// one unit of From is Rate units of To
+type ExchangeRate struct {
+	ID          int       `gorm:"primaryKey"                                               json:"id"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"                                           json:"created_at"`
+	From        string    `gorm:"column:from_currency;size:8;index:idx_exchange_rate_pair" json:"from"`
+	To          string    `gorm:"column:to_currency;size:8;index:idx_exchange_rate_pair"   json:"to"`
+	Rate        float64   `                                                                json:"rate"`
+	EffectiveAt time.Time `gorm:"index"                                                    json:"effective_at"`
+}
+
+func (r *ExchangeRate) BeforeCreate(_ *gorm.DB) error {
+	r.From = NormalizeCurrency(r.From)
+	r.To = NormalizeCurrency(r.To)
+
+	if r.From == r.To {
+		return errors.New("exchange rate currencies are the same")
+	}
+
+	if r.Rate <= 0 {
+		return errors.New("exchange rate must be positive")
+	}
+
+	if r.EffectiveAt.IsZero() {
+		r.EffectiveAt = time.Now()
+	}
+
+	return nil
+}
+
+func CreateExchangeRate(r *ExchangeRate) error {
+	err := DB.Create(r).Error
+	if err != nil {
+		return err
+	}
+
+	return LoadExchangeRates()
+}
+
+func DeleteExchangeRate(id int) error {
+	result := DB.Delete(&ExchangeRate{}, id)
+	if err := HandleUpdateResult(result, ErrExchangeRateNotFound); err != nil {
+		return err
+	}
+
+	return LoadExchangeRates()
+}
+
+func GetExchangeRate(id int) (*ExchangeRate, error) {
+	var r ExchangeRate
+
+	err := DB.Where("id = ?", id).First(&r).Error
+
+	return &r, HandleNotFound(err, ErrExchangeRateNotFound)
+}
+
+// GetExchangeRates returns the history of the rates, newest first, filtered
+// by the currencies of the pair
+func GetExchangeRates(from, to string, page, perPage int) ([]*ExchangeRate, int64, error) {
+	tx := DB.Model(&ExchangeRate{})
+	if from != "" {
+		tx = tx.Where("from_currency = ?", NormalizeCurrency(from))
+	}
+
+	if to != "" {
+		tx = tx.Where("to_currency = ?", NormalizeCurrency(to))
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if total <= 0 {
+		return nil, 0, nil
+	}
+
+	limit, offset := toLimitOffset(page, perPage)
+
+	var rates []*ExchangeRate
+
+	err := tx.
+		Order("effective_at desc, id desc").
+		Limit(limit).
+		Offset(offset).
+		Find(&rates).Error
+
+	return rates, total, err
+}
+
+// exchangeRates are the rates of the pairs sorted by EffectiveAt
+type exchangeRates map[[2]string][]*ExchangeRate
+
+var loadedExchangeRates atomic.Pointer[exchangeRates]
+
+func init() {
+	loadedExchangeRates.Store(&exchangeRates{})
+}
+
+// LoadExchangeRates loads the rate history into memory, it is reloaded with
+// the model caches
+func LoadExchangeRates() error {
+	var all []*ExchangeRate
+	if err := DB.Order("effective_at asc, id asc").Find(&all).Error; err != nil {
+		return err
+	}
+
+	SetExchangeRates(all)
+
+	return nil
+}
+
+// SetExchangeRates replaces the rates in memory
+func SetExchangeRates(all []*ExchangeRate) {
+	rates := make(exchangeRates)
+	for _, r := range all {
+		pair := [2]string{NormalizeCurrency(r.From), NormalizeCurrency(r.To)}
+		rates[pair] = append(rates[pair], r)
+	}
+
+	for _, history := range rates {
+		slices.SortStableFunc(history, func(a, b *ExchangeRate) int {
+			return a.EffectiveAt.Compare(b.EffectiveAt)
+		})
+	}
+
+	loadedExchangeRates.Store(&rates)
+}
+
+// rateAt returns the rate of the pair effective at the time, the inverse
+// pair is used when the pair has no rate
+func (r exchangeRates) rateAt(from, to string, at time.Time) (float64, bool) {
+	find := func(history []*ExchangeRate) *ExchangeRate {
+		var found *ExchangeRate
+		for _, rate := range history {
+			if rate.EffectiveAt.After(at) {
+				break
+			}
+
+			found = rate
+		}
+
+		return found
+	}
+
+	if rate := find(r[[2]string{from, to}]); rate != nil {
+		return rate.Rate, true
+	}
+
+	if rate := find(r[[2]string{to, from}]); rate != nil {
+		return 1 / rate.Rate, true
+	}
+
+	return 0, false
+}
+
+// ExchangeRateAt returns the rate converting from a currency to another at
+// the time, the pairs without a rate are converted through the default
+// currency
+func ExchangeRateAt(from, to string, at time.Time) (float64, error) {
+	from = NormalizeCurrency(from)
+	to = NormalizeCurrency(to)
+
+	if from == to {
+		return 1, nil
+	}
+
+	rates := *loadedExchangeRates.Load()
+
+	if rate, ok := rates.rateAt(from, to, at); ok {
+		return rate, nil
+	}
+
+	base := NormalizeCurrency("")
+	if from != base && to != base {
+		fromBase, ok := rates.rateAt(from, base, at)
+		if ok {
+			if baseTo, ok := rates.rateAt(base, to, at); ok {
+				return fromBase * baseTo, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("no exchange rate from %s to %s at %s", from, to, at.Format(time.RFC3339))
+}
+
+// ConvertCurrency converts the amount at the rate effective at the time, the
+// amount is zero without a rate, an amount in another currency is never
+// used as is
+func ConvertCurrency(amount float64, from, to string, at time.Time) (float64, error) {
+	if amount == 0 {
+		return 0, nil
+	}
+
+	rate, err := ExchangeRateAt(from, to, at)
+	if err != nil {
+		return 0, err
+	}
+
+	return amount * rate, nil
+}
@@ -0,0 +1,69 @@
+package model_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func TestConvertCurrency(t *testing.T) {
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	model.SetExchangeRates([]*model.ExchangeRate{
+		{From: "USD", To: "CNY", Rate: 7.3, EffectiveAt: feb},
+		{From: "usd", To: "cny", Rate: 7.1, EffectiveAt: jan},
+		{From: "EUR", To: "USD", Rate: 1.1, EffectiveAt: jan},
+	})
+	defer model.SetExchangeRates(nil)
+
+	tests := []struct {
+		name     string
+		amount   float64
+		from, to string
+		at       time.Time
+		want     float64
+		wantErr  bool
+	}{
+		{"same currency", 2, "CNY", "cny", jan, 2, false},
+		{"default currency", 1, "", "CNY", jan.Add(time.Hour), 7.1, false},
+		{"rate of the time", 1, "USD", "CNY", feb.Add(time.Hour), 7.3, false},
+		{"inverse pair", 7.3, "CNY", "USD", feb, 1, false},
+		{"through the default currency", 1, "EUR", "CNY", feb, 1.1 * 7.3, false},
+		{"before the first rate", 1, "USD", "CNY", jan.Add(-time.Hour), 0, true},
+		{"unknown pair", 1, "JPY", "CNY", feb, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := model.ConvertCurrency(tt.amount, tt.from, tt.to, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertCurrency() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ConvertCurrency() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectConditionalPriceCurrency(t *testing.T) {
+	price := model.Price{
+		Currency:   "CNY",
+		InputPrice: 1,
+		ConditionalPrices: []model.ConditionalPrice{
+			{
+				Condition: model.PriceCondition{InputTokenMin: 100},
+				Price:     model.Price{InputPrice: 2},
+			},
+		},
+	}
+
+	selected := price.SelectConditionalPrice(model.Usage{InputTokens: 200})
+	if selected.InputPrice != 2 || selected.Currency != "CNY" {
+		t.Errorf("Expected the conditional price in CNY, Got %+v", selected)
+	}
+}
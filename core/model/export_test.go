@@ -2,3 +2,7 @@ package model
 
 // Export for testing
 var ToLimitOffset = toLimitOffset
+
+var WebhookBackoff = webhookBackoff
+
+var MatchBudgets = matchBudgets
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ErrFileNotFound = "file"
+)
+
+const (
+	FileStatusUploaded  = "uploaded"
+	FileStatusProcessed = "processed"
+	FileStatusError     = "error"
+)
+
+// File is a file uploaded through the files api. Like StoreV2 it belongs to a
+// group and token. Files kept by the gateway have a StorageKey, files uploaded
+// to an upstream channel have a ChannelID and are pinned through StoreV2.
+type File struct {
+	ID         string    `gorm:"size:128;primaryKey:3"`
+	GroupID    string    `gorm:"size:64;primaryKey:1"`
+	TokenID    int       `gorm:"primaryKey:2"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index"`
+	Filename   string    `gorm:"size:256"`
+	Purpose    string    `gorm:"size:32;index"`
+	Bytes      int64
+	Status     string `gorm:"size:32"`
+	StorageKey string `gorm:"size:512"`
+	ChannelID  int
+	Model      string `gorm:"size:64"`
+}
+
+func (f *File) BeforeCreate(_ *gorm.DB) error {
+	if f.ID == "" {
+		return errors.New("file id is required")
+	}
+
+	if f.GroupID != "" && f.TokenID == 0 {
+		return errors.New("token id is required")
+	}
+
+	if f.StorageKey == "" && f.ChannelID == 0 {
+		return errors.New("file storage key or channel id is required")
+	}
+
+	if f.Status == "" {
+		f.Status = FileStatusProcessed
+	}
+
+	return nil
+}
+
+func CreateFile(f *File) error {
+	return LogDB.Create(f).Error
+}
+
+func GetFile(group string, tokenID int, id string) (*File, error) {
+	var f File
+
+	err := LogDB.Where("group_id = ? and token_id = ? and id = ?", group, tokenID, id).
+		First(&f).
+		Error
+
+	return &f, HandleNotFound(err, ErrFileNotFound)
+}
+
+// GetFiles lists files with the openai cursor pagination, after is the id of
+// the last file of the previous page
+func GetFiles(
+	group string,
+	tokenID int,
+	purpose string,
+	after string,
+	limit int,
+	order string,
+) (files []*File, hasMore bool, err error) {
+	tx := LogDB.Model(&File{}).
+		Where("group_id = ? and token_id = ?", group, tokenID)
+
+	if purpose != "" {
+		tx = tx.Where("purpose = ?", purpose)
+	}
+
+	asc := order == "asc"
+
+	if after != "" {
+		cursor, err := GetFile(group, tokenID, after)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if asc {
+			tx = tx.Where("created_at > ? or (created_at = ? and id > ?)",
+				cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+		} else {
+			tx = tx.Where("created_at < ? or (created_at = ? and id < ?)",
+				cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+		}
+	}
+
+	if asc {
+		tx = tx.Order("created_at asc, id asc")
+	} else {
+		tx = tx.Order("created_at desc, id desc")
+	}
+
+	err = tx.Limit(limit + 1).Find(&files).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(files) > limit {
+		return files[:limit], true, nil
+	}
+
+	return files, false, nil
+}
+
+func DeleteFile(group string, tokenID int, id string) error {
+	result := LogDB.
+		Where("group_id = ? and token_id = ? and id = ?", group, tokenID, id).
+		Delete(&File{})
+
+	return HandleUpdateResult(result, ErrFileNotFound)
+}
@@ -34,6 +34,10 @@ type Group struct {
 	UsedAmount             float64                 `json:"used_amount"              gorm:"index"`
 	RequestCount           int                     `json:"request_count"            gorm:"index"`
 	AvailableSets          []string                `json:"available_sets,omitempty" gorm:"serializer:fastjson;type:text"`
+	MCPToolACLs            MCPToolACLs             `json:"mcp_tool_acls,omitempty"  gorm:"serializer:fastjson;type:text"`
+	// Currency is the billing currency of the group, the consumes are
+	// converted into it, empty is the default currency
+	Currency string `json:"currency,omitempty" gorm:"size:8"`
 
 	BalanceAlertEnabled   bool    `gorm:"default:false" json:"balance_alert_enabled"`
 	BalanceAlertThreshold float64 `gorm:"default:0"     json:"balance_alert_threshold"`
@@ -65,6 +69,11 @@ func (g *Group) BeforeDelete(tx *gorm.DB) (err error) {
 		return err
 	}
 
+	err = tx.Model(&VirtualMCP{}).Where("group_id = ?", g.ID).Delete(&VirtualMCP{}).Error
+	if err != nil {
+		return err
+	}
+
 	return tx.Model(&GroupModelConfig{}).
 		Where("group_id = ?", g.ID).
 		Delete(&GroupModelConfig{}).
@@ -188,12 +197,14 @@ func DeleteGroupsByIDs(ids []string) (err error) {
 }
 
 type UpdateGroupRequest struct {
-	Status                int       `json:"status"`
-	RPMRatio              *float64  `json:"rpm_ratio,omitempty"`
-	TPMRatio              *float64  `json:"tpm_ratio,omitempty"`
-	AvailableSets         *[]string `json:"available_sets,omitempty"`
-	BalanceAlertEnabled   *bool     `json:"balance_alert_enabled"`
-	BalanceAlertThreshold *float64  `json:"balance_alert_threshold"`
+	Status                int          `json:"status"`
+	RPMRatio              *float64     `json:"rpm_ratio,omitempty"`
+	TPMRatio              *float64     `json:"tpm_ratio,omitempty"`
+	AvailableSets         *[]string    `json:"available_sets,omitempty"`
+	MCPToolACLs           *MCPToolACLs `json:"mcp_tool_acls,omitempty"`
+	Currency              *string      `json:"currency,omitempty"`
+	BalanceAlertEnabled   *bool        `json:"balance_alert_enabled"`
+	BalanceAlertThreshold *float64     `json:"balance_alert_threshold"`
 }
 
 func UpdateGroup(id string, update UpdateGroupRequest) (group *Group, err error) {
@@ -233,6 +244,18 @@ func UpdateGroup(id string, update UpdateGroupRequest) (group *Group, err error)
 		selects = append(selects, "available_sets")
 	}
 
+	if update.MCPToolACLs != nil {
+		group.MCPToolACLs = *update.MCPToolACLs
+
+		selects = append(selects, "mcp_tool_acls")
+	}
+
+	if update.Currency != nil {
+		group.Currency = strings.ToUpper(strings.TrimSpace(*update.Currency))
+
+		selects = append(selects, "currency")
+	}
+
 	if update.BalanceAlertEnabled != nil {
 		group.BalanceAlertEnabled = *update.BalanceAlertEnabled
 
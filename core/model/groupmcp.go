@@ -48,6 +48,10 @@ type GroupMCP struct {
 	Description   string               `                                     json:"description"`
 	ProxyConfig   *GroupMCPProxyConfig `gorm:"serializer:fastjson;type:text" json:"proxy_config,omitempty"`
 	OpenAPIConfig *MCPOpenAPIConfig    `gorm:"serializer:fastjson;type:text" json:"openapi_config,omitempty"`
+
+	// SamplingEnabled lets the mcp sample the models of the group, the
+	// sampling requests are billed to the group
+	SamplingEnabled bool `json:"sampling_enabled"`
 }
 
 func (g *GroupMCP) BeforeSave(_ *gorm.DB) (err error) {
@@ -113,6 +117,7 @@ func UpdateGroupMCP(mcp *GroupMCP) (err error) {
 		"proxy_config",
 		"openapi_config",
 		"description",
+		"sampling_enabled",
 	}
 	if mcp.Type != "" {
 		selects = append(selects, "type")
@@ -29,6 +29,10 @@ type GroupModelConfig struct {
 
 	OverrideForceSaveDetail bool `json:"override_force_save_detail"`
 	ForceSaveDetail         bool `json:"force_save_detail"`
+
+	// the plugin configs of the group replace the model ones by plugin name
+	OverridePlugin bool                      `json:"override_plugin"`
+	Plugin         map[string]map[string]any `json:"plugin,omitempty"        gorm:"serializer:fastjson;type:text"`
 }
 
 func (g *GroupModelConfig) BeforeSave(_ *gorm.DB) (err error) {
@@ -69,6 +69,13 @@ type Log struct {
 	Price            Price           `gorm:"embedded"                                                       json:"price,omitempty"`
 	Usage            Usage           `gorm:"embedded"                                                       json:"usage,omitempty"`
 	UsedAmount       float64         `                                                                      json:"used_amount,omitempty"`
+	// OriginalAmount is the amount in the price currency, UsedAmount is
+	// converted into the billing currency of the group
+	OriginalAmount float64 `                                                                      json:"original_amount,omitempty"`
+	Currency       string  `gorm:"size:8"                                                         json:"currency,omitempty"`
+	// CostAmount is what the upstream of the channel charges in the billing
+	// currency of the group
+	CostAmount float64 `                                                                      json:"cost_amount,omitempty"`
 	// https://platform.openai.com/docs/guides/safety-best-practices#end-user-ids
 	User     EmptyNullString   `gorm:"type:text"                                                      json:"user,omitempty"`
 	Metadata map[string]string `gorm:"serializer:fastjson;type:text"                                  json:"metadata,omitempty"`
@@ -267,6 +274,31 @@ func cleanLog(batchSize int) error {
 		}
 	}
 
+	if logStorageHours != 0 {
+		var ids []int
+		cutoffTime := time.Now().Add(-time.Duration(logStorageHours) * time.Hour)
+
+		err := LogDB.
+			Model(&MCPLog{}).
+			Where("created_at < ?", cutoffTime).
+			Limit(batchSize).
+			Select("id").
+			Find(&ids).Error
+		if err != nil {
+			return err
+		}
+
+		if len(ids) > 0 {
+			err = LogDB.
+				Session(&gorm.Session{SkipDefaultTransaction: true}).
+				Where("id IN (?)", ids).
+				Delete(&MCPLog{}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	retryLogStorageHours := config.GetRetryLogStorageHours()
 	if retryLogStorageHours == 0 {
 		retryLogStorageHours = logStorageHours
@@ -379,6 +411,9 @@ func RecordConsumeLog(
 	usage Usage,
 	modelPrice Price,
 	amount float64,
+	originalAmount float64,
+	costAmount float64,
+	currency string,
 	user string,
 	metadata map[string]string,
 ) error {
@@ -430,6 +465,9 @@ func RecordConsumeLog(
 		Price:                modelPrice,
 		Usage:                usage,
 		UsedAmount:           amount,
+		OriginalAmount:       originalAmount,
+		CostAmount:           costAmount,
+		Currency:             currency,
 		User:                 EmptyNullString(user),
 		Metadata:             metadata,
 	}
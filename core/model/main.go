@@ -162,9 +162,14 @@ func migrateDB() error {
 		&GroupModelConfig{},
 		&PublicMCPReusingParam{},
 		&GroupMCP{},
+		&VirtualMCP{},
 		&Group{},
 		&Option{},
 		&ModelConfig{},
+		&WebhookSubscription{},
+		&AdminKey{},
+		&Budget{},
+		&ExchangeRate{},
 	)
 	if err != nil {
 		return err
@@ -247,7 +252,14 @@ func migrateLogDB(batchSize int) error {
 		&GroupSummary{},
 		&Summary{},
 		&ConsumeError{},
+		&MCPLog{},
 		&StoreV2{},
+		&StoreBilled{},
+		&File{},
+		&BatchJob{},
+		&BatchJobRequest{},
+		&WebhookDelivery{},
+		&AuditLog{},
 		&SummaryMinute{},
 		&GroupSummaryMinute{},
 	)
@@ -0,0 +1,196 @@
+package model
+
+import (
+	"time"
+
+	"github.com/bytedance/sonic"
+	"gorm.io/gorm"
+)
+
+// MCPConsumeErrorModelPrefix prefixes the mcp id in the model of the
+// consume errors of the tools calls
+const MCPConsumeErrorModelPrefix = "mcp:"
+
+// MCPConsumeErrorModel is the model of the consume errors of the tools calls
+// of the mcp, the tools calls are summarized by the mcp logs and never in
+// the model summaries
+func MCPConsumeErrorModel(mcpID string) string {
+	return MCPConsumeErrorModelPrefix + mcpID
+}
+
+// MCPScope is whether the mcp is a public mcp or an mcp of the group
+type MCPScope string
+
+const (
+	MCPScopePublic MCPScope = "public"
+	MCPScopeGroup  MCPScope = "group"
+)
+
+// MCPLog is a tools/call passing through an mcp server of the gateway
+type MCPLog struct {
+	RequestAt           time.Time       `gorm:"index;index:idx_mcp_log_group_reqat,priority:2"   json:"request_at"`
+	CreatedAt           time.Time       `gorm:"autoCreateTime;index"                             json:"created_at"`
+	GroupID             string          `gorm:"size:64;index:idx_mcp_log_group_reqat,priority:1" json:"group"`
+	TokenName           EmptyNullString `gorm:"size:32"                                          json:"token_name,omitempty"`
+	MCPID               string          `gorm:"size:64;index"                                    json:"mcp_id"`
+	Scope               MCPScope        `gorm:"size:16"                                          json:"scope"`
+	Tool                string          `gorm:"size:128;index"                                   json:"tool"`
+	Error               string          `gorm:"type:text"                                        json:"error,omitempty"`
+	Currency            string          `gorm:"size:8"                                           json:"currency,omitempty"`
+	IP                  EmptyNullString `gorm:"size:64"                                          json:"ip,omitempty"`
+	ID                  int             `gorm:"primaryKey"                                       json:"id"`
+	TokenID             int             `                                                        json:"token_id,omitempty"`
+	ArgumentsSize       int             `                                                        json:"arguments_size"`
+	LatencyMilliseconds int64           `                                                        json:"latency_milliseconds"`
+	UsedAmount          float64         `                                                        json:"used_amount,omitempty"`
+}
+
+func (l *MCPLog) MarshalJSON() ([]byte, error) {
+	type Alias MCPLog
+
+	return sonic.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+		RequestAt int64 `json:"request_at"`
+	}{
+		Alias:     (*Alias)(l),
+		CreatedAt: l.CreatedAt.UnixMilli(),
+		RequestAt: l.RequestAt.UnixMilli(),
+	})
+}
+
+func RecordMCPLog(log *MCPLog) error {
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+
+	if log.RequestAt.IsZero() {
+		log.RequestAt = log.CreatedAt
+	}
+
+	return LogDB.Create(log).Error
+}
+
+func buildMCPLogsQuery(
+	group string,
+	tokenName string,
+	mcpID string,
+	tool string,
+	startTimestamp time.Time,
+	endTimestamp time.Time,
+	onlyError bool,
+) *gorm.DB {
+	tx := LogDB.Model(&MCPLog{})
+
+	if group != "" {
+		tx = tx.Where("group_id = ?", group)
+	}
+
+	if tokenName != "" {
+		tx = tx.Where("token_name = ?", tokenName)
+	}
+
+	if mcpID != "" {
+		tx = tx.Where("mcp_id = ?", mcpID)
+	}
+
+	if tool != "" {
+		tx = tx.Where("tool = ?", tool)
+	}
+
+	if !startTimestamp.IsZero() {
+		tx = tx.Where("request_at >= ?", startTimestamp)
+	}
+
+	if !endTimestamp.IsZero() {
+		tx = tx.Where("request_at <= ?", endTimestamp)
+	}
+
+	if onlyError {
+		tx = tx.Where("error IS NOT NULL AND error <> ''")
+	}
+
+	return tx
+}
+
+// SearchMCPLogs returns the mcp tools calls of the filters
+func SearchMCPLogs(
+	group string,
+	tokenName string,
+	mcpID string,
+	tool string,
+	startTimestamp time.Time,
+	endTimestamp time.Time,
+	onlyError bool,
+	page, perPage int,
+	order string,
+) (logs []*MCPLog, total int64, err error) {
+	tx := buildMCPLogsQuery(
+		group,
+		tokenName,
+		mcpID,
+		tool,
+		startTimestamp,
+		endTimestamp,
+		onlyError,
+	)
+
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if total <= 0 {
+		return nil, 0, nil
+	}
+
+	limit, offset := toLimitOffset(page, perPage)
+
+	err = tx.Order(getLogOrder(order)).Limit(limit).Offset(offset).Find(&logs).Error
+
+	return logs, total, err
+}
+
+// MCPUsage is the usage of a tool of an mcp summarized from the mcp logs
+type MCPUsage struct {
+	MCPID                    string  `json:"mcp_id"`
+	Tool                     string  `json:"tool"`
+	Currency                 string  `json:"currency,omitempty"`
+	Calls                    int64   `json:"calls"`
+	ErrorCalls               int64   `json:"error_calls"`
+	UsedAmount               float64 `json:"used_amount"`
+	TotalLatencyMilliseconds int64   `json:"total_latency_milliseconds"`
+}
+
+// GetMCPUsage returns the usage of the tools of the mcps of the filters, the
+// most charged tools first
+func GetMCPUsage(
+	group string,
+	tokenName string,
+	mcpID string,
+	startTimestamp time.Time,
+	endTimestamp time.Time,
+) ([]MCPUsage, error) {
+	var usages []MCPUsage
+
+	err := buildMCPLogsQuery(
+		group,
+		tokenName,
+		mcpID,
+		"",
+		startTimestamp,
+		endTimestamp,
+		false,
+	).
+		Select(
+			"mcp_id, tool, currency, " +
+				"COUNT(*) AS calls, " +
+				"SUM(CASE WHEN error IS NOT NULL AND error <> '' THEN 1 ELSE 0 END) AS error_calls, " +
+				"SUM(used_amount) AS used_amount, " +
+				"SUM(latency_milliseconds) AS total_latency_milliseconds",
+		).
+		Group("mcp_id, tool, currency").
+		Order("used_amount DESC").
+		Scan(&usages).Error
+
+	return usages, err
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"encoding"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/bytedance/sonic"
+	"github.com/wavespeed/llm-server/core/common/conv"
+	"github.com/redis/go-redis/v9"
+)
+
+// MCPToolACL is the tools of an mcp that can be called, the patterns are
+// globs, a denied tool is never allowed and a non empty allow list only
+// allows its tools
+type MCPToolACL struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a MCPToolACL) Allowed(tool string) bool {
+	if matchAny(a.Deny, tool) {
+		return false
+	}
+
+	return len(a.Allow) == 0 || matchAny(a.Allow, tool)
+}
+
+// MCPToolACLs is the tool acls by mcp id glob, a tool is allowed when all
+// the acls of its mcp allow it
+type MCPToolACLs map[string]MCPToolACL
+
+var (
+	_ redis.Scanner            = (*MCPToolACLs)(nil)
+	_ encoding.BinaryMarshaler = (*MCPToolACLs)(nil)
+)
+
+func (a *MCPToolACLs) ScanRedis(value string) error {
+	return sonic.Unmarshal(conv.StringToBytes(value), a)
+}
+
+func (a MCPToolACLs) MarshalBinary() ([]byte, error) {
+	return sonic.Marshal(a)
+}
+
+func (a MCPToolACLs) Allowed(mcpID, tool string) bool {
+	for pattern, acl := range a {
+		if ok, _ := path.Match(pattern, mcpID); ok && !acl.Allowed(tool) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Validate checks the patterns of the acls are valid globs
+func (a MCPToolACLs) Validate() error {
+	for pattern, acl := range a {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid mcp pattern %q: %w", pattern, err)
+		}
+
+		for _, tool := range slices.Concat(acl.Allow, acl.Deny) {
+			if _, err := path.Match(tool, ""); err != nil {
+				return fmt.Errorf("invalid tool pattern %q of mcp %q: %w", tool, pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func TestMCPToolACLsAllowed(t *testing.T) {
+	acls := model.MCPToolACLs{
+		"github": {
+			Allow: []string{"get_*", "list_*", "search_*", "create_pull_request"},
+			Deny:  []string{"create_pull_request", "delete_file"},
+		},
+		"*": {
+			Deny: []string{"*_secret"},
+		},
+	}
+
+	tests := []struct {
+		mcpID string
+		tool  string
+		want  bool
+	}{
+		{"github", "get_file_contents", true},
+		{"github", "list_issues", true},
+		{"github", "create_pull_request", false},
+		{"github", "delete_file", false},
+		{"github", "push_files", false},
+		{"github", "get_secret", false},
+		{"sqlite", "read_query", true},
+		{"sqlite", "read_secret", false},
+	}
+
+	for _, tt := range tests {
+		if got := acls.Allowed(tt.mcpID, tt.tool); got != tt.want {
+			t.Errorf("Allowed(%q, %q) = %v, want %v", tt.mcpID, tt.tool, got, tt.want)
+		}
+	}
+
+	var empty model.MCPToolACLs
+	if !empty.Allowed("github", "delete_file") {
+		t.Error("empty acls should allow all the tools")
+	}
+}
+
+func TestMCPToolACLsValidate(t *testing.T) {
+	if err := (model.MCPToolACLs{"github": {Allow: []string{"get_*"}}}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	if err := (model.MCPToolACLs{"github": {Deny: []string{"[get"}}}).Validate(); err == nil {
+		t.Error("Validate() should fail on an invalid tool pattern")
+	}
+
+	if err := (model.MCPToolACLs{"[github": {}}).Validate(); err == nil {
+		t.Error("Validate() should fail on an invalid mcp pattern")
+	}
+}
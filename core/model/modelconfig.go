@@ -1,8 +1,12 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -44,6 +48,40 @@ type ModelConfig struct {
 	WarnErrorRate   float64            `                                     json:"warn_error_rate,omitempty"      yaml:"warn_error_rate,omitempty"`
 	MaxErrorRate    float64            `                                     json:"max_error_rate,omitempty"       yaml:"max_error_rate,omitempty"`
 	ForceSaveDetail bool               `                                     json:"force_save_detail,omitempty"    yaml:"force_save_detail,omitempty"`
+	// FallbackModels are tried in order when all the channels of the model fail
+	FallbackModels []string `gorm:"serializer:fastjson;type:text" json:"fallback_models,omitempty" yaml:"fallback_models,omitempty"`
+	// Tokenizer counts the tokens of the model, a tokenizer of TOKENIZER_DIR or a tiktoken encoding
+	Tokenizer string `gorm:"size:64" json:"tokenizer,omitempty" yaml:"tokenizer,omitempty"`
+	// RoutingStrategy picks the channel of the requests, empty is by priority
+	RoutingStrategy RoutingStrategy `gorm:"size:32" json:"routing_strategy,omitempty" yaml:"routing_strategy,omitempty"`
+}
+
+type RoutingStrategy string
+
+const (
+	// RoutingStrategyPriority picks a random channel weighted by the
+	// priority and the error rate
+	RoutingStrategyPriority RoutingStrategy = "priority"
+	// RoutingStrategyLowestCost picks the channel with the lowest upstream
+	// cost, the channels without a cost are at the model price
+	RoutingStrategyLowestCost RoutingStrategy = "lowest_cost"
+	// RoutingStrategyLowestTTFB picks the channel with the lowest median
+	// time to first byte of the recent requests
+	RoutingStrategyLowestTTFB RoutingStrategy = "lowest_ttfb"
+	// RoutingStrategyLeastInFlight picks the channel with the fewest
+	// requests in flight
+	RoutingStrategyLeastInFlight RoutingStrategy = "least_in_flight"
+	// RoutingStrategyWeightedRoundRobin rotates the channels weighted by
+	// the priority
+	RoutingStrategyWeightedRoundRobin RoutingStrategy = "weighted_round_robin"
+)
+
+var RoutingStrategies = []RoutingStrategy{
+	RoutingStrategyPriority,
+	RoutingStrategyLowestCost,
+	RoutingStrategyLowestTTFB,
+	RoutingStrategyLeastInFlight,
+	RoutingStrategyWeightedRoundRobin,
 }
 
 func (c *ModelConfig) BeforeSave(_ *gorm.DB) (err error) {
@@ -55,6 +93,10 @@ func (c *ModelConfig) BeforeSave(_ *gorm.DB) (err error) {
 		return err
 	}
 
+	if c.RoutingStrategy != "" && !slices.Contains(RoutingStrategies, c.RoutingStrategy) {
+		return fmt.Errorf("invalid routing strategy: %s", c.RoutingStrategy)
+	}
+
 	return nil
 }
 
@@ -89,7 +131,28 @@ func (c *ModelConfig) LoadPluginConfig(pluginName string, config any) error {
 		return nil
 	}
 
-	return mapstructure.Decode(pluginConfig, config)
+	// plugin configs are documented with their json names
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:    "json",
+		Result:     config,
+		DecodeHook: rawMessageDecodeHook,
+	})
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(pluginConfig)
+}
+
+var rawMessageType = reflect.TypeFor[json.RawMessage]()
+
+// rawMessageDecodeHook keeps the nested plugin config which is decoded later
+func rawMessageDecodeHook(_, to reflect.Type, data any) (any, error) {
+	if to != rawMessageType {
+		return data, nil
+	}
+
+	return sonic.Marshal(data)
 }
 
 func (c *ModelConfig) LoadFromGroupModelConfig(groupModelConfig GroupModelConfig) ModelConfig {
@@ -112,6 +175,15 @@ func (c *ModelConfig) LoadFromGroupModelConfig(groupModelConfig GroupModelConfig
 		newC.ForceSaveDetail = groupModelConfig.ForceSaveDetail
 	}
 
+	if groupModelConfig.OverridePlugin && len(groupModelConfig.Plugin) > 0 {
+		newC.Plugin = maps.Clone(newC.Plugin)
+		if newC.Plugin == nil {
+			newC.Plugin = make(map[string]map[string]any, len(groupModelConfig.Plugin))
+		}
+
+		maps.Copy(newC.Plugin, groupModelConfig.Plugin)
+	}
+
 	return newC
 }
 
@@ -0,0 +1,82 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func TestModelConfig_LoadPluginConfig(t *testing.T) {
+	type engine struct {
+		Type string          `json:"type"`
+		Spec json.RawMessage `json:"spec"`
+	}
+
+	type pluginConfig struct {
+		Enable     bool     `json:"enable"`
+		MaxResults int      `json:"max_results"`
+		SearchFrom []engine `json:"search_from"`
+	}
+
+	mc := model.ModelConfig{
+		Plugin: map[string]map[string]any{
+			"test": {
+				"enable":      true,
+				"max_results": float64(5),
+				"search_from": []any{
+					map[string]any{
+						"type": "bing",
+						"spec": map[string]any{"api_key": "key"},
+					},
+				},
+			},
+		},
+	}
+
+	var config pluginConfig
+	if err := mc.LoadPluginConfig("test", &config); err != nil {
+		t.Fatalf("LoadPluginConfig() error = %v", err)
+	}
+
+	if !config.Enable || config.MaxResults != 5 {
+		t.Errorf("LoadPluginConfig() = %+v, want snake case fields decoded", config)
+	}
+
+	if len(config.SearchFrom) != 1 || string(config.SearchFrom[0].Spec) != `{"api_key":"key"}` {
+		t.Errorf("LoadPluginConfig() search_from = %+v, want raw spec kept", config.SearchFrom)
+	}
+
+	var missing pluginConfig
+	if err := mc.LoadPluginConfig("missing", &missing); err != nil || missing.Enable {
+		t.Errorf("LoadPluginConfig() missing plugin = %+v, %v", missing, err)
+	}
+}
+
+func TestModelConfig_LoadFromGroupModelConfigPlugin(t *testing.T) {
+	mc := model.ModelConfig{
+		Plugin: map[string]map[string]any{
+			"cache":     {"enable": true},
+			"guardrail": {"enable": false},
+		},
+	}
+
+	notOverridden := mc.LoadFromGroupModelConfig(model.GroupModelConfig{
+		Plugin: map[string]map[string]any{"guardrail": {"enable": true}},
+	})
+	if notOverridden.Plugin["guardrail"]["enable"] != false {
+		t.Errorf("plugin overridden without override_plugin: %v", notOverridden.Plugin)
+	}
+
+	overridden := mc.LoadFromGroupModelConfig(model.GroupModelConfig{
+		OverridePlugin: true,
+		Plugin:         map[string]map[string]any{"guardrail": {"enable": true}},
+	})
+	if overridden.Plugin["guardrail"]["enable"] != true || overridden.Plugin["cache"]["enable"] != true {
+		t.Errorf("LoadFromGroupModelConfig() plugin = %v", overridden.Plugin)
+	}
+
+	if mc.Plugin["guardrail"]["enable"] != false {
+		t.Errorf("model config plugin modified: %v", mc.Plugin)
+	}
+}
@@ -34,6 +34,7 @@ const (
 	PublicMCPTypeDocs            PublicMCPType = "mcp_docs" // read only
 	PublicMCPTypeOpenAPI         PublicMCPType = "mcp_openapi"
 	PublicMCPTypeEmbed           PublicMCPType = "mcp_embed"
+	PublicMCPTypeStdio           PublicMCPType = "mcp_stdio"
 )
 
 type ProxyParamType string
@@ -55,6 +56,15 @@ type MCPPrice struct {
 	ToolsCallPrices       map[string]float64 `json:"tools_call_prices"        gorm:"serializer:fastjson;type:text"`
 }
 
+// ToolsCallPrice is the price of a call of the tool
+func (p MCPPrice) ToolsCallPrice(tool string) float64 {
+	if price, ok := p.ToolsCallPrices[tool]; ok {
+		return price
+	}
+
+	return p.DefaultToolsCallPrice
+}
+
 type PublicMCPProxyReusingParam struct {
 	ReusingParam
 	Type ProxyParamType `json:"type"`
@@ -114,6 +124,40 @@ type MCPOpenAPIConfig struct {
 	Authorization  string `json:"authorization,omitempty"`
 }
 
+// MCPStdioLimits bounds the processes of a stdio mcp, zero is the default,
+// a negative cpu, memory or open files limit is unlimited
+type MCPStdioLimits struct {
+	// CPUSeconds is the cpu time of a process
+	CPUSeconds int64 `json:"cpu_seconds,omitempty"`
+	// MemoryMB is the virtual memory of a process
+	MemoryMB int64 `json:"memory_mb,omitempty"`
+	// OpenFiles is the open files of a process
+	OpenFiles int64 `json:"open_files,omitempty"`
+	// CallTimeoutSeconds is the time of a request to a process
+	CallTimeoutSeconds int64 `json:"call_timeout_seconds,omitempty"`
+	// IdleTimeoutSeconds is the idle time before a process is shut down
+	IdleTimeoutSeconds int64 `json:"idle_timeout_seconds,omitempty"`
+	// MaxProcesses is the processes of a group
+	MaxProcesses int `json:"max_processes,omitempty"`
+}
+
+// MCPStdioConfig is the command of a stdio mcp run by the gateway per group,
+// the reusing params of the group are set as env vars and the ${NAME} in
+// the args and env are expanded from the env
+type MCPStdioConfig struct {
+	Command string                  `json:"command"`
+	Args    []string                `json:"args,omitempty"`
+	Env     map[string]string       `json:"env,omitempty"`
+	Reusing map[string]ReusingParam `json:"reusing,omitempty"`
+	Limits  MCPStdioLimits          `json:"limits"`
+
+	// Dirs and Files are the reusing params holding the paths of a
+	// directory or a file, the paths are relative to the directory of the
+	// group on the gateway and can not leave it
+	Dirs  []string `json:"dirs,omitempty"`
+	Files []string `json:"files,omitempty"`
+}
+
 type MCPEmbeddingConfig struct {
 	Init    map[string]string       `json:"init"`
 	Reusing map[string]ReusingParam `json:"reusing"`
@@ -159,9 +203,14 @@ type PublicMCP struct {
 	LogoURL       string          `json:"logo_url,omitempty"`
 	Price         MCPPrice        `json:"price"                    gorm:"embedded"`
 
+	// SamplingEnabled lets the mcp sample the models of the groups using
+	// it, the sampling requests are billed to the groups
+	SamplingEnabled bool `json:"sampling_enabled"`
+
 	ProxyConfig   *PublicMCPProxyConfig `gorm:"serializer:fastjson;type:text" json:"proxy_config,omitempty"`
 	OpenAPIConfig *MCPOpenAPIConfig     `gorm:"serializer:fastjson;type:text" json:"openapi_config,omitempty"`
 	EmbedConfig   *MCPEmbeddingConfig   `gorm:"serializer:fastjson;type:text" json:"embed_config,omitempty"`
+	StdioConfig   *MCPStdioConfig       `gorm:"serializer:fastjson;type:text" json:"stdio_config,omitempty"`
 	// only used by list tools
 	TestConfig *TestConfig `gorm:"serializer:fastjson;type:text" json:"test_config,omitempty"`
 }
@@ -197,6 +246,11 @@ func (p *PublicMCP) BeforeSave(_ *gorm.DB) error {
 		return validateHTTPURL(config.URL)
 	}
 
+	if p.Type == PublicMCPTypeStdio &&
+		(p.StdioConfig == nil || p.StdioConfig.Command == "") {
+		return errors.New("stdio command is empty")
+	}
+
 	return nil
 }
 
@@ -293,7 +347,9 @@ func UpdatePublicMCP(mcp *PublicMCP) (err error) {
 		"proxy_config",
 		"openapi_config",
 		"embed_config",
+		"stdio_config",
 		"test_config",
+		"sampling_enabled",
 	}
 	if mcp.Status != 0 {
 		selects = append(selects, "status")
@@ -6,12 +6,16 @@ import (
 
 	"github.com/wavespeed/llm-server/core/common"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
 	ErrStoreNotFound = "store id"
 )
 
+// FineTuningJobIDPrefix is the id prefix of the upstream fine-tuning jobs
+const FineTuningJobIDPrefix = "ftjob-"
+
 // StoreV2 represents channel-associated data storage for various purposes:
 // - Video generation jobs and their results
 // - File storage with associated metadata
@@ -26,6 +30,14 @@ type StoreV2 struct {
 	Model     string `gorm:"size:64"`
 }
 
+// StoreBilled marks the store of a group, e.g. a fine-tuning job, as billed,
+// the primary key makes the mark atomic across the tokens and the instances
+type StoreBilled struct {
+	GroupID   string    `gorm:"size:64;primaryKey:1"`
+	ID        string    `gorm:"size:128;primaryKey:2"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
 func (s *StoreV2) BeforeSave(_ *gorm.DB) error {
 	if s.GroupID != "" {
 		if s.TokenID == 0 {
@@ -68,3 +80,111 @@ func GetStore(group string, tokenID int, id string) (*StoreV2, error) {
 
 	return &s, HandleNotFound(err, ErrStoreNotFound)
 }
+
+// DeleteStore deletes the store and its cache, e.g. after the pinned
+// upstream file is deleted
+func DeleteStore(group string, tokenID int, id string) error {
+	err := LogDB.
+		Where("group_id = ? and token_id = ? and id = ?", group, tokenID, id).
+		Delete(&StoreV2{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	return CacheDeleteStore(group, tokenID, id)
+}
+
+// GetGroupStore returns the store of the group saved by any of its tokens
+func GetGroupStore(group, id string) (*StoreV2, error) {
+	var s StoreV2
+
+	err := LogDB.Where("group_id = ? and id = ?", group, id).
+		First(&s).
+		Error
+
+	return &s, HandleNotFound(err, ErrStoreNotFound)
+}
+
+// GetLatestStore returns the latest unexpired store of the token with the id
+// prefix
+func GetLatestStore(group string, tokenID int, prefix string) (*StoreV2, error) {
+	var s StoreV2
+
+	err := LogDB.
+		Where("group_id = ? and token_id = ? and id LIKE ? and expires_at > ?",
+			group, tokenID, prefix+"%", time.Now()).
+		Order("created_at desc").
+		First(&s).
+		Error
+
+	return &s, HandleNotFound(err, ErrStoreNotFound)
+}
+
+// MarkStoreBilled marks the store of the group as billed, it returns false
+// when the store is already marked
+func MarkStoreBilled(group, id string) (bool, error) {
+	result := LogDB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&StoreBilled{
+			GroupID: group,
+			ID:      id,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// GetUnbilledStores scans a page of the unexpired stores with the id prefix
+// created before the time, ordered by the id, it returns the ones not marked
+// as billed and the last scanned id, which is empty after the last page
+func GetUnbilledStores(
+	prefix, afterID string,
+	before time.Time,
+	limit int,
+) ([]*StoreV2, string, error) {
+	var stores []*StoreV2
+
+	err := LogDB.
+		Where("id LIKE ? and id > ? and created_at < ? and expires_at > ?",
+			prefix+"%", afterID, before, time.Now()).
+		Order("id").
+		Limit(limit).
+		Find(&stores).
+		Error
+	if err != nil || len(stores) == 0 {
+		return nil, "", err
+	}
+
+	lastID := ""
+	if len(stores) == limit {
+		lastID = stores[len(stores)-1].ID
+	}
+
+	ids := make([]string, 0, len(stores))
+	for _, s := range stores {
+		ids = append(ids, s.ID)
+	}
+
+	var billed []*StoreBilled
+
+	err = LogDB.Where("id IN ?", ids).Find(&billed).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	billedSet := make(map[[2]string]struct{}, len(billed))
+	for _, b := range billed {
+		billedSet[[2]string{b.GroupID, b.ID}] = struct{}{}
+	}
+
+	unbilled := stores[:0]
+	for _, s := range stores {
+		if _, ok := billedSet[[2]string{s.GroupID, s.ID}]; !ok {
+			unbilled = append(unbilled, s)
+		}
+	}
+
+	return unbilled, lastID, nil
+}
@@ -154,10 +154,11 @@ func getChartDataMinute(
 	}
 
 	// Only include max metrics when we have specific channel and model
-	const selectFields = "minute_timestamp as timestamp, sum(used_amount) as used_amount, " +
+	const selectFields = "minute_timestamp as timestamp, sum(used_amount) as used_amount, sum(original_amount) as original_amount, " +
+		"sum(cost_amount) as cost_amount, sum(used_amount) - sum(cost_amount) as margin, " +
 		"sum(request_count) as request_count, sum(retry_count) as retry_count, sum(exception_count) as exception_count, sum(status4xx_count) as status4xx_count, sum(status5xx_count) as status5xx_count, sum(status400_count) as status400_count, sum(status429_count) as status429_count, sum(status500_count) as status500_count, " +
 		"sum(total_time_milliseconds) as total_time_milliseconds, sum(total_ttfb_milliseconds) as total_ttfb_milliseconds, " +
-		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, " +
+		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(audio_output_tokens) as audio_output_tokens, " +
 		"sum(cached_tokens) as cached_tokens, sum(cache_creation_tokens) as cache_creation_tokens, " +
 		"sum(total_tokens) as total_tokens, sum(web_search_count) as web_search_count, sum(cache_hit_count) as cache_hit_count"
 
@@ -213,10 +214,11 @@ func getGroupChartDataMinute(
 	}
 
 	// Only include max metrics when we have specific channel and model
-	const selectFields = "minute_timestamp as timestamp, sum(used_amount) as used_amount, " +
+	const selectFields = "minute_timestamp as timestamp, sum(used_amount) as used_amount, sum(original_amount) as original_amount, " +
+		"sum(cost_amount) as cost_amount, sum(used_amount) - sum(cost_amount) as margin, " +
 		"sum(request_count) as request_count, sum(exception_count) as exception_count, sum(status4xx_count) as status4xx_count, sum(status5xx_count) as status5xx_count, sum(status400_count) as status400_count, sum(status429_count) as status429_count, sum(status500_count) as status500_count, " +
 		"sum(total_time_milliseconds) as total_time_milliseconds, sum(total_ttfb_milliseconds) as total_ttfb_milliseconds, " +
-		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, " +
+		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(audio_output_tokens) as audio_output_tokens, " +
 		"sum(cached_tokens) as cached_tokens, sum(cache_creation_tokens) as cache_creation_tokens, " +
 		"sum(total_tokens) as total_tokens, sum(web_search_count) as web_search_count, sum(cache_hit_count) as cache_hit_count"
 
@@ -505,6 +507,11 @@ type SummaryDataV2 struct {
 	TokenName  string  `json:"token_name,omitempty"`
 	Model      string  `json:"model"`
 	UsedAmount float64 `json:"used_amount"`
+	// OriginalAmount is the amount in the currencies of the prices
+	OriginalAmount float64 `json:"original_amount,omitempty"`
+	CostAmount     float64 `json:"cost_amount,omitempty"`
+	// Margin is the used amount minus the cost amount
+	Margin float64 `json:"margin"`
 
 	TotalTimeMilliseconds int64 `json:"total_time_milliseconds"`
 	TotalTTFBMilliseconds int64 `json:"total_ttfb_milliseconds"`
@@ -558,11 +565,12 @@ func GetTimeSeriesModelData(
 	}
 
 	const selectFields = "hour_timestamp as timestamp, channel_id, model, " +
-		"sum(used_amount) as used_amount, " +
+		"sum(used_amount) as used_amount, sum(original_amount) as original_amount, " +
+		"sum(cost_amount) as cost_amount, sum(used_amount) - sum(cost_amount) as margin, " +
 		"sum(request_count) as request_count, sum(retry_count) as retry_count, sum(exception_count) as exception_count, sum(status4xx_count) as status4xx_count, sum(status5xx_count) as status5xx_count, sum(status400_count) as status400_count, sum(status429_count) as status429_count, sum(status500_count) as status500_count, " +
 		"sum(total_time_milliseconds) as total_time_milliseconds, sum(total_ttfb_milliseconds) as total_ttfb_milliseconds, " +
 		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, " +
-		"sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(cached_tokens) as cached_tokens, " +
+		"sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(audio_output_tokens) as audio_output_tokens, sum(cached_tokens) as cached_tokens, " +
 		"sum(cache_creation_tokens) as cache_creation_tokens, sum(total_tokens) as total_tokens, " +
 		"sum(web_search_count) as web_search_count, sum(cache_hit_count) as cache_hit_count"
 
@@ -642,11 +650,12 @@ func GetGroupTimeSeriesModelData(
 	}
 
 	const selectFields = "hour_timestamp as timestamp, group_id, token_name, model, " +
-		"sum(used_amount) as used_amount, " +
+		"sum(used_amount) as used_amount, sum(original_amount) as original_amount, " +
+		"sum(cost_amount) as cost_amount, sum(used_amount) - sum(cost_amount) as margin, " +
 		"sum(request_count) as request_count, sum(exception_count) as exception_count, sum(status4xx_count) as status4xx_count, sum(status5xx_count) as status5xx_count, sum(status400_count) as status400_count, sum(status429_count) as status429_count, sum(status500_count) as status500_count, " +
 		"sum(total_time_milliseconds) as total_time_milliseconds, sum(total_ttfb_milliseconds) as total_ttfb_milliseconds, " +
 		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, " +
-		"sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(cached_tokens) as cached_tokens, " +
+		"sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(audio_output_tokens) as audio_output_tokens, sum(cached_tokens) as cached_tokens, " +
 		"sum(cache_creation_tokens) as cache_creation_tokens, sum(total_tokens) as total_tokens, " +
 		"sum(web_search_count) as web_search_count, sum(cache_hit_count) as cache_hit_count"
 
@@ -890,11 +899,12 @@ func getTimeSeriesModelDataMinute(
 	}
 
 	const selectFields = "minute_timestamp as timestamp, channel_id, model, " +
-		"sum(used_amount) as used_amount, " +
+		"sum(used_amount) as used_amount, sum(original_amount) as original_amount, " +
+		"sum(cost_amount) as cost_amount, sum(used_amount) - sum(cost_amount) as margin, " +
 		"sum(request_count) as request_count, sum(retry_count) as retry_count, sum(exception_count) as exception_count, sum(status4xx_count) as status4xx_count, sum(status5xx_count) as status5xx_count, sum(status400_count) as status400_count, sum(status429_count) as status429_count, sum(status500_count) as status500_count, " +
 		"sum(total_time_milliseconds) as total_time_milliseconds, sum(total_ttfb_milliseconds) as total_ttfb_milliseconds, " +
 		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, " +
-		"sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(cached_tokens) as cached_tokens, " +
+		"sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(audio_output_tokens) as audio_output_tokens, sum(cached_tokens) as cached_tokens, " +
 		"sum(cache_creation_tokens) as cache_creation_tokens, sum(total_tokens) as total_tokens, " +
 		"sum(web_search_count) as web_search_count, sum(cache_hit_count) as cache_hit_count"
 
@@ -960,11 +970,12 @@ func getGroupTimeSeriesModelDataMinute(
 	}
 
 	const selectFields = "minute_timestamp as timestamp, group_id, token_name, model, " +
-		"sum(used_amount) as used_amount, " +
+		"sum(used_amount) as used_amount, sum(original_amount) as original_amount, " +
+		"sum(cost_amount) as cost_amount, sum(used_amount) - sum(cost_amount) as margin, " +
 		"sum(request_count) as request_count, sum(exception_count) as exception_count, sum(status4xx_count) as status4xx_count, sum(status5xx_count) as status5xx_count, sum(status400_count) as status400_count, sum(status429_count) as status429_count, sum(status500_count) as status500_count, " +
 		"sum(total_time_milliseconds) as total_time_milliseconds, sum(total_ttfb_milliseconds) as total_ttfb_milliseconds, " +
 		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, " +
-		"sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(cached_tokens) as cached_tokens, " +
+		"sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(audio_output_tokens) as audio_output_tokens, sum(cached_tokens) as cached_tokens, " +
 		"sum(cache_creation_tokens) as cache_creation_tokens, sum(total_tokens) as total_tokens, " +
 		"sum(web_search_count) as web_search_count, sum(cache_hit_count) as cache_hit_count"
 
@@ -1063,6 +1074,18 @@ func aggregatToSpan(
 			NewFromFloat(currentData.UsedAmount).
 			Add(decimal.NewFromFloat(data.UsedAmount)).
 			InexactFloat64()
+		currentData.OriginalAmount = decimal.
+			NewFromFloat(currentData.OriginalAmount).
+			Add(decimal.NewFromFloat(data.OriginalAmount)).
+			InexactFloat64()
+		currentData.CostAmount = decimal.
+			NewFromFloat(currentData.CostAmount).
+			Add(decimal.NewFromFloat(data.CostAmount)).
+			InexactFloat64()
+		currentData.Margin = decimal.
+			NewFromFloat(currentData.Margin).
+			Add(decimal.NewFromFloat(data.Margin)).
+			InexactFloat64()
 		currentData.TotalTimeMilliseconds += data.TotalTimeMilliseconds
 		currentData.TotalTTFBMilliseconds += data.TotalTTFBMilliseconds
 
@@ -1155,6 +1178,18 @@ func aggregatToSpanGroup(
 			NewFromFloat(currentData.UsedAmount).
 			Add(decimal.NewFromFloat(data.UsedAmount)).
 			InexactFloat64()
+		currentData.OriginalAmount = decimal.
+			NewFromFloat(currentData.OriginalAmount).
+			Add(decimal.NewFromFloat(data.OriginalAmount)).
+			InexactFloat64()
+		currentData.CostAmount = decimal.
+			NewFromFloat(currentData.CostAmount).
+			Add(decimal.NewFromFloat(data.CostAmount)).
+			InexactFloat64()
+		currentData.Margin = decimal.
+			NewFromFloat(currentData.Margin).
+			Add(decimal.NewFromFloat(data.Margin)).
+			InexactFloat64()
 		currentData.TotalTimeMilliseconds += data.TotalTimeMilliseconds
 		currentData.TotalTTFBMilliseconds += data.TotalTTFBMilliseconds
 
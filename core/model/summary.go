@@ -88,6 +88,11 @@ type SummaryData struct {
 	Usage
 
 	UsedAmount float64 `json:"used_amount"`
+	// OriginalAmount is the amount in the currencies of the prices before
+	// the conversion into the billing currencies of the groups
+	OriginalAmount float64 `json:"original_amount,omitempty"`
+	// CostAmount is what the upstreams of the channels charge
+	CostAmount float64 `json:"cost_amount,omitempty"`
 
 	TotalTimeMilliseconds int64 `json:"total_time_milliseconds,omitempty"`
 	TotalTTFBMilliseconds int64 `json:"total_ttfb_milliseconds,omitempty"`
@@ -100,6 +105,17 @@ func (d *SummaryData) buildUpdateData(tableName string) map[string]any {
 		data["used_amount"] = gorm.Expr(tableName+".used_amount + ?", d.UsedAmount)
 	}
 
+	if d.OriginalAmount > 0 {
+		data["original_amount"] = gorm.Expr(
+			tableName+".original_amount + ?",
+			d.OriginalAmount,
+		)
+	}
+
+	if d.CostAmount > 0 {
+		data["cost_amount"] = gorm.Expr(tableName+".cost_amount + ?", d.CostAmount)
+	}
+
 	if d.RequestCount > 0 {
 		data["request_count"] = gorm.Expr(tableName+".request_count + ?", d.RequestCount)
 	}
@@ -200,6 +216,13 @@ func (d *SummaryData) buildUpdateData(tableName string) map[string]any {
 		)
 	}
 
+	if d.AudioOutputTokens > 0 {
+		data["audio_output_tokens"] = gorm.Expr(
+			fmt.Sprintf("COALESCE(%s.audio_output_tokens, 0) + ?", tableName),
+			d.AudioOutputTokens,
+		)
+	}
+
 	if d.TotalTokens > 0 {
 		data["total_tokens"] = gorm.Expr(
 			fmt.Sprintf("COALESCE(%s.total_tokens, 0) + ?", tableName),
@@ -228,6 +251,13 @@ func (d *SummaryData) buildUpdateData(tableName string) map[string]any {
 		)
 	}
 
+	if d.TrainedTokens > 0 {
+		data["trained_tokens"] = gorm.Expr(
+			fmt.Sprintf("COALESCE(%s.trained_tokens, 0) + ?", tableName),
+			d.TrainedTokens,
+		)
+	}
+
 	if d.CacheHitCount > 0 {
 		data["cache_hit_count"] = gorm.Expr(
 			fmt.Sprintf("COALESCE(%s.cache_hit_count, 0) + ?", tableName),
@@ -367,10 +397,11 @@ func getChartData(
 		query = query.Where("hour_timestamp <= ?", end.Unix())
 	}
 
-	const selectFields = "hour_timestamp as timestamp, sum(used_amount) as used_amount, " +
+	const selectFields = "hour_timestamp as timestamp, sum(used_amount) as used_amount, sum(original_amount) as original_amount, " +
+		"sum(cost_amount) as cost_amount, sum(used_amount) - sum(cost_amount) as margin, " +
 		"sum(request_count) as request_count, sum(retry_count) as retry_count, sum(exception_count) as exception_count, sum(status4xx_count) as status4xx_count, sum(status5xx_count) as status5xx_count, sum(status400_count) as status400_count, sum(status429_count) as status429_count, sum(status500_count) as status500_count, " +
 		"sum(total_time_milliseconds) as total_time_milliseconds, sum(total_ttfb_milliseconds) as total_ttfb_milliseconds, " +
-		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, " +
+		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(audio_output_tokens) as audio_output_tokens, " +
 		"sum(cached_tokens) as cached_tokens, sum(cache_creation_tokens) as cache_creation_tokens, " +
 		"sum(total_tokens) as total_tokens, sum(web_search_count) as web_search_count, sum(cache_hit_count) as cache_hit_count"
 
@@ -425,10 +456,11 @@ func getGroupChartData(
 		query = query.Where("hour_timestamp <= ?", end.Unix())
 	}
 
-	const selectFields = "hour_timestamp as timestamp, sum(used_amount) as used_amount, " +
+	const selectFields = "hour_timestamp as timestamp, sum(used_amount) as used_amount, sum(original_amount) as original_amount, " +
+		"sum(cost_amount) as cost_amount, sum(used_amount) - sum(cost_amount) as margin, " +
 		"sum(request_count) as request_count, sum(exception_count) as exception_count, sum(status4xx_count) as status4xx_count, sum(status5xx_count) as status5xx_count, sum(status400_count) as status400_count, sum(status429_count) as status429_count, sum(status500_count) as status500_count, " +
 		"sum(total_time_milliseconds) as total_time_milliseconds, sum(total_ttfb_milliseconds) as total_ttfb_milliseconds, " +
-		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, " +
+		"sum(input_tokens) as input_tokens, sum(image_input_tokens) as image_input_tokens, sum(audio_input_tokens) as audio_input_tokens, sum(output_tokens) as output_tokens, sum(image_output_tokens) as image_output_tokens, sum(audio_output_tokens) as audio_output_tokens, " +
 		"sum(cached_tokens) as cached_tokens, sum(cache_creation_tokens) as cache_creation_tokens, " +
 		"sum(total_tokens) as total_tokens, sum(web_search_count) as web_search_count, sum(cache_hit_count) as cache_hit_count"
 
@@ -588,8 +620,12 @@ func getGroupLogGroupByValues[T cmp.Ordered](
 }
 
 type ChartData struct {
-	Timestamp  int64   `json:"timestamp"`
-	UsedAmount float64 `json:"used_amount"`
+	Timestamp      int64   `json:"timestamp"`
+	UsedAmount     float64 `json:"used_amount"`
+	OriginalAmount float64 `json:"original_amount,omitempty"`
+	CostAmount     float64 `json:"cost_amount,omitempty"`
+	// Margin is the used amount minus the cost amount
+	Margin float64 `json:"margin"`
 
 	TotalTimeMilliseconds int64 `json:"total_time_milliseconds"`
 	TotalTTFBMilliseconds int64 `json:"total_ttfb_milliseconds"`
@@ -609,7 +645,10 @@ type DashboardResponse struct {
 	MaxRPM int64 `json:"max_rpm"`
 	MaxTPM int64 `json:"max_tpm"`
 
-	UsedAmount float64 `json:"used_amount"`
+	UsedAmount     float64 `json:"used_amount"`
+	OriginalAmount float64 `json:"original_amount,omitempty"`
+	CostAmount     float64 `json:"cost_amount,omitempty"`
+	Margin         float64 `json:"margin"`
 
 	TotalCount int64 `json:"total_count"` // use Count.RequestCount instead
 	Count
@@ -689,6 +728,18 @@ func aggregateDataToSpan(
 			NewFromFloat(currentData.UsedAmount).
 			Add(decimal.NewFromFloat(data.UsedAmount)).
 			InexactFloat64()
+		currentData.OriginalAmount = decimal.
+			NewFromFloat(currentData.OriginalAmount).
+			Add(decimal.NewFromFloat(data.OriginalAmount)).
+			InexactFloat64()
+		currentData.CostAmount = decimal.
+			NewFromFloat(currentData.CostAmount).
+			Add(decimal.NewFromFloat(data.CostAmount)).
+			InexactFloat64()
+		currentData.Margin = decimal.
+			NewFromFloat(currentData.Margin).
+			Add(decimal.NewFromFloat(data.Margin)).
+			InexactFloat64()
 
 		dataMap[timestamp] = currentData
 	}
@@ -707,6 +758,9 @@ func sumDashboardResponse(chartData []ChartData) DashboardResponse {
 	}
 
 	usedAmount := decimal.NewFromFloat(0)
+	originalAmount := decimal.NewFromFloat(0)
+	costAmount := decimal.NewFromFloat(0)
+
 	for _, data := range chartData {
 		dashboardResponse.Count.Add(data.Count)
 		dashboardResponse.TotalCount = dashboardResponse.RequestCount
@@ -716,6 +770,8 @@ func sumDashboardResponse(chartData []ChartData) DashboardResponse {
 		dashboardResponse.TotalTimeMilliseconds += data.TotalTimeMilliseconds
 		dashboardResponse.TotalTTFBMilliseconds += data.TotalTTFBMilliseconds
 		usedAmount = usedAmount.Add(decimal.NewFromFloat(data.UsedAmount))
+		originalAmount = originalAmount.Add(decimal.NewFromFloat(data.OriginalAmount))
+		costAmount = costAmount.Add(decimal.NewFromFloat(data.CostAmount))
 		dashboardResponse.UsedAmount = decimal.
 			NewFromFloat(dashboardResponse.UsedAmount).
 			Add(decimal.NewFromFloat(data.UsedAmount)).
@@ -723,6 +779,9 @@ func sumDashboardResponse(chartData []ChartData) DashboardResponse {
 	}
 
 	dashboardResponse.UsedAmount = usedAmount.InexactFloat64()
+	dashboardResponse.OriginalAmount = originalAmount.InexactFloat64()
+	dashboardResponse.CostAmount = costAmount.InexactFloat64()
+	dashboardResponse.Margin = usedAmount.Sub(costAmount).InexactFloat64()
 
 	return dashboardResponse
 }
@@ -41,6 +41,9 @@ type Token struct {
 	Status    int             `json:"status"     gorm:"default:1;index"`
 	ID        int             `json:"id"         gorm:"primaryKey"`
 
+	// MCPToolACLs is the tools of the mcps the token can call
+	MCPToolACLs MCPToolACLs `json:"mcp_tool_acls,omitempty" gorm:"serializer:fastjson;type:text"`
+
 	UsedAmount   float64 `json:"used_amount"   gorm:"index"`
 	RequestCount int     `json:"request_count" gorm:"index"`
 
@@ -678,10 +681,11 @@ func DeleteTokensByIDs(ids []int) (err error) {
 }
 
 type UpdateTokenRequest struct {
-	Name    *string   `json:"name"`
-	Subnets *[]string `json:"subnets"`
-	Models  *[]string `json:"models"`
-	Status  int       `json:"status"`
+	Name        *string      `json:"name"`
+	Subnets     *[]string    `json:"subnets"`
+	Models      *[]string    `json:"models"`
+	MCPToolACLs *MCPToolACLs `json:"mcp_tool_acls"`
+	Status      int          `json:"status"`
 	// Quota system
 	Quota                *float64 `json:"quota"`
 	PeriodQuota          *float64 `json:"period_quota"`
@@ -750,6 +754,12 @@ func UpdateToken(id int, update UpdateTokenRequest) (token *Token, err error) {
 		selects = append(selects, "models")
 	}
 
+	if update.MCPToolACLs != nil {
+		token.MCPToolACLs = *update.MCPToolACLs
+
+		selects = append(selects, "mcp_tool_acls")
+	}
+
 	if update.Status != 0 {
 		selects = append(selects, "status")
 	}
@@ -838,6 +848,12 @@ func UpdateGroupToken(
 		selects = append(selects, "models")
 	}
 
+	if update.MCPToolACLs != nil {
+		token.MCPToolACLs = *update.MCPToolACLs
+
+		selects = append(selects, "mcp_tool_acls")
+	}
+
 	if update.Status != 0 {
 		selects = append(selects, "status")
 	}
@@ -867,6 +883,8 @@ func UpdateTokenUsedAmount(id int, amount float64, requestCount int) (err error)
 			if err := CacheUpdateTokenUsedAmountOnlyIncrease(token.Key, token.UsedAmount); err != nil {
 				log.Error("update token used amount in cache failed: " + err.Error())
 			}
+
+			emitTokenQuotaExhausted(token, amount)
 		}
 	}()
 
@@ -874,10 +892,16 @@ func UpdateTokenUsedAmount(id int, amount float64, requestCount int) (err error)
 		Model(token).
 		Clauses(clause.Returning{
 			Columns: []clause.Column{
+				{Name: "id"},
 				{Name: "key"},
+				{Name: "name"},
+				{Name: "group_id"},
 				{Name: "quota"},
 				{Name: "used_amount"},
 				{Name: "period_quota"},
+				{Name: "period_type"},
+				{Name: "period_last_update_time"},
+				{Name: "period_last_update_amount"},
 			},
 		}).
 		Where("id = ?", id).
@@ -1028,6 +1052,21 @@ func ResetTokenPeriodUsage(id int) error {
 		if cacheErr := CacheResetTokenPeriodUsage(token.Key, newPeriodStartTime, token.UsedAmount); cacheErr != nil {
 			log.Error("reset token period usage in cache failed: " + cacheErr.Error())
 		}
+
+		EmitWebhookEvent(
+			token.GroupID,
+			WebhookEventTokenPeriodReset,
+			string(token.Name),
+			TokenWebhookData{
+				TokenID:       token.ID,
+				TokenName:     string(token.Name),
+				PeriodQuota:   token.PeriodQuota,
+				PeriodType:    string(token.PeriodType),
+				PeriodStartAt: newPeriodStartTime.Unix(),
+				UsedAmount:    token.UsedAmount,
+				Quota:         token.Quota,
+			},
+		)
 	}
 
 	return err
@@ -21,6 +21,10 @@ type ConditionalPrice struct {
 }
 
 type Price struct {
+	// Currency is the currency of the prices, empty is the default currency,
+	// the conditional prices without a currency use it
+	Currency string `gorm:"column:price_currency;size:8" json:"currency,omitempty"`
+
 	PerRequestPrice ZeroNullFloat64 `json:"per_request_price,omitempty"`
 
 	InputPrice     ZeroNullFloat64 `json:"input_price,omitempty"`
@@ -38,6 +42,9 @@ type Price struct {
 	ImageOutputPrice     ZeroNullFloat64 `json:"image_output_price,omitempty"`
 	ImageOutputPriceUnit ZeroNullInt64   `json:"image_output_price_unit,omitempty"`
 
+	AudioOutputPrice     ZeroNullFloat64 `json:"audio_output_price,omitempty"`
+	AudioOutputPriceUnit ZeroNullInt64   `json:"audio_output_price_unit,omitempty"`
+
 	// when ThinkingModeOutputPrice and ReasoningTokens are not 0, OutputPrice and OutputPriceUnit
 	// will be overwritten
 	ThinkingModeOutputPrice     ZeroNullFloat64 `json:"thinking_mode_output_price,omitempty"`
@@ -52,6 +59,10 @@ type Price struct {
 	WebSearchPrice     ZeroNullFloat64 `json:"web_search_price,omitempty"`
 	WebSearchPriceUnit ZeroNullInt64   `json:"web_search_price_unit,omitempty"`
 
+	// TrainingPrice is the fine-tuning price of trained tokens
+	TrainingPrice     ZeroNullFloat64 `json:"training_price,omitempty"`
+	TrainingPriceUnit ZeroNullInt64   `json:"training_price_unit,omitempty"`
+
 	ConditionalPrices []ConditionalPrice `gorm:"serializer:fastjson;type:text" json:"conditional_prices,omitempty"`
 }
 
@@ -282,7 +293,12 @@ func (p *Price) SelectConditionalPrice(usage Usage) Price {
 			continue
 		}
 
-		return conditionalPrice.Price
+		price := conditionalPrice.Price
+		if price.Currency == "" {
+			price.Currency = p.Currency
+		}
+
+		return price
 	}
 
 	return *p
@@ -323,6 +339,13 @@ func (p *Price) GetImageOutputPriceUnit() int64 {
 	return PriceUnit
 }
 
+func (p *Price) GetAudioOutputPriceUnit() int64 {
+	if p.AudioOutputPriceUnit > 0 {
+		return int64(p.AudioOutputPriceUnit)
+	}
+	return PriceUnit
+}
+
 func (p *Price) GetCachedPriceUnit() int64 {
 	if p.CachedPriceUnit > 0 {
 		return int64(p.CachedPriceUnit)
@@ -344,17 +367,26 @@ func (p *Price) GetWebSearchPriceUnit() int64 {
 	return PriceUnit
 }
 
+func (p *Price) GetTrainingPriceUnit() int64 {
+	if p.TrainingPriceUnit > 0 {
+		return int64(p.TrainingPriceUnit)
+	}
+	return PriceUnit
+}
+
 type Usage struct {
 	InputTokens         ZeroNullInt64 `json:"input_tokens,omitempty"`
 	ImageInputTokens    ZeroNullInt64 `json:"image_input_tokens,omitempty"`
 	AudioInputTokens    ZeroNullInt64 `json:"audio_input_tokens,omitempty"`
 	OutputTokens        ZeroNullInt64 `json:"output_tokens,omitempty"`
 	ImageOutputTokens   ZeroNullInt64 `json:"image_output_tokens,omitempty"`
+	AudioOutputTokens   ZeroNullInt64 `json:"audio_output_tokens,omitempty"`
 	CachedTokens        ZeroNullInt64 `json:"cached_tokens,omitempty"`
 	CacheCreationTokens ZeroNullInt64 `json:"cache_creation_tokens,omitempty"`
 	ReasoningTokens     ZeroNullInt64 `json:"reasoning_tokens,omitempty"`
 	TotalTokens         ZeroNullInt64 `json:"total_tokens,omitempty"`
 	WebSearchCount      ZeroNullInt64 `json:"web_search_count,omitempty"`
+	TrainedTokens       ZeroNullInt64 `json:"trained_tokens,omitempty"`
 }
 
 func (u *Usage) Add(other Usage) {
@@ -363,8 +395,10 @@ func (u *Usage) Add(other Usage) {
 	u.AudioInputTokens += other.AudioInputTokens
 	u.OutputTokens += other.OutputTokens
 	u.ImageOutputTokens += other.ImageOutputTokens
+	u.AudioOutputTokens += other.AudioOutputTokens
 	u.CachedTokens += other.CachedTokens
 	u.CacheCreationTokens += other.CacheCreationTokens
 	u.TotalTokens += other.TotalTokens
 	u.WebSearchCount += other.WebSearchCount
+	u.TrainedTokens += other.TrainedTokens
 }
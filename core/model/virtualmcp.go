@@ -0,0 +1,356 @@
+package model
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/common/conv"
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type VirtualMCPStatus int
+
+const (
+	VirtualMCPStatusEnabled VirtualMCPStatus = iota + 1
+	VirtualMCPStatusDisabled
+)
+
+const (
+	ErrVirtualMCPNotFound = "virtual mcp"
+)
+
+// VirtualMCPNamespaceSeparator joins the namespace of a server of a virtual
+// mcp and the names of its tools and prompts
+const VirtualMCPNamespaceSeparator = "__"
+
+// VirtualMCPServer is an mcp composed into a virtual mcp, the public scope
+// includes the embed mcps
+type VirtualMCPServer struct {
+	Scope MCPScope `json:"scope"`
+	ID    string   `json:"id"`
+	// Namespace prefixes the tools and the prompts of the mcp, the id is
+	// the default
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (s VirtualMCPServer) GetNamespace() string {
+	if s.Namespace == "" {
+		return s.ID
+	}
+
+	return s.Namespace
+}
+
+type VirtualMCPServers []VirtualMCPServer
+
+var (
+	_ redis.Scanner            = (*VirtualMCPServers)(nil)
+	_ encoding.BinaryMarshaler = (*VirtualMCPServers)(nil)
+)
+
+func (s *VirtualMCPServers) ScanRedis(value string) error {
+	return sonic.Unmarshal(conv.StringToBytes(value), s)
+}
+
+func (s VirtualMCPServers) MarshalBinary() ([]byte, error) {
+	return sonic.Marshal(s)
+}
+
+// Validate checks the servers have a scope and unique namespaces
+func (s VirtualMCPServers) Validate() error {
+	if len(s) == 0 {
+		return errors.New("virtual mcp servers is empty")
+	}
+
+	namespaces := make(map[string]struct{}, len(s))
+	for _, server := range s {
+		if server.Scope != MCPScopePublic && server.Scope != MCPScopeGroup {
+			return fmt.Errorf("invalid scope %q of mcp %s", server.Scope, server.ID)
+		}
+
+		if err := validateMCPID(server.ID); err != nil {
+			return err
+		}
+
+		namespace := server.GetNamespace()
+		if strings.Contains(namespace, VirtualMCPNamespaceSeparator) {
+			return fmt.Errorf(
+				"namespace %s cannot contain %s",
+				namespace,
+				VirtualMCPNamespaceSeparator,
+			)
+		}
+
+		if _, ok := namespaces[namespace]; ok {
+			return fmt.Errorf("duplicate namespace %s", namespace)
+		}
+
+		namespaces[namespace] = struct{}{}
+	}
+
+	return nil
+}
+
+// VirtualMCP is one mcp endpoint of a group combining the tools, resources
+// and prompts of several mcps
+type VirtualMCP struct {
+	ID          string            `gorm:"primaryKey"                    json:"id"`
+	GroupID     string            `gorm:"primaryKey"                    json:"group_id"`
+	Group       *Group            `gorm:"foreignKey:GroupID"            json:"-"`
+	Status      VirtualMCPStatus  `gorm:"index;default:1"               json:"status"`
+	CreatedAt   time.Time         `gorm:"index,autoCreateTime"          json:"created_at"`
+	UpdateAt    time.Time         `gorm:"index,autoUpdateTime"          json:"update_at"`
+	Name        string            `                                     json:"name"`
+	Description string            `                                     json:"description"`
+	Servers     VirtualMCPServers `gorm:"serializer:fastjson;type:text" json:"servers"`
+}
+
+func (v *VirtualMCP) BeforeSave(_ *gorm.DB) error {
+	if v.GroupID == "" {
+		return errors.New("group id is empty")
+	}
+
+	if err := validateMCPID(v.ID); err != nil {
+		return err
+	}
+
+	if v.UpdateAt.IsZero() {
+		v.UpdateAt = time.Now()
+	}
+
+	if v.Status == 0 {
+		v.Status = VirtualMCPStatusEnabled
+	}
+
+	return v.Servers.Validate()
+}
+
+// CreateVirtualMCP creates a new VirtualMCP
+func CreateVirtualMCP(mcp *VirtualMCP) error {
+	err := DB.Create(mcp).Error
+	if err != nil && errors.Is(err, gorm.ErrDuplicatedKey) {
+		return errors.New("virtual mcp already exists")
+	}
+
+	return err
+}
+
+// UpdateVirtualMCP updates an existing VirtualMCP
+func UpdateVirtualMCP(mcp *VirtualMCP) (err error) {
+	defer func() {
+		if err == nil {
+			if err := CacheDeleteVirtualMCP(mcp.GroupID, mcp.ID); err != nil {
+				log.Error("cache delete virtual mcp error: " + err.Error())
+			}
+		}
+	}()
+
+	selects := []string{
+		"name",
+		"description",
+		"servers",
+	}
+
+	if mcp.Status != 0 {
+		selects = append(selects, "status")
+	}
+
+	result := DB.
+		Select(selects).
+		Where("id = ? AND group_id = ?", mcp.ID, mcp.GroupID).
+		Updates(mcp)
+
+	return HandleUpdateResult(result, ErrVirtualMCPNotFound)
+}
+
+func UpdateVirtualMCPStatus(id, groupID string, status VirtualMCPStatus) (err error) {
+	defer func() {
+		if err == nil {
+			if err := CacheDeleteVirtualMCP(groupID, id); err != nil {
+				log.Error("cache delete virtual mcp error: " + err.Error())
+			}
+		}
+	}()
+
+	result := DB.Model(&VirtualMCP{}).
+		Where("id = ? AND group_id = ?", id, groupID).
+		Update("status", status)
+
+	return HandleUpdateResult(result, ErrVirtualMCPNotFound)
+}
+
+// DeleteVirtualMCP deletes a VirtualMCP by ID and GroupID
+func DeleteVirtualMCP(id, groupID string) (err error) {
+	defer func() {
+		if err == nil {
+			if err := CacheDeleteVirtualMCP(groupID, id); err != nil {
+				log.Error("cache delete virtual mcp error: " + err.Error())
+			}
+		}
+	}()
+
+	if id == "" || groupID == "" {
+		return errors.New("virtual mcp id or group id is empty")
+	}
+
+	result := DB.Where("id = ? AND group_id = ?", id, groupID).Delete(&VirtualMCP{})
+
+	return HandleUpdateResult(result, ErrVirtualMCPNotFound)
+}
+
+// GetVirtualMCPByID retrieves a VirtualMCP by ID and GroupID
+func GetVirtualMCPByID(id, groupID string) (VirtualMCP, error) {
+	var mcp VirtualMCP
+	if id == "" || groupID == "" {
+		return mcp, errors.New("virtual mcp id or group id is empty")
+	}
+
+	err := DB.Where("id = ? AND group_id = ?", id, groupID).First(&mcp).Error
+
+	return mcp, HandleNotFound(err, ErrVirtualMCPNotFound)
+}
+
+// GetVirtualMCPs retrieves the VirtualMCPs of the group with pagination and
+// filtering
+func GetVirtualMCPs(
+	groupID string,
+	page, perPage int,
+	keyword string,
+	status VirtualMCPStatus,
+) (mcps []VirtualMCP, total int64, err error) {
+	if groupID == "" {
+		return nil, 0, errors.New("group id is empty")
+	}
+
+	tx := DB.Model(&VirtualMCP{}).Where("group_id = ?", groupID)
+
+	if status != 0 {
+		tx = tx.Where("status = ?", status)
+	}
+
+	if keyword != "" {
+		if common.UsingPostgreSQL {
+			tx = tx.Where(
+				"(id ILIKE ? OR name ILIKE ? OR description ILIKE ?)",
+				"%"+keyword+"%",
+				"%"+keyword+"%",
+				"%"+keyword+"%",
+			)
+		} else {
+			tx = tx.Where(
+				"(id LIKE ? OR name LIKE ? OR description LIKE ?)",
+				"%"+keyword+"%",
+				"%"+keyword+"%",
+				"%"+keyword+"%",
+			)
+		}
+	}
+
+	err = tx.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if total <= 0 {
+		return nil, 0, nil
+	}
+
+	limit, offset := toLimitOffset(page, perPage)
+	err = tx.
+		Limit(limit).
+		Offset(offset).
+		Find(&mcps).
+		Error
+
+	return mcps, total, err
+}
+
+type VirtualMCPCache struct {
+	ID      string            `json:"id"       redis:"i"`
+	GroupID string            `json:"group_id" redis:"g"`
+	Name    string            `json:"name"     redis:"n"`
+	Status  VirtualMCPStatus  `json:"status"   redis:"s"`
+	Servers VirtualMCPServers `json:"servers"  redis:"sv"`
+}
+
+func (v *VirtualMCP) ToVirtualMCPCache() *VirtualMCPCache {
+	return &VirtualMCPCache{
+		ID:      v.ID,
+		GroupID: v.GroupID,
+		Name:    v.Name,
+		Status:  v.Status,
+		Servers: v.Servers,
+	}
+}
+
+const (
+	VirtualMCPCacheKey = "virtual_mcp:%s:%s" // group_id:mcp_id
+)
+
+func CacheDeleteVirtualMCP(groupID, mcpID string) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+
+	return common.RDB.Del(context.Background(), common.RedisKeyf(VirtualMCPCacheKey, groupID, mcpID)).
+		Err()
+}
+
+func CacheSetVirtualMCP(virtualMCP *VirtualMCPCache) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+
+	key := common.RedisKeyf(VirtualMCPCacheKey, virtualMCP.GroupID, virtualMCP.ID)
+	pipe := common.RDB.Pipeline()
+	pipe.HSet(context.Background(), key, virtualMCP)
+
+	expireTime := SyncFrequency + time.Duration(rand.Int64N(60)-30)*time.Second
+	pipe.Expire(context.Background(), key, expireTime)
+	_, err := pipe.Exec(context.Background())
+
+	return err
+}
+
+func CacheGetVirtualMCP(groupID, mcpID string) (*VirtualMCPCache, error) {
+	if !common.RedisEnabled {
+		virtualMCP, err := GetVirtualMCPByID(mcpID, groupID)
+		if err != nil {
+			return nil, err
+		}
+
+		return virtualMCP.ToVirtualMCPCache(), nil
+	}
+
+	cacheKey := common.RedisKeyf(VirtualMCPCacheKey, groupID, mcpID)
+	virtualMCPCache := &VirtualMCPCache{}
+
+	err := common.RDB.HGetAll(context.Background(), cacheKey).Scan(virtualMCPCache)
+	if err == nil && virtualMCPCache.ID != "" {
+		return virtualMCPCache, nil
+	} else if err != nil && !errors.Is(err, redis.Nil) {
+		log.Errorf("get virtual mcp (%s:%s) from redis error: %s", groupID, mcpID, err.Error())
+	}
+
+	virtualMCP, err := GetVirtualMCPByID(mcpID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	vmc := virtualMCP.ToVirtualMCPCache()
+
+	if err := CacheSetVirtualMCP(vmc); err != nil {
+		log.Error("redis set virtual mcp error: " + err.Error())
+	}
+
+	return vmc, nil
+}
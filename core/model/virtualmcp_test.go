@@ -0,0 +1,59 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func TestVirtualMCPServersValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers model.VirtualMCPServers
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			servers: model.VirtualMCPServers{
+				{Scope: model.MCPScopePublic, ID: "github"},
+				{Scope: model.MCPScopeGroup, ID: "jira", Namespace: "tickets"},
+			},
+		},
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+		{
+			name: "invalid scope",
+			servers: model.VirtualMCPServers{
+				{Scope: "private", ID: "github"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate namespace",
+			servers: model.VirtualMCPServers{
+				{Scope: model.MCPScopePublic, ID: "github"},
+				{Scope: model.MCPScopeGroup, ID: "gitlab", Namespace: "github"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "namespace with separator",
+			servers: model.VirtualMCPServers{
+				{Scope: model.MCPScopePublic, ID: "github", Namespace: "git__hub"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		if err := tt.servers.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if ns := (model.VirtualMCPServer{ID: "github"}).GetNamespace(); ns != "github" {
+		t.Errorf("GetNamespace() = %q, want the id", ns)
+	}
+}
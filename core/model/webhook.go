@@ -0,0 +1,606 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/common/notify"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	ErrWebhookSubscriptionNotFound = "webhook subscription"
+	ErrWebhookDeliveryNotFound     = "webhook delivery"
+)
+
+type WebhookEvent string
+
+const (
+	// WebhookEventTokenQuotaExhausted is sent once when the used amount of a
+	// token reaches its total or period quota
+	WebhookEventTokenQuotaExhausted WebhookEvent = "token.quota_exhausted"
+	// WebhookEventTokenPeriodReset is sent when the period usage of a token is reset
+	WebhookEventTokenPeriodReset WebhookEvent = "token.period_reset"
+	// WebhookEventGroupBalanceLow is sent when the group balance falls below
+	// its balance alert threshold
+	WebhookEventGroupBalanceLow WebhookEvent = "group.balance_low"
+	// WebhookEventGroupUsageSpike is sent when the usage alert detects a spike
+	WebhookEventGroupUsageSpike WebhookEvent = "group.usage_spike"
+	// WebhookEventChannelAutoDisabled is sent to the subscribed groups which
+	// can use the model when the monitor bans a channel of it
+	WebhookEventChannelAutoDisabled WebhookEvent = "channel.auto_disabled"
+	// WebhookEventBudgetSoftLimitReached is sent once a window when the used
+	// amount of a budget reaches its soft limit
+	WebhookEventBudgetSoftLimitReached WebhookEvent = "budget.soft_limit_reached"
+	// WebhookEventBudgetExhausted is sent once a window when the used amount
+	// of a budget reaches its hard limit
+	WebhookEventBudgetExhausted WebhookEvent = "budget.exhausted"
+)
+
+var WebhookEvents = []WebhookEvent{
+	WebhookEventTokenQuotaExhausted,
+	WebhookEventTokenPeriodReset,
+	WebhookEventGroupBalanceLow,
+	WebhookEventGroupUsageSpike,
+	WebhookEventChannelAutoDisabled,
+	WebhookEventBudgetSoftLimitReached,
+	WebhookEventBudgetExhausted,
+}
+
+const (
+	WebhookEventHeader    = "X-Aiproxy-Event"
+	WebhookDeliveryHeader = "X-Aiproxy-Delivery"
+)
+
+type WebhookSubscriptionStatus int
+
+const (
+	WebhookSubscriptionStatusEnabled WebhookSubscriptionStatus = iota + 1
+	WebhookSubscriptionStatusDisabled
+)
+
+// WebhookSubscription is a webhook url of a group and the events it receives,
+// TokenNames limits the token events to the tokens, empty means all tokens
+type WebhookSubscription struct {
+	ID         int                       `gorm:"primaryKey"                    json:"id"`
+	GroupID    string                    `gorm:"size:64;index"                 json:"group_id"`
+	CreatedAt  time.Time                 `gorm:"autoCreateTime"                json:"created_at"`
+	UpdatedAt  time.Time                 `gorm:"autoUpdateTime"                json:"updated_at"`
+	URL        string                    `                                     json:"url"`
+	Secret     string                    `gorm:"size:128"                      json:"secret"`
+	Events     []WebhookEvent            `gorm:"serializer:fastjson;type:text" json:"events"`
+	TokenNames []string                  `gorm:"serializer:fastjson;type:text" json:"token_names,omitempty"`
+	Status     WebhookSubscriptionStatus `gorm:"default:1;index"               json:"status"`
+}
+
+func (s *WebhookSubscription) BeforeSave(_ *gorm.DB) error {
+	if s.GroupID == "" {
+		return errors.New("group id is empty")
+	}
+
+	if err := validateHTTPURL(s.URL); err != nil {
+		return err
+	}
+
+	if len(s.Events) == 0 {
+		return errors.New("events is empty")
+	}
+
+	for _, event := range s.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return fmt.Errorf("unknown webhook event: %s", event)
+		}
+	}
+
+	if s.Status == 0 {
+		s.Status = WebhookSubscriptionStatusEnabled
+	}
+
+	return nil
+}
+
+func (s *WebhookSubscription) match(event WebhookEvent, tokenName string) bool {
+	if !slices.Contains(s.Events, event) {
+		return false
+	}
+
+	if tokenName == "" || len(s.TokenNames) == 0 {
+		return true
+	}
+
+	return slices.Contains(s.TokenNames, tokenName)
+}
+
+func generateWebhookSecret() string {
+	var buf [24]byte
+
+	_, _ = rand.Read(buf[:])
+
+	return "whsec_" + hex.EncodeToString(buf[:])
+}
+
+func CreateWebhookSubscription(s *WebhookSubscription) error {
+	if s.Secret == "" {
+		s.Secret = generateWebhookSecret()
+	}
+
+	return DB.Create(s).Error
+}
+
+func UpdateWebhookSubscription(s *WebhookSubscription) error {
+	selects := []string{
+		"url",
+		"events",
+		"token_names",
+	}
+	if s.Secret != "" {
+		selects = append(selects, "secret")
+	}
+
+	if s.Status != 0 {
+		selects = append(selects, "status")
+	}
+
+	result := DB.
+		Select(selects).
+		Where("id = ? and group_id = ?", s.ID, s.GroupID).
+		Updates(s)
+
+	return HandleUpdateResult(result, ErrWebhookSubscriptionNotFound)
+}
+
+func DeleteWebhookSubscription(group string, id int) error {
+	result := DB.
+		Where("id = ? and group_id = ?", id, group).
+		Delete(&WebhookSubscription{})
+
+	return HandleUpdateResult(result, ErrWebhookSubscriptionNotFound)
+}
+
+func GetWebhookSubscription(group string, id int) (*WebhookSubscription, error) {
+	var s WebhookSubscription
+
+	err := DB.Where("id = ? and group_id = ?", id, group).First(&s).Error
+
+	return &s, HandleNotFound(err, ErrWebhookSubscriptionNotFound)
+}
+
+func GetWebhookSubscriptions(group string) ([]*WebhookSubscription, error) {
+	var subscriptions []*WebhookSubscription
+
+	err := DB.Where("group_id = ?", group).Order("id asc").Find(&subscriptions).Error
+
+	return subscriptions, err
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSuccess WebhookDeliveryStatus = "success"
+	WebhookDeliveryStatusFailed  WebhookDeliveryStatus = "failed"
+)
+
+const (
+	webhookMaxAttempts  = 6
+	webhookRetryBackoff = 30 * time.Second
+	webhookTimeout      = 10 * time.Second
+	webhookMaxErrorSize = 512
+)
+
+// WebhookDelivery is a delivery of an event to a subscription, the failed
+// deliveries are retried with an exponential backoff
+type WebhookDelivery struct {
+	ID             int                   `gorm:"primaryKey"           json:"id"`
+	SubscriptionID int                   `gorm:"index"                json:"subscription_id"`
+	GroupID        string                `gorm:"size:64;index"        json:"group_id"`
+	Event          WebhookEvent          `gorm:"size:64;index"        json:"event"`
+	EventID        string                `gorm:"size:64"              json:"event_id"`
+	Payload        string                `gorm:"type:text"            json:"payload"`
+	Status         WebhookDeliveryStatus `gorm:"size:16;index"        json:"status"`
+	Attempts       int                   `                            json:"attempts"`
+	ResponseCode   int                   `                            json:"response_code"`
+	Error          string                `gorm:"type:text"            json:"error,omitempty"`
+	NextRetryAt    time.Time             `gorm:"index"                json:"next_retry_at"`
+	DeliveredAt    time.Time             `                            json:"delivered_at"`
+	CreatedAt      time.Time             `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// webhookBackoff is the delay before the next attempt, 30s, 1m, 2m, 4m, 8m
+func webhookBackoff(attempts int) time.Duration {
+	return webhookRetryBackoff << (attempts - 1)
+}
+
+type WebhookPayload struct {
+	ID        string       `json:"id"`
+	Event     WebhookEvent `json:"event"`
+	GroupID   string       `json:"group_id"`
+	CreatedAt int64        `json:"created_at"`
+	Data      any          `json:"data"`
+}
+
+// EmitWebhookEvent delivers the event to the enabled subscriptions of the
+// group asynchronously, the token name filters the token events
+func EmitWebhookEvent(group string, event WebhookEvent, tokenName string, data any) {
+	go func() {
+		subscriptions, err := getEnabledWebhookSubscriptions(group)
+		if err == nil {
+			err = emitWebhookEvent(subscriptions, event, tokenName, data)
+		}
+
+		if err != nil {
+			log.Errorf("emit webhook event %s of group %s failed: %v", event, group, err)
+		}
+	}()
+}
+
+// EmitModelWebhookEvent delivers the event asynchronously to the enabled
+// subscriptions of the groups which can use the model
+func EmitModelWebhookEvent(modelName string, event WebhookEvent, data any) {
+	go func() {
+		subscriptions, err := getEnabledWebhookSubscriptions("")
+		if err == nil {
+			err = emitWebhookEvent(
+				filterModelWebhookSubscriptions(subscriptions, modelName),
+				event,
+				"",
+				data,
+			)
+		}
+
+		if err != nil {
+			log.Errorf("emit webhook event %s of model %s failed: %v", event, modelName, err)
+		}
+	}()
+}
+
+// getEnabledWebhookSubscriptions returns the enabled subscriptions of the
+// group, or of all groups when the group is empty
+func getEnabledWebhookSubscriptions(group string) ([]*WebhookSubscription, error) {
+	var subscriptions []*WebhookSubscription
+
+	tx := DB.Where("status = ?", WebhookSubscriptionStatusEnabled)
+	if group != "" {
+		tx = tx.Where("group_id = ?", group)
+	}
+
+	return subscriptions, tx.Find(&subscriptions).Error
+}
+
+// filterModelWebhookSubscriptions keeps the subscriptions of the enabled
+// groups which have the model in one of their available sets
+func filterModelWebhookSubscriptions(
+	subscriptions []*WebhookSubscription,
+	modelName string,
+) []*WebhookSubscription {
+	modelCaches := LoadModelCaches()
+	canUse := make(map[string]bool)
+
+	return slices.DeleteFunc(subscriptions, func(s *WebhookSubscription) bool {
+		ok, checked := canUse[s.GroupID]
+		if !checked {
+			ok = groupCanUseModel(s.GroupID, modelName, modelCaches)
+			canUse[s.GroupID] = ok
+		}
+
+		return !ok
+	})
+}
+
+func groupCanUseModel(groupID, modelName string, modelCaches *ModelCaches) bool {
+	group, err := CacheGetGroup(groupID)
+	if err != nil || group.Status != GroupStatusEnabled {
+		return false
+	}
+
+	for _, set := range group.GetAvailableSets() {
+		if slices.Contains(modelCaches.EnabledModelsBySet[set], modelName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func emitWebhookEvent(
+	subscriptions []*WebhookSubscription,
+	event WebhookEvent,
+	tokenName string,
+	data any,
+) error {
+	now := time.Now()
+	eventID := "evt_" + common.ShortUUID()
+
+	for _, s := range subscriptions {
+		if !s.match(event, tokenName) {
+			continue
+		}
+
+		payload, err := sonic.Marshal(WebhookPayload{
+			ID:        eventID,
+			Event:     event,
+			GroupID:   s.GroupID,
+			CreatedAt: now.Unix(),
+			Data:      data,
+		})
+		if err != nil {
+			return err
+		}
+
+		// created claimed, the inline attempt decides the next retry
+		d := &WebhookDelivery{
+			SubscriptionID: s.ID,
+			GroupID:        s.GroupID,
+			Event:          event,
+			EventID:        eventID,
+			Payload:        string(payload),
+			Status:         WebhookDeliveryStatusPending,
+			NextRetryAt:    now.Add(webhookTimeout * 2),
+		}
+		if err := LogDB.Create(d).Error; err != nil {
+			return err
+		}
+
+		deliverWebhook(s, d)
+	}
+
+	return nil
+}
+
+// deliverWebhook posts the payload and records the attempt
+func deliverWebhook(s *WebhookSubscription, d *WebhookDelivery) {
+	code, err := postWebhook(s, d)
+
+	d.Attempts++
+	d.ResponseCode = code
+
+	values := map[string]any{
+		"attempts":      d.Attempts,
+		"response_code": code,
+	}
+
+	switch {
+	case err == nil:
+		d.Status = WebhookDeliveryStatusSuccess
+		d.DeliveredAt = time.Now()
+		d.Error = ""
+		values["delivered_at"] = d.DeliveredAt
+	case d.Attempts >= webhookMaxAttempts:
+		d.Status = WebhookDeliveryStatusFailed
+		d.Error = err.Error()
+	default:
+		d.NextRetryAt = time.Now().Add(webhookBackoff(d.Attempts))
+		d.Error = err.Error()
+		values["next_retry_at"] = d.NextRetryAt
+	}
+
+	values["status"] = d.Status
+	values["error"] = d.Error
+
+	if err := LogDB.Model(&WebhookDelivery{}).
+		Where("id = ?", d.ID).
+		Updates(values).
+		Error; err != nil {
+		log.Errorf("update webhook delivery %d failed: %v", d.ID, err)
+	}
+}
+
+func postWebhook(s *WebhookSubscription, d *WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		s.URL,
+		strings.NewReader(d.Payload),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(d.Event))
+	req.Header.Set(WebhookDeliveryHeader, strconv.Itoa(d.ID))
+	req.Header.Set(notify.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(
+		notify.WebhookSignatureHeader,
+		notify.WebhookSignature(s.Secret, timestamp, []byte(d.Payload)),
+	)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxErrorSize))
+
+	return resp.StatusCode, fmt.Errorf("status code %d: %s", resp.StatusCode, body)
+}
+
+// RetryWebhookDeliveries retries the pending deliveries which are due, a
+// delivery is claimed by moving its next retry time so only one instance
+// sends it
+func RetryWebhookDeliveries(limit int) error {
+	var deliveries []*WebhookDelivery
+
+	now := time.Now()
+
+	err := LogDB.
+		Where("status = ? and next_retry_at <= ?", WebhookDeliveryStatusPending, now).
+		Order("next_retry_at asc").
+		Limit(limit).
+		Find(&deliveries).
+		Error
+	if err != nil {
+		return err
+	}
+
+	subscriptions := make(map[int]*WebhookSubscription)
+
+	for _, d := range deliveries {
+		result := LogDB.Model(&WebhookDelivery{}).
+			Where("id = ? and status = ? and next_retry_at <= ?",
+				d.ID, WebhookDeliveryStatusPending, now).
+			Update("next_retry_at", now.Add(webhookTimeout*2))
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		s, ok := subscriptions[d.SubscriptionID]
+		if !ok {
+			s, err = GetWebhookSubscription(d.GroupID, d.SubscriptionID)
+			if err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+
+				s = nil
+			}
+
+			subscriptions[d.SubscriptionID] = s
+		}
+
+		if s == nil || s.Status != WebhookSubscriptionStatusEnabled {
+			failWebhookDelivery(d, "subscription is deleted or disabled")
+			continue
+		}
+
+		deliverWebhook(s, d)
+	}
+
+	return nil
+}
+
+func failWebhookDelivery(d *WebhookDelivery, reason string) {
+	if err := LogDB.Model(&WebhookDelivery{}).
+		Where("id = ?", d.ID).
+		Updates(map[string]any{
+			"status": WebhookDeliveryStatusFailed,
+			"error":  reason,
+		}).
+		Error; err != nil {
+		log.Errorf("update webhook delivery %d failed: %v", d.ID, err)
+	}
+}
+
+// RedeliverWebhookDelivery queues the delivery again with fresh attempts
+func RedeliverWebhookDelivery(group string, id int) error {
+	result := LogDB.Model(&WebhookDelivery{}).
+		Where("id = ? and group_id = ?", id, group).
+		Updates(map[string]any{
+			"status":        WebhookDeliveryStatusPending,
+			"attempts":      0,
+			"next_retry_at": time.Now(),
+		})
+
+	return HandleUpdateResult(result, ErrWebhookDeliveryNotFound)
+}
+
+// GetWebhookDeliveries lists the deliveries of the group newest first
+func GetWebhookDeliveries(
+	group string,
+	subscriptionID int,
+	event WebhookEvent,
+	status WebhookDeliveryStatus,
+	page, perPage int,
+) (deliveries []*WebhookDelivery, total int64, err error) {
+	tx := LogDB.Model(&WebhookDelivery{}).Where("group_id = ?", group)
+
+	if subscriptionID != 0 {
+		tx = tx.Where("subscription_id = ?", subscriptionID)
+	}
+
+	if event != "" {
+		tx = tx.Where("event = ?", event)
+	}
+
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if total <= 0 {
+		return nil, 0, nil
+	}
+
+	limit, offset := toLimitOffset(page, perPage)
+
+	err = tx.Order("id desc").Limit(limit).Offset(offset).Find(&deliveries).Error
+
+	return deliveries, total, err
+}
+
+// TokenWebhookData is the data of the token events, QuotaType is total or
+// period for the quota exhausted event
+type TokenWebhookData struct {
+	TokenID       int     `json:"token_id"`
+	TokenName     string  `json:"token_name"`
+	Quota         float64 `json:"quota"`
+	PeriodQuota   float64 `json:"period_quota"`
+	PeriodType    string  `json:"period_type,omitempty"`
+	UsedAmount    float64 `json:"used_amount"`
+	QuotaType     string  `json:"quota_type,omitempty"`
+	PeriodStartAt int64   `json:"period_start_at,omitempty"`
+}
+
+// emitTokenQuotaExhausted emits the event when the amount made the used
+// amount cross the total or the period quota
+func emitTokenQuotaExhausted(t *Token, amount float64) {
+	data := TokenWebhookData{
+		TokenID:     t.ID,
+		TokenName:   string(t.Name),
+		Quota:       t.Quota,
+		PeriodQuota: t.PeriodQuota,
+		PeriodType:  string(t.PeriodType),
+		UsedAmount:  t.UsedAmount,
+	}
+
+	switch {
+	case t.Quota > 0 && t.UsedAmount >= t.Quota && t.UsedAmount-amount < t.Quota:
+		data.QuotaType = "total"
+	case t.PeriodQuota > 0:
+		// the usage belongs to a new period which is not reset yet
+		if needsReset, err := t.NeedsPeriodReset(); err != nil || needsReset {
+			return
+		}
+
+		periodUsage := t.UsedAmount - t.PeriodLastUpdateAmount
+		if periodUsage < t.PeriodQuota || periodUsage-amount >= t.PeriodQuota {
+			return
+		}
+
+		data.QuotaType = "period"
+		data.PeriodStartAt = t.PeriodLastUpdateTime.Unix()
+	default:
+		return
+	}
+
+	EmitWebhookEvent(t.GroupID, WebhookEventTokenQuotaExhausted, data.TokenName, data)
+}
@@ -0,0 +1,80 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func TestWebhookSubscriptionBeforeSave(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription model.WebhookSubscription
+		wantErr      bool
+	}{
+		{
+			name: "valid",
+			subscription: model.WebhookSubscription{
+				GroupID: "group",
+				URL:     "https://billing.example.com/hook",
+				Events:  []model.WebhookEvent{model.WebhookEventTokenQuotaExhausted},
+			},
+		},
+		{
+			name: "unknown event",
+			subscription: model.WebhookSubscription{
+				GroupID: "group",
+				URL:     "https://billing.example.com/hook",
+				Events:  []model.WebhookEvent{"token.created"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no events",
+			subscription: model.WebhookSubscription{
+				GroupID: "group",
+				URL:     "https://billing.example.com/hook",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid url",
+			subscription: model.WebhookSubscription{
+				GroupID: "group",
+				URL:     "billing.example.com",
+				Events:  []model.WebhookEvent{model.WebhookEventGroupBalanceLow},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.subscription.BeforeSave(nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BeforeSave() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && tt.subscription.Status != model.WebhookSubscriptionStatusEnabled {
+				t.Errorf("Status = %v, want enabled", tt.subscription.Status)
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	want := []time.Duration{
+		30 * time.Second,
+		time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		8 * time.Minute,
+	}
+
+	for i, w := range want {
+		if got := model.WebhookBackoff(i + 1); got != w {
+			t.Errorf("WebhookBackoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package monitor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// inFlight counts the requests in flight of the channels on this replica
+var inFlight sync.Map // map[int64]*atomic.Int64
+
+func inFlightCounter(channelID int64) *atomic.Int64 {
+	v, _ := inFlight.LoadOrStore(channelID, &atomic.Int64{})
+	counter, _ := v.(*atomic.Int64)
+
+	return counter
+}
+
+// StartInFlight counts a request sent to the channel until the returned
+// func is called
+func StartInFlight(channelID int64) func() {
+	counter := inFlightCounter(channelID)
+	counter.Add(1)
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			counter.Add(-1)
+		})
+	}
+}
+
+// GetInFlight returns the requests in flight of the channel
+func GetInFlight(channelID int64) int64 {
+	v, ok := inFlight.Load(channelID)
+	if !ok {
+		return 0
+	}
+
+	counter, _ := v.(*atomic.Int64)
+
+	return counter.Load()
+}
@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// ttfbWindow is the sliding window of the ttfb samples
+	ttfbWindow = 5 * time.Minute
+	// maxTTFBSamples bounds the samples kept per model channel
+	maxTTFBSamples = 256
+)
+
+type ttfbSample struct {
+	at   time.Time
+	ttfb time.Duration
+}
+
+type ttfbStats struct {
+	samples []ttfbSample
+}
+
+func (s *ttfbStats) cleanup(now time.Time) {
+	cutoff := now.Add(-ttfbWindow)
+
+	i := 0
+	for i < len(s.samples) && s.samples[i].at.Before(cutoff) {
+		i++
+	}
+
+	s.samples = s.samples[i:]
+}
+
+// ttfbMonitor keeps the recent ttfb of the channels of the models in memory,
+// each replica routes by its own samples
+type ttfbMonitor struct {
+	mu    sync.Mutex
+	stats map[string]map[int64]*ttfbStats
+}
+
+var channelTTFB = &ttfbMonitor{
+	stats: make(map[string]map[int64]*ttfbStats),
+}
+
+// AddTTFB records the time to first byte of a request of the model on the
+// channel
+func AddTTFB(model string, channelID int64, ttfb time.Duration) {
+	channelTTFB.mu.Lock()
+	defer channelTTFB.mu.Unlock()
+
+	channels, ok := channelTTFB.stats[model]
+	if !ok {
+		channels = make(map[int64]*ttfbStats)
+		channelTTFB.stats[model] = channels
+	}
+
+	stats, ok := channels[channelID]
+	if !ok {
+		stats = &ttfbStats{}
+		channels[channelID] = stats
+	}
+
+	now := time.Now()
+	stats.cleanup(now)
+
+	if len(stats.samples) >= maxTTFBSamples {
+		stats.samples = stats.samples[1:]
+	}
+
+	stats.samples = append(stats.samples, ttfbSample{at: now, ttfb: ttfb})
+}
+
+// GetModelChannelTTFBP50 returns the median ttfb of the channels of the
+// model over the sliding window, the channels without samples are absent
+func GetModelChannelTTFBP50(model string) map[int64]time.Duration {
+	channelTTFB.mu.Lock()
+	defer channelTTFB.mu.Unlock()
+
+	now := time.Now()
+	p50 := make(map[int64]time.Duration)
+
+	for channelID, stats := range channelTTFB.stats[model] {
+		stats.cleanup(now)
+
+		if len(stats.samples) == 0 {
+			delete(channelTTFB.stats[model], channelID)
+			continue
+		}
+
+		ttfbs := make([]time.Duration, len(stats.samples))
+		for i, sample := range stats.samples {
+			ttfbs[i] = sample.ttfb
+		}
+
+		slices.Sort(ttfbs)
+		p50[channelID] = ttfbs[len(ttfbs)/2]
+	}
+
+	return p50
+}
@@ -0,0 +1,52 @@
+package monitor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wavespeed/llm-server/core/monitor"
+)
+
+func TestGetModelChannelTTFBP50(t *testing.T) {
+	for _, ttfb := range []time.Duration{300, 100, 200} {
+		monitor.AddTTFB("ttfb-model", 1, ttfb*time.Millisecond)
+	}
+
+	monitor.AddTTFB("ttfb-model", 2, 50*time.Millisecond)
+	monitor.AddTTFB("other-model", 3, time.Second)
+
+	p50 := monitor.GetModelChannelTTFBP50("ttfb-model")
+	if len(p50) != 2 {
+		t.Fatalf("Expected 2 channels, Got %v", p50)
+	}
+
+	if p50[1] != 200*time.Millisecond {
+		t.Errorf("Expected 200ms for channel 1, Got %v", p50[1])
+	}
+
+	if p50[2] != 50*time.Millisecond {
+		t.Errorf("Expected 50ms for channel 2, Got %v", p50[2])
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	done1 := monitor.StartInFlight(10)
+	done2 := monitor.StartInFlight(10)
+
+	if got := monitor.GetInFlight(10); got != 2 {
+		t.Errorf("Expected 2 in flight, Got %d", got)
+	}
+
+	done1()
+	done1()
+
+	if got := monitor.GetInFlight(10); got != 1 {
+		t.Errorf("Expected 1 in flight, Got %d", got)
+	}
+
+	done2()
+
+	if got := monitor.GetInFlight(11); got != 0 {
+		t.Errorf("Expected 0 in flight, Got %d", got)
+	}
+}
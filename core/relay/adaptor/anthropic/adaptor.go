@@ -30,6 +30,7 @@ func (a *Adaptor) DefaultBaseURL() string {
 func (a *Adaptor) SupportMode(m mode.Mode) bool {
 	return m == mode.ChatCompletions ||
 		m == mode.Anthropic ||
+		m == mode.AnthropicCountTokens ||
 		m == mode.Gemini
 }
 
@@ -40,7 +41,12 @@ func (a *Adaptor) GetRequestURL(
 ) (adaptor.RequestURL, error) {
 	u := meta.Channel.BaseURL
 
-	url, err := url.JoinPath(u, "/messages")
+	path := "/messages"
+	if meta.Mode == mode.AnthropicCountTokens {
+		path = "/messages/count_tokens"
+	}
+
+	url, err := url.JoinPath(u, path)
 	if err != nil {
 		return adaptor.RequestURL{}, err
 	}
@@ -171,6 +177,8 @@ func (a *Adaptor) ConvertRequest(
 		}, nil
 	case mode.Anthropic:
 		return ConvertRequest(meta, req)
+	case mode.AnthropicCountTokens:
+		return ConvertCountTokensRequest(meta, req)
 	case mode.Gemini:
 		return ConvertGeminiRequest(meta, req)
 	default:
@@ -206,6 +214,8 @@ func (a *Adaptor) DoResponse(
 		} else {
 			usage, err = Handler(meta, c, resp)
 		}
+	case mode.AnthropicCountTokens:
+		usage, err = CountTokensHandler(meta, c, resp)
 	case mode.Gemini:
 		if utils.IsStreamResponse(resp) {
 			usage, err = GeminiStreamHandler(meta, c, resp)
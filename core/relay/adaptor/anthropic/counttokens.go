@@ -0,0 +1,82 @@
+package anthropic
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic/ast"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+)
+
+// ConvertCountTokensRequestToBytes sets the actual model of a count tokens
+// request, the other fields are passed through
+func ConvertCountTokensRequestToBytes(
+	meta *meta.Meta,
+	req *http.Request,
+	callbacks ...func(node *ast.Node) error,
+) ([]byte, error) {
+	node, err := common.UnmarshalRequest2NodeReusable(req)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = node.Set("model", ast.NewString(meta.ActualModel))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, callback := range callbacks {
+		if callback == nil {
+			continue
+		}
+
+		if err := callback(&node); err != nil {
+			return nil, err
+		}
+	}
+
+	return node.MarshalJSON()
+}
+
+func ConvertCountTokensRequest(
+	meta *meta.Meta,
+	req *http.Request,
+) (adaptor.ConvertResult, error) {
+	data, err := ConvertCountTokensRequestToBytes(meta, req)
+	if err != nil {
+		return adaptor.ConvertResult{}, err
+	}
+
+	return adaptor.ConvertResult{
+		Header: http.Header{
+			"Content-Type":   {"application/json"},
+			"Content-Length": {strconv.Itoa(len(data))},
+		},
+		Body: bytes.NewReader(data),
+	}, nil
+}
+
+// CountTokensHandler passes the count through, counting is not billed
+func CountTokensHandler(
+	_ *meta.Meta,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode != http.StatusOK {
+		return model.Usage{}, ErrorHandler(resp)
+	}
+
+	defer resp.Body.Close()
+
+	c.Writer.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	c.Writer.Header().Set("Content-Length", resp.Header.Get("Content-Length"))
+	_, _ = io.Copy(c.Writer, resp.Body)
+
+	return model.Usage{}, nil
+}
@@ -25,7 +25,8 @@ func (a *Adaptor) SupportMode(m mode.Mode) bool {
 	return m == mode.ChatCompletions ||
 		m == mode.Anthropic ||
 		m == mode.Embeddings ||
-		m == mode.Gemini
+		m == mode.Gemini ||
+		m == mode.GeminiCountTokens
 }
 
 var v1ModelMap = map[string]struct{}{}
@@ -56,6 +57,8 @@ func (a *Adaptor) GetRequestURL(
 	switch meta.Mode {
 	case mode.Embeddings:
 		action = "batchEmbedContents"
+	case mode.GeminiCountTokens:
+		action = "countTokens"
 	default:
 		action = "generateContent"
 	}
@@ -92,6 +95,8 @@ func (a *Adaptor) ConvertRequest(
 		return ConvertClaudeRequest(meta, req)
 	case mode.Gemini:
 		return NativeConvertRequest(meta, req)
+	case mode.GeminiCountTokens:
+		return ConvertCountTokensRequest(meta, req)
 	default:
 		return adaptor.ConvertResult{}, fmt.Errorf("unsupported mode: %s", meta.Mode)
 	}
@@ -134,6 +139,8 @@ func (a *Adaptor) DoResponse(
 		} else {
 			usage, err = NativeHandler(meta, c, resp)
 		}
+	case mode.GeminiCountTokens:
+		usage, err = CountTokensHandler(meta, c, resp)
 	default:
 		return model.Usage{}, relaymodel.WrapperOpenAIErrorWithMessage(
 			fmt.Sprintf("unsupported mode: %s", meta.Mode),
@@ -0,0 +1,111 @@
+package gemini
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic/ast"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+)
+
+const generateContentRequestKey = "generateContentRequest"
+
+// ConvertCountTokensRequest wraps a request with more than contents in a
+// generateContentRequest of the actual model, as the countTokens api only
+// takes the contents at the top level
+func ConvertCountTokensRequest(
+	meta *meta.Meta,
+	req *http.Request,
+) (adaptor.ConvertResult, error) {
+	node, err := common.UnmarshalRequest2NodeReusable(req)
+	if err != nil {
+		return adaptor.ConvertResult{}, err
+	}
+
+	generateContentRequest := node.Get(generateContentRequestKey)
+	if !generateContentRequest.Exists() {
+		fields, err := node.Map()
+		if err != nil {
+			return adaptor.ConvertResult{}, err
+		}
+
+		if len(fields) > 1 {
+			inner := node
+			node = ast.NewObject([]ast.Pair{
+				ast.NewPair(generateContentRequestKey, inner),
+			})
+			generateContentRequest = node.Get(generateContentRequestKey)
+		}
+	}
+
+	if generateContentRequest.Exists() {
+		_, err = generateContentRequest.Set(
+			"model",
+			ast.NewString("models/"+meta.ActualModel),
+		)
+		if err != nil {
+			return adaptor.ConvertResult{}, err
+		}
+	}
+
+	return marshalCountTokensRequest(&node)
+}
+
+// ConvertVertexCountTokensRequest unwraps a generateContentRequest, vertex
+// takes all the fields at the top level
+func ConvertVertexCountTokensRequest(
+	_ *meta.Meta,
+	req *http.Request,
+) (adaptor.ConvertResult, error) {
+	node, err := common.UnmarshalRequest2NodeReusable(req)
+	if err != nil {
+		return adaptor.ConvertResult{}, err
+	}
+
+	if generateContentRequest := node.Get(generateContentRequestKey); generateContentRequest.Exists() {
+		node = *generateContentRequest
+		_, _ = node.Unset("model")
+	}
+
+	return marshalCountTokensRequest(&node)
+}
+
+func marshalCountTokensRequest(node *ast.Node) (adaptor.ConvertResult, error) {
+	body, err := node.MarshalJSON()
+	if err != nil {
+		return adaptor.ConvertResult{}, err
+	}
+
+	return adaptor.ConvertResult{
+		Header: http.Header{
+			"Content-Type":   {"application/json"},
+			"Content-Length": {strconv.Itoa(len(body))},
+		},
+		Body: bytes.NewReader(body),
+	}, nil
+}
+
+// CountTokensHandler passes the count through, counting is not billed
+func CountTokensHandler(
+	_ *meta.Meta,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode != http.StatusOK {
+		return model.Usage{}, ErrorHandler(resp)
+	}
+
+	defer resp.Body.Close()
+
+	c.Writer.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	c.Writer.Header().Set("Content-Length", resp.Header.Get("Content-Length"))
+	_, _ = io.Copy(c.Writer, resp.Body)
+
+	return model.Usage{}, nil
+}
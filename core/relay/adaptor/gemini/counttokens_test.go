@@ -0,0 +1,83 @@
+package gemini_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/adaptor/gemini"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func convertCountTokens(
+	convert func(*meta.Meta, *http.Request) (adaptor.ConvertResult, error),
+	body string,
+) map[string]any {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	result, err := convert(&meta.Meta{ActualModel: "gemini-2.5-flash"}, req)
+	convey.So(err, convey.ShouldBeNil)
+
+	data, err := io.ReadAll(result.Body)
+	convey.So(err, convey.ShouldBeNil)
+
+	var m map[string]any
+	convey.So(sonic.Unmarshal(data, &m), convey.ShouldBeNil)
+
+	return m
+}
+
+func TestConvertCountTokensRequest(t *testing.T) {
+	convey.Convey("ConvertCountTokensRequest", t, func() {
+		convey.Convey("should pass only contents through", func() {
+			m := convertCountTokens(
+				gemini.ConvertCountTokensRequest,
+				`{"contents":[{"parts":[{"text":"hi"}]}]}`,
+			)
+			convey.So(m, convey.ShouldContainKey, "contents")
+			convey.So(m, convey.ShouldNotContainKey, "generateContentRequest")
+		})
+
+		convey.Convey("should wrap a full request with the actual model", func() {
+			m := convertCountTokens(
+				gemini.ConvertCountTokensRequest,
+				`{"contents":[{"parts":[{"text":"hi"}]}],"systemInstruction":{"parts":[{"text":"be brief"}]}}`,
+			)
+			convey.So(m, convey.ShouldHaveLength, 1)
+
+			inner, ok := m["generateContentRequest"].(map[string]any)
+			convey.So(ok, convey.ShouldBeTrue)
+			convey.So(inner["model"], convey.ShouldEqual, "models/gemini-2.5-flash")
+			convey.So(inner, convey.ShouldContainKey, "systemInstruction")
+		})
+
+		convey.Convey("should set the model of a wrapped request", func() {
+			m := convertCountTokens(
+				gemini.ConvertCountTokensRequest,
+				`{"generateContentRequest":{"model":"models/alias","contents":[]}}`,
+			)
+
+			inner, ok := m["generateContentRequest"].(map[string]any)
+			convey.So(ok, convey.ShouldBeTrue)
+			convey.So(inner["model"], convey.ShouldEqual, "models/gemini-2.5-flash")
+		})
+	})
+
+	convey.Convey("ConvertVertexCountTokensRequest", t, func() {
+		convey.Convey("should unwrap a wrapped request", func() {
+			m := convertCountTokens(
+				gemini.ConvertVertexCountTokensRequest,
+				`{"generateContentRequest":{"model":"models/alias","contents":[]}}`,
+			)
+			convey.So(m, convey.ShouldContainKey, "contents")
+			convey.So(m, convey.ShouldNotContainKey, "model")
+			convey.So(m, convey.ShouldNotContainKey, "generateContentRequest")
+		})
+	})
+}
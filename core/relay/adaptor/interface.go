@@ -25,6 +25,12 @@ type StoreCache struct {
 type Store interface {
 	GetStore(group string, tokenID int, id string) (StoreCache, error)
 	SaveStore(store StoreCache) error
+	DeleteStore(group string, tokenID int, id string) error
+	// GetGroupStore returns the store saved by any token of the group
+	GetGroupStore(group string, id string) (StoreCache, error)
+	// MarkStoreBilled atomically marks the store of the group as billed, it
+	// returns false when the store is already marked
+	MarkStoreBilled(group string, id string) (bool, error)
 }
 
 type Metadata struct {
@@ -46,18 +46,39 @@ func (a *Adaptor) SupportMode(m mode.Mode) bool {
 		m == mode.ResponsesGet ||
 		m == mode.ResponsesDelete ||
 		m == mode.ResponsesCancel ||
-		m == mode.ResponsesInputItems
+		m == mode.ResponsesInputItems ||
+		m == mode.Files ||
+		m == mode.FilesGet ||
+		m == mode.FilesDelete ||
+		m == mode.FilesContent ||
+		m == mode.FineTuningJobs ||
+		m == mode.FineTuningJobsList ||
+		m == mode.FineTuningJobsGet ||
+		m == mode.FineTuningJobsCancel ||
+		m == mode.FineTuningJobsEvents ||
+		m == mode.FineTuningJobsCheckpoints ||
+		m == mode.Realtime
 }
 
 //nolint:gocyclo
 func (a *Adaptor) GetRequestURL(
 	meta *meta.Meta,
 	_ adaptor.Store,
-	_ *gin.Context,
+	c *gin.Context,
 ) (adaptor.RequestURL, error) {
 	u := meta.Channel.BaseURL
 
 	switch meta.Mode {
+	case mode.Realtime:
+		requestURL, err := url.JoinPath(u, "/realtime")
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodGet,
+			URL:    requestURL + "?model=" + url.QueryEscape(meta.ActualModel),
+		}, nil
 	case mode.Responses:
 		url, err := url.JoinPath(u, "/responses")
 		if err != nil {
@@ -251,11 +272,128 @@ func (a *Adaptor) GetRequestURL(
 			Method: http.MethodGet,
 			URL:    url,
 		}, nil
+	case mode.Files:
+		url, err := url.JoinPath(u, "/files")
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodPost,
+			URL:    url,
+		}, nil
+	case mode.FilesGet:
+		url, err := url.JoinPath(u, "/files", meta.FileID)
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodGet,
+			URL:    url,
+		}, nil
+	case mode.FilesDelete:
+		url, err := url.JoinPath(u, "/files", meta.FileID)
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodDelete,
+			URL:    url,
+		}, nil
+	case mode.FilesContent:
+		url, err := url.JoinPath(u, "/files", meta.FileID, "content")
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodGet,
+			URL:    url,
+		}, nil
+	case mode.FineTuningJobs:
+		url, err := url.JoinPath(u, "/fine_tuning/jobs")
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodPost,
+			URL:    url,
+		}, nil
+	case mode.FineTuningJobsList:
+		url, err := url.JoinPath(u, "/fine_tuning/jobs")
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodGet,
+			URL:    withRawQuery(url, c),
+		}, nil
+	case mode.FineTuningJobsGet:
+		url, err := url.JoinPath(u, "/fine_tuning/jobs", meta.JobID)
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodGet,
+			URL:    url,
+		}, nil
+	case mode.FineTuningJobsCancel:
+		url, err := url.JoinPath(u, "/fine_tuning/jobs", meta.JobID, "cancel")
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodPost,
+			URL:    url,
+		}, nil
+	case mode.FineTuningJobsEvents:
+		url, err := url.JoinPath(u, "/fine_tuning/jobs", meta.JobID, "events")
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodGet,
+			URL:    withRawQuery(url, c),
+		}, nil
+	case mode.FineTuningJobsCheckpoints:
+		url, err := url.JoinPath(u, "/fine_tuning/jobs", meta.JobID, "checkpoints")
+		if err != nil {
+			return adaptor.RequestURL{}, err
+		}
+
+		return adaptor.RequestURL{
+			Method: http.MethodGet,
+			URL:    withRawQuery(url, c),
+		}, nil
 	default:
 		return adaptor.RequestURL{}, fmt.Errorf("unsupported mode: %s", meta.Mode)
 	}
 }
 
+// withRawQuery forwards the pagination query of list requests, the gateway
+// only model query is dropped
+func withRawQuery(u string, c *gin.Context) string {
+	if c == nil || c.Request.URL.RawQuery == "" {
+		return u
+	}
+
+	query := c.Request.URL.Query()
+	query.Del("model")
+
+	if len(query) == 0 {
+		return u
+	}
+
+	return u + "?" + query.Encode()
+}
+
 func (a *Adaptor) SetupRequestHeader(
 	meta *meta.Meta,
 	_ adaptor.Store,
@@ -329,6 +467,18 @@ func ConvertRequest(
 			return ConvertGeminiToResponsesRequest(meta, req)
 		}
 		return ConvertGeminiRequest(meta, req)
+	case mode.Files:
+		return ConvertFileUploadRequest(meta, req)
+	case mode.FilesGet, mode.FilesDelete, mode.FilesContent:
+		return adaptor.ConvertResult{}, nil
+	case mode.FineTuningJobs:
+		return ConvertFineTuningJobRequest(meta, req)
+	case mode.FineTuningJobsList, mode.FineTuningJobsGet, mode.FineTuningJobsCancel,
+		mode.FineTuningJobsEvents, mode.FineTuningJobsCheckpoints:
+		return adaptor.ConvertResult{}, nil
+	case mode.Realtime:
+		// the frames are proxied after the upgrade
+		return adaptor.ConvertResult{}, nil
 	default:
 		return adaptor.ConvertResult{}, fmt.Errorf("unsupported mode: %s", meta.Mode)
 	}
@@ -421,6 +571,23 @@ func DoResponse(
 				usage, err = GeminiHandler(meta, c, resp)
 			}
 		}
+	case mode.Files:
+		usage, err = FileUploadHandler(meta, store, c, resp)
+	case mode.FilesDelete:
+		usage, err = FileDeleteHandler(meta, store, c, resp)
+	case mode.FilesGet, mode.FilesContent:
+		usage, err = FileHandler(meta, c, resp)
+	case mode.FineTuningJobs:
+		usage, err = FineTuningJobHandler(meta, store, c, resp)
+	case mode.FineTuningJobsGet:
+		usage, err = FineTuningJobGetHandler(meta, store, c, resp)
+	case mode.FineTuningJobsList:
+		usage, err = FineTuningJobListHandler(meta, store, c, resp)
+	case mode.FineTuningJobsCancel, mode.FineTuningJobsEvents,
+		mode.FineTuningJobsCheckpoints:
+		usage, err = FineTuningHandler(meta, c, resp)
+	case mode.Realtime:
+		usage, err = RealtimeHandler(meta, c, resp)
 	default:
 		return model.Usage{}, relaymodel.WrapperOpenAIErrorWithMessage(
 			fmt.Sprintf("unsupported mode: %s", meta.Mode),
@@ -437,9 +604,13 @@ const MetaResponseFormat = "response_format"
 func (a *Adaptor) DoRequest(
 	meta *meta.Meta,
 	_ adaptor.Store,
-	_ *gin.Context,
+	c *gin.Context,
 	req *http.Request,
 ) (*http.Response, error) {
+	if meta.Mode == mode.Realtime {
+		return RealtimeDoRequest(meta, c, req)
+	}
+
 	return utils.DoRequest(req, meta.RequestTimeout)
 }
 
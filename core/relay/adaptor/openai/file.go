@@ -0,0 +1,156 @@
+package openai
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+)
+
+// upstream files do not expire unless deleted, keep the pin for a long time
+const fileStoreExpiration = time.Hour * 24 * 365
+
+// ConvertFileUploadRequest rebuilds the multipart upload without the gateway
+// only model field
+func ConvertFileUploadRequest(
+	_ *meta.Meta,
+	request *http.Request,
+) (adaptor.ConvertResult, error) {
+	if err := request.ParseMultipartForm(1024 * 1024 * 4); err != nil {
+		return adaptor.ConvertResult{}, fmt.Errorf("parse multipart form: %w", err)
+	}
+
+	multipartBody := &bytes.Buffer{}
+	multipartWriter := multipart.NewWriter(multipartBody)
+
+	for key, values := range request.MultipartForm.Value {
+		if key == "model" || len(values) == 0 {
+			continue
+		}
+
+		if err := multipartWriter.WriteField(key, values[0]); err != nil {
+			return adaptor.ConvertResult{}, fmt.Errorf("write field %s: %w", key, err)
+		}
+	}
+
+	if err := processFormFiles(multipartWriter, request.MultipartForm.File); err != nil {
+		return adaptor.ConvertResult{}, fmt.Errorf("process form files: %w", err)
+	}
+
+	multipartWriter.Close()
+
+	return adaptor.ConvertResult{
+		Header: http.Header{
+			"Content-Type": {multipartWriter.FormDataContentType()},
+		},
+		Body: multipartBody,
+	}, nil
+}
+
+// FileUploadHandler handles POST /v1/files and pins the upstream file id to
+// the channel so later requests reach the same upstream account
+func FileUploadHandler(
+	meta *meta.Meta,
+	store adaptor.Store,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode != http.StatusOK {
+		return model.Usage{}, ErrorHanlder(resp)
+	}
+
+	defer resp.Body.Close()
+
+	responseBody, err := common.GetResponseBody(resp)
+	if err != nil {
+		return model.Usage{}, relaymodel.WrapperOpenAIError(
+			err,
+			"read_response_body_failed",
+			http.StatusInternalServerError,
+		)
+	}
+
+	var file relaymodel.File
+
+	err = sonic.Unmarshal(responseBody, &file)
+	if err != nil {
+		return model.Usage{}, relaymodel.WrapperOpenAIError(
+			err,
+			"unmarshal_response_body_failed",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if file.ID != "" {
+		err = store.SaveStore(adaptor.StoreCache{
+			ID:        file.ID,
+			GroupID:   meta.Group.ID,
+			TokenID:   meta.Token.ID,
+			ChannelID: meta.Channel.ID,
+			Model:     meta.ActualModel,
+			ExpiresAt: time.Now().Add(fileStoreExpiration),
+		})
+		if err != nil {
+			log := common.GetLogger(c)
+			log.Errorf("save file store failed: %v", err)
+		}
+	}
+
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.Header().Set("Content-Length", strconv.Itoa(len(responseBody)))
+	_, _ = c.Writer.Write(responseBody)
+
+	return model.Usage{}, nil
+}
+
+// FileDeleteHandler handles DELETE /v1/files/{id} and deletes the pin of the
+// deleted upstream file
+func FileDeleteHandler(
+	meta *meta.Meta,
+	store adaptor.Store,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode == http.StatusOK && meta.FileID != "" {
+		if err := store.DeleteStore(meta.Group.ID, meta.Token.ID, meta.FileID); err != nil {
+			log := common.GetLogger(c)
+			log.Errorf("delete file store failed: %v", err)
+		}
+	}
+
+	return FileHandler(meta, c, resp)
+}
+
+// FileHandler handles GET/DELETE /v1/files/{id} and GET /v1/files/{id}/content
+func FileHandler(
+	_ *meta.Meta,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode != http.StatusOK {
+		return model.Usage{}, ErrorHanlder(resp)
+	}
+
+	defer resp.Body.Close()
+
+	c.Writer.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+
+	if contentLength := resp.Header.Get("Content-Length"); contentLength != "" {
+		c.Writer.Header().Set("Content-Length", contentLength)
+	}
+
+	_, _ = io.Copy(c.Writer, resp.Body)
+
+	return model.Usage{}, nil
+}
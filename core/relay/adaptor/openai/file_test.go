@@ -0,0 +1,92 @@
+package openai_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/adaptor/openai"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fileStore struct {
+	adaptor.Store
+
+	deleted []string
+}
+
+func (s *fileStore) DeleteStore(group string, _ int, id string) error {
+	s.deleted = append(s.deleted, group+"/"+id)
+	return nil
+}
+
+func newFileResponse(status int, contentLength, body string) *http.Response {
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+
+	if contentLength != "" {
+		resp.Header.Set("Content-Length", contentLength)
+	}
+
+	return resp
+}
+
+func TestFileDeleteHandler(t *testing.T) {
+	const body = `{"id":"file-1","object":"file","deleted":true}`
+
+	m := &meta.Meta{FileID: "file-1"}
+	m.Group.ID = "group"
+
+	store := &fileStore{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	// a chunked upstream response has no content length
+	_, err := openai.FileDeleteHandler(m, store, c, newFileResponse(http.StatusOK, "", body))
+	require.Nil(t, err)
+
+	assert.Equal(t, []string{"group/file-1"}, store.deleted)
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Equal(t, body, w.Body.String())
+}
+
+func TestFileDeleteHandlerUpstreamError(t *testing.T) {
+	m := &meta.Meta{FileID: "file-1"}
+	m.Group.ID = "group"
+
+	store := &fileStore{}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	_, err := openai.FileDeleteHandler(m, store, c, newFileResponse(
+		http.StatusNotFound,
+		"",
+		`{"error":{"message":"no such file","type":"invalid_request_error"}}`,
+	))
+	require.NotNil(t, err)
+
+	assert.Empty(t, store.deleted)
+}
+
+func TestFileHandlerContentLength(t *testing.T) {
+	const body = `{"id":"file-1","object":"file"}`
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	_, err := openai.FileHandler(&meta.Meta{}, c, newFileResponse(http.StatusOK, "31", body))
+	require.Nil(t, err)
+
+	assert.Equal(t, "31", w.Header().Get("Content-Length"))
+	assert.Equal(t, body, w.Body.String())
+}
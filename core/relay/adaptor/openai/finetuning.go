@@ -0,0 +1,269 @@
+package openai
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/bytedance/sonic/ast"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+)
+
+// fine-tuning jobs do not expire, keep the pin for a long time
+const fineTuningStoreExpiration = time.Hour * 24 * 365
+
+func ConvertFineTuningJobRequest(
+	meta *meta.Meta,
+	req *http.Request,
+) (adaptor.ConvertResult, error) {
+	node, err := common.UnmarshalRequest2NodeReusable(req)
+	if err != nil {
+		return adaptor.ConvertResult{}, err
+	}
+
+	_, err = node.Set("model", ast.NewString(meta.ActualModel))
+	if err != nil {
+		return adaptor.ConvertResult{}, err
+	}
+
+	jsonData, err := sonic.Marshal(&node)
+	if err != nil {
+		return adaptor.ConvertResult{}, err
+	}
+
+	return adaptor.ConvertResult{
+		Header: http.Header{
+			"Content-Type":   {"application/json"},
+			"Content-Length": {strconv.Itoa(len(jsonData))},
+		},
+		Body: bytes.NewReader(jsonData),
+	}, nil
+}
+
+func readFineTuningJob(resp *http.Response) ([]byte, *relaymodel.FineTuningJob, adaptor.Error) {
+	defer resp.Body.Close()
+
+	responseBody, err := common.GetResponseBody(resp)
+	if err != nil {
+		return nil, nil, relaymodel.WrapperOpenAIError(
+			err,
+			"read_response_body_failed",
+			http.StatusInternalServerError,
+		)
+	}
+
+	var job relaymodel.FineTuningJob
+
+	err = sonic.Unmarshal(responseBody, &job)
+	if err != nil {
+		return nil, nil, relaymodel.WrapperOpenAIError(
+			err,
+			"unmarshal_response_body_failed",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return responseBody, &job, nil
+}
+
+func writeFineTuningResponse(c *gin.Context, responseBody []byte) {
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.Header().Set("Content-Length", strconv.Itoa(len(responseBody)))
+	_, _ = c.Writer.Write(responseBody)
+}
+
+// FineTuningJobHandler handles POST /v1/fine_tuning/jobs and pins the job to
+// the channel so later requests reach the same upstream account
+func FineTuningJobHandler(
+	meta *meta.Meta,
+	store adaptor.Store,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode != http.StatusOK {
+		return model.Usage{}, ErrorHanlder(resp)
+	}
+
+	responseBody, job, err := readFineTuningJob(resp)
+	if err != nil {
+		return model.Usage{}, err
+	}
+
+	if job.ID != "" {
+		saveErr := store.SaveStore(adaptor.StoreCache{
+			ID:        job.ID,
+			GroupID:   meta.Group.ID,
+			TokenID:   meta.Token.ID,
+			ChannelID: meta.Channel.ID,
+			// the stored model is distributed again, so keep the requested name
+			Model:     meta.OriginModel,
+			ExpiresAt: time.Now().Add(fineTuningStoreExpiration),
+		})
+		if saveErr != nil {
+			log := common.GetLogger(c)
+			log.Errorf("save fine-tuning job store failed: %v", saveErr)
+		}
+	}
+
+	writeFineTuningResponse(c, responseBody)
+
+	return model.Usage{}, nil
+}
+
+// FineTuningJobGetHandler handles GET /v1/fine_tuning/jobs/{id}, the trained
+// tokens of a succeeded job are returned as usage the first time the group
+// sees it
+func FineTuningJobGetHandler(
+	meta *meta.Meta,
+	store adaptor.Store,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode != http.StatusOK {
+		return model.Usage{}, ErrorHanlder(resp)
+	}
+
+	responseBody, job, err := readFineTuningJob(resp)
+	if err != nil {
+		return model.Usage{}, err
+	}
+
+	writeFineTuningResponse(c, responseBody)
+
+	return model.Usage{
+		TrainedTokens: model.ZeroNullInt64(billFineTuningJob(meta, store, c, job)),
+	}, nil
+}
+
+// FineTuningJobListHandler handles GET /v1/fine_tuning/jobs, the listed jobs
+// share the upstream account with other groups, so only the succeeded jobs
+// pinned by the group to the requested model are billed
+func FineTuningJobListHandler(
+	meta *meta.Meta,
+	store adaptor.Store,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode != http.StatusOK {
+		return model.Usage{}, ErrorHanlder(resp)
+	}
+
+	defer resp.Body.Close()
+
+	responseBody, err := common.GetResponseBody(resp)
+	if err != nil {
+		return model.Usage{}, relaymodel.WrapperOpenAIError(
+			err,
+			"read_response_body_failed",
+			http.StatusInternalServerError,
+		)
+	}
+
+	var list relaymodel.FineTuningJobList
+
+	err = sonic.Unmarshal(responseBody, &list)
+	if err != nil {
+		return model.Usage{}, relaymodel.WrapperOpenAIError(
+			err,
+			"unmarshal_response_body_failed",
+			http.StatusInternalServerError,
+		)
+	}
+
+	writeFineTuningResponse(c, responseBody)
+
+	var trainedTokens int64
+
+	for i := range list.Data {
+		job := &list.Data[i]
+		if !isFineTuningJobFinished(job) {
+			continue
+		}
+
+		pin, err := store.GetGroupStore(meta.Group.ID, job.ID)
+		if err != nil || pin.Model != meta.OriginModel {
+			continue
+		}
+
+		trainedTokens += billFineTuningJob(meta, store, c, job)
+	}
+
+	return model.Usage{
+		TrainedTokens: model.ZeroNullInt64(trainedTokens),
+	}, nil
+}
+
+func isFineTuningJobFinished(job *relaymodel.FineTuningJob) bool {
+	switch job.Status {
+	case relaymodel.FineTuningJobStatusSucceeded,
+		relaymodel.FineTuningJobStatusFailed,
+		relaymodel.FineTuningJobStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// billFineTuningJob returns the trained tokens to bill for a job, the finished
+// jobs are marked once per group, the failed and cancelled ones too so they
+// are no longer polled
+func billFineTuningJob(
+	meta *meta.Meta,
+	store adaptor.Store,
+	c *gin.Context,
+	job *relaymodel.FineTuningJob,
+) int64 {
+	if job.ID == "" || !isFineTuningJobFinished(job) {
+		return 0
+	}
+
+	marked, err := store.MarkStoreBilled(meta.Group.ID, job.ID)
+	if err != nil {
+		// do not bill without the mark, or every later request bills again
+		log := common.GetLogger(c)
+		log.Errorf("mark fine-tuning job billed failed: %v", err)
+
+		return 0
+	}
+
+	if !marked ||
+		job.Status != relaymodel.FineTuningJobStatusSucceeded ||
+		job.TrainedTokens == nil {
+		return 0
+	}
+
+	return max(*job.TrainedTokens, 0)
+}
+
+// FineTuningHandler handles the fine-tuning requests which are passed through
+func FineTuningHandler(
+	_ *meta.Meta,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode != http.StatusOK {
+		return model.Usage{}, ErrorHanlder(resp)
+	}
+
+	defer resp.Body.Close()
+
+	responseBody, err := common.GetResponseBody(resp)
+	if err != nil {
+		return model.Usage{}, relaymodel.WrapperOpenAIError(
+			err,
+			"read_response_body_failed",
+			http.StatusInternalServerError,
+		)
+	}
+
+	writeFineTuningResponse(c, responseBody)
+
+	return model.Usage{}, nil
+}
@@ -0,0 +1,236 @@
+package openai
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+)
+
+const (
+	metaRealtimeConn = "realtime_conn"
+	// MetaRealtimeUsageHandler is a func(model.Usage) bool called with the
+	// usage of every response, the session is closed when it returns false,
+	// the usage handed to it is not returned by the handler
+	MetaRealtimeUsageHandler = "realtime_usage_handler"
+
+	realtimeWriteTimeout = 10 * time.Second
+)
+
+var realtimeDialer = websocket.Dialer{
+	Proxy:            http.ProxyFromEnvironment,
+	HandshakeTimeout: 30 * time.Second,
+}
+
+var realtimeUpgrader = websocket.Upgrader{
+	// the clients are authenticated by the token, browsers connect from any
+	// origin
+	CheckOrigin:  func(_ *http.Request) bool { return true },
+	Subprotocols: []string{"realtime"},
+}
+
+func realtimeURL(u string) string {
+	switch {
+	case strings.HasPrefix(u, "https://"):
+		return "wss://" + strings.TrimPrefix(u, "https://")
+	case strings.HasPrefix(u, "http://"):
+		return "ws://" + strings.TrimPrefix(u, "http://")
+	default:
+		return u
+	}
+}
+
+// RealtimeDoRequest dials the upstream realtime websocket, a failed
+// handshake is returned as the response so it is handled as an upstream
+// error
+func RealtimeDoRequest(meta *meta.Meta, c *gin.Context, req *http.Request) (*http.Response, error) {
+	header := req.Header.Clone()
+	if beta := c.Request.Header.Get("Openai-Beta"); beta != "" {
+		header.Set("Openai-Beta", beta)
+	}
+
+	conn, resp, err := realtimeDialer.DialContext(req.Context(), realtimeURL(req.URL.String()), header)
+	if err != nil {
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			return resp, nil
+		}
+
+		return nil, err
+	}
+
+	meta.Set(metaRealtimeConn, conn)
+
+	return &http.Response{
+		StatusCode: http.StatusSwitchingProtocols,
+		Header:     resp.Header,
+		Body:       http.NoBody,
+	}, nil
+}
+
+// RealtimeHandler upgrades the client connection and proxies the frames
+// both ways until one side closes, the usage of the response.done events is
+// billed by the usage handler or returned when there is none
+func RealtimeHandler(
+	meta *meta.Meta,
+	c *gin.Context,
+	resp *http.Response,
+) (model.Usage, adaptor.Error) {
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return model.Usage{}, ErrorHanlder(resp)
+	}
+
+	upstream, ok := meta.MustGet(metaRealtimeConn).(*websocket.Conn)
+	if !ok {
+		return model.Usage{}, relaymodel.WrapperOpenAIErrorWithMessage(
+			"realtime connection not found",
+			"realtime_conn_not_found",
+			http.StatusInternalServerError,
+		)
+	}
+	defer upstream.Close()
+
+	client, err := realtimeUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader has written the error response
+		return model.Usage{}, relaymodel.WrapperOpenAIErrorWithMessage(
+			"upgrade realtime connection failed: "+err.Error(),
+			"realtime_upgrade_failed",
+			http.StatusBadRequest,
+		)
+	}
+	defer client.Close()
+
+	var usageHandler func(model.Usage) bool
+	if v, ok := meta.Get(MetaRealtimeUsageHandler); ok {
+		usageHandler, _ = v.(func(model.Usage) bool)
+	}
+
+	log := common.GetLogger(c)
+
+	var (
+		usage     model.Usage
+		closeOnce sync.Once
+	)
+
+	closeBoth := func(code int, text string) {
+		closeOnce.Do(func() {
+			message := websocket.FormatCloseMessage(code, text)
+			deadline := time.Now().Add(realtimeWriteTimeout)
+			_ = client.WriteControl(websocket.CloseMessage, message, deadline)
+			_ = upstream.WriteControl(websocket.CloseMessage, message, deadline)
+			_ = client.Close()
+			_ = upstream.Close()
+		})
+	}
+
+	done := make(chan struct{})
+
+	// client to upstream
+	go func() {
+		defer close(done)
+
+		for {
+			messageType, data, err := client.ReadMessage()
+			if err != nil {
+				closeBoth(realtimeCloseCode(err), "")
+				return
+			}
+
+			_ = upstream.SetWriteDeadline(time.Now().Add(realtimeWriteTimeout))
+			if err := upstream.WriteMessage(messageType, data); err != nil {
+				closeBoth(websocket.CloseGoingAway, "upstream unavailable")
+				return
+			}
+		}
+	}()
+
+	// upstream to client
+	for {
+		messageType, data, err := upstream.ReadMessage()
+		if err != nil {
+			closeBoth(realtimeCloseCode(err), "")
+			break
+		}
+
+		_ = client.SetWriteDeadline(time.Now().Add(realtimeWriteTimeout))
+		if err := client.WriteMessage(messageType, data); err != nil {
+			closeBoth(websocket.CloseGoingAway, "")
+			break
+		}
+
+		if messageType != websocket.TextMessage {
+			continue
+		}
+
+		responseUsage, ok := realtimeResponseUsage(data)
+		if !ok {
+			continue
+		}
+
+		if usageHandler == nil {
+			usage.Add(responseUsage)
+			continue
+		}
+
+		if !usageHandler(responseUsage) {
+			log.Warn("realtime session closed, the balance is not enough")
+			closeBoth(websocket.ClosePolicyViolation, "balance not enough")
+
+			break
+		}
+	}
+
+	<-done
+
+	return usage, nil
+}
+
+var realtimeResponseDone = []byte(relaymodel.RealtimeEventTypeResponseDone)
+
+func realtimeResponseUsage(data []byte) (model.Usage, bool) {
+	// skip the audio deltas without parsing them
+	if !bytes.Contains(data, realtimeResponseDone) {
+		return model.Usage{}, false
+	}
+
+	var event relaymodel.RealtimeEvent
+	if err := sonic.Unmarshal(data, &event); err != nil {
+		return model.Usage{}, false
+	}
+
+	if event.Type != relaymodel.RealtimeEventTypeResponseDone ||
+		event.Response == nil ||
+		event.Response.Usage == nil {
+		return model.Usage{}, false
+	}
+
+	return event.Response.Usage.ToModelUsage(), true
+}
+
+func realtimeCloseCode(err error) int {
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) {
+		return websocket.CloseNormalClosure
+	}
+
+	// the reserved codes can not be sent in a close frame
+	switch closeErr.Code {
+	case websocket.CloseNoStatusReceived,
+		websocket.CloseAbnormalClosure,
+		websocket.CloseTLSHandshake:
+		return websocket.CloseNormalClosure
+	default:
+		return closeErr.Code
+	}
+}
@@ -0,0 +1,186 @@
+package openai_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/adaptor/openai"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const realtimeResponseDone = `{"type":"response.done","response":{"id":"resp_1","status":"completed",` +
+	`"usage":{"total_tokens":30,"input_tokens":20,"output_tokens":10,` +
+	`"input_token_details":{"cached_tokens":5,"text_tokens":8,"audio_tokens":12},` +
+	`"output_token_details":{"text_tokens":4,"audio_tokens":6}}}}`
+
+// newRealtimeUpstream answers every client frame with a response.done
+// event, the auth header is checked as the upstream would
+func newRealtimeUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-upstream" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"invalid key","type":"invalid_request_error"}}`))
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(realtimeResponseDone)); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func newRealtimeGateway(
+	t *testing.T,
+	upstreamURL string,
+	key string,
+	usageHandler func(model.Usage) bool,
+	result chan<- model.Usage,
+) *httptest.Server {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/v1/realtime", func(c *gin.Context) {
+		m := meta.NewMeta(&model.Channel{
+			BaseURL: upstreamURL + "/v1",
+			Key:     key,
+		}, mode.Realtime, "gpt-realtime", model.ModelConfig{})
+
+		if usageHandler != nil {
+			m.Set(openai.MetaRealtimeUsageHandler, usageHandler)
+		}
+
+		a := &openai.Adaptor{}
+
+		requestURL, err := a.GetRequestURL(m, nil, c)
+		require.NoError(t, err)
+
+		req, err := http.NewRequestWithContext(c.Request.Context(), requestURL.Method, requestURL.URL, nil)
+		require.NoError(t, err)
+		require.NoError(t, a.SetupRequestHeader(m, nil, c, req))
+
+		resp, err := a.DoRequest(m, nil, c, req)
+		require.NoError(t, err)
+
+		usage, relayErr := a.DoResponse(m, nil, c, resp)
+		if relayErr != nil {
+			c.JSON(relayErr.StatusCode(), relayErr)
+		}
+
+		result <- usage
+	})
+
+	return httptest.NewServer(router)
+}
+
+func dialGateway(t *testing.T, gateway *httptest.Server) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	u := "ws" + strings.TrimPrefix(gateway.URL, "http") + "/v1/realtime"
+
+	return websocket.DefaultDialer.Dial(u, nil)
+}
+
+func TestRealtimeProxiesAndReturnsUsage(t *testing.T) {
+	upstream := newRealtimeUpstream(t)
+	defer upstream.Close()
+
+	result := make(chan model.Usage, 1)
+
+	gateway := newRealtimeGateway(t, upstream.URL, "sk-upstream", nil, result)
+	defer gateway.Close()
+
+	conn, _, err := dialGateway(t, gateway)
+	require.NoError(t, err)
+
+	for range 2 {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"response.create"}`)))
+
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.JSONEq(t, realtimeResponseDone, string(data))
+	}
+
+	require.NoError(t, conn.Close())
+
+	usage := <-result
+	assert.Equal(t, model.ZeroNullInt64(40), usage.InputTokens)
+	assert.Equal(t, model.ZeroNullInt64(20), usage.OutputTokens)
+	assert.Equal(t, model.ZeroNullInt64(24), usage.AudioInputTokens)
+	assert.Equal(t, model.ZeroNullInt64(12), usage.AudioOutputTokens)
+	assert.Equal(t, model.ZeroNullInt64(10), usage.CachedTokens)
+}
+
+func TestRealtimeUsageHandlerClosesSession(t *testing.T) {
+	upstream := newRealtimeUpstream(t)
+	defer upstream.Close()
+
+	var handled []model.Usage
+
+	result := make(chan model.Usage, 1)
+
+	gateway := newRealtimeGateway(t, upstream.URL, "sk-upstream", func(usage model.Usage) bool {
+		handled = append(handled, usage)
+		return false
+	}, result)
+	defer gateway.Close()
+
+	conn, _, err := dialGateway(t, gateway)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"response.create"}`)))
+
+	_, _, err = conn.ReadMessage()
+	require.NoError(t, err)
+
+	_, _, err = conn.ReadMessage()
+	require.Error(t, err)
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+
+	usage := <-result
+	assert.Zero(t, usage.InputTokens)
+	require.Len(t, handled, 1)
+	assert.Equal(t, model.ZeroNullInt64(20), handled[0].InputTokens)
+}
+
+func TestRealtimeUpstreamHandshakeError(t *testing.T) {
+	upstream := newRealtimeUpstream(t)
+	defer upstream.Close()
+
+	result := make(chan model.Usage, 1)
+
+	gateway := newRealtimeGateway(t, upstream.URL, "sk-wrong", nil, result)
+	defer gateway.Close()
+
+	_, resp, err := dialGateway(t, gateway)
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
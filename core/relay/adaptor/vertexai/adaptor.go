@@ -23,7 +23,11 @@ func (a *Adaptor) DefaultBaseURL() string {
 }
 
 func (a *Adaptor) SupportMode(m mode.Mode) bool {
-	return m == mode.ChatCompletions || m == mode.Anthropic || m == mode.Gemini
+	return m == mode.ChatCompletions ||
+		m == mode.Anthropic ||
+		m == mode.Gemini ||
+		m == mode.AnthropicCountTokens ||
+		m == mode.GeminiCountTokens
 }
 
 type Config struct {
@@ -89,13 +93,23 @@ func (a *Adaptor) GetRequestURL(
 		isStream = meta.GetBool("stream")
 	}
 
-	if strings.HasPrefix(meta.ActualModel, "gemini") {
+	// the claude count tokens endpoint is shared by the models, the model is
+	// set in the body
+	modelPath := meta.ActualModel
+
+	switch {
+	case meta.Mode == mode.GeminiCountTokens:
+		suffix = "countTokens"
+	case meta.Mode == mode.AnthropicCountTokens:
+		modelPath = "count-tokens"
+		suffix = "rawPredict"
+	case strings.HasPrefix(meta.ActualModel, "gemini"):
 		if isStream {
 			suffix = "streamGenerateContent?alt=sse"
 		} else {
 			suffix = "generateContent"
 		}
-	} else {
+	default:
 		if isStream {
 			suffix = "streamRawPredict?alt=sse"
 		} else {
@@ -121,7 +135,7 @@ func (a *Adaptor) GetRequestURL(
 					"%s/v1/publishers/%s/models/%s:%s",
 					meta.Channel.BaseURL,
 					publishers,
-					meta.ActualModel,
+					modelPath,
 					suffix,
 				),
 			}, nil
@@ -135,7 +149,7 @@ func (a *Adaptor) GetRequestURL(
 				config.ProjectID,
 				config.Region,
 				publishers,
-				meta.ActualModel,
+				modelPath,
 				suffix,
 			),
 		}, nil
@@ -155,7 +169,7 @@ func (a *Adaptor) GetRequestURL(
 				"https://%s/v1/publishers/%s/models/%s:%s",
 				requestDoamin,
 				publishers,
-				meta.ActualModel,
+				modelPath,
 				suffix,
 			),
 		}, nil
@@ -169,7 +183,7 @@ func (a *Adaptor) GetRequestURL(
 			config.ProjectID,
 			config.Region,
 			publishers,
-			meta.ActualModel,
+			modelPath,
 			suffix,
 		),
 	}, nil
@@ -45,6 +45,11 @@ func (a *Adaptor) ConvertRequest(
 		data, err = handleAnthropicRequest(meta, request)
 	case mode.Gemini:
 		data, err = handleGeminiRequest(meta, request)
+	case mode.AnthropicCountTokens:
+		data, err = anthropic.ConvertCountTokensRequestToBytes(meta, request, func(node *ast.Node) error {
+			anthropic.RemoveToolsExamples(node)
+			return nil
+		})
 	default:
 		return adaptor.ConvertResult{}, fmt.Errorf("unsupported mode: %s", meta.Mode)
 	}
@@ -171,6 +176,8 @@ func (a *Adaptor) DoResponse(
 		} else {
 			usage, err = anthropic.GeminiHandler(meta, c, resp)
 		}
+	case mode.AnthropicCountTokens:
+		usage, err = anthropic.CountTokensHandler(meta, c, resp)
 	default:
 		return model.Usage{}, relaymodel.WrapperOpenAIErrorWithMessage(
 			fmt.Sprintf("unsupported mode: %s", meta.Mode),
@@ -1,6 +1,7 @@
 package vertexai
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -24,6 +25,10 @@ func (a *Adaptor) ConvertRequest(
 		return gemini.ConvertClaudeRequest(meta, request)
 	case mode.Gemini:
 		return gemini.NativeConvertRequest(meta, request, gemini.CleanFunctionResponseID)
+	case mode.GeminiCountTokens:
+		return gemini.ConvertVertexCountTokensRequest(meta, request)
+	case mode.AnthropicCountTokens:
+		return adaptor.ConvertResult{}, fmt.Errorf("unsupported mode: %s", meta.Mode)
 	default:
 		return gemini.ConvertRequest(meta, request)
 	}
@@ -58,6 +63,8 @@ func (a *Adaptor) DoResponse(
 		} else {
 			usage, err = gemini.NativeHandler(meta, c, resp)
 		}
+	case mode.GeminiCountTokens:
+		usage, err = gemini.CountTokensHandler(meta, c, resp)
 	default:
 		if utils.IsStreamResponse(resp) {
 			usage, err = gemini.StreamHandler(meta, c, resp)
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/model"
+	relaymodel "github.com/wavespeed/llm-server/core/relay/model"
+)
+
+type geminiCountTokensRequest struct {
+	relaymodel.GeminiChatRequest
+	GenerateContentRequest *relaymodel.GeminiChatRequest `json:"generateContentRequest,omitempty"`
+}
+
+// GetGeminiCountTokensRequestUsage counts the contents of a countTokens
+// request, either the contents or the wrapped generateContentRequest
+func GetGeminiCountTokensRequestUsage(
+	c *gin.Context,
+	mc model.ModelConfig,
+) (model.Usage, error) {
+	var req geminiCountTokensRequest
+
+	err := common.UnmarshalRequestReusable(c.Request, &req)
+	if err != nil {
+		return model.Usage{}, err
+	}
+
+	if req.GenerateContentRequest != nil {
+		return geminiRequestUsage(req.GenerateContentRequest, mc), nil
+	}
+
+	return geminiRequestUsage(&req.GeminiChatRequest, mc), nil
+}
+
+// AnthropicCountTokensLocal answers a count tokens request with the local
+// count when no channel can count it
+func AnthropicCountTokensLocal(c *gin.Context, usage model.Usage) {
+	c.JSON(http.StatusOK, gin.H{
+		"input_tokens": int64(usage.InputTokens),
+	})
+}
+
+// GeminiCountTokensLocal answers a countTokens request with the local count
+// when no channel can count it
+func GeminiCountTokensLocal(c *gin.Context, usage model.Usage) {
+	c.JSON(http.StatusOK, gin.H{
+		"totalTokens": int64(usage.InputTokens),
+	})
+}
@@ -182,6 +182,12 @@ func prepareAndDoRequest(
 
 	convertResult, err := a.ConvertRequest(meta, store, c.Request)
 	if err != nil {
+		// plugins may reject the request with their own error
+		var relayErr adaptor.Error
+		if errors.As(err, &relayErr) {
+			return nil, relayErr
+		}
+
 		return nil, relaymodel.WrapperErrorWithMessage(
 			meta.Mode,
 			http.StatusBadRequest,
@@ -393,6 +399,10 @@ func updateUsageMetrics(usage model.Usage, log *log.Entry) {
 		log.Data["t_image_output"] = usage.ImageOutputTokens
 	}
 
+	if usage.AudioOutputTokens > 0 {
+		log.Data["t_audio_output"] = usage.AudioOutputTokens
+	}
+
 	if usage.TotalTokens > 0 {
 		log.Data["t_total"] = usage.TotalTokens
 	}
@@ -412,4 +422,8 @@ func updateUsageMetrics(usage model.Usage, log *log.Entry) {
 	if usage.WebSearchCount > 0 {
 		log.Data["t_websearch"] = usage.WebSearchCount
 	}
+
+	if usage.TrainedTokens > 0 {
+		log.Data["t_trained"] = usage.TrainedTokens
+	}
 }
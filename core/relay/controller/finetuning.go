@@ -0,0 +1,15 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+// GetFineTuningRequestPrice only keeps the training price, fine-tuning jobs
+// are billed by trained tokens when the job succeeds
+func GetFineTuningRequestPrice(_ *gin.Context, mc model.ModelConfig) (model.Price, error) {
+	return model.Price{
+		TrainingPrice:     mc.Price.TrainingPrice,
+		TrainingPriceUnit: mc.Price.TrainingPriceUnit,
+	}, nil
+}
@@ -19,6 +19,10 @@ func GetGeminiRequestUsage(c *gin.Context, mc model.ModelConfig) (model.Usage, e
 		return model.Usage{}, err
 	}
 
+	return geminiRequestUsage(&geminiReq, mc), nil
+}
+
+func geminiRequestUsage(geminiReq *relaymodel.GeminiChatRequest, mc model.ModelConfig) model.Usage {
 	// Count tokens from all content parts
 	totalTokens := int64(0)
 	imageCount := int64(0)
@@ -69,7 +73,7 @@ func GetGeminiRequestUsage(c *gin.Context, mc model.ModelConfig) (model.Usage, e
 	return model.Usage{
 		InputTokens:      model.ZeroNullInt64(totalTokens + imageInputTokens),
 		ImageInputTokens: model.ZeroNullInt64(imageInputTokens),
-	}, nil
+	}
 }
 
 // countTokensForText provides a rough estimate of token count
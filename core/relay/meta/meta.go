@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -15,10 +16,16 @@ type ChannelMeta struct {
 	ID           int
 	Type         model.ChannelType
 	ModelMapping map[string]string
+	Currency     string
+	CostPrices   map[string]model.Price
+	CostRatio    float64
 }
 
 type Meta struct {
-	values         map[string]any
+	values map[string]any
+	// traceCtx holds the current span of the relay, plugins start their
+	// spans from it
+	traceCtx       context.Context
 	Channel        ChannelMeta
 	ChannelConfigs model.ChannelConfigs
 	Group          model.GroupCache
@@ -40,6 +47,7 @@ type Meta struct {
 	JobID        string
 	GenerationID string
 	ResponseID   string
+	FileID       string
 }
 
 type Option func(meta *Meta)
@@ -104,6 +112,12 @@ func WithResponseID(responseID string) Option {
 	}
 }
 
+func WithFileID(fileID string) Option {
+	return func(meta *Meta) {
+		meta.FileID = fileID
+	}
+}
+
 func NewMeta(
 	channel *model.Channel,
 	mode mode.Mode,
@@ -142,6 +156,9 @@ func (m *Meta) SetChannel(channel *model.Channel) {
 	m.Channel.Type = channel.Type
 
 	m.Channel.ModelMapping = channel.ModelMapping
+	m.Channel.Currency = channel.Currency
+	m.Channel.CostPrices = channel.CostPrices
+	m.Channel.CostRatio = channel.CostRatio
 	m.ChannelConfigs = channel.Configs
 
 	m.ActualModel, _ = GetMappedModelName(m.OriginModel, channel.ModelMapping)
@@ -153,6 +170,19 @@ func (m *Meta) CopyChannelFromMeta(meta *Meta) {
 	m.ActualModel, _ = GetMappedModelName(meta.OriginModel, meta.Channel.ModelMapping)
 }
 
+// TraceContext returns the context of the current span of the relay
+func (m *Meta) TraceContext() context.Context {
+	if m.traceCtx == nil {
+		return context.Background()
+	}
+
+	return m.traceCtx
+}
+
+func (m *Meta) SetTraceContext(ctx context.Context) {
+	m.traceCtx = ctx
+}
+
 func (m *Meta) ClearValues() {
 	clear(m.values)
 }
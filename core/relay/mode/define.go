@@ -50,6 +50,36 @@ func (m Mode) String() string {
 		return "ResponsesInputItems"
 	case Gemini:
 		return "Gemini"
+	case Files:
+		return "Files"
+	case FilesList:
+		return "FilesList"
+	case FilesGet:
+		return "FilesGet"
+	case FilesDelete:
+		return "FilesDelete"
+	case FilesContent:
+		return "FilesContent"
+	case Batch:
+		return "Batch"
+	case FineTuningJobs:
+		return "FineTuningJobs"
+	case FineTuningJobsList:
+		return "FineTuningJobsList"
+	case FineTuningJobsGet:
+		return "FineTuningJobsGet"
+	case FineTuningJobsCancel:
+		return "FineTuningJobsCancel"
+	case FineTuningJobsEvents:
+		return "FineTuningJobsEvents"
+	case FineTuningJobsCheckpoints:
+		return "FineTuningJobsCheckpoints"
+	case AnthropicCountTokens:
+		return "AnthropicCountTokens"
+	case GeminiCountTokens:
+		return "GeminiCountTokens"
+	case Realtime:
+		return "Realtime"
 	default:
 		return fmt.Sprintf("Mode(%d)", m)
 	}
@@ -78,4 +108,19 @@ const (
 	ResponsesCancel
 	ResponsesInputItems
 	Gemini
+	Files
+	FilesList
+	FilesGet
+	FilesDelete
+	FilesContent
+	Batch
+	FineTuningJobs
+	FineTuningJobsList
+	FineTuningJobsGet
+	FineTuningJobsCancel
+	FineTuningJobsEvents
+	FineTuningJobsCheckpoints
+	AnthropicCountTokens
+	GeminiCountTokens
+	Realtime
 )
@@ -0,0 +1,52 @@
+package model
+
+// FilePurpose is the intended purpose of an uploaded file
+type FilePurpose = string
+
+const (
+	FilePurposeAssistants FilePurpose = "assistants"
+	FilePurposeBatch      FilePurpose = "batch"
+	FilePurposeBatchOut   FilePurpose = "batch_output"
+	FilePurposeFineTune   FilePurpose = "fine-tune"
+	FilePurposeVision     FilePurpose = "vision"
+	FilePurposeUserData   FilePurpose = "user_data"
+	FilePurposeEvals      FilePurpose = "evals"
+)
+
+func IsValidUploadFilePurpose(purpose string) bool {
+	switch purpose {
+	case FilePurposeAssistants,
+		FilePurposeBatch,
+		FilePurposeFineTune,
+		FilePurposeVision,
+		FilePurposeUserData,
+		FilePurposeEvals:
+		return true
+	default:
+		return false
+	}
+}
+
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status,omitempty"`
+}
+
+type FileList struct {
+	Object  string `json:"object"`
+	Data    []File `json:"data"`
+	FirstID string `json:"first_id,omitempty"`
+	LastID  string `json:"last_id,omitempty"`
+	HasMore bool   `json:"has_more"`
+}
+
+type FileDeleted struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
@@ -0,0 +1,42 @@
+package model
+
+// https://platform.openai.com/docs/api-reference/fine-tuning
+
+const (
+	FineTuningJobStatusSucceeded = "succeeded"
+	FineTuningJobStatusFailed    = "failed"
+	FineTuningJobStatusCancelled = "cancelled"
+)
+
+type CreateFineTuningJobRequest struct {
+	Model           string            `json:"model"`
+	TrainingFile    string            `json:"training_file"`
+	ValidationFile  string            `json:"validation_file,omitempty"`
+	Suffix          string            `json:"suffix,omitempty"`
+	Seed            *int64            `json:"seed,omitempty"`
+	Method          map[string]any    `json:"method,omitempty"`
+	Hyperparameters map[string]any    `json:"hyperparameters,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+type FineTuningJob struct {
+	ID             string   `json:"id"`
+	Object         string   `json:"object"`
+	CreatedAt      int64    `json:"created_at"`
+	FinishedAt     *int64   `json:"finished_at"`
+	Model          string   `json:"model"`
+	FineTunedModel *string  `json:"fine_tuned_model"`
+	OrganizationID string   `json:"organization_id,omitempty"`
+	Status         string   `json:"status"`
+	TrainingFile   string   `json:"training_file"`
+	ValidationFile *string  `json:"validation_file"`
+	ResultFiles    []string `json:"result_files"`
+	TrainedTokens  *int64   `json:"trained_tokens"`
+	Seed           int64    `json:"seed,omitempty"`
+}
+
+type FineTuningJobList struct {
+	Object  string          `json:"object"`
+	Data    []FineTuningJob `json:"data"`
+	HasMore bool            `json:"has_more"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import "github.com/wavespeed/llm-server/core/model"
+
+const RealtimeEventTypeResponseDone = "response.done"
+
+// RealtimeEvent is the part of a realtime server event the gateway reads,
+// the other fields are passed through
+type RealtimeEvent struct {
+	Type     string            `json:"type"`
+	Response *RealtimeResponse `json:"response,omitempty"`
+}
+
+type RealtimeResponse struct {
+	ID     string         `json:"id,omitempty"`
+	Status string         `json:"status,omitempty"`
+	Usage  *RealtimeUsage `json:"usage,omitempty"`
+}
+
+type RealtimeUsage struct {
+	TotalTokens        int64                       `json:"total_tokens"`
+	InputTokens        int64                       `json:"input_tokens"`
+	OutputTokens       int64                       `json:"output_tokens"`
+	InputTokenDetails  *RealtimeInputTokenDetails  `json:"input_token_details,omitempty"`
+	OutputTokenDetails *RealtimeOutputTokenDetails `json:"output_token_details,omitempty"`
+}
+
+type RealtimeInputTokenDetails struct {
+	CachedTokens int64 `json:"cached_tokens"`
+	TextTokens   int64 `json:"text_tokens"`
+	AudioTokens  int64 `json:"audio_tokens"`
+}
+
+type RealtimeOutputTokenDetails struct {
+	TextTokens  int64 `json:"text_tokens"`
+	AudioTokens int64 `json:"audio_tokens"`
+}
+
+func (u *RealtimeUsage) ToModelUsage() model.Usage {
+	usage := model.Usage{
+		InputTokens:  model.ZeroNullInt64(u.InputTokens),
+		OutputTokens: model.ZeroNullInt64(u.OutputTokens),
+		TotalTokens:  model.ZeroNullInt64(u.TotalTokens),
+	}
+
+	if u.InputTokenDetails != nil {
+		usage.AudioInputTokens = model.ZeroNullInt64(u.InputTokenDetails.AudioTokens)
+		usage.CachedTokens = model.ZeroNullInt64(u.InputTokenDetails.CachedTokens)
+	}
+
+	if u.OutputTokenDetails != nil {
+		usage.AudioOutputTokens = model.ZeroNullInt64(u.OutputTokenDetails.AudioTokens)
+	}
+
+	return usage
+}
@@ -55,7 +55,8 @@ type Item struct {
 // Cache implements caching functionality for AI requests
 type Cache struct {
 	noop.Noop
-	rdb *redis.Client
+	rdb   *redis.Client
+	embed Embed
 }
 
 var (
@@ -70,9 +71,10 @@ var (
 	}
 )
 
-// NewCachePlugin creates a new cache plugin
-func NewCachePlugin(rdb *redis.Client) plugin.Plugin {
-	return &Cache{rdb: rdb}
+// NewCachePlugin creates a new cache plugin, embed is used by the semantic
+// mode and may be nil if it is not needed
+func NewCachePlugin(rdb *redis.Client, embed Embed) plugin.Plugin {
+	return &Cache{rdb: rdb, embed: embed}
 }
 
 // Cache metadata helpers
@@ -258,6 +260,18 @@ func (c *Cache) ConvertRequest(
 		return adaptor.ConvertResult{}, nil
 	}
 
+	if pluginConfig.Semantic.Enable {
+		item, ok, err := c.semanticLookup(ctx, meta, store, pluginConfig, body)
+		if err != nil {
+			// the semantic mode is best effort, fall back to the exact cache
+			log := common.GetLoggerFromReq(req)
+			log.Warnf("semantic cache lookup failed: %v", err)
+		} else if ok {
+			setCacheHit(meta, item)
+			return adaptor.ConvertResult{}, nil
+		}
+	}
+
 	return do.ConvertRequest(meta, store, req)
 }
 
@@ -357,7 +371,12 @@ func (c *Cache) DoResponse(
 		// Override specific headers
 		ctx.Header("Content-Type", item.Header["Content-Type"][0])
 		ctx.Header("Content-Length", strconv.Itoa(len(item.Body)))
-		c.writeCacheHeader(ctx, pluginConfig, "hit")
+		if similarity, ok := getSemanticSimilarity(meta); ok {
+			c.writeCacheHeader(ctx, pluginConfig, fmt.Sprintf("hit; similarity=%.4f", similarity))
+		} else {
+			c.writeCacheHeader(ctx, pluginConfig, "hit")
+		}
+
 		_, _ = ctx.Writer.Write(item.Body)
 
 		return item.Usage, nil
@@ -400,6 +419,10 @@ func (c *Cache) DoResponse(
 
 		ttl := time.Duration(pluginConfig.TTL) * time.Second
 		c.setToCache(ctx.Request.Context(), getCacheKey(meta), item, ttl)
+
+		if query := getSemanticQuery(meta); query != nil {
+			c.addSemantic(ctx.Request.Context(), pluginConfig, query, getCacheKey(meta))
+		}
 	}()
 
 	return do.DoResponse(meta, store, ctx, resp)
@@ -1,9 +1,19 @@
 package cache
 
 type Config struct {
-	Enable            bool   `json:"enable"`
-	TTL               int    `json:"ttl"`
-	ItemMaxSize       int    `json:"item_max_size"`
-	AddCacheHitHeader bool   `json:"add_cache_hit_header"`
-	CacheHitHeader    string `json:"cache_hit_header"`
+	Enable            bool           `json:"enable"`
+	TTL               int            `json:"ttl"`
+	ItemMaxSize       int            `json:"item_max_size"`
+	AddCacheHitHeader bool           `json:"add_cache_hit_header"`
+	CacheHitHeader    string         `json:"cache_hit_header"`
+	Semantic          SemanticConfig `json:"semantic"`
+}
+
+// SemanticConfig serves cached responses of similar requests, the last user
+// message is embedded and matched against the stored vectors
+type SemanticConfig struct {
+	Enable         bool    `json:"enable"`
+	EmbeddingModel string  `json:"embedding_model"`
+	Threshold      float64 `json:"threshold"`
+	MaxEntries     int     `json:"max_entries"`
 }
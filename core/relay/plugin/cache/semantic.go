@@ -0,0 +1,578 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	gcache "github.com/patrickmn/go-cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// Embed returns the embedding of the input, it is called through the
+// channels of the gateway and billed to the group of the meta
+type Embed func(meta *meta.Meta, store adaptor.Store, modelName, input string) ([]float64, error)
+
+const (
+	semanticQueryKey      = "cache_semantic_query"
+	semanticSimilarityKey = "cache_semantic_similarity"
+	// the sorted set of the cache keys of a namespace scored by the expiry
+	redisSemanticPrefix = "cache:semantic:"
+	// the vector of a cache key of a namespace
+	redisSemanticVectorPrefix = "cache:semantic_vector:"
+)
+
+const (
+	defaultSemanticThreshold  = 0.95
+	defaultSemanticMaxEntries = 256
+	// used when the ttl of the plugin is not set, same as the memory cache
+	defaultSemanticTTL = 30 * time.Second
+)
+
+// semanticIndexes holds a vector index for each namespace, the namespace
+// expires after the last entry added to it
+var semanticIndexes = gcache.New(defaultSemanticTTL, 5*time.Minute)
+
+// semanticQuery is the embedded request, it is kept in the meta until the
+// response is stored in the cache
+type semanticQuery struct {
+	Namespace string
+	Vector    []float32
+}
+
+type semanticEntry struct {
+	Key       string
+	Vector    []float32
+	ExpiresAt time.Time
+}
+
+func getSemanticQuery(meta *meta.Meta) *semanticQuery {
+	v, ok := meta.Get(semanticQueryKey)
+	if !ok {
+		return nil
+	}
+
+	query, ok := v.(*semanticQuery)
+	if !ok {
+		panic(fmt.Sprintf("semantic query type not match: %T", v))
+	}
+
+	return query
+}
+
+func setSemanticQuery(meta *meta.Meta, query *semanticQuery) {
+	meta.Set(semanticQueryKey, query)
+}
+
+func getSemanticSimilarity(meta *meta.Meta) (float64, bool) {
+	v, ok := meta.Get(semanticSimilarityKey)
+	if !ok {
+		return 0, false
+	}
+
+	similarity, ok := v.(float64)
+	if !ok {
+		panic(fmt.Sprintf("semantic similarity type not match: %T", v))
+	}
+
+	return similarity, true
+}
+
+func setSemanticSimilarity(meta *meta.Meta, similarity float64) {
+	meta.Set(semanticSimilarityKey, similarity)
+}
+
+func semanticThreshold(config SemanticConfig) float64 {
+	if config.Threshold <= 0 {
+		return defaultSemanticThreshold
+	}
+
+	return config.Threshold
+}
+
+func semanticMaxEntries(config SemanticConfig) int {
+	if config.MaxEntries <= 0 {
+		return defaultSemanticMaxEntries
+	}
+
+	return config.MaxEntries
+}
+
+func semanticTTL(config *Config) time.Duration {
+	if config.TTL <= 0 {
+		return defaultSemanticTTL
+	}
+
+	return time.Duration(config.TTL) * time.Second
+}
+
+// messageText returns the text of a chat or anthropic message content
+func messageText(content any) string {
+	switch content := content.(type) {
+	case string:
+		return content
+	case []any:
+		texts := make([]string, 0, len(content))
+		for _, part := range content {
+			p, ok := part.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			if t, _ := p["type"].(string); t != "text" {
+				continue
+			}
+
+			if text, ok := p["text"].(string); ok {
+				texts = append(texts, text)
+			}
+		}
+
+		return strings.Join(texts, "\n")
+	default:
+		return ""
+	}
+}
+
+// parseSemanticRequest returns the text of the last user message and the
+// namespace of the request, the namespace hashes everything else of the body
+// so only requests that differ in the last user message are matched
+func parseSemanticRequest(m mode.Mode, body []byte) (namespace, text string, err error) {
+	switch m {
+	case mode.ChatCompletions, mode.Anthropic:
+	default:
+		return "", "", nil
+	}
+
+	var request map[string]any
+	if err := sonic.Unmarshal(body, &request); err != nil {
+		return "", "", err
+	}
+
+	messages, _ := request["messages"].([]any)
+	for i := len(messages) - 1; i >= 0; i-- {
+		message, ok := messages[i].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if role, _ := message["role"].(string); role != "user" {
+			continue
+		}
+
+		text = strings.TrimSpace(messageText(message["content"]))
+		if text == "" {
+			return "", "", nil
+		}
+
+		message["content"] = ""
+
+		break
+	}
+
+	if text == "" {
+		return "", "", nil
+	}
+
+	// the std config sorts the map keys, so the namespace is stable
+	rest, err := sonic.ConfigStd.Marshal(request)
+	if err != nil {
+		return "", "", err
+	}
+
+	hash := sha256.Sum256(rest)
+
+	return fmt.Sprintf("%d:%s", m, hex.EncodeToString(hash[:])), text, nil
+}
+
+// normalizeVector returns the unit vector, so the cosine similarity is the
+// dot product of two vectors
+func normalizeVector(v []float64) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+
+	if norm == 0 {
+		return nil
+	}
+
+	norm = math.Sqrt(norm)
+
+	normalized := make([]float32, len(v))
+	for i, x := range v {
+		normalized[i] = float32(x / norm)
+	}
+
+	return normalized
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+
+	return dot
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, len(v)*4)
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[i*4:], math.Float32bits(x))
+	}
+
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+
+	return v
+}
+
+// searchEntries returns the most similar entry which is not expired
+func searchEntries(
+	entries []semanticEntry,
+	vector []float32,
+	now time.Time,
+) (key string, similarity float64, ok bool) {
+	for _, entry := range entries {
+		if !entry.ExpiresAt.After(now) {
+			continue
+		}
+
+		s := cosineSimilarity(vector, entry.Vector)
+		if !ok || s > similarity {
+			key = entry.Key
+			similarity = s
+			ok = true
+		}
+	}
+
+	return key, similarity, ok
+}
+
+// semanticIndex is an in-process vector index, the search is a linear scan
+// which is fine for the bounded number of entries
+type semanticIndex struct {
+	mu      sync.RWMutex
+	entries []semanticEntry
+}
+
+var semanticIndexesLock sync.Mutex
+
+func getSemanticIndex(namespace string, create bool) *semanticIndex {
+	if v, ok := semanticIndexes.Get(namespace); ok {
+		index, ok := v.(*semanticIndex)
+		if !ok {
+			panic(fmt.Sprintf("semantic index type not match: %T", v))
+		}
+
+		return index
+	}
+
+	if !create {
+		return nil
+	}
+
+	semanticIndexesLock.Lock()
+	defer semanticIndexesLock.Unlock()
+
+	if v, ok := semanticIndexes.Get(namespace); ok {
+		index, ok := v.(*semanticIndex)
+		if !ok {
+			panic(fmt.Sprintf("semantic index type not match: %T", v))
+		}
+
+		return index
+	}
+
+	index := &semanticIndex{}
+	semanticIndexes.SetDefault(namespace, index)
+
+	return index
+}
+
+func (i *semanticIndex) search(vector []float32, now time.Time) (string, float64, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return searchEntries(i.entries, vector, now)
+}
+
+func (i *semanticIndex) add(entry semanticEntry, maxEntries int, now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.entries = slices.DeleteFunc(i.entries, func(e semanticEntry) bool {
+		return e.Key == entry.Key || !e.ExpiresAt.After(now)
+	})
+
+	i.entries = append(i.entries, entry)
+	if len(i.entries) > maxEntries {
+		// entries share the same ttl, the oldest ones are at the front
+		i.entries = slices.Delete(i.entries, 0, len(i.entries)-maxEntries)
+	}
+}
+
+// vectors returns the vectors of the keys which are in the index
+func (i *semanticIndex) vectors(keys []string) map[string][]float32 {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	vectors := make(map[string][]float32, len(keys))
+
+	for _, entry := range i.entries {
+		if slices.Contains(keys, entry.Key) {
+			vectors[entry.Key] = entry.Vector
+		}
+	}
+
+	return vectors
+}
+
+func redisSemanticVectorKey(namespace, key string) string {
+	return common.RedisKey(redisSemanticVectorPrefix, namespace, key)
+}
+
+// searchRedisSemantic reads the unexpired keys of the namespace, at most the
+// max entries, and only the vectors missing from the in-process index, so
+// the reads are bounded and the shared keys keep the instances in sync
+func (c *Cache) searchRedisSemantic(
+	ctx context.Context,
+	query *semanticQuery,
+	maxEntries int,
+	now time.Time,
+) (string, float64, bool, error) {
+	redisKey := common.RedisKey(redisSemanticPrefix, query.Namespace)
+
+	members, err := c.rdb.ZRevRangeByScoreWithScores(ctx, redisKey, &redis.ZRangeBy{
+		Min:   "(" + strconv.FormatInt(now.Unix(), 10),
+		Max:   "+inf",
+		Count: int64(maxEntries),
+	}).Result()
+	if err != nil {
+		return "", 0, false, err
+	}
+
+	if len(members) == 0 {
+		return "", 0, false, nil
+	}
+
+	keys := make([]string, 0, len(members))
+	for _, member := range members {
+		key, _ := member.Member.(string)
+		keys = append(keys, key)
+	}
+
+	index := getSemanticIndex(query.Namespace, true)
+	vectors := index.vectors(keys)
+
+	var missing []string
+
+	for _, key := range keys {
+		if _, ok := vectors[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) > 0 {
+		vectorKeys := make([]string, 0, len(missing))
+		for _, key := range missing {
+			vectorKeys = append(vectorKeys, redisSemanticVectorKey(query.Namespace, key))
+		}
+
+		values, err := c.rdb.MGet(ctx, vectorKeys...).Result()
+		if err != nil {
+			return "", 0, false, err
+		}
+
+		for i, value := range values {
+			if v, ok := value.(string); ok {
+				vectors[missing[i]] = decodeVector([]byte(v))
+			}
+		}
+	}
+
+	entries := make([]semanticEntry, 0, len(members))
+
+	for i, member := range members {
+		vector, ok := vectors[keys[i]]
+		if !ok {
+			continue
+		}
+
+		entry := semanticEntry{
+			Key:       keys[i],
+			Vector:    vector,
+			ExpiresAt: time.Unix(int64(member.Score), 0),
+		}
+		entries = append(entries, entry)
+
+		if slices.Contains(missing, entry.Key) {
+			index.add(entry, maxEntries, now)
+		}
+	}
+
+	key, similarity, ok := searchEntries(entries, query.Vector, now)
+
+	return key, similarity, ok, nil
+}
+
+// addRedisSemantic adds the key to the sorted set of the namespace and trims
+// the expired and the oldest keys beyond the max entries in the same
+// transaction, the vector is stored under its own key with the ttl
+func (c *Cache) addRedisSemantic(
+	ctx context.Context,
+	query *semanticQuery,
+	entry semanticEntry,
+	maxEntries int,
+	ttl time.Duration,
+	now time.Time,
+) error {
+	redisKey := common.RedisKey(redisSemanticPrefix, query.Namespace)
+
+	pipe := c.rdb.TxPipeline()
+	pipe.Set(ctx, redisSemanticVectorKey(query.Namespace, entry.Key), encodeVector(entry.Vector), ttl)
+	pipe.ZAdd(ctx, redisKey, redis.Z{
+		Score:  float64(entry.ExpiresAt.Unix()),
+		Member: entry.Key,
+	})
+	pipe.ZRemRangeByScore(ctx, redisKey, "-inf", strconv.FormatInt(now.Unix(), 10))
+	pipe.ZRemRangeByRank(ctx, redisKey, 0, int64(-maxEntries-1))
+	pipe.Expire(ctx, redisKey, ttl)
+
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// searchSemantic returns the most similar cached request, redis is searched
+// first if available and the in-process index is the fallback
+func (c *Cache) searchSemantic(
+	ctx context.Context,
+	config *Config,
+	query *semanticQuery,
+) (key string, similarity float64, ok bool) {
+	now := time.Now()
+
+	if c.rdb != nil {
+		maxEntries := semanticMaxEntries(config.Semantic)
+
+		key, similarity, ok, err := c.searchRedisSemantic(ctx, query, maxEntries, now)
+		if err == nil {
+			return key, similarity, ok
+		}
+		// If Redis fails, fallback to the in-process index
+	}
+
+	index := getSemanticIndex(query.Namespace, false)
+	if index == nil {
+		return "", 0, false
+	}
+
+	return index.search(query.Vector, now)
+}
+
+// addSemantic stores the vector of the request which is cached under the key
+func (c *Cache) addSemantic(
+	ctx context.Context,
+	config *Config,
+	query *semanticQuery,
+	key string,
+) {
+	ttl := semanticTTL(config)
+	maxEntries := semanticMaxEntries(config.Semantic)
+	now := time.Now()
+
+	entry := semanticEntry{
+		Key:       key,
+		Vector:    query.Vector,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	if c.rdb != nil {
+		_ = c.addRedisSemantic(ctx, query, entry, maxEntries, ttl, now)
+	}
+
+	index := getSemanticIndex(query.Namespace, true)
+	index.add(entry, maxEntries, now)
+	// keep the index until its last entry expires
+	semanticIndexes.Set(query.Namespace, index, ttl)
+}
+
+// semanticLookup embeds the last user message of the request and looks up the
+// most similar cached request, the query is kept in the meta on a miss
+func (c *Cache) semanticLookup(
+	ctx context.Context,
+	meta *meta.Meta,
+	store adaptor.Store,
+	config *Config,
+	body []byte,
+) (*Item, bool, error) {
+	if c.embed == nil {
+		return nil, false, errors.New("semantic cache embedding is not available")
+	}
+
+	if config.Semantic.EmbeddingModel == "" {
+		return nil, false, errors.New("semantic cache embedding model is not set")
+	}
+
+	namespace, text, err := parseSemanticRequest(meta.Mode, body)
+	if err != nil || text == "" {
+		return nil, false, err
+	}
+
+	embedding, err := c.embed(meta, store, config.Semantic.EmbeddingModel, text)
+	if err != nil {
+		return nil, false, err
+	}
+
+	vector := normalizeVector(embedding)
+	if len(vector) == 0 {
+		return nil, false, errors.New("semantic cache embedding is empty")
+	}
+
+	query := &semanticQuery{
+		// the cached responses of a group are never served to another one
+		Namespace: meta.Group.ID + ":" + namespace,
+		Vector:    vector,
+	}
+	setSemanticQuery(meta, query)
+
+	key, similarity, ok := c.searchSemantic(ctx, config, query)
+	if !ok || similarity < semanticThreshold(config.Semantic) {
+		return nil, false, nil
+	}
+
+	item, ok := c.getFromCache(ctx, key)
+	if !ok {
+		return nil, false, nil
+	}
+
+	setSemanticSimilarity(meta, similarity)
+
+	return item, true, nil
+}
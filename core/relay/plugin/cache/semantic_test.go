@@ -0,0 +1,194 @@
+package cache_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/model"
+	"github.com/wavespeed/llm-server/core/relay/adaptor"
+	"github.com/wavespeed/llm-server/core/relay/meta"
+	"github.com/wavespeed/llm-server/core/relay/mode"
+	"github.com/wavespeed/llm-server/core/relay/plugin"
+	"github.com/wavespeed/llm-server/core/relay/plugin/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type upstream struct {
+	converted int
+	body      string
+}
+
+func (u *upstream) ConvertRequest(
+	_ *meta.Meta,
+	_ adaptor.Store,
+	_ *http.Request,
+) (adaptor.ConvertResult, error) {
+	u.converted++
+	return adaptor.ConvertResult{}, nil
+}
+
+func (u *upstream) DoResponse(
+	_ *meta.Meta,
+	_ adaptor.Store,
+	c *gin.Context,
+	_ *http.Response,
+) (model.Usage, adaptor.Error) {
+	c.Header("Content-Type", "application/json")
+	_, _ = c.Writer.WriteString(u.body)
+
+	return model.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15}, nil
+}
+
+var embeddings = map[string][]float64{
+	"What is the capital of France?": {1, 0, 0},
+	"what's the capital of france":   {0.99, 0.05, 0},
+	"How tall is Mount Everest?":     {0, 1, 0},
+}
+
+func newSemanticCache(t *testing.T, embedded *[]string) plugin.Plugin {
+	t.Helper()
+
+	return cache.NewCachePlugin(nil,
+		func(_ *meta.Meta, _ adaptor.Store, modelName, input string) ([]float64, error) {
+			assert.Equal(t, "text-embedding-3-small", modelName)
+			*embedded = append(*embedded, input)
+
+			return embeddings[input], nil
+		},
+	)
+}
+
+func chatBody(t *testing.T, modelName, system, question string) []byte {
+	t.Helper()
+
+	body, err := sonic.Marshal(map[string]any{
+		"model": modelName,
+		"messages": []map[string]any{
+			{"role": "system", "content": system},
+			{"role": "user", "content": []map[string]any{{"type": "text", "text": question}}},
+		},
+	})
+	require.NoError(t, err)
+
+	return body
+}
+
+func doCached(
+	t *testing.T,
+	p plugin.Plugin,
+	u *upstream,
+	modelName string,
+	body []byte,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return doGroupCached(t, p, u, "semantic-group", modelName, body)
+}
+
+func doGroupCached(
+	t *testing.T,
+	p plugin.Plugin,
+	u *upstream,
+	group string,
+	modelName string,
+	body []byte,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	m := meta.NewMeta(nil, mode.ChatCompletions, modelName, model.ModelConfig{
+		Model: modelName,
+		Plugin: map[string]map[string]any{
+			"cache": {
+				"enable":               true,
+				"ttl":                  60,
+				"add_cache_hit_header": true,
+				"semantic": map[string]any{
+					"enable":          true,
+					"embedding_model": "text-embedding-3-small",
+					"threshold":       0.9,
+				},
+			},
+		},
+	}, meta.WithGroup(model.GroupCache{ID: group}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	common.SetRequestBody(req, body)
+
+	_, err := p.ConvertRequest(m, nil, req, u)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	usage, respErr := p.DoResponse(m, nil, c, &http.Response{}, u)
+	require.Nil(t, respErr)
+	assert.Equal(t, model.ZeroNullInt64(15), usage.TotalTokens)
+
+	return w
+}
+
+func TestSemanticCache(t *testing.T) {
+	var embedded []string
+
+	p := newSemanticCache(t, &embedded)
+	u := &upstream{body: `{"answer":"Paris"}`}
+	// the cache is global, keep the runs apart
+	modelName := "semantic-cache-test-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	firstBody := chatBody(t, modelName, "be brief", "What is the capital of France?")
+
+	w := doCached(t, p, u, modelName, firstBody)
+	assert.Equal(t, "miss", w.Header().Get("X-Aiproxy-Cache"))
+	assert.Equal(t, 1, u.converted)
+
+	w = doCached(t, p, u, modelName,
+		chatBody(t, modelName, "be brief", "what's the capital of france"))
+	assert.Regexp(t, `^hit; similarity=0\.99\d\d$`, w.Header().Get("X-Aiproxy-Cache"))
+	assert.Equal(t, `{"answer":"Paris"}`, w.Body.String())
+	assert.Equal(t, 1, u.converted)
+
+	w = doCached(t, p, u, modelName,
+		chatBody(t, modelName, "be brief", "How tall is Mount Everest?"))
+	assert.Equal(t, "miss", w.Header().Get("X-Aiproxy-Cache"))
+	assert.Equal(t, 2, u.converted)
+
+	// the rest of the request is different, the cached answer does not apply
+	w = doCached(t, p, u, modelName,
+		chatBody(t, modelName, "answer in french", "what's the capital of france"))
+	assert.Equal(t, "miss", w.Header().Get("X-Aiproxy-Cache"))
+	assert.Equal(t, 3, u.converted)
+
+	// an identical request is served by the exact cache without embedding
+	embeddedBefore := len(embedded)
+	w = doCached(t, p, u, modelName, firstBody)
+	assert.Equal(t, "hit", w.Header().Get("X-Aiproxy-Cache"))
+	assert.Len(t, embedded, embeddedBefore)
+	assert.Equal(t, 3, u.converted)
+}
+
+func TestSemanticCacheGroup(t *testing.T) {
+	var embedded []string
+
+	p := newSemanticCache(t, &embedded)
+	u := &upstream{body: `{"answer":"Paris"}`}
+	modelName := "semantic-cache-group-test-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	w := doGroupCached(t, p, u, "group-a", modelName,
+		chatBody(t, modelName, "be brief", "What is the capital of France?"))
+	assert.Equal(t, "miss", w.Header().Get("X-Aiproxy-Cache"))
+
+	// a similar request of another group is not served from the cache
+	w = doGroupCached(t, p, u, "group-b", modelName,
+		chatBody(t, modelName, "be brief", "what's the capital of france"))
+	assert.Equal(t, "miss", w.Header().Get("X-Aiproxy-Cache"))
+	assert.Equal(t, 2, u.converted)
+}
@@ -0,0 +1,71 @@
+package guardrail
+
+// Action is what the guardrail does when a rule matches
+type Action = string
+
+const (
+	// ActionBlock rejects the request, or the response when it is not streamed
+	ActionBlock Action = "block"
+	// ActionRedact replaces the matched text with the replacement
+	ActionRedact Action = "redact"
+	// ActionFlag only records the rule into the log metadata
+	ActionFlag Action = "flag"
+)
+
+// Stage is the direction a rule is applied to
+type Stage = string
+
+const (
+	StageInput  Stage = "input"
+	StageOutput Stage = "output"
+	// StageBoth is the default of the rules
+	StageBoth Stage = "both"
+)
+
+// RuleType is the detector of a rule
+type RuleType = string
+
+const (
+	RuleTypeEmail      RuleType = "email"
+	RuleTypePhone      RuleType = "phone"
+	RuleTypeIDCard     RuleType = "id_card"
+	RuleTypeCreditCard RuleType = "credit_card"
+	RuleTypeRegex      RuleType = "regex"
+	RuleTypeDictionary RuleType = "dictionary"
+)
+
+// Config represents the plugin configuration
+type Config struct {
+	Enable     bool             `json:"enable"`
+	Rules      []Rule           `json:"rules"`
+	Classifier ClassifierConfig `json:"classifier"`
+}
+
+// Rule is a detector and the action taken when it matches
+type Rule struct {
+	// Name is recorded into the log metadata, defaults to the type
+	Name string   `json:"name"`
+	Type RuleType `json:"type"`
+	// Pattern is the regular expression of the regex rules
+	Pattern string `json:"pattern"`
+	// Words are matched case-insensitively by the dictionary rules
+	Words       []string `json:"words"`
+	Action      Action   `json:"action"`
+	Stage       Stage    `json:"stage"`
+	Replacement string   `json:"replacement"`
+}
+
+// ClassifierConfig asks a model through the gateway whether the content is
+// unsafe, e.g. a prompt injection
+type ClassifierConfig struct {
+	Enable    bool   `json:"enable"`
+	ModelName string `json:"model_name"`
+	// Prompt is the system prompt of the classifier, the model must answer
+	// with the unsafe label when the content should be caught
+	Prompt      string `json:"prompt"`
+	UnsafeLabel string `json:"unsafe_label"`
+	// Action is block or flag, redact is not supported by the classifier
+	Action Action `json:"action"`
+	// Stage defaults to input, the output is only classified when not streamed
+	Stage Stage `json:"stage"`
+}
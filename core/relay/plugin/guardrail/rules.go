@@ -0,0 +1,374 @@
+package guardrail
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultReplacement = "[REDACTED]"
+	// streamTailSize is the length of the streamed text kept to detect the
+	// matches split across the stream chunks
+	streamTailSize = 256
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(
+		`(?:\+\d{1,3}[ \-]?)?(?:\(\d{3}\)|\b\d{3})[ \-]?\d{3,4}[ \-]?\d{4}\b`,
+	)
+	// mainland China resident identity card number
+	idCardPattern = regexp.MustCompile(
+		`\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`,
+	)
+	creditCardPattern = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+)
+
+// rule is a compiled Rule
+type rule struct {
+	name        string
+	action      Action
+	stage       Stage
+	replacement string
+	pattern     *regexp.Regexp
+	// validate filters the false positives of the pattern
+	validate func(match string) bool
+}
+
+func (r *rule) appliesTo(stage Stage) bool {
+	return r.stage == StageBoth || r.stage == stage
+}
+
+func (r *rule) valid(match string) bool {
+	return r.validate == nil || r.validate(match)
+}
+
+// match reports whether the text contains a valid match of the rule
+func (r *rule) match(text string) bool {
+	for _, m := range r.pattern.FindAllString(text, -1) {
+		if r.valid(m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *rule) redact(text string) string {
+	return r.pattern.ReplaceAllStringFunc(text, func(m string) string {
+		if !r.valid(m) {
+			return m
+		}
+
+		return r.replacement
+	})
+}
+
+func digits(s string) []int {
+	d := make([]int, 0, len(s))
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			d = append(d, int(c-'0'))
+		}
+	}
+
+	return d
+}
+
+// luhnValid validates the check digit of a credit card number
+func luhnValid(number string) bool {
+	d := digits(number)
+	if len(d) < 13 || len(d) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := range d {
+		v := d[len(d)-1-i]
+		if i%2 == 1 {
+			v *= 2
+			if v > 9 {
+				v -= 9
+			}
+		}
+
+		sum += v
+	}
+
+	return sum%10 == 0
+}
+
+var idCardWeights = [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+const idCardCheckCodes = "10X98765432"
+
+// idCardValid validates the check code of an identity card number
+func idCardValid(number string) bool {
+	if len(number) != 18 {
+		return false
+	}
+
+	sum := 0
+	for i, w := range idCardWeights {
+		sum += int(number[i]-'0') * w
+	}
+
+	return strings.EqualFold(string(idCardCheckCodes[sum%11]), number[17:])
+}
+
+func compileRule(r Rule) (*rule, error) {
+	compiled := &rule{
+		name:        r.Name,
+		action:      r.Action,
+		stage:       r.Stage,
+		replacement: r.Replacement,
+	}
+
+	if compiled.name == "" {
+		compiled.name = r.Type
+	}
+
+	switch compiled.action {
+	case ActionBlock, ActionRedact, ActionFlag:
+	case "":
+		compiled.action = ActionFlag
+	default:
+		return nil, fmt.Errorf("rule %s: unknown action: %s", compiled.name, r.Action)
+	}
+
+	switch compiled.stage {
+	case StageInput, StageOutput, StageBoth:
+	case "":
+		compiled.stage = StageBoth
+	default:
+		return nil, fmt.Errorf("rule %s: unknown stage: %s", compiled.name, r.Stage)
+	}
+
+	if compiled.replacement == "" {
+		compiled.replacement = defaultReplacement
+	}
+
+	switch r.Type {
+	case RuleTypeEmail:
+		compiled.pattern = emailPattern
+	case RuleTypePhone:
+		compiled.pattern = phonePattern
+	case RuleTypeIDCard:
+		compiled.pattern = idCardPattern
+		compiled.validate = idCardValid
+	case RuleTypeCreditCard:
+		compiled.pattern = creditCardPattern
+		compiled.validate = luhnValid
+	case RuleTypeRegex:
+		if r.Pattern == "" {
+			return nil, fmt.Errorf("rule %s: pattern is required", compiled.name)
+		}
+
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", compiled.name, err)
+		}
+
+		compiled.pattern = pattern
+	case RuleTypeDictionary:
+		words := make([]string, 0, len(r.Words))
+		for _, word := range r.Words {
+			if word == "" {
+				continue
+			}
+
+			words = append(words, regexp.QuoteMeta(word))
+		}
+
+		if len(words) == 0 {
+			return nil, fmt.Errorf("rule %s: words are required", compiled.name)
+		}
+
+		compiled.pattern = regexp.MustCompile(`(?i)` + strings.Join(words, "|"))
+	default:
+		return nil, fmt.Errorf("rule %s: unknown type: %s", compiled.name, r.Type)
+	}
+
+	return compiled, nil
+}
+
+func compileRules(rules []Rule) ([]*rule, error) {
+	compiled := make([]*rule, 0, len(rules))
+
+	var errs []error
+
+	for _, r := range rules {
+		c, err := compileRule(r)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		compiled = append(compiled, c)
+	}
+
+	return compiled, errors.Join(errs...)
+}
+
+// scanResult is what the rules found in a request or a response
+type scanResult struct {
+	// blocked is the first block rule which matched
+	blocked  *rule
+	findings []string
+	modified bool
+}
+
+func (s *scanResult) add(r *rule) {
+	finding := r.name + ":" + r.action
+	for _, f := range s.findings {
+		if f == finding {
+			return
+		}
+	}
+
+	s.findings = append(s.findings, finding)
+}
+
+// scanText applies the rules of the stage to the text, a block rule is
+// downgraded to redact when blocking is not possible
+func scanText(rules []*rule, stage Stage, text string, canBlock bool, result *scanResult) string {
+	for _, r := range rules {
+		if !r.appliesTo(stage) || !r.match(text) {
+			continue
+		}
+
+		result.add(r)
+
+		switch {
+		case r.action == ActionBlock && canBlock:
+			if result.blocked == nil {
+				result.blocked = r
+			}
+		case r.action == ActionBlock, r.action == ActionRedact:
+			text = r.redact(text)
+			result.modified = true
+		}
+	}
+
+	return text
+}
+
+// scanStreamText applies the rules of the stage to the delta of a stream
+// with the tail of the text streamed before, so the matches split across the
+// chunks are detected, only the part of a match in the delta can be redacted
+// as the rest is already sent, block rules redact as the stream can not be
+// taken back
+func scanStreamText(rules []*rule, stage Stage, tail, delta string, result *scanResult) string {
+	for _, r := range rules {
+		if !r.appliesTo(stage) {
+			continue
+		}
+
+		window := tail + delta
+
+		var (
+			redacted strings.Builder
+			matched  bool
+			// the end of the delta copied into redacted
+			end int
+		)
+
+		for _, loc := range r.pattern.FindAllStringIndex(window, -1) {
+			// the matches within the tail were scanned with the chunks before
+			if loc[1] <= len(tail) || !r.valid(window[loc[0]:loc[1]]) {
+				continue
+			}
+
+			matched = true
+
+			if r.action == ActionFlag {
+				continue
+			}
+
+			start := max(loc[0]-len(tail), 0)
+			redacted.WriteString(delta[end:start])
+			redacted.WriteString(r.replacement)
+			end = loc[1] - len(tail)
+		}
+
+		if !matched {
+			continue
+		}
+
+		result.add(r)
+
+		if r.action == ActionFlag {
+			continue
+		}
+
+		redacted.WriteString(delta[end:])
+		delta = redacted.String()
+		result.modified = true
+	}
+
+	return delta
+}
+
+// streamTail returns the end of the streamed text which is scanned with the
+// next delta, it is cut on a rune boundary
+func streamTail(text string) string {
+	if len(text) <= streamTailSize {
+		return text
+	}
+
+	text = text[len(text)-streamTailSize:]
+	for i := 0; i < len(text) && i < utf8.UTFMax; i++ {
+		if utf8.RuneStart(text[i]) {
+			return text[i:]
+		}
+	}
+
+	return text
+}
+
+// walkText calls fn for every string value under one of the keys, nested
+// objects and arrays are walked as well
+func walkText(v any, keys map[string]struct{}, fn func(string) string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if _, ok := keys[key]; ok {
+				switch value := value.(type) {
+				case string:
+					v[key] = fn(value)
+					continue
+				case []any:
+					for i, item := range value {
+						if s, ok := item.(string); ok {
+							value[i] = fn(s)
+						}
+					}
+				}
+			}
+
+			walkText(value, keys, fn)
+		}
+	case []any:
+		for _, item := range v {
+			walkText(item, keys, fn)
+		}
+	}
+}
+
+// collectText joins the strings walkText visits, it is what the classifier sees
+func collectText(v any, keys map[string]struct{}) string {
+	var texts []string
+
+	walkText(v, keys, func(s string) string {
+		if s != "" {
+			texts = append(texts, s)
+		}
+
+		return s
+	})
+
+	return strings.Join(texts, "\n")
+}
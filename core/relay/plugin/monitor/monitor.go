@@ -136,6 +136,7 @@ func handleDoRequestError(meta *meta.Meta, c *gin.Context, err error, requestCos
 			requestCost,
 			time.Minute*15,
 		)
+		emitChannelAutoDisabled(meta)
 	case beyondThreshold:
 		notifyChannelRequestIssue(
 			meta,
@@ -148,6 +149,21 @@ func handleDoRequestError(meta *meta.Meta, c *gin.Context, err error, requestCos
 	}
 }
 
+// emitChannelAutoDisabled tells the subscribed groups which can use the
+// model that the channel is banned for it until the monitor recovers it, the
+// channel name and the upstream error are internal
+func emitChannelAutoDisabled(meta *meta.Meta) {
+	model.EmitModelWebhookEvent(
+		meta.OriginModel,
+		model.WebhookEventChannelAutoDisabled,
+		map[string]any{
+			"channel_id":   meta.Channel.ID,
+			"channel_type": meta.Channel.Type.String(),
+			"model":        meta.OriginModel,
+		},
+	)
+}
+
 func notifyChannelRequestIssue(
 	meta *meta.Meta,
 	issueType, titleSuffix string,
@@ -257,6 +273,7 @@ func handleAdaptorError(meta *meta.Meta, c *gin.Context, relayErr adaptor.Error)
 	switch {
 	case banExecution:
 		notifyChannelResponseIssue(c, meta, "autoBanned", "Auto Banned", relayErr, time.Minute*15)
+		emitChannelAutoDisabled(meta)
 	case beyondThreshold:
 		notifyChannelResponseIssue(
 			c,
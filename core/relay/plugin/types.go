@@ -2,11 +2,15 @@ package plugin
 
 import (
 	"net/http"
+	"path"
+	"reflect"
 
 	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/common/tracing"
 	"github.com/wavespeed/llm-server/core/model"
 	"github.com/wavespeed/llm-server/core/relay/adaptor"
 	"github.com/wavespeed/llm-server/core/relay/meta"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // adaptor hook
@@ -60,17 +64,47 @@ func WrapperAdaptor(adaptor adaptor.Adaptor, plugins ...Plugin) adaptor.Adaptor
 		result = &wrappedAdaptor{
 			Adaptor: result,
 			plugin:  plugins[i],
+			name:    pluginName(plugins[i]),
 		}
 	}
 
 	return result
 }
 
+// pluginName names the plugin by its package and type, e.g. cache.Cache
+func pluginName(p Plugin) string {
+	t := reflect.TypeOf(p)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
 var _ adaptor.Adaptor = (*wrappedAdaptor)(nil)
 
 type wrappedAdaptor struct {
 	adaptor.Adaptor
 	plugin Plugin
+	name   string
+}
+
+// startSpan starts the span of a plugin hook, the spans of the inner plugins
+// and of the adaptor are its children
+func (w *wrappedAdaptor) startSpan(meta *meta.Meta, hook string) func(err error) {
+	if !tracing.Enabled() {
+		return func(error) {}
+	}
+
+	parent := meta.TraceContext()
+	ctx, span := tracing.Start(parent, "plugin "+w.name+" "+hook)
+	meta.SetTraceContext(ctx)
+
+	return func(err error) {
+		tracing.RecordError(span, err)
+		span.End()
+		meta.SetTraceContext(parent)
+	}
 }
 
 func (w *wrappedAdaptor) GetRequestURL(
@@ -78,7 +112,12 @@ func (w *wrappedAdaptor) GetRequestURL(
 	store adaptor.Store,
 	c *gin.Context,
 ) (adaptor.RequestURL, error) {
-	return w.plugin.GetRequestURL(meta, store, c, w.Adaptor)
+	end := w.startSpan(meta, "GetRequestURL")
+
+	u, err := w.plugin.GetRequestURL(meta, store, c, w.Adaptor)
+	end(err)
+
+	return u, err
 }
 
 func (w *wrappedAdaptor) SetupRequestHeader(
@@ -87,7 +126,12 @@ func (w *wrappedAdaptor) SetupRequestHeader(
 	c *gin.Context,
 	req *http.Request,
 ) error {
-	return w.plugin.SetupRequestHeader(meta, store, c, req, w.Adaptor)
+	end := w.startSpan(meta, "SetupRequestHeader")
+
+	err := w.plugin.SetupRequestHeader(meta, store, c, req, w.Adaptor)
+	end(err)
+
+	return err
 }
 
 func (w *wrappedAdaptor) ConvertRequest(
@@ -95,7 +139,12 @@ func (w *wrappedAdaptor) ConvertRequest(
 	store adaptor.Store,
 	req *http.Request,
 ) (adaptor.ConvertResult, error) {
-	return w.plugin.ConvertRequest(meta, store, req, w.Adaptor)
+	end := w.startSpan(meta, "ConvertRequest")
+
+	result, err := w.plugin.ConvertRequest(meta, store, req, w.Adaptor)
+	end(err)
+
+	return result, err
 }
 
 func (w *wrappedAdaptor) DoRequest(
@@ -104,7 +153,17 @@ func (w *wrappedAdaptor) DoRequest(
 	c *gin.Context,
 	req *http.Request,
 ) (*http.Response, error) {
-	return w.plugin.DoRequest(meta, store, c, req, w.Adaptor)
+	end := w.startSpan(meta, "DoRequest")
+	if tracing.Enabled() {
+		// the upstream call is traced from the context of the request
+		req = req.WithContext(trace.ContextWithSpan(req.Context(),
+			trace.SpanFromContext(meta.TraceContext())))
+	}
+
+	resp, err := w.plugin.DoRequest(meta, store, c, req, w.Adaptor)
+	end(err)
+
+	return resp, err
 }
 
 func (w *wrappedAdaptor) DoResponse(
@@ -113,5 +172,14 @@ func (w *wrappedAdaptor) DoResponse(
 	c *gin.Context,
 	resp *http.Response,
 ) (model.Usage, adaptor.Error) {
-	return w.plugin.DoResponse(meta, store, c, resp, w.Adaptor)
+	end := w.startSpan(meta, "DoResponse")
+
+	usage, relayErr := w.plugin.DoResponse(meta, store, c, resp, w.Adaptor)
+	if relayErr != nil {
+		end(relayErr)
+	} else {
+		end(nil)
+	}
+
+	return usage, relayErr
 }
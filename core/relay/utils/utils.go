@@ -15,8 +15,11 @@ import (
 	"github.com/bytedance/sonic"
 	"github.com/bytedance/sonic/ast"
 	"github.com/wavespeed/llm-server/core/common"
+	"github.com/wavespeed/llm-server/core/common/tracing"
 	model "github.com/wavespeed/llm-server/core/relay/model"
 	"github.com/patrickmn/go-cache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func UnmarshalGeneralThinking(req *http.Request) (model.GeneralOpenAIThinkingRequest, error) {
@@ -206,13 +209,28 @@ func DoRequest(req *http.Request, timeout time.Duration) (*http.Response, error)
 	// Record upstream request time and attach to context
 	upstreamRequestAt := time.Now()
 	ctx := context.WithValue(req.Context(), "upstreamRequestAt", upstreamRequestAt)
+
+	ctx, span := tracing.Start(ctx, "upstream "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer span.End()
+
 	req = req.WithContext(ctx)
+	tracing.Inject(ctx, req.Header)
 
 	resp, err := loadHTTPClient(timeout).Do(req)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
 	return resp, nil
 }
 
@@ -327,3 +345,8 @@ func NewScanner(r io.Reader) (*bufio.Scanner, func()) {
 func IsGeminiStreamRequest(path string) bool {
 	return strings.HasSuffix(path, ":streamGenerateContent")
 }
+
+// IsGeminiCountTokensRequest checks if the request path ends with :countTokens
+func IsGeminiCountTokensRequest(path string) bool {
+	return strings.HasSuffix(path, ":countTokens")
+}
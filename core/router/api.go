@@ -7,6 +7,7 @@ import (
 	"github.com/wavespeed/llm-server/core/controller"
 	mcp "github.com/wavespeed/llm-server/core/controller/mcp"
 	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
 )
 
 func SetAPIRouter(router *gin.Engine) {
@@ -25,9 +26,10 @@ func SetAPIRouter(router *gin.Engine) {
 	}
 
 	apiRouter := api.Group("")
-	apiRouter.Use(middleware.AdminAuth)
+	apiRouter.Use(middleware.AdminAuth, middleware.Audit)
 	{
 		modelsRoute := apiRouter.Group("/models")
+		modelsRoute.Use(middleware.RequirePermission(model.PermissionModelRead))
 		{
 			modelsRoute.GET("/builtin", controller.BuiltinModels)
 			modelsRoute.GET("/builtin/channel", controller.ChannelBuiltinModels)
@@ -37,9 +39,11 @@ func SetAPIRouter(router *gin.Engine) {
 			modelsRoute.GET("/sets", controller.EnabledModelSets)
 			modelsRoute.GET("/default", controller.ChannelDefaultModelsAndMapping)
 			modelsRoute.GET("/default/:type", controller.ChannelDefaultModelsAndMappingByType)
+			modelsRoute.GET("/tokenizers", controller.Tokenizers)
 		}
 
 		dashboardRoute := apiRouter.Group("/dashboard")
+		dashboardRoute.Use(middleware.RequirePermission(model.PermissionDashboardRead))
 		{
 			dashboardRoute.GET("/", controller.GetDashboard)
 			dashboardRoute.GET("/:group", controller.GetGroupDashboard)
@@ -47,257 +51,404 @@ func SetAPIRouter(router *gin.Engine) {
 		}
 
 		dashboardV2Route := apiRouter.Group("/dashboardv2")
+		dashboardV2Route.Use(middleware.RequirePermission(model.PermissionDashboardRead))
 		{
 			dashboardV2Route.GET("/", controller.GetTimeSeriesModelData)
 			dashboardV2Route.GET("/:group", controller.GetGroupTimeSeriesModelData)
 		}
 
-		// Groups - admin only
 		groupsRoute := apiRouter.Group("/groups")
-		groupsRoute.Use(middleware.RequireAdmin)
+		groupsRoute.Use(middleware.RequirePermission(model.PermissionGroupRead))
 		{
 			groupsRoute.GET("/", controller.GetGroups)
 			groupsRoute.GET("/search", controller.SearchGroups)
-			groupsRoute.POST("/batch_delete", controller.DeleteGroups)
-			groupsRoute.POST("/batch_status", controller.UpdateGroupsStatus)
 			groupsRoute.GET("/ip_groups", controller.GetIPGroupList)
+
+			groupsRouteWrite := groupsRoute.Group("")
+			groupsRouteWrite.Use(middleware.RequirePermission(model.PermissionGroupWrite))
+			{
+				groupsRouteWrite.POST("/batch_delete", controller.DeleteGroups)
+				groupsRouteWrite.POST("/batch_status", controller.UpdateGroupsStatus)
+			}
 		}
 
 		groupRoute := apiRouter.Group("/group")
-		groupRoute.Use(middleware.RequireAdmin)
+		groupRoute.Use(middleware.RequirePermission(model.PermissionGroupRead))
 		{
-			groupRoute.POST("/:group", controller.CreateGroup)
-			groupRoute.PUT("/:group", controller.UpdateGroup)
 			groupRoute.GET("/:group", controller.GetGroup)
-			groupRoute.DELETE("/:group", controller.DeleteGroup)
-			groupRoute.POST("/:group/status", controller.UpdateGroupStatus)
-			groupRoute.POST("/:group/rpm_ratio", controller.UpdateGroupRPMRatio)
-			groupRoute.POST("/:group/tpm_ratio", controller.UpdateGroupTPMRatio)
-
-			groupModelConfigsRoute := groupRoute.Group("/:group/model_configs")
-			{
-				groupModelConfigsRoute.GET("/", controller.GetGroupModelConfigs)
-				groupModelConfigsRoute.POST("/", controller.SaveGroupModelConfigs)
-				groupModelConfigsRoute.PUT("/", controller.UpdateGroupModelConfigs)
-				groupModelConfigsRoute.DELETE("/", controller.DeleteGroupModelConfigs)
-			}
+			groupRoute.GET("/:group/model_configs/", controller.GetGroupModelConfigs)
+			groupRoute.GET("/:group/model_config/*model", controller.GetGroupModelConfig)
 
-			groupModelConfigRoute := groupRoute.Group("/:group/model_config")
+			groupRouteWrite := groupRoute.Group("")
+			groupRouteWrite.Use(middleware.RequirePermission(model.PermissionGroupWrite))
 			{
-				groupModelConfigRoute.POST("/*model", controller.SaveGroupModelConfig)
-				groupModelConfigRoute.PUT("/*model", controller.UpdateGroupModelConfig)
-				groupModelConfigRoute.DELETE("/*model", controller.DeleteGroupModelConfig)
-				groupModelConfigRoute.GET("/*model", controller.GetGroupModelConfig)
+				groupRouteWrite.POST("/:group", controller.CreateGroup)
+				groupRouteWrite.PUT("/:group", controller.UpdateGroup)
+				groupRouteWrite.DELETE("/:group", controller.DeleteGroup)
+				groupRouteWrite.POST("/:group/status", controller.UpdateGroupStatus)
+				groupRouteWrite.POST("/:group/rpm_ratio", controller.UpdateGroupRPMRatio)
+				groupRouteWrite.POST("/:group/tpm_ratio", controller.UpdateGroupTPMRatio)
+
+				groupModelConfigsRoute := groupRouteWrite.Group("/:group/model_configs")
+				{
+					groupModelConfigsRoute.POST("/", controller.SaveGroupModelConfigs)
+					groupModelConfigsRoute.PUT("/", controller.UpdateGroupModelConfigs)
+					groupModelConfigsRoute.DELETE("/", controller.DeleteGroupModelConfigs)
+				}
+
+				groupModelConfigRoute := groupRouteWrite.Group("/:group/model_config")
+				{
+					groupModelConfigRoute.POST("/*model", controller.SaveGroupModelConfig)
+					groupModelConfigRoute.PUT("/*model", controller.UpdateGroupModelConfig)
+					groupModelConfigRoute.DELETE("/*model", controller.DeleteGroupModelConfig)
+				}
 			}
 
 			groupMcpRoute := groupRoute.Group("/:group/mcp")
+			groupMcpRoute.Use(middleware.RequirePermission(model.PermissionMCPRead))
 			{
 				groupMcpRoute.GET("/", mcp.GetGroupPublicMCPs)
 				groupMcpRoute.GET("/:id", mcp.GetGroupPublicMCPByID)
 			}
 		}
 
+		webhookRoute := apiRouter.Group("/webhook/:group")
+		webhookRoute.Use(middleware.RequirePermission(model.PermissionWebhookRead))
+		{
+			webhookRoute.GET("/subscriptions", controller.GetWebhookSubscriptions)
+			webhookRoute.GET("/subscription/:id", controller.GetWebhookSubscription)
+			webhookRoute.GET("/deliveries", controller.GetWebhookDeliveries)
+
+			webhookRouteWrite := webhookRoute.Group("")
+			webhookRouteWrite.Use(middleware.RequirePermission(model.PermissionWebhookWrite))
+			{
+				webhookRouteWrite.POST("/subscriptions", controller.CreateWebhookSubscription)
+				webhookRouteWrite.PUT("/subscription/:id", controller.UpdateWebhookSubscription)
+				webhookRouteWrite.DELETE("/subscription/:id", controller.DeleteWebhookSubscription)
+				webhookRouteWrite.POST("/delivery/:id/redeliver", controller.RedeliverWebhookDelivery)
+			}
+		}
+
+		budgetRoute := apiRouter.Group("/budget/:group")
+		budgetRoute.Use(middleware.RequirePermission(model.PermissionBudgetRead))
+		{
+			budgetRoute.GET("/budgets", controller.GetBudgets)
+			budgetRoute.GET("/budget/:id", controller.GetBudget)
+			budgetRoute.GET("/remaining", controller.GetRemainingBudgets)
+
+			budgetRouteWrite := budgetRoute.Group("")
+			budgetRouteWrite.Use(middleware.RequirePermission(model.PermissionBudgetWrite))
+			{
+				budgetRouteWrite.POST("/budgets", controller.CreateBudget)
+				budgetRouteWrite.PUT("/budget/:id", controller.UpdateBudget)
+				budgetRouteWrite.DELETE("/budget/:id", controller.DeleteBudget)
+			}
+		}
+
+		exchangeRatesRoute := apiRouter.Group("/exchange_rates")
+		exchangeRatesRoute.Use(middleware.RequirePermission(model.PermissionModelRead))
+		{
+			exchangeRatesRoute.GET("/", controller.GetExchangeRates)
+			exchangeRatesRoute.GET("/convert", controller.ConvertCurrency)
+			exchangeRatesRoute.POST("/",
+				middleware.RequirePermission(model.PermissionExchangeRateWrite),
+				controller.CreateExchangeRate,
+			)
+		}
+
+		exchangeRateRoute := apiRouter.Group("/exchange_rate")
+		exchangeRateRoute.Use(middleware.RequirePermission(model.PermissionModelRead))
+		{
+			exchangeRateRoute.GET("/:id", controller.GetExchangeRate)
+			exchangeRateRoute.DELETE("/:id",
+				middleware.RequirePermission(model.PermissionExchangeRateWrite),
+				controller.DeleteExchangeRate,
+			)
+		}
+
 		optionRoute := apiRouter.Group("/option")
+		optionRoute.Use(middleware.RequirePermission(model.PermissionOptionRead))
 		{
 			optionRoute.GET("/", controller.GetOptions)
 			optionRoute.GET("/:key", controller.GetOption)
-			optionRoute.PUT("/", controller.UpdateOption)
-			optionRoute.POST("/", controller.UpdateOption)
-			optionRoute.PUT("/:key", controller.UpdateOptionByKey)
-			optionRoute.POST("/batch", controller.UpdateOptions)
+
+			optionRouteWrite := optionRoute.Group("")
+			optionRouteWrite.Use(middleware.RequirePermission(model.PermissionOptionWrite))
+			{
+				optionRouteWrite.PUT("/", controller.UpdateOption)
+				optionRouteWrite.POST("/", controller.UpdateOption)
+				optionRouteWrite.PUT("/:key", controller.UpdateOptionByKey)
+				optionRouteWrite.POST("/batch", controller.UpdateOptions)
+			}
 		}
 
-		// Channels - admin only
 		channelsRoute := apiRouter.Group("/channels")
 		channelsRoute.GET("/type_metas", controller.ChannelTypeMetas)
-		channelsRoute.Use(middleware.RequireAdmin)
+		channelsRoute.Use(middleware.RequirePermission(model.PermissionChannelRead))
 		{
 			channelsRoute.GET("/", controller.GetChannels)
 			channelsRoute.GET("/all", controller.GetAllChannels)
 			// channelsRoute.GET("/type_metas", controller.ChannelTypeMetas)
-			channelsRoute.POST("/", controller.AddChannels)
 			channelsRoute.GET("/search", controller.SearchChannels)
-			channelsRoute.GET("/update_balance", controller.UpdateAllChannelsBalance)
-			channelsRoute.POST("/batch_delete", controller.DeleteChannels)
-			channelsRoute.GET("/test", controller.TestAllChannels)
 
-			importRoute := channelsRoute.Group("/import")
+			channelsRouteWrite := channelsRoute.Group("")
+			channelsRouteWrite.Use(middleware.RequirePermission(model.PermissionChannelWrite))
 			{
-				importRoute.POST("/oneapi", controller.ImportChannelFromOneAPI)
+				channelsRouteWrite.POST("/", controller.AddChannels)
+				channelsRouteWrite.GET("/update_balance", controller.UpdateAllChannelsBalance)
+				channelsRouteWrite.POST("/batch_delete", controller.DeleteChannels)
+				channelsRouteWrite.GET("/test", controller.TestAllChannels)
+
+				importRoute := channelsRouteWrite.Group("/import")
+				{
+					importRoute.POST("/oneapi", controller.ImportChannelFromOneAPI)
+				}
 			}
 		}
 
 		channelRoute := apiRouter.Group("/channel")
-		channelRoute.Use(middleware.RequireAdmin)
+		channelRoute.Use(middleware.RequirePermission(model.PermissionChannelRead))
 		{
 			channelRoute.GET("/:id", controller.GetChannel)
-			channelRoute.POST("/", controller.AddChannel)
-			channelRoute.PUT("/:id", controller.UpdateChannel)
-			channelRoute.POST("/:id/status", controller.UpdateChannelStatus)
-			channelRoute.DELETE("/:id", controller.DeleteChannel)
-			channelRoute.GET("/:id/test", controller.TestChannelModels)
-			channelRoute.GET("/:id/test/*model", controller.TestChannel)
-			channelRoute.GET("/:id/update_balance", controller.UpdateChannelBalance)
+
+			channelRouteWrite := channelRoute.Group("")
+			channelRouteWrite.Use(middleware.RequirePermission(model.PermissionChannelWrite))
+			{
+				channelRouteWrite.POST("/", controller.AddChannel)
+				channelRouteWrite.PUT("/:id", controller.UpdateChannel)
+				channelRouteWrite.POST("/:id/status", controller.UpdateChannelStatus)
+				channelRouteWrite.DELETE("/:id", controller.DeleteChannel)
+				channelRouteWrite.GET("/:id/test", controller.TestChannelModels)
+				channelRouteWrite.GET("/:id/test/*model", controller.TestChannel)
+				channelRouteWrite.GET("/:id/update_balance", controller.UpdateChannelBalance)
+			}
 		}
 
-		// Tokens - read for all (filtered by group), write for admin only
+		// Tokens - the reads are filtered by the groups of scoped callers,
+		// the writes by token id need an unscoped caller
 		tokensRoute := apiRouter.Group("/tokens")
 		{
-			// Read operations - all users (will be filtered by controller)
-			tokensRoute.GET("/", controller.GetTokens)
-			tokensRoute.GET("/:id", controller.GetToken)
-			tokensRoute.GET("/search", controller.SearchTokens)
-
-			// Write operations - admin only
-			tokensRouteAdmin := tokensRoute.Group("")
-			tokensRouteAdmin.Use(middleware.RequireAdmin)
+			tokensRouteRead := tokensRoute.Group("")
+			tokensRouteRead.Use(
+				middleware.FilterByUserGroup,
+				middleware.RequirePermission(model.PermissionTokenRead),
+			)
+			{
+				tokensRouteRead.GET("/", controller.GetTokens)
+				tokensRouteRead.GET("/:id", controller.GetToken)
+				tokensRouteRead.GET("/search", controller.SearchTokens)
+			}
+
+			tokensRouteWrite := tokensRoute.Group("")
+			tokensRouteWrite.Use(middleware.RequirePermission(model.PermissionTokenWrite))
 			{
-				tokensRouteAdmin.PUT("/:id", controller.UpdateToken)
-				tokensRouteAdmin.POST("/:id/status", controller.UpdateTokenStatus)
-				tokensRouteAdmin.POST("/:id/name", controller.UpdateTokenName)
-				tokensRouteAdmin.DELETE("/:id", controller.DeleteToken)
-				tokensRouteAdmin.POST("/batch_delete", controller.DeleteTokens)
+				tokensRouteWrite.PUT("/:id", controller.UpdateToken)
+				tokensRouteWrite.POST("/:id/status", controller.UpdateTokenStatus)
+				tokensRouteWrite.POST("/:id/name", controller.UpdateTokenName)
+				tokensRouteWrite.DELETE("/:id", controller.DeleteToken)
+				tokensRouteWrite.POST("/batch_delete", controller.DeleteTokens)
 			}
 		}
 
 		tokenRoute := apiRouter.Group("/token")
+		tokenRoute.Use(middleware.RequirePermission(model.PermissionTokenRead))
 		{
-			// Read operations - all users
 			tokenRoute.GET("/:group/search", controller.SearchGroupTokens)
 			tokenRoute.GET("/:group", controller.GetGroupTokens)
 			tokenRoute.GET("/:group/:id", controller.GetGroupToken)
 
-			// Write operations - admin only
-			tokenRouteAdmin := tokenRoute.Group("")
-			tokenRouteAdmin.Use(middleware.RequireAdmin)
+			tokenRouteWrite := tokenRoute.Group("")
+			tokenRouteWrite.Use(middleware.RequirePermission(model.PermissionTokenWrite))
 			{
-				tokenRouteAdmin.POST("/:group/batch_delete", controller.DeleteGroupTokens)
-				tokenRouteAdmin.POST("/:group", controller.AddGroupToken)
-				tokenRouteAdmin.PUT("/:group/:id", controller.UpdateGroupToken)
-				tokenRouteAdmin.POST("/:group/:id/status", controller.UpdateGroupTokenStatus)
-				tokenRouteAdmin.POST("/:group/:id/name", controller.UpdateGroupTokenName)
-				tokenRouteAdmin.DELETE("/:group/:id", controller.DeleteGroupToken)
+				tokenRouteWrite.POST("/:group/batch_delete", controller.DeleteGroupTokens)
+				tokenRouteWrite.POST("/:group", controller.AddGroupToken)
+				tokenRouteWrite.PUT("/:group/:id", controller.UpdateGroupToken)
+				tokenRouteWrite.POST("/:group/:id/status", controller.UpdateGroupTokenStatus)
+				tokenRouteWrite.POST("/:group/:id/name", controller.UpdateGroupTokenName)
+				tokenRouteWrite.DELETE("/:group/:id", controller.DeleteGroupToken)
 			}
 		}
 
-		// Logs - auto-filtered by user group
+		// Logs - auto-filtered by the groups of scoped callers
 		logsRoute := apiRouter.Group("/logs")
-		logsRoute.Use(middleware.FilterByUserGroup)
+		logsRoute.Use(
+			middleware.FilterByUserGroup,
+			middleware.RequirePermission(model.PermissionLogRead),
+		)
 		{
 			logsRoute.GET("/", controller.GetLogs)
 			logsRoute.GET("/search", controller.SearchLogs)
 			logsRoute.GET("/consume_error", controller.SearchConsumeError)
+			logsRoute.GET("/mcp", controller.SearchMCPLogs)
+			logsRoute.GET("/mcp/usage", controller.GetMCPUsage)
 			logsRoute.GET("/detail/:log_id", controller.GetLogDetail)
 
-			// Delete - admin only
-			logsRouteAdmin := logsRoute.Group("")
-			logsRouteAdmin.Use(middleware.RequireAdmin)
+			logsRouteWrite := logsRoute.Group("")
+			logsRouteWrite.Use(middleware.RequirePermission(model.PermissionLogWrite))
 			{
-				logsRouteAdmin.DELETE("/", controller.DeleteHistoryLogs)
+				logsRouteWrite.DELETE("/", controller.DeleteHistoryLogs)
 			}
 		}
 
+		auditLogsRoute := apiRouter.Group("/audit_logs")
+		auditLogsRoute.Use(middleware.RequirePermission(model.PermissionAuditRead))
+		{
+			auditLogsRoute.GET("/", controller.SearchAuditLogs)
+			auditLogsRoute.GET("/export", controller.ExportAuditLogs)
+		}
+
 		logRoute := apiRouter.Group("/log")
-		logRoute.Use(middleware.FilterByUserGroup)
+		logRoute.Use(
+			middleware.FilterByUserGroup,
+			middleware.RequirePermission(model.PermissionLogRead),
+		)
 		{
 			logRoute.GET("/:group", controller.GetGroupLogs)
 			logRoute.GET("/:group/search", controller.SearchGroupLogs)
+			logRoute.GET("/:group/mcp", controller.SearchGroupMCPLogs)
+			logRoute.GET("/:group/mcp/usage", controller.GetGroupMCPUsage)
 			logRoute.GET("/:group/detail/:log_id", controller.GetGroupLogDetail)
 		}
 
-		// Model configs - read for all, write for admin only
 		modelConfigsRoute := apiRouter.Group("/model_configs")
+		modelConfigsRoute.Use(middleware.RequirePermission(model.PermissionModelRead))
 		{
-			// Read operations - all users
 			modelConfigsRoute.GET("/", controller.GetModelConfigs)
 			modelConfigsRoute.GET("/search", controller.SearchModelConfigs)
 			modelConfigsRoute.GET("/all", controller.GetAllModelConfigs)
 			modelConfigsRoute.POST("/contains", controller.GetModelConfigsByModelsContains)
 
-			// Write operations - admin only
-			modelConfigsRouteAdmin := modelConfigsRoute.Group("")
-			modelConfigsRouteAdmin.Use(middleware.RequireAdmin)
+			modelConfigsRouteWrite := modelConfigsRoute.Group("")
+			modelConfigsRouteWrite.Use(middleware.RequirePermission(model.PermissionModelWrite))
 			{
-				modelConfigsRouteAdmin.POST("/", controller.SaveModelConfigs)
-				modelConfigsRouteAdmin.POST("/batch_delete", controller.DeleteModelConfigs)
+				modelConfigsRouteWrite.POST("/", controller.SaveModelConfigs)
+				modelConfigsRouteWrite.POST("/batch_delete", controller.DeleteModelConfigs)
 			}
 		}
 
 		modelConfigRoute := apiRouter.Group("/model_config")
+		modelConfigRoute.Use(middleware.RequirePermission(model.PermissionModelRead))
 		{
-			// Read operations - all users
 			modelConfigRoute.GET("/*model", controller.GetModelConfig)
 
-			// Write operations - admin only
-			modelConfigRouteAdmin := modelConfigRoute.Group("")
-			modelConfigRouteAdmin.Use(middleware.RequireAdmin)
+			modelConfigRouteWrite := modelConfigRoute.Group("")
+			modelConfigRouteWrite.Use(middleware.RequirePermission(model.PermissionModelWrite))
 			{
-				modelConfigRouteAdmin.POST("/*model", controller.SaveModelConfig)
-				modelConfigRouteAdmin.DELETE("/*model", controller.DeleteModelConfig)
+				modelConfigRouteWrite.POST("/*model", controller.SaveModelConfig)
+				modelConfigRouteWrite.DELETE("/*model", controller.DeleteModelConfig)
 			}
 		}
 
 		monitorRoute := apiRouter.Group("/monitor")
+		monitorRoute.Use(middleware.RequirePermission(model.PermissionMonitorRead))
 		{
 			monitorRoute.GET("/", controller.GetAllChannelModelErrorRates)
 			monitorRoute.GET("/:id", controller.GetChannelModelErrorRates)
-			monitorRoute.DELETE("/", controller.ClearAllModelErrors)
-			monitorRoute.DELETE("/:id", controller.ClearChannelAllModelErrors)
-			monitorRoute.DELETE("/:id/*model", controller.ClearChannelModelErrors)
 			monitorRoute.GET("/models", controller.GetModelsErrorRate)
 			monitorRoute.GET("/banned_channels", controller.GetAllBannedModelChannels)
-		}
 
-		publicsMcpRoute := apiRouter.Group("/mcp/publics")
-		{
-			publicsMcpRoute.GET("/", mcp.GetPublicMCPs)
-			publicsMcpRoute.GET("/all", mcp.GetAllPublicMCPs)
-			publicsMcpRoute.POST("/", mcp.SavePublicMCPs)
+			monitorRouteWrite := monitorRoute.Group("")
+			monitorRouteWrite.Use(middleware.RequirePermission(model.PermissionMonitorWrite))
+			{
+				monitorRouteWrite.DELETE("/", controller.ClearAllModelErrors)
+				monitorRouteWrite.DELETE("/:id", controller.ClearChannelAllModelErrors)
+				monitorRouteWrite.DELETE("/:id/*model", controller.ClearChannelModelErrors)
+			}
 		}
 
-		publicMcpRoute := apiRouter.Group("/mcp/public")
+		mcpRoute := apiRouter.Group("")
+		mcpRoute.Use(middleware.RequirePermission(model.PermissionMCPRead))
 		{
-			publicMcpRoute.GET("/:id", mcp.GetPublicMCPByID)
-			publicMcpRoute.POST("/", mcp.CreatePublicMCP)
-			publicMcpRoute.POST("/:id", mcp.UpdatePublicMCP)
-			publicMcpRoute.PUT("/:id", mcp.SavePublicMCP)
-			publicMcpRoute.DELETE("/:id", mcp.DeletePublicMCP)
-			publicMcpRoute.POST("/:id/status", mcp.UpdatePublicMCPStatus)
-			publicMcpRoute.GET("/:id/group/:group/params", mcp.GetGroupPublicMCPReusingParam)
-			publicMcpRoute.POST(
-				"/:id/group/:group/params",
-				mcp.SaveGroupPublicMCPReusingParam,
-			)
-		}
+			publicsMcpRoute := mcpRoute.Group("/mcp/publics")
+			{
+				publicsMcpRoute.GET("/", mcp.GetPublicMCPs)
+				publicsMcpRoute.GET("/all", mcp.GetAllPublicMCPs)
+			}
 
-		groupMcpRoute := apiRouter.Group("/mcp/group")
-		{
-			groupMcpRoute.GET("/:group", mcp.GetGroupMCPs)
-			groupMcpRoute.GET("/all", mcp.GetAllGroupMCPs)
-			groupMcpRoute.GET("/:group/:id", mcp.GetGroupMCPByID)
-			groupMcpRoute.POST("/:group", mcp.CreateGroupMCP)
-			groupMcpRoute.PUT("/:group/:id", mcp.UpdateGroupMCP)
-			groupMcpRoute.DELETE("/:group/:id", mcp.DeleteGroupMCP)
-			groupMcpRoute.POST("/:group/:id/status", mcp.UpdateGroupMCPStatus)
-		}
+			publicMcpRoute := mcpRoute.Group("/mcp/public")
+			{
+				publicMcpRoute.GET("/:id", mcp.GetPublicMCPByID)
+				publicMcpRoute.GET("/:id/group/:group/params", mcp.GetGroupPublicMCPReusingParam)
+			}
 
-		embedMcpRoute := apiRouter.Group("/embedmcp")
-		{
-			embedMcpRoute.GET("/", mcp.GetEmbedMCPs)
-			embedMcpRoute.POST("/", mcp.SaveEmbedMCP)
-		}
+			groupMcpRoute := mcpRoute.Group("/mcp/group")
+			{
+				groupMcpRoute.GET("/:group", mcp.GetGroupMCPs)
+				groupMcpRoute.GET("/all", mcp.GetAllGroupMCPs)
+				groupMcpRoute.GET("/:group/:id", mcp.GetGroupMCPByID)
+			}
 
-		testEmbedMcpRoute := apiRouter.Group("/test-embedmcp")
-		{
-			testEmbedMcpRoute.GET("/:id/sse", mcp.TestEmbedMCPSseServer)
-			testEmbedMcpRoute.GET("/:id", mcp.TestEmbedMCPStreamable)
-			testEmbedMcpRoute.POST("/:id", mcp.TestEmbedMCPStreamable)
-			testEmbedMcpRoute.DELETE("/:id", mcp.TestEmbedMCPStreamable)
+			virtualMcpRoute := mcpRoute.Group("/mcp/virtual")
+			{
+				virtualMcpRoute.GET("/:group", mcp.GetVirtualMCPs)
+				virtualMcpRoute.GET("/:group/:id", mcp.GetVirtualMCPByID)
+			}
+
+			embedMcpRoute := mcpRoute.Group("/embedmcp")
+			{
+				embedMcpRoute.GET("/", mcp.GetEmbedMCPs)
+			}
+
+			mcpRouteWrite := mcpRoute.Group("")
+			mcpRouteWrite.Use(middleware.RequirePermission(model.PermissionMCPWrite))
+			{
+				mcpRouteWrite.POST("/mcp/publics", mcp.SavePublicMCPs)
+
+				publicMcpRouteWrite := mcpRouteWrite.Group("/mcp/public")
+				{
+					publicMcpRouteWrite.POST("/", mcp.CreatePublicMCP)
+					publicMcpRouteWrite.POST("/:id", mcp.UpdatePublicMCP)
+					publicMcpRouteWrite.PUT("/:id", mcp.SavePublicMCP)
+					publicMcpRouteWrite.DELETE("/:id", mcp.DeletePublicMCP)
+					publicMcpRouteWrite.POST("/:id/status", mcp.UpdatePublicMCPStatus)
+					publicMcpRouteWrite.POST(
+						"/:id/group/:group/params",
+						mcp.SaveGroupPublicMCPReusingParam,
+					)
+				}
+
+				groupMcpRouteWrite := mcpRouteWrite.Group("/mcp/group")
+				{
+					groupMcpRouteWrite.POST("/:group", mcp.CreateGroupMCP)
+					groupMcpRouteWrite.PUT("/:group/:id", mcp.UpdateGroupMCP)
+					groupMcpRouteWrite.DELETE("/:group/:id", mcp.DeleteGroupMCP)
+					groupMcpRouteWrite.POST("/:group/:id/status", mcp.UpdateGroupMCPStatus)
+				}
+
+				virtualMcpRouteWrite := mcpRouteWrite.Group("/mcp/virtual")
+				{
+					virtualMcpRouteWrite.POST("/:group", mcp.CreateVirtualMCP)
+					virtualMcpRouteWrite.PUT("/:group/:id", mcp.UpdateVirtualMCP)
+					virtualMcpRouteWrite.DELETE("/:group/:id", mcp.DeleteVirtualMCP)
+					virtualMcpRouteWrite.POST("/:group/:id/status", mcp.UpdateVirtualMCPStatus)
+				}
+
+				mcpRouteWrite.POST("/embedmcp", mcp.SaveEmbedMCP)
+
+				testEmbedMcpRoute := mcpRouteWrite.Group("/test-embedmcp")
+				{
+					testEmbedMcpRoute.GET("/:id/sse", mcp.TestEmbedMCPSseServer)
+					testEmbedMcpRoute.GET("/:id", mcp.TestEmbedMCPStreamable)
+					testEmbedMcpRoute.POST("/:id", mcp.TestEmbedMCPStreamable)
+					testEmbedMcpRoute.DELETE("/:id", mcp.TestEmbedMCPStreamable)
+				}
+
+				testPublicMcpRoute := mcpRouteWrite.Group("/test-publicmcp")
+				{
+					testPublicMcpRoute.GET("/:group/:id/sse", mcp.TestPublicMCPSSEServer)
+				}
+			}
 		}
 
-		testPublicMcpRoute := apiRouter.Group("/test-publicmcp")
+		adminKeysRoute := apiRouter.Group("/admin_keys")
+		adminKeysRoute.Use(middleware.RequirePermission(model.PermissionAdminKeyWrite))
 		{
-			testPublicMcpRoute.GET("/:group/:id/sse", mcp.TestPublicMCPSSEServer)
+			adminKeysRoute.GET("/", controller.GetAdminKeys)
+			adminKeysRoute.POST("/", controller.CreateAdminKey)
+			adminKeysRoute.GET("/:id", controller.GetAdminKey)
+			adminKeysRoute.PUT("/:id", controller.UpdateAdminKey)
+			adminKeysRoute.DELETE("/:id", controller.DeleteAdminKey)
+			adminKeysRoute.POST("/:id/status", controller.UpdateAdminKeyStatus)
 		}
 	}
 }
@@ -2,11 +2,14 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/controller"
 	mcp "github.com/wavespeed/llm-server/core/controller/mcp"
 	"github.com/wavespeed/llm-server/core/middleware"
 )
 
 func SetMCPRouter(router *gin.Engine) {
+	mcp.SetSampling(controller.MCPSampling)
+
 	mcpRoute := router.Group("/mcp", middleware.MCPAuth)
 
 	mcpRoute.GET("/public/:id/sse", mcp.PublicMCPSSEServer)
@@ -19,6 +22,11 @@ func SetMCPRouter(router *gin.Engine) {
 	mcpRoute.POST("/group/:id", mcp.GroupMCPStreamable)
 	mcpRoute.DELETE("/group/:id", mcp.GroupMCPStreamable)
 
+	mcpRoute.GET("/virtual/:id/sse", mcp.VirtualMCPSSEServer)
+	mcpRoute.GET("/virtual/:id", mcp.VirtualMCPStreamable)
+	mcpRoute.POST("/virtual/:id", mcp.VirtualMCPStreamable)
+	mcpRoute.DELETE("/virtual/:id", mcp.VirtualMCPStreamable)
+
 	router.GET("/sse", middleware.MCPAuth, mcp.HostMCPSSEServer)
 	router.POST("/message", mcp.MCPMessage)
 	router.GET("/mcp", middleware.MCPAuth, mcp.HostMCPStreamable)
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wavespeed/llm-server/core/controller"
+	"github.com/wavespeed/llm-server/core/middleware"
+	"github.com/wavespeed/llm-server/core/model"
+)
+
+func SetMetricsRouter(router *gin.Engine) {
+	router.GET(
+		"/metrics",
+		middleware.AdminAuth,
+		middleware.RequirePermission(model.PermissionMonitorRead),
+		controller.Metrics,
+	)
+}
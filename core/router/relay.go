@@ -20,6 +20,12 @@ func SetRelayRouter(router *gin.Engine) {
 		modelsRouter.GET("/:model", controller.RetrieveModel)
 	}
 
+	tokenizeRouter := v1Router.Group("")
+	{
+		tokenizeRouter.POST("/tokenize", controller.Tokenize)
+		tokenizeRouter.POST("/count_tokens", controller.CountTokens)
+	}
+
 	// gemini
 	{
 		v1Router.POST(
@@ -53,6 +59,14 @@ func SetRelayRouter(router *gin.Engine) {
 			"/messages",
 			controller.Anthropic()...,
 		)
+		relayRouter.POST(
+			"/messages/count_tokens",
+			controller.AnthropicCountTokens()...,
+		)
+		relayRouter.GET(
+			"/realtime",
+			controller.Realtime()...,
+		)
 		relayRouter.POST(
 			"/images/edits",
 			controller.ImagesEdits()...,
@@ -116,18 +130,25 @@ func SetRelayRouter(router *gin.Engine) {
 		relayRouter.GET(
 			"/responses/:response_id/input_items",
 			controller.GetResponseInputItems()...)
+		relayRouter.POST("/files", controller.UploadFile()...)
+		relayRouter.GET("/files", controller.ListFiles()...)
+		relayRouter.GET("/files/:id", controller.RetrieveFile()...)
+		relayRouter.DELETE("/files/:id", controller.DeleteFile()...)
+		relayRouter.GET("/files/:id/content", controller.RetrieveFileContent()...)
+		relayRouter.POST("/batches", controller.CreateBatch()...)
+		relayRouter.GET("/batches", controller.ListBatches()...)
+		relayRouter.GET("/batches/:id", controller.RetrieveBatch()...)
+		relayRouter.POST("/batches/:id/cancel", controller.CancelBatch()...)
+		relayRouter.POST("/fine_tuning/jobs", controller.CreateFineTuningJob()...)
+		relayRouter.GET("/fine_tuning/jobs", controller.ListFineTuningJobs()...)
+		relayRouter.GET("/fine_tuning/jobs/:id", controller.RetrieveFineTuningJob()...)
+		relayRouter.POST("/fine_tuning/jobs/:id/cancel", controller.CancelFineTuningJob()...)
+		relayRouter.GET("/fine_tuning/jobs/:id/events", controller.ListFineTuningJobEvents()...)
+		relayRouter.GET(
+			"/fine_tuning/jobs/:id/checkpoints",
+			controller.ListFineTuningJobCheckpoints()...)
 
 		relayRouter.POST("/images/variations", controller.RelayNotImplemented)
-		relayRouter.GET("/files", controller.RelayNotImplemented)
-		relayRouter.POST("/files", controller.RelayNotImplemented)
-		relayRouter.DELETE("/files/:id", controller.RelayNotImplemented)
-		relayRouter.GET("/files/:id", controller.RelayNotImplemented)
-		relayRouter.GET("/files/:id/content", controller.RelayNotImplemented)
-		relayRouter.POST("/fine_tuning/jobs", controller.RelayNotImplemented)
-		relayRouter.GET("/fine_tuning/jobs", controller.RelayNotImplemented)
-		relayRouter.GET("/fine_tuning/jobs/:id", controller.RelayNotImplemented)
-		relayRouter.POST("/fine_tuning/jobs/:id/cancel", controller.RelayNotImplemented)
-		relayRouter.GET("/fine_tuning/jobs/:id/events", controller.RelayNotImplemented)
 		relayRouter.DELETE("/models/:model", controller.RelayNotImplemented)
 		relayRouter.POST("/assistants", controller.RelayNotImplemented)
 		relayRouter.GET("/assistants/:id", controller.RelayNotImplemented)
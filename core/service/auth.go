@@ -9,6 +9,7 @@ import (
 	"github.com/wavespeed/llm-server/core/common/wavespeed"
 	"github.com/wavespeed/llm-server/core/model"
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 const (
@@ -23,15 +24,17 @@ type LoginRequest struct {
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	Success  bool    `json:"success"`
-	UserType string  `json:"user_type"` // "admin" or "regular"
-	Token    string  `json:"token"`
-	Balance  float64 `json:"balance,omitempty"`
-	Message  string  `json:"message,omitempty"`
+	Success  bool       `json:"success"`
+	UserType string     `json:"user_type"` // "admin" or "regular"
+	Role     model.Role `json:"role,omitempty"`
+	Groups   []string   `json:"groups,omitempty"`
+	Token    string     `json:"token"`
+	Balance  float64    `json:"balance,omitempty"`
+	Message  string     `json:"message,omitempty"`
 }
 
 // Login handles the login logic
-// 1. Check if token is ADMIN_KEY -> return admin
+// 1. Check if token is ADMIN_KEY or an admin key -> return admin with its role
 // 2. Validate against WaveSpeed API -> if invalid, return error
 // 3. Check if token exists in local DB -> if not, create it
 // 4. Sync balance from WaveSpeed
@@ -47,10 +50,31 @@ func Login(ctx context.Context, token string) (*LoginResponse, error) {
 		return &LoginResponse{
 			Success:  true,
 			UserType: UserTypeAdmin,
+			Role:     model.RoleSuperAdmin,
 			Token:    token,
 		}, nil
 	}
 
+	adminKey, err := model.GetEnabledAdminKeyByKey(token)
+	switch {
+	case err == nil:
+		log.WithFields(log.Fields{
+			"user_type": UserTypeAdmin,
+			"admin":     adminKey.Name,
+			"role":      adminKey.Role,
+		}).Info("Admin key login successful")
+
+		return &LoginResponse{
+			Success:  true,
+			UserType: UserTypeAdmin,
+			Role:     model.Role(adminKey.Role),
+			Groups:   adminKey.Groups,
+			Token:    token,
+		}, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		log.WithError(err).Error("Failed to get admin key")
+	}
+
 	// Step 2: Validate against WaveSpeed API
 	client := wavespeed.NewClient()
 	balanceResp, err := client.ValidateAPIKey(ctx, token)
@@ -17,9 +17,11 @@ import (
 	"github.com/wavespeed/llm-server/core/common"
 	"github.com/wavespeed/llm-server/core/common/balance"
 	"github.com/wavespeed/llm-server/core/common/config"
+	"github.com/wavespeed/llm-server/core/common/filestorage"
 	"github.com/wavespeed/llm-server/core/common/conv"
 	"github.com/wavespeed/llm-server/core/common/notify"
 	"github.com/wavespeed/llm-server/core/common/pprof"
+	"github.com/wavespeed/llm-server/core/common/tracing"
 	"github.com/wavespeed/llm-server/core/middleware"
 	"github.com/wavespeed/llm-server/core/model"
 	"github.com/wavespeed/llm-server/core/router"
@@ -47,9 +49,33 @@ func initializeServices(pprofPort int) error {
 		return err
 	}
 
+	if err := filestorage.Init(); err != nil {
+		return err
+	}
+
 	return model.InitLogDB(int(config.GetCleanLogBatchSize()))
 }
 
+// initializeTracing starts the trace exporter, the returned function flushes
+// the pending spans on shutdown
+func initializeTracing() func(context.Context) {
+	shutdown, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Errorf("init tracing failed: %v", err)
+		return func(context.Context) {}
+	}
+
+	if tracing.Enabled() {
+		log.Info("OTEL_EXPORTER_OTLP_ENDPOINT is set, traces will be exported")
+	}
+
+	return func(ctx context.Context) {
+		if err := shutdown(ctx); err != nil {
+			log.Errorf("shutdown tracing failed: %v", err)
+		}
+	}
+}
+
 func initializePprof(pprofPort int) {
 	go func() {
 		err := pprof.RunPprofServer(pprofPort)
@@ -72,11 +98,18 @@ func initializeBalance() error {
 }
 
 func initializeNotifier() {
-	feishuWh := os.Getenv("NOTIFY_FEISHU_WEBHOOK")
-	if feishuWh != "" {
-		notify.SetDefaultNotifier(notify.NewFeishuNotify(feishuWh))
-		log.Info("NOTIFY_FEISHU_WEBHOOK is set, notifier will be use feishu")
+	routes := notify.RoutesFromEnv()
+	if len(routes) == 0 {
+		return
 	}
+
+	names := make([]string, 0, len(routes))
+	for _, route := range routes {
+		names = append(names, route.Name)
+	}
+
+	notify.SetDefaultNotifier(notify.NewMultiNotifier(routes...))
+	log.Infof("notifiers are enabled: %s", strings.Join(names, ", "))
 }
 
 func initializeOptionAndCaches() error {
@@ -34,6 +34,21 @@ func AutoTestBannedModelsTask(ctx context.Context) {
 	}
 }
 
+// BatchTask 执行待处理的批量任务
+func BatchTask(ctx context.Context) {
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			controller.RunPendingBatches(ctx)
+		}
+	}
+}
+
 // DetectIPGroupsTask 检测 IP 使用多个 group 的情况
 func DetectIPGroupsTask(ctx context.Context) {
 	ticker := time.NewTicker(time.Minute)
@@ -197,6 +212,19 @@ func checkUsageAlert() {
 		return
 	}
 
+	for _, alert := range validAlerts {
+		model.EmitWebhookEvent(
+			alert.GroupID,
+			model.WebhookEventGroupUsageSpike,
+			"",
+			map[string]any{
+				"three_day_avg_amount": alert.ThreeDayAvgAmount,
+				"today_amount":         alert.TodayAmount,
+				"ratio":                alert.Ratio,
+			},
+		)
+	}
+
 	message := formatGroupUsageAlerts(validAlerts)
 	notify.Warn(
 		fmt.Sprintf("Detected %d groups with abnormal usage", len(validAlerts)),
@@ -224,6 +252,47 @@ func formatGroupUsageAlerts(alerts []model.GroupUsageAlertItem) string {
 	return result.String()
 }
 
+// FineTuningTask 轮询未计费的微调任务
+func FineTuningTask(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute * 10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !trylock.Lock("pollFineTuningJobs", time.Minute*10) {
+				continue
+			}
+
+			controller.PollFineTuningJobs(ctx)
+		}
+	}
+}
+
+// WebhookRetryTask 重试失败的 webhook 投递
+func WebhookRetryTask(ctx context.Context) {
+	ticker := time.NewTicker(time.Second * 15)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := model.RetryWebhookDeliveries(100); err != nil {
+				notify.ErrorThrottle(
+					"webhookRetryError",
+					time.Minute*5,
+					"retry webhook deliveries failed",
+					err.Error(),
+				)
+			}
+		}
+	}
+}
+
 // CleanLogTask 清理日志任务
 func CleanLogTask(ctx context.Context) {
 	// the interval should not be too large to avoid cleaning too much at once
@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"alibabacloud-dms",
 			"Alibaba Cloud DMS",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("阿里云DMS"),
 			mcpservers.WithGitHubURL(
 				"https://github.com/aliyun/alibabacloud-dms-mcp-server",
@@ -31,6 +31,26 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"AI 首选的统一数据访问通道，支持30多种数据源(阿里云全系/主流数据库/数仓)的安全访问。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "uvx",
+				Args:    []string{"alibabacloud-dms-mcp-server@latest"},
+				Reusing: map[string]model.ReusingParam{
+					"ALIBABA_CLOUD_ACCESS_KEY_ID": {
+						Name:        "Access Key ID",
+						Description: "The Alibaba Cloud access key id",
+						Required:    true,
+					},
+					"ALIBABA_CLOUD_ACCESS_KEY_SECRET": {
+						Name:        "Access Key Secret",
+						Description: "The Alibaba Cloud access key secret",
+						Required:    true,
+					},
+					"ALIBABA_CLOUD_SECURITY_TOKEN": {
+						Name:        "Security Token",
+						Description: "The STS security token, required with an STS access key",
+					},
+				},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
@@ -16,12 +16,41 @@ func init() {
 		mcpservers.NewMcp(
 			"alipay",
 			"Alipay",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("支付宝"),
 			mcpservers.WithTags([]string{"pay"}),
 			mcpservers.WithDescription(
 				"支付宝 MCP Server，让你可以轻松将支付宝开放平台提供的交易创建、查询、退款等能力集成到你的 LLM 应用中，并进一步创建具备支付能力的智能工具。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "npx",
+				Args:    []string{"-y", "@alipay/mcp-server-alipay"},
+				Reusing: map[string]model.ReusingParam{
+					"AP_APP_ID": {
+						Name:        "App ID",
+						Description: "The app id of the Alipay open platform",
+						Required:    true,
+					},
+					"AP_APP_KEY": {
+						Name:        "App Private Key",
+						Description: "The private key of the app",
+						Required:    true,
+					},
+					"AP_PUB_KEY": {
+						Name:        "Alipay Public Key",
+						Description: "The public key of Alipay",
+						Required:    true,
+					},
+					"AP_RETURN_URL": {
+						Name:        "Return URL",
+						Description: "The page opened after a payment",
+					},
+					"AP_NOTIFY_URL": {
+						Name:        "Notify URL",
+						Description: "The url notified of the payments",
+					},
+				},
+			}),
 			mcpservers.WithReadme(readme),
 		),
 	)
@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"allvoicelab",
 			"AllVoiceLab",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("趣丸千音"),
 			mcpservers.WithTags([]string{"voice"}),
 			mcpservers.WithGitHubURL("https://github.com/allvoicelab/AllVoiceLab-MCP"),
@@ -29,6 +29,27 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"官方 AllVoiceLab 模型上下文协议(MCP)服务器，支持与强大的文本转语音和视频翻译API交互。允许MCP客户端如Claude Desktop、Cursor、Windsurf、OpenAI Agents等生成语音、翻译视频、智能变声等功能。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "uvx",
+				Args:    []string{"allvoicelab-mcp"},
+				Reusing: map[string]model.ReusingParam{
+					"ALLVOICELAB_API_KEY": {
+						Name:        "API Key",
+						Description: "The AllVoiceLab API key",
+						Required:    true,
+					},
+					"ALLVOICELAB_API_DOMAIN": {
+						Name:        "API Domain",
+						Description: "The AllVoiceLab API domain",
+						Required:    true,
+					},
+					"ALLVOICELAB_BASE_PATH": {
+						Name:        "Output Directory",
+						Description: "The directory of the output files, relative to the directory of the group on the gateway",
+					},
+				},
+				Dirs: []string{"ALLVOICELAB_BASE_PATH"},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
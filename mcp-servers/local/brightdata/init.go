@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"brightdata",
 			"Bright Data",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("Bright Data"),
 			mcpservers.WithTags([]string{"web-scraping", "data-extraction", "proxy"}),
 			mcpservers.WithGitHubURL(
@@ -31,6 +31,25 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"使用实时网络数据增强AI代理。让LLM、代理和应用程序能够实时访问、发现和提取网络数据，允许无缝的网络搜索、网站导航和数据检索而不被阻止。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "npx",
+				Args:    []string{"-y", "@brightdata/mcp"},
+				Reusing: map[string]model.ReusingParam{
+					"API_TOKEN": {
+						Name:        "API Token",
+						Description: "The Bright Data API token",
+						Required:    true,
+					},
+					"WEB_UNLOCKER_ZONE": {
+						Name:        "Web Unlocker Zone",
+						Description: "The web unlocker zone, mcp_unlocker by default",
+					},
+					"BROWSER_ZONE": {
+						Name:        "Browser Zone",
+						Description: "The browser zone, mcp_browser by default",
+					},
+				},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
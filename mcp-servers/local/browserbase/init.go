@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"browserbase",
 			"BrowserBase Cloud Browser Automation",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("BrowserBase云浏览器自动化"),
 			mcpservers.WithTags([]string{"browser"}),
 			mcpservers.WithGitHubURL(
@@ -31,6 +31,22 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"该服务器使用 Browserbase、Puppeteer 和 Stagehand 提供云浏览器自动化功能。此服务器使大型语言模型（LLMs）能够与网页交互、截屏以及在云浏览器环境中执行 JavaScript。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "npx",
+				Args:    []string{"-y", "@browserbasehq/mcp"},
+				Reusing: map[string]model.ReusingParam{
+					"BROWSERBASE_API_KEY": {
+						Name:        "API Key",
+						Description: "The Browserbase API key",
+						Required:    true,
+					},
+					"BROWSERBASE_PROJECT_ID": {
+						Name:        "Project ID",
+						Description: "The Browserbase project id",
+						Required:    true,
+					},
+				},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"elasticsearch",
 			"Elasticsearch",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithTags([]string{"database"}),
 			mcpservers.WithGitHubURL("https://github.com/elastic/mcp-server-elasticsearch"),
 			mcpservers.WithDescription(
@@ -28,6 +28,22 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"将Claude和其他MCP客户端连接到Elasticsearch数据，允许用户通过自然语言对话与其Elasticsearch索引进行交互。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "npx",
+				Args:    []string{"-y", "@elastic/mcp-server-elasticsearch"},
+				Reusing: map[string]model.ReusingParam{
+					"ES_URL": {
+						Name:        "Elasticsearch URL",
+						Description: "The url of the Elasticsearch cluster",
+						Required:    true,
+					},
+					"ES_API_KEY": {
+						Name:        "API Key",
+						Description: "The Elasticsearch API key",
+						Required:    true,
+					},
+				},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"filesystem",
 			"Filesystem",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("文件系统"),
 			mcpservers.WithTags([]string{"filesystem"}),
 			mcpservers.WithGitHubURL(
@@ -31,6 +31,18 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"实现用于文件系统操作的模型上下文协议（MCP）的 Node.js 服务器。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "npx",
+				Args:    []string{"-y", "@modelcontextprotocol/server-filesystem", "${ALLOWED_DIR}"},
+				Reusing: map[string]model.ReusingParam{
+					"ALLOWED_DIR": {
+						Name:        "Allowed Directory",
+						Description: "The directory the server can access, relative to the directory of the group on the gateway",
+						Required:    true,
+					},
+				},
+				Dirs: []string{"ALLOWED_DIR"},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"git",
 			"Git",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("Git"),
 			mcpservers.WithTags([]string{"git"}),
 			mcpservers.WithGitHubURL(
@@ -31,6 +31,18 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"一个用于Git操作的Node.js MCP服务器。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "uvx",
+				Args:    []string{"mcp-server-git", "--repository", "${GIT_REPOSITORY}"},
+				Reusing: map[string]model.ReusingParam{
+					"GIT_REPOSITORY": {
+						Name:        "Git Repository",
+						Description: "The path of the git repository, relative to the directory of the group on the gateway",
+						Required:    true,
+					},
+				},
+				Dirs: []string{"GIT_REPOSITORY"},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"mysql",
 			"MySQL",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("MySQL"),
 			mcpservers.WithGitHubURL(
 				"https://github.com/designcomputer/mysql_mcp_server",
@@ -31,6 +31,36 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"允许AI助手通过受控接口列出表、读取数据和执行SQL查询，使数据库的探索和分析更加安全和有结构。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "uvx",
+				Args:    []string{"--from", "mysql-mcp-server", "mysql_mcp_server"},
+				Reusing: map[string]model.ReusingParam{
+					"MYSQL_HOST": {
+						Name:        "Host",
+						Description: "The host of the MySQL server",
+						Required:    true,
+					},
+					"MYSQL_PORT": {
+						Name:        "Port",
+						Description: "The port of the MySQL server, 3306 by default",
+					},
+					"MYSQL_USER": {
+						Name:        "User",
+						Description: "The MySQL user",
+						Required:    true,
+					},
+					"MYSQL_PASSWORD": {
+						Name:        "Password",
+						Description: "The password of the MySQL user",
+						Required:    true,
+					},
+					"MYSQL_DATABASE": {
+						Name:        "Database",
+						Description: "The MySQL database",
+						Required:    true,
+					},
+				},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"playwright",
 			"Microsoft Playwright",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("微软Playwright"),
 			mcpservers.WithTags([]string{"browser"}),
 			mcpservers.WithGitHubURL(
@@ -31,6 +31,10 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"使大型语言模型能够通过结构化的可访问性快照与网页交互，而无需使用视觉模型或截图。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "npx",
+				Args:    []string{"-y", "@playwright/mcp@latest", "--headless", "--isolated"},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"sqlite",
 			"SQLite",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("SQLite"),
 			mcpservers.WithTags([]string{"database"}),
 			mcpservers.WithGitHubURL(
@@ -31,6 +31,18 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"一种模型上下文协议（MCP）服务器实现，通过SQLite提供数据库交互和商业智能功能。该服务器支持运行SQL查询、分析业务数据以及自动生成业务洞察备忘录。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "uvx",
+				Args:    []string{"mcp-server-sqlite", "--db-path", "${SQLITE_DB_PATH}"},
+				Reusing: map[string]model.ReusingParam{
+					"SQLITE_DB_PATH": {
+						Name:        "SQLite DB Path",
+						Description: "The path of the sqlite database, relative to the directory of the group on the gateway",
+						Required:    true,
+					},
+				},
+				Files: []string{"SQLITE_DB_PATH"},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
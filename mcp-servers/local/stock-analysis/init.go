@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"stock-analysis",
 			"Stock Analysis",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("股票分析"),
 			mcpservers.WithGitHubURL(
 				"https://github.com/giptilabs/mcp-stock-analysis",
@@ -31,6 +31,10 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"通过Yahoo Finance API提供对实时和历史印度股票数据的访问，使本地LLM能够通过与MCP兼容的代理（如Claude Desktop和Cursor）检索股票信息。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "npx",
+				Args:    []string{"-y", "mcp-stock-analysis"},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
@@ -19,7 +19,7 @@ func init() {
 		mcpservers.NewMcp(
 			"wecom-bot",
 			"WeCom Bot",
-			model.PublicMCPTypeDocs,
+			model.PublicMCPTypeStdio,
 			mcpservers.WithNameCN("企业微信机器人"),
 			mcpservers.WithGitHubURL(
 				"https://github.com/mamertofabian/mcp-wecom-bot",
@@ -31,6 +31,17 @@ func init() {
 			mcpservers.WithDescriptionCN(
 				"一个使用FastMCP通过企业微信机器人发送消息的服务器，支持通过Webhook进行异步通信和消息追踪。",
 			),
+			mcpservers.WithStdioConfig(&model.MCPStdioConfig{
+				Command: "uvx",
+				Args:    []string{"wecom-bot-mcp-server"},
+				Reusing: map[string]model.ReusingParam{
+					"WECOM_WEBHOOK_URL": {
+						Name:        "Webhook URL",
+						Description: "The webhook url of the WeCom bot",
+						Required:    true,
+					},
+				},
+			}),
 			mcpservers.WithReadme(readme),
 			mcpservers.WithReadmeCN(readmeCN),
 		),
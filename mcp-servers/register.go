@@ -87,6 +87,10 @@ func Register(mcp McpServer) {
 		if len(mcp.ProxyConfigTemplates) == 0 {
 			panic(fmt.Sprintf("mcp %s proxy config templates is required", mcp.ID))
 		}
+	case model.PublicMCPTypeStdio:
+		if mcp.StdioConfig == nil || mcp.StdioConfig.Command == "" {
+			panic(fmt.Sprintf("mcp %s stdio config is required", mcp.ID))
+		}
 	default:
 	}
 
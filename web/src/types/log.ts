@@ -17,6 +17,8 @@ export interface LogPrice {
   thinking_mode_output_price_unit: number
   web_search_price: number
   web_search_price_unit: number
+  training_price?: number
+  training_price_unit?: number
 }
 
 // 使用情况
@@ -29,6 +31,7 @@ export interface LogUsage {
   reasoning_tokens: number
   total_tokens: number
   web_search_count: number
+  trained_tokens?: number
 }
 
 // 请求详情
@@ -25,6 +25,8 @@ export interface ModelPrice {
     image_output_price_unit?: number
     web_search_price?: number
     web_search_price_unit?: number
+    training_price?: number
+    training_price_unit?: number
 }
 
 export interface ModelConfig {
@@ -43,6 +45,7 @@ export interface ModelConfig {
     timeout?: number
     max_error_rate?: number
     force_save_detail?: boolean
+    fallback_models?: string[]
     plugin: Plugin
 }
 